@@ -0,0 +1,2 @@
+-- +migrate Down
+DROP TABLE IF EXISTS order_access_log;
\ No newline at end of file
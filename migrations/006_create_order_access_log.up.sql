@@ -0,0 +1,7 @@
+-- +migrate Up
+CREATE TABLE order_access_log (
+    order_uid TEXT PRIMARY KEY,
+    hits BIGINT NOT NULL DEFAULT 0,
+    last_accessed TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+CREATE INDEX order_access_log_hits_idx ON order_access_log (hits DESC);
\ No newline at end of file
@@ -0,0 +1,2 @@
+-- +migrate Down
+DROP TABLE IF EXISTS api_keys;
\ No newline at end of file
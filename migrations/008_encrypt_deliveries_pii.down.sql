@@ -0,0 +1,18 @@
+-- +migrate Down
+-- откат удаляет шифротекст и ключи данных: пока есть зашифрованные строки, он запрещён.
+-- Сначала остановите запись с PII_KEY_FILE и выполните `order-service pii decrypt`
+DO $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM deliveries WHERE data_key_id IS NOT NULL) THEN
+        RAISE EXCEPTION 'в deliveries есть зашифрованные строки: выполните order-service pii decrypt перед откатом';
+    END IF;
+END $$;
+ALTER TABLE deliveries
+    DROP COLUMN IF EXISTS data_key_id,
+    DROP COLUMN IF EXISTS name_enc,
+    DROP COLUMN IF EXISTS phone_enc,
+    DROP COLUMN IF EXISTS address_enc,
+    DROP COLUMN IF EXISTS email_enc,
+    DROP COLUMN IF EXISTS phone_bidx,
+    DROP COLUMN IF EXISTS email_bidx;
+DROP TABLE IF EXISTS data_keys;
\ No newline at end of file
@@ -0,0 +1,23 @@
+-- +migrate Up
+-- ключи данных для шифрования персональных данных, обёрнуты мастер-ключом из файла PII_KEY_FILE
+CREATE TABLE data_keys (
+    id BIGSERIAL PRIMARY KEY,
+    master_key_id TEXT NOT NULL,
+    wrapped_key BYTEA NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    rewrapped_at TIMESTAMPTZ
+);
+
+-- шифротекст и слепые индексы доставки; существующие строки шифрует `order-service pii encrypt`,
+-- он же обнуляет открытые колонки name, phone, address, email
+ALTER TABLE deliveries
+    ADD COLUMN data_key_id BIGINT REFERENCES data_keys(id),
+    ADD COLUMN name_enc BYTEA,
+    ADD COLUMN phone_enc BYTEA,
+    ADD COLUMN address_enc BYTEA,
+    ADD COLUMN email_enc BYTEA,
+    ADD COLUMN phone_bidx TEXT,
+    ADD COLUMN email_bidx TEXT;
+CREATE INDEX deliveries_phone_bidx_idx ON deliveries (phone_bidx);
+CREATE INDEX deliveries_email_bidx_idx ON deliveries (email_bidx);
+CREATE INDEX deliveries_data_key_id_idx ON deliveries (data_key_id);
\ No newline at end of file
@@ -0,0 +1,11 @@
+-- +migrate Up
+CREATE TABLE audit_log (
+    id BIGSERIAL PRIMARY KEY,
+    actor TEXT NOT NULL,
+    action TEXT NOT NULL,
+    order_uid TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    source_ip INET,
+    trace_id TEXT
+);
+CREATE INDEX audit_log_order_uid_idx ON audit_log (order_uid, created_at DESC);
\ No newline at end of file
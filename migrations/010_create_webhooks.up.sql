@@ -0,0 +1,15 @@
+-- +migrate Up
+CREATE TABLE webhooks (
+    id BIGSERIAL PRIMARY KEY,
+    url TEXT NOT NULL,
+    secret TEXT NOT NULL,
+    events TEXT[] NOT NULL DEFAULT '{}',
+    customer_id TEXT NOT NULL DEFAULT '',
+    delivery_service TEXT NOT NULL DEFAULT '',
+    include_pii BOOLEAN NOT NULL DEFAULT false,
+    failures INT NOT NULL DEFAULT 0,
+    last_error TEXT,
+    last_delivered_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    disabled_at TIMESTAMPTZ
+);
\ No newline at end of file
@@ -0,0 +1,4 @@
+-- +migrate Down
+DROP INDEX IF EXISTS orders_date_created_idx;
+DROP INDEX IF EXISTS orders_customer_id_idx;
+DROP INDEX IF EXISTS items_order_uid_idx;
\ No newline at end of file
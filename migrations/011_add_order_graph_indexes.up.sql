@@ -0,0 +1,5 @@
+-- +migrate Up
+-- выборки GraphQL: товары пачкой заказов, заказы клиента и страницы с фильтрами
+CREATE INDEX items_order_uid_idx ON items (order_uid);
+CREATE INDEX orders_customer_id_idx ON orders (customer_id, date_created DESC, order_uid);
+CREATE INDEX orders_date_created_idx ON orders (date_created DESC, order_uid);
\ No newline at end of file
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"slices"
+	"time"
+
+	"order-service/internal/auth"
+	"order-service/internal/config"
+	"order-service/internal/db"
+)
+
+const apiKeyUsage = `использование:
+  order-service apikey create -name NAME -scopes orders:read,orders:write [-ttl 720h]
+  order-service apikey revoke -name NAME
+
+Подключение к БД берётся из POSTGRES_DSN или CONFIG_FILE, как у сервиса.`
+
+// runAPIKeyCommand выпуск и отзыв API ключей. Ключ печатается один раз, в БД остаётся только хэш
+func runAPIKeyCommand(args []string) error {
+	if len(args) == 0 {
+		return errors.New(apiKeyUsage)
+	}
+
+	fs := flag.NewFlagSet("apikey "+args[0], flag.ContinueOnError)
+	name := fs.String("name", "", "уникальное имя ключа, например prometheus или billing")
+	scopes := fs.String("scopes", "", "области через запятую: "+fmt.Sprint(auth.KnownScopes))
+	ttl := fs.Duration("ttl", 0, "срок действия, 0 — бессрочный")
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+	if *name == "" {
+		return errors.New("не задано имя ключа (-name)")
+	}
+
+	cfg, err := config.Load(nil)
+	if err != nil {
+		return err
+	}
+	pgDB, err := db.NewPostgresDB(cfg.Postgres.ConnString())
+	if err != nil {
+		return fmt.Errorf("не удалось подключиться к базе данных: %w", err)
+	}
+	defer pgDB.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	switch args[0] {
+	case "create":
+		keyScopes := auth.ParseScopes(*scopes)
+		if len(keyScopes) == 0 {
+			return errors.New("не заданы области ключа (-scopes)")
+		}
+		for _, s := range keyScopes {
+			if !slices.Contains(auth.KnownScopes, s) {
+				return fmt.Errorf("неизвестная область %q, допустимые: %v", s, auth.KnownScopes)
+			}
+		}
+
+		var expiresAt *time.Time
+		if *ttl > 0 {
+			t := time.Now().Add(*ttl)
+			expiresAt = &t
+		}
+
+		key, err := auth.GenerateAPIKey()
+		if err != nil {
+			return err
+		}
+		if err := pgDB.CreateAPIKey(ctx, *name, auth.HashAPIKey(key), keyScopes, expiresAt); err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "Ключ %s создан, сохраните его — повторно он не показывается:\n", *name)
+		fmt.Println(key)
+
+	case "revoke":
+		err := pgDB.RevokeAPIKey(ctx, *name)
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("действующий ключ %s не найден", *name)
+		}
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "Ключ %s отозван, в кэше реплик он живёт до auth.api_key_cache_ttl\n", *name)
+
+	default:
+		return errors.New(apiKeyUsage)
+	}
+	return nil
+}
@@ -2,48 +2,78 @@ package main
 
 import (
 	"context"
-	"log"
+	"fmt"
+	"log/slog"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
 	"time"
 
+	"order-service/internal/audit"
+	"order-service/internal/auth"
 	"order-service/internal/cache"
+	"order-service/internal/config"
 	"order-service/internal/db"
+	"order-service/internal/fieldcrypt"
+	"order-service/internal/graphapi"
+	"order-service/internal/grpcapi"
 	"order-service/internal/handlers"
+	"order-service/internal/health"
 	"order-service/internal/interfaces"
+	"order-service/internal/invalidation"
 	"order-service/internal/kafka"
+	"order-service/internal/logging"
 	"order-service/internal/metrics"
 	"order-service/internal/middleware"
+	"order-service/internal/pubsub"
+	"order-service/internal/router"
+	"order-service/internal/tlsconfig"
 	"order-service/internal/tracing"
+	"order-service/internal/warmup"
+	"order-service/internal/webhook"
 
 	"go.opentelemetry.io/otel"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 )
 
 func main() {
-	// инициализация метрик
-	metrics.InitMetrics()
-
-	kafkaBrokers := []string{"kafka:9092"}
-	if val := os.Getenv("KAFKA_BROKERS"); val != "" {
-		kafkaBrokers = []string{val}
+	// служебные команды: order-service apikey create|revoke, order-service pii keygen|encrypt|rotate|find,
+	// order-service webhook create|list|enable|delete
+	if len(os.Args) > 1 {
+		commands := map[string]func([]string) error{"apikey": runAPIKeyCommand, "pii": runPIICommand, "webhook": runWebhookCommand}
+		if run, ok := commands[os.Args[1]]; ok {
+			if err := run(os.Args[2:]); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+			return
+		}
 	}
 
-	postgresDSN := os.Getenv("POSTGRES_DSN")
-	if postgresDSN == "" {
-		log.Fatal("POSTGRES_DSN is not set")
+	// конфигурация: флаги > окружение > YAML файл > значения по умолчанию
+	cfg, err := config.Load(os.Args[1:])
+	if err != nil {
+		fatal("Ошибка конфигурации", err)
+	}
+	if err := logging.Setup(cfg.Logging); err != nil {
+		fatal("Ошибка настройки логирования", err)
 	}
 
-	tp, err := tracing.InitTracer("order-service")
+	// инициализация метрик
+	metrics.InitMetrics()
+
+	tp, err := tracing.InitTracer(cfg.Tracing)
 	if err != nil {
-		log.Fatalf("Failed to initialize tracing: %v", err)
+		fatal("Не удалось инициализировать трейсинг", err)
 	}
 	defer func() {
 		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 		defer cancel()
 		if err := tp.Shutdown(ctx); err != nil {
-			log.Printf("Error shutting down tracer: %v", err)
+			slog.Error("Ошибка остановки трейсинга", logging.Err(err))
 		}
 	}()
 	tracer := otel.GetTracerProvider().Tracer("main")
@@ -51,79 +81,328 @@ func main() {
 	defer span.End()
 
 	var dbConn interfaces.Database
-	var cacheStore interfaces.Cache
+	var cacheStore, localCache interfaces.Cache
 
-	dbConn, err = db.NewPostgresDB(postgresDSN)
+	pgDB, err := db.NewPostgresDB(cfg.Postgres.ConnString())
 	if err != nil {
-		log.Fatal("Не удалось подключиться к базе данных:", err)
+		fatal("Не удалось подключиться к базе данных", err)
 	}
+	dbConn = pgDB
 	defer dbConn.Close()
 
-	// кэш с ttl 5 минут
-	cacheStore = cache.New(5*time.Minute, 1000)
+	// шифрование персональных данных доставок: без файла ключей они пишутся открытым текстом
+	if cfg.PII.KeyFile != "" {
+		kf, err := fieldcrypt.LoadKeyfile(cfg.PII.KeyFile)
+		if err != nil {
+			fatal("Не удалось загрузить ключи шифрования", err)
+		}
+		if pgDB.PII, err = fieldcrypt.New(ctx, kf, pgDB); err != nil {
+			fatal("Не удалось загрузить ключи данных", err)
+		}
+		slog.Info("Персональные данные доставок шифруются", slog.Int64("data_key_id", pgDB.PII.Active().ID))
+	} else {
+		slog.Warn("PII_KEY_FILE не задан, персональные данные доставок хранятся открытым текстом")
+	}
 
-	// инициализация кэша из db
-	log.Println("Восстановление кэша из базы данных...")
-	orders, err := dbConn.GetRecentOrders(1000)
+	// кэш: memory (по умолчанию), redis или tiered (memory + redis)
+	cacheStore, localCache, err = cache.NewFromConfig(ctx, cfg.Cache)
 	if err != nil {
-		log.Printf("Восстановление кэша не удалось: %v", err)
+		fatal("Не удалось инициализировать кэш", err)
+	}
+
+	// инвалидация локального кэша при изменениях заказов на других репликах
+	var invListener *invalidation.Listener
+	if localCache != nil && cfg.Cache.Invalidation != "off" {
+		origin := invalidation.NewOrigin()
+		invListener, err = invalidation.NewListener(cfg.Postgres.ConnString(), origin, cfg.Cache.Invalidation, localCache, dbConn)
+		if err != nil {
+			fatal("Не удалось запустить подписку на инвалидацию кэша", err)
+		}
+		pgDB.InvalidationOrigin = origin
+	}
+
+	// восстановление кэша из снимка, если его нет или он устарел — прогрев из db
+	restored := false
+	if memCache, ok := cacheStore.(*cache.Cache); ok && cfg.Cache.SnapshotPath != "" {
+		n, err := memCache.LoadSnapshot(cfg.Cache.SnapshotPath, cfg.Cache.SnapshotMaxAge)
+		if err != nil {
+			slog.Warn("Снимок кэша не использован", logging.Err(err))
+		} else {
+			restored = true
+			slog.Info("Кэш восстановлен из снимка", slog.Int("orders", n))
+		}
+	}
+
+	warmer, err := warmup.New(cacheStore, pgDB, warmup.Strategy(cfg.Cache.WarmupStrategy), cfg.Cache.WarmupLimit)
+	if err != nil {
+		fatal("Некорректная настройка прогрева кэша", err)
+	}
+
+	// журнал аудита пишется в фоне и останавливается последним, чтобы дописать события HTTP и Kafka
+	var auditLog *audit.Logger
+	auditCtx, stopAudit := context.WithCancel(context.Background())
+	auditDone := make(chan struct{})
+	if cfg.Audit.Enabled {
+		auditLog = audit.New(pgDB, cfg.Audit.BufferSize, cfg.Audit.FlushInterval)
+		auditLog.ClientIP = func(r *http.Request) string {
+			return middleware.ResolveClientIP(r, cfg.RateLimit.TrustForwardedFor)
+		}
+		go func() {
+			auditLog.Run(auditCtx)
+			close(auditDone)
+		}()
 	} else {
-		cacheStore.BulkSet(orders)
-		log.Printf("Кэш инициализирован с %d заказами", len(orders))
+		close(auditDone)
+		slog.Warn("Журнал аудита выключен")
+	}
+
+	// новые заказы из Kafka и API для подписчиков WatchOrders
+	hub := pubsub.NewHub()
+
+	// вебхуки партнёрам; рассылка останавливается после Kafka consumer и серверов, чтобы принять их последние заказы
+	var webhooks *webhook.Dispatcher
+	webhookCtx, stopWebhooks := context.WithCancel(context.Background())
+	webhooksDone := make(chan struct{})
+	if cfg.Webhook.Enabled {
+		webhooks = webhook.New(pgDB, cfg.Webhook, tracer)
+		go func() {
+			webhooks.Run(webhookCtx)
+			close(webhooksDone)
+		}()
+	} else {
+		close(webhooksDone)
+		slog.Info("Вебхуки выключены")
 	}
 
 	// Kafka Consumer (читает заказы и сохраняет в БД + кэш)
-	consumer := kafka.NewConsumer(
-		kafkaBrokers,
-		"orders",
-		"order_service_group",
-		"orders_dlq",
-		dbConn,
-		cacheStore,
-		tracer,
-	)
+	consumer, err := kafka.NewConsumer(cfg.Kafka, dbConn, cacheStore, tracer)
+	if err != nil {
+		fatal("Не удалось настроить подключение к Kafka", err)
+	}
+	consumer.Audit = auditLog
+	consumer.Hub = hub
+	consumer.Webhooks = webhooks
 	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
 	go consumer.Run(ctx)
+	if invListener != nil {
+		go invListener.Run(ctx)
+	}
+	// ключ данных, созданный pii rotate, становится активным без перезапуска
+	if pgDB.PII != nil {
+		go pgDB.PII.Refresh(ctx, cfg.PII.KeyRefreshInterval)
+	}
 
-	// HTTP Handlers (только просмотр заказов)
+	// прогрев идёт в фоне, HTTP сервер стартует сразу
+	if restored {
+		warmer.MarkReady()
+	} else {
+		slog.Info("Прогрев кэша в фоне", slog.String("strategy", cfg.Cache.WarmupStrategy), slog.Int("limit", cfg.Cache.WarmupLimit))
+		go warmer.Run(ctx)
+	}
+
+	// журнал обращений для стратегии frequent
+	accessRecorder := warmup.NewAccessRecorder(pgDB, cfg.Cache.AccessFlushInterval)
+	go accessRecorder.Run(ctx)
+
+	// HTTP Handlers: просмотр, список и приём заказов
 	handler := handlers.NewHandler(cacheStore, dbConn, tracer)
+	handler.Access = accessRecorder
+	handler.Audit = auditLog
+	handler.Hub = hub
+	handler.Webhooks = webhooks
+	handler.Stream = cfg.Stream
+	handler.WebDir = cfg.HTTP.WebDir
+	handler.SwaggerUIDir = cfg.HTTP.SwaggerUIDir
+	if cfg.GraphQL.Enabled {
+		// после заполнения handler: GraphQL берёт из него кэш, журнал аудита и учёт обращений
+		handler.GraphQL = graphapi.NewHandler(handler, pgDB, cfg.GraphQL)
+	}
 
-	// роутер и мидлвэр метрик
-	router := http.NewServeMux()
-	router.Handle("/static/", http.StripPrefix("/static/", http.FileServer(http.Dir("web"))))
-	router.HandleFunc("/order/", handler.OrderHandler)
-	router.HandleFunc("/", handler.WebInterfaceHandler)
-	router.Handle("/metrics", middleware.MetricsMiddleware(http.HandlerFunc(handler.MetricsHandler)))
+	// проверки зависимостей: БД и прогрев кэша влияют на готовность, Kafka и трейсинг только на /health
+	checks := health.New()
+	checks.Register("postgres", true, health.PingCheck(pgDB))
+	if redisCache, ok := cacheStore.(health.Pinger); ok {
+		checks.Register("redis", true, health.PingCheck(redisCache))
+	}
+	checks.Register("cache_warmup", true, health.WarmupCheck(warmer.Ready))
+	checks.Register("kafka", false, health.KafkaCheck(func() health.KafkaStatus {
+		s := consumer.Status()
+		return health.KafkaStatus{Running: s.Running, Lag: s.Lag, LastError: s.LastError, LastFetch: s.LastFetch}
+	}))
+	checks.Register("tracing", false, health.TracerCheck(func() health.ExporterStatus {
+		lastExport, lastErr := tracing.ExporterStatus()
+		return health.ExporterStatus{LastExport: lastExport, LastError: lastErr}
+	}))
+
+	// API ключи из БД и JWT по локальному JWKS; nil при AUTH_ENABLED=false
+	authn, err := auth.New(cfg.Auth, pgDB)
+	if err != nil {
+		fatal("Не удалось настроить аутентификацию", err)
+	}
+	if authn == nil {
+		slog.Warn("Аутентификация выключена, API и метрики открыты")
+	}
+
+	// token bucket на маршрут и клиента; nil при RATE_LIMIT_ENABLED=false
+	limiter := middleware.NewRateLimiter(cfg.RateLimit)
+	if limiter == nil {
+		slog.Warn("Ограничение частоты запросов выключено")
+	}
+
+	// маршруты с методами и мидлвэры трейсинга, метрик и сжатия (метрики видят размер сжатого ответа)
+	mux := router.New(handler, checks, authn, limiter)
 
 	// HTTP сервер
 	srv := &http.Server{
-		Addr:         ":8081",
-		Handler:      router,
-		ReadTimeout:  5 * time.Second,
-		WriteTimeout: 10 * time.Second,
+		Addr:         cfg.HTTP.Addr,
+		Handler:      middleware.TracingMiddleware(middleware.MetricsMiddleware(middleware.CompressMiddleware(mux))),
+		ReadTimeout:  cfg.HTTP.ReadTimeout,
+		WriteTimeout: cfg.HTTP.WriteTimeout,
+	}
+
+	// HTTPS при заданном сертификате; сертификат и CA клиентов перечитываются по SIGHUP
+	serverTLS, err := tlsconfig.NewServer(cfg.HTTP.TLS)
+	if err != nil {
+		fatal("Не удалось настроить TLS", err)
+	}
+	if serverTLS != nil {
+		srv.TLSConfig = serverTLS.Config()
+		go reloadOnSIGHUP(ctx, serverTLS)
 	}
 
 	go func() {
-		log.Println("Запуск сервера на :8081")
-		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			log.Fatalf("Ошибка сервера: %v", err)
+		slog.Info("Запуск сервера", slog.String("addr", cfg.HTTP.Addr), slog.Bool("tls", serverTLS != nil),
+			slog.String("client_auth", cfg.HTTP.TLS.ClientAuth))
+		var err error
+		if serverTLS != nil {
+			err = srv.ListenAndServeTLS("", "")
+		} else {
+			err = srv.ListenAndServe()
+		}
+		if err != nil && err != http.ErrServerClosed {
+			fatal("Ошибка сервера", err)
 		}
 	}()
 
+	// пробы и метрики без TLS для оркестратора и Prometheus
+	var opsSrv *http.Server
+	if cfg.HTTP.OpsAddr != "" {
+		opsSrv = &http.Server{
+			Addr:         cfg.HTTP.OpsAddr,
+			Handler:      router.Ops(handler, checks, authn, limiter),
+			ReadTimeout:  cfg.HTTP.ReadTimeout,
+			WriteTimeout: cfg.HTTP.WriteTimeout,
+		}
+		go func() {
+			slog.Info("Запуск служебного сервера", slog.String("addr", cfg.HTTP.OpsAddr))
+			if err := opsSrv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				fatal("Ошибка служебного сервера", err)
+			}
+		}()
+	}
+
+	// gRPC API на тех же кэше, БД и аутентификации; TLS с тем же сертификатом, что у HTTPS
+	var grpcSrv *grpc.Server
+	if cfg.GRPC.Enabled {
+		lis, err := net.Listen("tcp", cfg.GRPC.Addr)
+		if err != nil {
+			fatal("Не удалось открыть порт gRPC", err)
+		}
+		var opts []grpc.ServerOption
+		if serverTLS != nil {
+			opts = append(opts, grpc.Creds(credentials.NewTLS(serverTLS.Config())))
+		}
+		grpcSrv = grpcapi.New(grpcapi.NewServer(handler), authn, limiter, opts...)
+		go func() {
+			slog.Info("Запуск gRPC сервера", slog.String("addr", cfg.GRPC.Addr), slog.Bool("tls", serverTLS != nil))
+			if err := grpcSrv.Serve(lis); err != nil {
+				fatal("Ошибка gRPC сервера", err)
+			}
+		}()
+	}
+
 	// Корректное завершение по SIGINT/SIGTERM
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
-	log.Println("отключение сервера...")
+	slog.Info("Отключение сервера")
 
-	ctxTimeout, cancelTimeout := context.WithTimeout(context.Background(), 10*time.Second)
+	// сначала снимаем готовность и даём балансировщику время убрать реплику
+	checks.SetDraining()
+	time.Sleep(cfg.HTTP.DrainDelay)
+
+	// подписки WatchOrders, SSE и WebSocket закрываются, иначе остановка серверов ждала бы их до таймаута
+	hub.Close()
+	ctxTimeout, cancelTimeout := context.WithTimeout(context.Background(), cfg.HTTP.ShutdownTimeout)
 	defer cancelTimeout()
+	if grpcSrv != nil {
+		stopGRPC(ctxTimeout, grpcSrv)
+	}
 	if err := srv.Shutdown(ctxTimeout); err != nil {
-		log.Fatalf("Сервер принудительно отключен: %v", err)
+		fatal("Сервер принудительно отключен", err)
+	}
+	if opsSrv != nil {
+		if err := opsSrv.Shutdown(ctxTimeout); err != nil {
+			slog.Error("Служебный сервер принудительно отключен", logging.Err(err))
+		}
 	}
 
 	cancel() // остановка Kafka consumer
 	consumer.Close()
-	log.Println("сервер завершил работу корректно")
+	stopWebhooks()
+	<-webhooksDone
+	stopAudit()
+	<-auditDone
+	if invListener != nil {
+		if err := invListener.Close(); err != nil {
+			slog.Error("Ошибка закрытия подписки на инвалидацию", logging.Err(err))
+		}
+	}
+
+	// остановка фоновой очистки кэша
+	if err := cacheStore.Close(); err != nil {
+		slog.Error("Ошибка закрытия кэша", logging.Err(err))
+	}
+	slog.Info("Сервер завершил работу корректно")
+}
+
+// reloadOnSIGHUP перечитывает сертификат сервера по SIGHUP; при ошибке остаётся прежний
+func reloadOnSIGHUP(ctx context.Context, serverTLS *tlsconfig.Server) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+	for {
+		select {
+		case <-hup:
+			if err := serverTLS.Reload(); err != nil {
+				slog.Error("Сертификат не перечитан, используется прежний", logging.Err(err))
+				continue
+			}
+			slog.Info("Сертификат сервера перечитан", slog.Time("not_after", serverTLS.Certificate().NotAfter))
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// stopGRPC дожидается текущих вызовов, по истечении ctx обрывает их
+func stopGRPC(ctx context.Context, s *grpc.Server) {
+	done := make(chan struct{})
+	go func() {
+		s.GracefulStop()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		slog.Warn("gRPC вызовы не завершились за отведённое время, соединения закрыты")
+		s.Stop()
+	}
+}
+
+// fatal пишет ошибку и завершает процесс
+func fatal(msg string, err error) {
+	slog.Error(msg, logging.Err(err))
+	os.Exit(1)
 }
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"order-service/internal/config"
+	"order-service/internal/db"
+	"order-service/internal/fieldcrypt"
+)
+
+const piiUsage = `использование:
+  order-service pii keygen -keyfile PATH       новый файл ключей или новый основной мастер-ключ в существующем
+  order-service pii encrypt [-batch 500]       зашифровать доставки, которые ещё хранятся открытым текстом
+  order-service pii rotate [-batch 500]        переобернуть ключи данных основным мастер-ключом,
+                                               создать новый ключ данных и перешифровать им все доставки
+  order-service pii decrypt [-batch 500]       вернуть доставки в открытые колонки перед откатом миграции 008;
+                                               сначала остановите реплики, запускайте их уже без PII_KEY_FILE
+  order-service pii find -phone +79991234567 | -email user@mail.ru
+
+Файл ключей берётся из PII_KEY_FILE, подключение к БД — из POSTGRES_DSN или CONFIG_FILE, как у сервиса.`
+
+// runPIICommand управление шифрованием персональных данных доставок
+func runPIICommand(args []string) error {
+	if len(args) == 0 {
+		return errors.New(piiUsage)
+	}
+
+	fs := flag.NewFlagSet("pii "+args[0], flag.ContinueOnError)
+	keyfilePath := fs.String("keyfile", os.Getenv("PII_KEY_FILE"), "файл мастер-ключей")
+	batch := fs.Int("batch", 500, "строк в одной транзакции при шифровании")
+	phone := fs.String("phone", "", "телефон для поиска")
+	email := fs.String("email", "", "email для поиска")
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	if args[0] == "keygen" {
+		return keygen(*keyfilePath)
+	}
+
+	cfg, err := config.Load(nil)
+	if err != nil {
+		return err
+	}
+	if cfg.PII.KeyFile == "" {
+		return errors.New("не задан файл ключей (PII_KEY_FILE)")
+	}
+	kf, err := fieldcrypt.LoadKeyfile(cfg.PII.KeyFile)
+	if err != nil {
+		return err
+	}
+	pgDB, err := db.NewPostgresDB(cfg.Postgres.ConnString())
+	if err != nil {
+		return fmt.Errorf("не удалось подключиться к базе данных: %w", err)
+	}
+	defer pgDB.Close()
+
+	ctx := context.Background()
+	pgDB.PII, err = fieldcrypt.New(ctx, kf, pgDB)
+	if err != nil {
+		return err
+	}
+
+	switch args[0] {
+	case "encrypt":
+		return reencrypt(ctx, pgDB, *batch)
+
+	case "rotate":
+		n, err := pgDB.PII.Rewrap(ctx)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "Переобёрнуто мастер-ключом %s ключей данных: %d\n", kf.Primary, n)
+
+		id, err := pgDB.PII.Rotate(ctx)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "Создан ключ данных %d\n", id)
+		if err := reencrypt(ctx, pgDB, *batch); err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "Реплики начнут писать ключом %d в течение %s (PII_KEY_REFRESH_INTERVAL); "+
+			"записанное ими до этого дошифрует повторный pii encrypt\n", id, cfg.PII.KeyRefreshInterval)
+		return nil
+
+	case "decrypt":
+		start := time.Now()
+		n, err := pgDB.DecryptDeliveries(ctx, *batch)
+		fmt.Fprintf(os.Stderr, "Расшифровано доставок: %d за %s\n", n, time.Since(start).Round(time.Millisecond))
+		return err
+
+	case "find":
+		var uids []string
+		switch {
+		case *phone != "":
+			uids, err = pgDB.FindOrderUIDsByPhone(ctx, *phone)
+		case *email != "":
+			uids, err = pgDB.FindOrderUIDsByEmail(ctx, *email)
+		default:
+			return errors.New("задайте -phone или -email")
+		}
+		if err != nil {
+			return err
+		}
+		for _, uid := range uids {
+			fmt.Println(uid)
+		}
+		return nil
+	}
+	return errors.New(piiUsage)
+}
+
+func keygen(path string) error {
+	if path == "" {
+		return errors.New("не задан файл ключей (-keyfile или PII_KEY_FILE)")
+	}
+
+	kf, err := fieldcrypt.LoadKeyfile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		if kf, err = fieldcrypt.NewKeyfile(); err != nil {
+			return err
+		}
+		if err := kf.Save(path); err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "Создан файл ключей %s, мастер-ключ %s. Храните его вне БД и её резервных копий\n", path, kf.Primary)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	id, err := kf.AddMasterKey()
+	if err != nil {
+		return err
+	}
+	if err := kf.Save(path); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "Основной мастер-ключ теперь %s, выполните pii rotate и перезапустите реплики\n", id)
+	return nil
+}
+
+func reencrypt(ctx context.Context, pgDB *db.PostgresDB, batch int) error {
+	start := time.Now()
+	n, err := pgDB.ReencryptDeliveries(ctx, batch)
+	fmt.Fprintf(os.Stderr, "Зашифровано ключом %d доставок: %d за %s\n", pgDB.PII.Active().ID, n, time.Since(start).Round(time.Millisecond))
+	return err
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"order-service/internal/config"
+	"order-service/internal/db"
+	"order-service/internal/webhook"
+)
+
+const webhookUsage = `использование:
+  order-service webhook create -url https://partner.example/hook [-events order.stored,order.status_changed] [-customer-id ID] [-delivery-service NAME] [-pii]
+  order-service webhook list
+  order-service webhook enable -id ID
+  order-service webhook delete -id ID
+
+Подключение к БД берётся из POSTGRES_DSN или CONFIG_FILE, как у сервиса.`
+
+// runWebhookCommand управление подписками на вебхуки. Секрет подписи печатается один раз при создании
+func runWebhookCommand(args []string) error {
+	if len(args) == 0 {
+		return errors.New(webhookUsage)
+	}
+
+	fs := flag.NewFlagSet("webhook "+args[0], flag.ContinueOnError)
+	hookURL := fs.String("url", "", "адрес партнёра, принимающий POST с JSON")
+	events := fs.String("events", "", "события через запятую, пусто — все: "+strings.Join(webhook.KnownEvents, ", "))
+	customerID := fs.String("customer-id", "", "только заказы этого клиента")
+	deliveryService := fs.String("delivery-service", "", "только заказы этой службы доставки")
+	includePII := fs.Bool("pii", false, "отправлять персональные данные доставки без маскирования")
+	id := fs.Int64("id", 0, "id подписки из webhook list")
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	cfg, err := config.Load(nil)
+	if err != nil {
+		return err
+	}
+	pgDB, err := db.NewPostgresDB(cfg.Postgres.ConnString())
+	if err != nil {
+		return fmt.Errorf("не удалось подключиться к базе данных: %w", err)
+	}
+	defer pgDB.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	switch args[0] {
+	case "create":
+		u, err := url.Parse(*hookURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return errors.New("нужен абсолютный http(s) адрес подписки (-url)")
+		}
+		sub := &webhook.Subscription{
+			URL:             *hookURL,
+			CustomerID:      *customerID,
+			DeliveryService: *deliveryService,
+			IncludePII:      *includePII,
+		}
+		for _, e := range strings.Split(*events, ",") {
+			if e = strings.TrimSpace(e); e == "" {
+				continue
+			}
+			if !slices.Contains(webhook.KnownEvents, e) {
+				return fmt.Errorf("неизвестное событие %q, допустимые: %v", e, webhook.KnownEvents)
+			}
+			sub.Events = append(sub.Events, e)
+		}
+		if sub.Secret, err = webhook.GenerateSecret(); err != nil {
+			return err
+		}
+
+		newID, err := pgDB.CreateWebhook(ctx, sub)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "Подписка %d создана, передайте партнёру секрет для проверки %s:\n", newID, webhook.HeaderSignature)
+		fmt.Println(sub.Secret)
+
+	case "list":
+		subs, err := pgDB.ListWebhooks(ctx)
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tURL\tСОБЫТИЯ\tФИЛЬТР\tPII\tНЕУДАЧ\tСОСТОЯНИЕ")
+		for _, s := range subs {
+			state := "активна"
+			if s.DisabledAt != nil {
+				state = "выключена " + s.DisabledAt.Format(time.RFC3339) + ": " + s.LastError
+			}
+			fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%t\t%d\t%s\n", s.ID, s.URL, listOrAll(s.Events),
+				filterString(s), s.IncludePII, s.Failures, state)
+		}
+		return w.Flush()
+
+	case "enable", "delete":
+		if *id <= 0 {
+			return errors.New("не задан id подписки (-id)")
+		}
+		change, done := pgDB.EnableWebhook, "включена, счётчик неудач сброшен"
+		if args[0] == "delete" {
+			change, done = pgDB.DeleteWebhook, "удалена"
+		}
+		err := change(ctx, *id)
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("подписка %d не найдена", *id)
+		}
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "Подписка %d %s\n", *id, done)
+
+	default:
+		return errors.New(webhookUsage)
+	}
+	return nil
+}
+
+func listOrAll(events []string) string {
+	if len(events) == 0 {
+		return "все"
+	}
+	return strings.Join(events, ",")
+}
+
+func filterString(s webhook.Subscription) string {
+	var parts []string
+	if s.CustomerID != "" {
+		parts = append(parts, "customer_id="+s.CustomerID)
+	}
+	if s.DeliveryService != "" {
+		parts = append(parts, "delivery_service="+s.DeliveryService)
+	}
+	if len(parts) == 0 {
+		return "-"
+	}
+	return strings.Join(parts, ",")
+}
@@ -0,0 +1,199 @@
+// internal/audit/audit.go
+package audit
+
+import (
+	"context"
+	"log/slog"
+	"net"
+	"net/http"
+	"time"
+
+	"order-service/internal/auth"
+	"order-service/internal/logging"
+	"order-service/internal/metrics"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Действия над заказом
+const (
+	ActionRead  = "read"
+	ActionWrite = "write"
+)
+
+// Клиенты без аутентификации
+const (
+	ActorAnonymous = "anonymous"
+	ActorKafka     = "kafka"
+)
+
+// flushTimeout сколько ждать БД при записи пачки, в том числе последней при остановке
+const flushTimeout = 5 * time.Second
+
+// Event запись журнала: кто, что и когда сделал с заказом
+type Event struct {
+	ID       int64     `json:"id,omitempty"`
+	Actor    string    `json:"actor"`
+	Action   string    `json:"action"`
+	OrderUID string    `json:"order_uid"`
+	At       time.Time `json:"at"`
+	SourceIP string    `json:"source_ip,omitempty"`
+	TraceID  string    `json:"trace_id,omitempty"`
+}
+
+// Store хранилище журнала
+type Store interface {
+	InsertAuditEvents(ctx context.Context, events []Event) error
+	ListAuditEvents(ctx context.Context, orderUID string, limit int) ([]Event, error)
+}
+
+// Logger пишет журнал асинхронно: события копятся в канале и уходят в БД пачками,
+// запрос не ждёт записи. При переполнении буфера событие отбрасывается и учитывается в метрике
+type Logger struct {
+	store     Store
+	events    chan Event
+	batchSize int
+	interval  time.Duration
+	now       func() time.Time
+
+	// ClientIP адрес клиента HTTP запроса, nil — адрес соединения.
+	// Задаётся до первого Record, чтобы журнал и лимитер одинаково понимали X-Forwarded-For
+	ClientIP func(r *http.Request) string
+}
+
+// New журнал с буфером на bufferSize событий и сбросом не реже interval
+func New(store Store, bufferSize int, interval time.Duration) *Logger {
+	return &Logger{
+		store:     store,
+		events:    make(chan Event, bufferSize),
+		batchSize: min(bufferSize, 500),
+		interval:  interval,
+		now:       time.Now,
+	}
+}
+
+// Record ставит событие в очередь. Время, клиент и trace_id берутся из контекста запроса;
+// у nil журнала ничего не делает
+func (l *Logger) Record(ctx context.Context, action, orderUID string, r *http.Request) {
+	if l == nil {
+		return
+	}
+	if r == nil {
+		l.record(ctx, action, orderUID, ActorKafka, "")
+		return
+	}
+	remote := r.RemoteAddr
+	if l.ClientIP != nil {
+		remote = l.ClientIP(r)
+	}
+	l.record(ctx, action, orderUID, ActorAnonymous, remote)
+}
+
+// RecordRemote то же для запросов не по HTTP (gRPC): remoteAddr — адрес соединения клиента
+func (l *Logger) RecordRemote(ctx context.Context, action, orderUID, remoteAddr string) {
+	if l == nil {
+		return
+	}
+	l.record(ctx, action, orderUID, ActorAnonymous, remoteAddr)
+}
+
+// record anonymous — кем считать клиента, если его нет в контексте
+func (l *Logger) record(ctx context.Context, action, orderUID, anonymous, remoteAddr string) {
+	e := Event{
+		Actor:    actor(ctx, anonymous),
+		Action:   action,
+		OrderUID: orderUID,
+		At:       l.now().UTC(),
+		SourceIP: sourceIP(remoteAddr),
+	}
+	if sc := trace.SpanContextFromContext(ctx); sc.HasTraceID() {
+		e.TraceID = sc.TraceID().String()
+	}
+
+	select {
+	case l.events <- e:
+	default:
+		metrics.AuditEvents.WithLabelValues(action, "dropped").Inc()
+		slog.WarnContext(ctx, "Буфер журнала аудита переполнен, событие потеряно", slog.String("action", action))
+	}
+}
+
+// Query события заказа, новые первыми
+func (l *Logger) Query(ctx context.Context, orderUID string, limit int) ([]Event, error) {
+	return l.store.ListAuditEvents(ctx, orderUID, limit)
+}
+
+// Run пишет события пачками по batchSize или раз в interval. После отмены ctx
+// дописывает всё, что успело попасть в буфер, и возвращается
+func (l *Logger) Run(ctx context.Context) {
+	ticker := time.NewTicker(l.interval)
+	defer ticker.Stop()
+
+	batch := make([]Event, 0, l.batchSize)
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		l.write(batch)
+		batch = batch[:0]
+	}
+
+	for {
+		select {
+		case e := <-l.events:
+			batch = append(batch, e)
+			if len(batch) == l.batchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		case <-ctx.Done():
+			for {
+				select {
+				case e := <-l.events:
+					batch = append(batch, e)
+					if len(batch) == l.batchSize {
+						flush()
+					}
+				default:
+					flush()
+					return
+				}
+			}
+		}
+	}
+}
+
+func (l *Logger) write(batch []Event) {
+	ctx, cancel := context.WithTimeout(context.Background(), flushTimeout)
+	defer cancel()
+
+	result := "written"
+	if err := l.store.InsertAuditEvents(ctx, batch); err != nil {
+		result = "error"
+		slog.Error("Ошибка записи журнала аудита", slog.Int("events", len(batch)), logging.Err(err))
+	}
+	for _, e := range batch {
+		metrics.AuditEvents.WithLabelValues(e.Action, result).Inc()
+	}
+}
+
+// actor имя API ключа или sub JWT с префиксом способа; без аутентификации — anonymous
+func actor(ctx context.Context, anonymous string) string {
+	if p := auth.FromContext(ctx); p != nil {
+		return p.Method + ":" + p.Subject
+	}
+	return anonymous
+}
+
+// sourceIP адрес соединения; не IP (unix сокет) не пишется, колонка source_ip имеет тип inet
+func sourceIP(remoteAddr string) string {
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		host = remoteAddr
+	}
+	if net.ParseIP(host) == nil {
+		return ""
+	}
+	return host
+}
@@ -0,0 +1,199 @@
+// internal/audit/audit_test.go
+package audit
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"order-service/internal/auth"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type memStore struct {
+	mu      sync.Mutex
+	batches [][]Event
+	err     error
+}
+
+func (s *memStore) InsertAuditEvents(ctx context.Context, events []Event) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		return s.err
+	}
+	s.batches = append(s.batches, append([]Event(nil), events...))
+	return nil
+}
+
+func (s *memStore) ListAuditEvents(ctx context.Context, orderUID string, limit int) ([]Event, error) {
+	var out []Event
+	for _, e := range s.events() {
+		if e.OrderUID == orderUID {
+			out = append(out, e)
+		}
+	}
+	return out, nil
+}
+
+func (s *memStore) events() []Event {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var out []Event
+	for _, b := range s.batches {
+		out = append(out, b...)
+	}
+	return out
+}
+
+// drain запускает Run с уже отменённым контекстом: всё из буфера пишется и Run возвращается
+func drain(l *Logger) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	l.Run(ctx)
+}
+
+func TestLogger_RecordFromRequest(t *testing.T) {
+	store := &memStore{}
+	l := New(store, 10, time.Hour)
+	at := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	l.now = func() time.Time { return at }
+
+	traceID, _ := trace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+	spanID, _ := trace.SpanIDFromHex("00f067aa0ba902b7")
+	ctx := trace.ContextWithSpanContext(context.Background(),
+		trace.NewSpanContext(trace.SpanContextConfig{TraceID: traceID, SpanID: spanID}))
+	ctx = auth.WithPrincipal(ctx, &auth.Principal{Subject: "billing", Method: auth.MethodAPIKey})
+
+	r := httptest.NewRequest("GET", "/order/abc", nil)
+	r.RemoteAddr = "192.0.2.10:51234"
+	l.Record(ctx, ActionRead, "abc", r)
+	drain(l)
+
+	assert.Equal(t, []Event{{
+		Actor:    "api_key:billing",
+		Action:   ActionRead,
+		OrderUID: "abc",
+		At:       at,
+		SourceIP: "192.0.2.10",
+		TraceID:  "4bf92f3577b34da6a3ce929d0e0e4736",
+	}}, store.events())
+}
+
+func TestLogger_ClientIP(t *testing.T) {
+	store := &memStore{}
+	l := New(store, 10, time.Hour)
+	l.ClientIP = func(r *http.Request) string { return r.Header.Get("X-Forwarded-For") }
+
+	r := httptest.NewRequest("GET", "/order/abc", nil)
+	r.RemoteAddr = "10.0.0.2:51234"
+	r.Header.Set("X-Forwarded-For", "198.51.100.7")
+	l.Record(context.Background(), ActionRead, "abc", r)
+	l.RecordRemote(context.Background(), ActionRead, "abc", "10.0.0.3:40000")
+	drain(l)
+
+	events := store.events()
+	require.Len(t, events, 2)
+	assert.Equal(t, "198.51.100.7", events[0].SourceIP)
+	// gRPC не проходит через ClientIP: адрес соединения
+	assert.Equal(t, "10.0.0.3", events[1].SourceIP)
+}
+
+func TestLogger_Actors(t *testing.T) {
+	store := &memStore{}
+	l := New(store, 10, time.Hour)
+
+	r := httptest.NewRequest("POST", "/api/v1/orders", nil)
+	r.RemoteAddr = "@"
+	l.Record(context.Background(), ActionWrite, "api", r)
+	l.Record(context.Background(), ActionWrite, "kafka", nil)
+	l.RecordRemote(context.Background(), ActionRead, "grpc", "192.0.2.5:50051")
+	drain(l)
+
+	events := store.events()
+	require.Len(t, events, 3)
+	assert.Equal(t, ActorAnonymous, events[0].Actor)
+	// не IP адрес в inet не пишется
+	assert.Empty(t, events[0].SourceIP)
+	assert.Equal(t, ActorKafka, events[1].Actor)
+	assert.Empty(t, events[1].SourceIP)
+	assert.Empty(t, events[1].TraceID)
+	assert.Equal(t, ActorAnonymous, events[2].Actor)
+	assert.Equal(t, "192.0.2.5", events[2].SourceIP)
+}
+
+func TestLogger_Batches(t *testing.T) {
+	store := &memStore{}
+	l := New(store, 3, time.Hour)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		l.Run(ctx)
+		close(done)
+	}()
+
+	// полная пачка пишется сразу, не дожидаясь интервала
+	for _, uid := range []string{"a", "b", "c"} {
+		l.Record(context.Background(), ActionRead, uid, nil)
+	}
+	require.Eventually(t, func() bool { return len(store.events()) == 3 }, time.Second, 5*time.Millisecond)
+
+	// остаток дописывается при остановке
+	l.Record(context.Background(), ActionRead, "d", nil)
+	cancel()
+	<-done
+	assert.Len(t, store.events(), 4)
+	assert.Len(t, store.batches, 2)
+}
+
+func TestLogger_FlushInterval(t *testing.T) {
+	store := &memStore{}
+	l := New(store, 100, 10*time.Millisecond)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go l.Run(ctx)
+
+	l.Record(context.Background(), ActionWrite, "a", nil)
+	assert.Eventually(t, func() bool { return len(store.events()) == 1 }, time.Second, 5*time.Millisecond)
+}
+
+func TestLogger_DropsWhenFull(t *testing.T) {
+	store := &memStore{}
+	l := New(store, 2, time.Hour)
+
+	for _, uid := range []string{"a", "b", "c"} {
+		l.Record(context.Background(), ActionRead, uid, nil)
+	}
+	drain(l)
+
+	events := store.events()
+	require.Len(t, events, 2)
+	assert.Equal(t, "a", events[0].OrderUID)
+	assert.Equal(t, "b", events[1].OrderUID)
+}
+
+func TestLogger_StoreError(t *testing.T) {
+	store := &memStore{err: errors.New("db down")}
+	l := New(store, 10, time.Hour)
+
+	l.Record(context.Background(), ActionRead, "a", nil)
+	// ошибка записи только логируется, Run не зависает
+	drain(l)
+	assert.Empty(t, store.events())
+}
+
+func TestLogger_Nil(t *testing.T) {
+	var l *Logger
+	assert.NotPanics(t, func() {
+		l.Record(context.Background(), ActionRead, "a", nil)
+	})
+}
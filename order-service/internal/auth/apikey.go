@@ -0,0 +1,54 @@
+// internal/auth/apikey.go
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+	"time"
+)
+
+// apiKeyPrefix по префиксу ключ легко найти в логах и сканерах секретов
+const apiKeyPrefix = "osk_"
+
+// APIKey запись таблицы api_keys; сам ключ не хранится, только его хэш
+type APIKey struct {
+	Name      string
+	Scopes    []string
+	ExpiresAt *time.Time
+}
+
+// KeyStore хранилище API ключей. LookupAPIKey возвращает sql.ErrNoRows
+// для неизвестного, отозванного или просроченного ключа
+type KeyStore interface {
+	LookupAPIKey(ctx context.Context, keyHash string) (*APIKey, error)
+}
+
+// GenerateAPIKey новый случайный ключ, показывается один раз при создании
+func GenerateAPIKey() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return apiKeyPrefix + base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// HashAPIKey sha256 ключа. Ключ случайный и длинный, поэтому медленный хэш
+// не нужен, а детерминированный позволяет искать запись по индексу
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// ParseScopes разбирает список областей через запятую или пробел
+func ParseScopes(val string) []string {
+	fields := strings.FieldsFunc(val, func(r rune) bool { return r == ',' || r == ' ' })
+	scopes := make([]string, 0, len(fields))
+	for _, f := range fields {
+		scopes = append(scopes, strings.TrimSpace(f))
+	}
+	return scopes
+}
@@ -0,0 +1,54 @@
+// internal/auth/auth.go
+package auth
+
+import (
+	"context"
+	"slices"
+)
+
+// Области доступа. admin включает все остальные
+const (
+	ScopeOrdersRead  = "orders:read"
+	ScopeOrdersWrite = "orders:write"
+	ScopeMetricsRead = "metrics:read"
+	// ScopePIIRead персональные данные заказа без маскирования
+	ScopePIIRead = "pii:read"
+	ScopeAdmin   = "admin"
+)
+
+// KnownScopes области, которые можно выдать ключу
+var KnownScopes = []string{ScopeOrdersRead, ScopeOrdersWrite, ScopeMetricsRead, ScopePIIRead, ScopeAdmin}
+
+// Способы аутентификации для логов и трейсов
+const (
+	MethodAPIKey = "api_key"
+	MethodJWT    = "jwt"
+)
+
+// Principal аутентифицированный клиент: имя ключа или sub токена и его области
+type Principal struct {
+	Subject string
+	Method  string
+	Scopes  []string
+}
+
+// HasScope есть ли у клиента область; admin проходит любую проверку
+func (p *Principal) HasScope(scope string) bool {
+	if p == nil {
+		return false
+	}
+	return slices.Contains(p.Scopes, scope) || slices.Contains(p.Scopes, ScopeAdmin)
+}
+
+type principalKey struct{}
+
+// WithPrincipal кладёт клиента в контекст запроса
+func WithPrincipal(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// FromContext клиент запроса; nil если аутентификация выключена
+func FromContext(ctx context.Context) *Principal {
+	p, _ := ctx.Value(principalKey{}).(*Principal)
+	return p
+}
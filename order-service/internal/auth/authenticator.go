@@ -0,0 +1,250 @@
+// internal/auth/authenticator.go
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"order-service/internal/config"
+	"order-service/internal/logging"
+	"order-service/internal/metrics"
+
+	"github.com/golang-jwt/jwt/v5"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	realm = "order-service"
+	// jwtLeeway допуск расхождения часов с издателем токенов
+	jwtLeeway = 30 * time.Second
+	// unknownKeyTTL сколько помнить хеш неизвестного ключа: перебор ключей не доходит до БД,
+	// а только что выпущенный ключ начинает работать не позже чем через это время
+	unknownKeyTTL = 10 * time.Second
+	// maxUnknownKeys предел записей о неизвестных ключах, при переполнении вытесняется любая
+	maxUnknownKeys = 10000
+)
+
+var (
+	ErrNoCredentials      = errors.New("не переданы учётные данные")
+	ErrInvalidCredentials = errors.New("неверные учётные данные")
+)
+
+// асимметричные алгоритмы: в локальном JWKS только открытые ключи, HS* и none не принимаются
+var jwtAlgorithms = []string{
+	"RS256", "RS384", "RS512",
+	"PS256", "PS384", "PS512",
+	"ES256", "ES384", "ES512",
+	"EdDSA",
+}
+
+// Authenticator проверяет API ключи (X-API-Key или Authorization: Bearer) и JWT по локальному JWKS
+type Authenticator struct {
+	keys   KeyStore
+	jwks   *JWKS
+	parser *jwt.Parser
+
+	cacheTTL time.Duration
+	mu       sync.Mutex
+	cache    map[string]cachedKey
+	// unknown хеши ключей, которых нет в БД, и момент истечения записи
+	unknown map[string]time.Time
+	now     func() time.Time
+}
+
+type cachedKey struct {
+	key     *APIKey
+	expires time.Time
+}
+
+type tokenClaims struct {
+	// scope строка через пробел (RFC 8693), scp массив — так их выдают разные IdP
+	Scope string   `json:"scope"`
+	Scp   []string `json:"scp"`
+	jwt.RegisteredClaims
+}
+
+// New аутентификатор по конфигурации. При выключенной аутентификации возвращает nil:
+// Require у nil пропускает запросы без проверки
+func New(cfg config.AuthConfig, keys KeyStore) (*Authenticator, error) {
+	if !cfg.Enabled {
+		return nil, nil
+	}
+
+	a := &Authenticator{
+		keys:     keys,
+		cacheTTL: cfg.APIKeyCacheTTL,
+		cache:    make(map[string]cachedKey),
+		unknown:  make(map[string]time.Time),
+		now:      time.Now,
+	}
+
+	if cfg.JWKSFile != "" {
+		jwks, err := LoadJWKS(cfg.JWKSFile)
+		if err != nil {
+			return nil, err
+		}
+		a.jwks = jwks
+
+		opts := []jwt.ParserOption{
+			jwt.WithValidMethods(jwtAlgorithms),
+			jwt.WithExpirationRequired(),
+			jwt.WithLeeway(jwtLeeway),
+		}
+		if cfg.JWTIssuer != "" {
+			opts = append(opts, jwt.WithIssuer(cfg.JWTIssuer))
+		}
+		if cfg.JWTAudience != "" {
+			opts = append(opts, jwt.WithAudience(cfg.JWTAudience))
+		}
+		a.parser = jwt.NewParser(opts...)
+	}
+	return a, nil
+}
+
+// Require пропускает запрос только с действительными учётными данными и областью scope:
+// 401 без них или с неверными, 403 без нужной области
+func (a *Authenticator) Require(scope string, next http.Handler) http.Handler {
+	if a == nil {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		principal, err := a.Authenticate(ctx, r)
+		if err != nil {
+			method := "none"
+			result := "invalid"
+			challenge := `Bearer realm="` + realm + `"`
+			switch {
+			case errors.Is(err, ErrNoCredentials):
+				result = "unauthenticated"
+			case errors.Is(err, ErrInvalidCredentials):
+				challenge += `, error="invalid_token"`
+			default:
+				// хранилище ключей недоступно — это не вина клиента
+				result = "error"
+				slog.ErrorContext(ctx, "Ошибка проверки учётных данных", logging.Err(err))
+				metrics.AuthRequests.WithLabelValues(method, result).Inc()
+				http.Error(w, "внутренняя ошибка сервера", http.StatusInternalServerError)
+				return
+			}
+			metrics.AuthRequests.WithLabelValues(method, result).Inc()
+			w.Header().Set("WWW-Authenticate", challenge)
+			http.Error(w, "требуется аутентификация", http.StatusUnauthorized)
+			return
+		}
+
+		trace.SpanFromContext(ctx).SetAttributes(
+			attribute.String("auth.subject", principal.Subject),
+			attribute.String("auth.method", principal.Method),
+		)
+
+		if !principal.HasScope(scope) {
+			slog.WarnContext(ctx, "Недостаточно прав",
+				slog.String("subject", principal.Subject), slog.String("scope", scope))
+			metrics.AuthRequests.WithLabelValues(principal.Method, "forbidden").Inc()
+			w.Header().Set("WWW-Authenticate",
+				`Bearer realm="`+realm+`", error="insufficient_scope", scope="`+scope+`"`)
+			http.Error(w, "недостаточно прав: нужна область "+scope, http.StatusForbidden)
+			return
+		}
+
+		metrics.AuthRequests.WithLabelValues(principal.Method, "success").Inc()
+		next.ServeHTTP(w, r.WithContext(WithPrincipal(ctx, principal)))
+	})
+}
+
+// Authenticate определяет клиента по заголовкам запроса
+func (a *Authenticator) Authenticate(ctx context.Context, r *http.Request) (*Principal, error) {
+	return a.AuthenticateCredentials(ctx, r.Header.Get("X-API-Key"), r.Header.Get("Authorization"))
+}
+
+// AuthenticateCredentials определяет клиента по значениям X-API-Key и Authorization,
+// откуда бы они ни пришли: из заголовков HTTP или метаданных gRPC
+func (a *Authenticator) AuthenticateCredentials(ctx context.Context, apiKey, authorization string) (*Principal, error) {
+	if apiKey != "" {
+		return a.authenticateAPIKey(ctx, apiKey)
+	}
+
+	scheme, credentials, ok := strings.Cut(authorization, " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") || credentials == "" {
+		return nil, ErrNoCredentials
+	}
+	credentials = strings.TrimSpace(credentials)
+
+	// JWT из трёх частей через точку, иначе это API ключ (так его передаёт Prometheus)
+	if strings.Count(credentials, ".") == 2 {
+		return a.authenticateJWT(credentials)
+	}
+	return a.authenticateAPIKey(ctx, credentials)
+}
+
+func (a *Authenticator) authenticateAPIKey(ctx context.Context, key string) (*Principal, error) {
+	hash := HashAPIKey(key)
+	now := a.now()
+
+	a.mu.Lock()
+	cached, ok := a.cache[hash]
+	unknownUntil, unknown := a.unknown[hash]
+	a.mu.Unlock()
+	if unknown && now.Before(unknownUntil) {
+		return nil, ErrInvalidCredentials
+	}
+
+	apiKey := cached.key
+	if !ok || now.After(cached.expires) {
+		var err error
+		apiKey, err = a.keys.LookupAPIKey(ctx, hash)
+		if errors.Is(err, sql.ErrNoRows) {
+			a.rememberUnknown(hash, now)
+			return nil, ErrInvalidCredentials
+		}
+		if err != nil {
+			return nil, fmt.Errorf("поиск API ключа: %w", err)
+		}
+
+		a.mu.Lock()
+		a.cache[hash] = cachedKey{key: apiKey, expires: now.Add(a.cacheTTL)}
+		a.mu.Unlock()
+	}
+
+	// срок мог истечь, пока запись лежала в кэше
+	if apiKey.ExpiresAt != nil && now.After(*apiKey.ExpiresAt) {
+		return nil, ErrInvalidCredentials
+	}
+	return &Principal{Subject: apiKey.Name, Method: MethodAPIKey, Scopes: apiKey.Scopes}, nil
+}
+
+// rememberUnknown запоминает хеш неизвестного ключа на unknownKeyTTL
+func (a *Authenticator) rememberUnknown(hash string, now time.Time) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if _, exists := a.unknown[hash]; !exists && len(a.unknown) >= maxUnknownKeys {
+		for h := range a.unknown {
+			delete(a.unknown, h)
+			break
+		}
+	}
+	a.unknown[hash] = now.Add(unknownKeyTTL)
+}
+
+func (a *Authenticator) authenticateJWT(raw string) (*Principal, error) {
+	if a.jwks == nil {
+		return nil, ErrInvalidCredentials
+	}
+
+	var claims tokenClaims
+	if _, err := a.parser.ParseWithClaims(raw, &claims, a.jwks.Keyfunc); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCredentials, err)
+	}
+
+	scopes := append(strings.Fields(claims.Scope), claims.Scp...)
+	return &Principal{Subject: claims.Subject, Method: MethodJWT, Scopes: scopes}, nil
+}
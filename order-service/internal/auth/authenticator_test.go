@@ -0,0 +1,334 @@
+// internal/auth/authenticator_test.go
+package auth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"order-service/internal/config"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeKeyStore ключи по хэшу и счётчик обращений
+type fakeKeyStore struct {
+	mu      sync.Mutex
+	keys    map[string]*APIKey
+	err     error
+	lookups int
+}
+
+func (f *fakeKeyStore) LookupAPIKey(ctx context.Context, keyHash string) (*APIKey, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.lookups++
+	if f.err != nil {
+		return nil, f.err
+	}
+	key, ok := f.keys[keyHash]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	return key, nil
+}
+
+func (f *fakeKeyStore) add(key string, apiKey *APIKey) {
+	f.keys[HashAPIKey(key)] = apiKey
+}
+
+type testSigner struct {
+	rsaKey *rsa.PrivateKey
+	ecKey  *ecdsa.PrivateKey
+}
+
+func newTestAuthenticator(t *testing.T, cfg config.AuthConfig) (*Authenticator, *fakeKeyStore, *testSigner) {
+	signer := &testSigner{}
+	var err error
+	signer.rsaKey, err = rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	signer.ecKey, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	require.NoError(t, os.WriteFile(path, jwksJSON(t,
+		rsaJWK("rsa-1", &signer.rsaKey.PublicKey),
+		ecJWK("ec-1", &signer.ecKey.PublicKey),
+	), 0o644))
+
+	cfg.Enabled = true
+	cfg.JWKSFile = path
+	store := &fakeKeyStore{keys: map[string]*APIKey{}}
+	a, err := New(cfg, store)
+	require.NoError(t, err)
+	return a, store, signer
+}
+
+func (s *testSigner) sign(t *testing.T, method jwt.SigningMethod, kid string, claims jwt.MapClaims) string {
+	token := jwt.NewWithClaims(method, claims)
+	token.Header["kid"] = kid
+	var key interface{} = s.rsaKey
+	if _, ok := method.(*jwt.SigningMethodECDSA); ok {
+		key = s.ecKey
+	}
+	signed, err := token.SignedString(key)
+	require.NoError(t, err)
+	return signed
+}
+
+func validClaims(scope string) jwt.MapClaims {
+	return jwt.MapClaims{
+		"sub":   "billing-service",
+		"iss":   "https://idp.example.com",
+		"aud":   "order-service",
+		"exp":   time.Now().Add(time.Hour).Unix(),
+		"scope": scope,
+	}
+}
+
+// serveProtected запрос к обработчику под областью scope; возвращает ответ и клиента из контекста
+func serveProtected(a *Authenticator, scope string, header http.Header) (*httptest.ResponseRecorder, *Principal) {
+	var got *Principal
+	h := a.Require(scope, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = FromContext(r.Context())
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/orders", nil)
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	return w, got
+}
+
+func TestNew_Disabled(t *testing.T) {
+	a, err := New(config.AuthConfig{Enabled: false}, nil)
+	require.NoError(t, err)
+	assert.Nil(t, a)
+
+	// выключенная аутентификация пропускает всё
+	w, principal := serveProtected(a, ScopeAdmin, nil)
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Nil(t, principal)
+}
+
+func TestNew_BadJWKS(t *testing.T) {
+	_, err := New(config.AuthConfig{Enabled: true, JWKSFile: filepath.Join(t.TempDir(), "missing.json")}, nil)
+	assert.Error(t, err)
+}
+
+func TestRequire_NoCredentials(t *testing.T) {
+	a, _, _ := newTestAuthenticator(t, config.AuthConfig{})
+
+	for _, header := range []http.Header{
+		nil,
+		{"Authorization": {"Basic dXNlcjpwYXNz"}},
+		{"Authorization": {"Bearer"}},
+	} {
+		w, _ := serveProtected(a, ScopeOrdersRead, header)
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		assert.Equal(t, `Bearer realm="order-service"`, w.Header().Get("WWW-Authenticate"))
+	}
+}
+
+func TestRequire_APIKey(t *testing.T) {
+	a, store, _ := newTestAuthenticator(t, config.AuthConfig{APIKeyCacheTTL: time.Minute})
+	key, err := GenerateAPIKey()
+	require.NoError(t, err)
+	store.add(key, &APIKey{Name: "web", Scopes: []string{ScopeOrdersRead}})
+
+	for _, header := range []http.Header{
+		{"X-Api-Key": {key}},
+		{"Authorization": {"Bearer " + key}},
+	} {
+		w, principal := serveProtected(a, ScopeOrdersRead, header)
+		require.Equal(t, http.StatusNoContent, w.Code)
+		require.NotNil(t, principal)
+		assert.Equal(t, "web", principal.Subject)
+		assert.Equal(t, MethodAPIKey, principal.Method)
+	}
+	// второй запрос взят из кэша
+	assert.Equal(t, 1, store.lookups)
+
+	w, _ := serveProtected(a, ScopeOrdersWrite, http.Header{"X-Api-Key": {key}})
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Contains(t, w.Header().Get("WWW-Authenticate"), `error="insufficient_scope", scope="orders:write"`)
+}
+
+func TestRequire_UnknownAPIKey(t *testing.T) {
+	a, store, _ := newTestAuthenticator(t, config.AuthConfig{})
+
+	w, _ := serveProtected(a, ScopeOrdersRead, http.Header{"X-Api-Key": {"osk_unknown"}})
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Contains(t, w.Header().Get("WWW-Authenticate"), `error="invalid_token"`)
+
+	// повтор неизвестного ключа не доходит до БД, пока запись не истекла
+	w, _ = serveProtected(a, ScopeOrdersRead, http.Header{"X-Api-Key": {"osk_unknown"}})
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Equal(t, 1, store.lookups)
+
+	// ключ выпущен после промаха: работает, как только запись истекла
+	store.add("osk_unknown", &APIKey{Name: "new", Scopes: []string{ScopeOrdersRead}})
+	a.now = func() time.Time { return time.Now().Add(unknownKeyTTL + time.Second) }
+	w, _ = serveProtected(a, ScopeOrdersRead, http.Header{"X-Api-Key": {"osk_unknown"}})
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Equal(t, 2, store.lookups)
+}
+
+func TestRequire_UnknownAPIKeysBounded(t *testing.T) {
+	a, _, _ := newTestAuthenticator(t, config.AuthConfig{})
+
+	for i := 0; i < maxUnknownKeys+100; i++ {
+		a.rememberUnknown(HashAPIKey(fmt.Sprintf("osk_%d", i)), time.Now())
+	}
+	assert.Len(t, a.unknown, maxUnknownKeys)
+}
+
+func TestRequire_APIKeyExpiredInCache(t *testing.T) {
+	a, store, _ := newTestAuthenticator(t, config.AuthConfig{APIKeyCacheTTL: time.Hour})
+	expires := time.Now().Add(time.Minute)
+	store.add("osk_expiring", &APIKey{Name: "tmp", Scopes: []string{ScopeOrdersRead}, ExpiresAt: &expires})
+
+	w, _ := serveProtected(a, ScopeOrdersRead, http.Header{"X-Api-Key": {"osk_expiring"}})
+	require.Equal(t, http.StatusNoContent, w.Code)
+
+	a.now = func() time.Time { return expires.Add(time.Second) }
+	w, _ = serveProtected(a, ScopeOrdersRead, http.Header{"X-Api-Key": {"osk_expiring"}})
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+func TestRequire_KeyStoreError(t *testing.T) {
+	a, store, _ := newTestAuthenticator(t, config.AuthConfig{})
+	store.err = errors.New("connection refused")
+
+	w, _ := serveProtected(a, ScopeOrdersRead, http.Header{"X-Api-Key": {"osk_any"}})
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+}
+
+func TestRequire_AdminHasAllScopes(t *testing.T) {
+	a, store, _ := newTestAuthenticator(t, config.AuthConfig{})
+	store.add("osk_admin", &APIKey{Name: "ops", Scopes: []string{ScopeAdmin}})
+
+	for _, scope := range KnownScopes {
+		w, _ := serveProtected(a, scope, http.Header{"X-Api-Key": {"osk_admin"}})
+		assert.Equal(t, http.StatusNoContent, w.Code, scope)
+	}
+}
+
+func TestRequire_JWT(t *testing.T) {
+	a, _, signer := newTestAuthenticator(t, config.AuthConfig{
+		JWTIssuer:   "https://idp.example.com",
+		JWTAudience: "order-service",
+	})
+
+	for _, tc := range []struct {
+		method jwt.SigningMethod
+		kid    string
+	}{
+		{jwt.SigningMethodRS256, "rsa-1"},
+		{jwt.SigningMethodPS256, "rsa-1"},
+		{jwt.SigningMethodES256, "ec-1"},
+	} {
+		token := signer.sign(t, tc.method, tc.kid, validClaims("orders:read orders:write"))
+		w, principal := serveProtected(a, ScopeOrdersWrite, http.Header{"Authorization": {"Bearer " + token}})
+		require.Equal(t, http.StatusNoContent, w.Code, tc.method.Alg())
+		assert.Equal(t, "billing-service", principal.Subject)
+		assert.Equal(t, MethodJWT, principal.Method)
+		assert.ElementsMatch(t, []string{ScopeOrdersRead, ScopeOrdersWrite}, principal.Scopes)
+	}
+
+	// области массивом scp
+	claims := validClaims("")
+	claims["scp"] = []string{ScopeMetricsRead}
+	token := signer.sign(t, jwt.SigningMethodRS256, "rsa-1", claims)
+	w, _ := serveProtected(a, ScopeMetricsRead, http.Header{"Authorization": {"Bearer " + token}})
+	assert.Equal(t, http.StatusNoContent, w.Code)
+
+	token = signer.sign(t, jwt.SigningMethodRS256, "rsa-1", validClaims(ScopeOrdersRead))
+	w, _ = serveProtected(a, ScopeAdmin, http.Header{"Authorization": {"Bearer " + token}})
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
+
+func TestRequire_InvalidJWT(t *testing.T) {
+	a, _, signer := newTestAuthenticator(t, config.AuthConfig{
+		JWTIssuer:   "https://idp.example.com",
+		JWTAudience: "order-service",
+	})
+
+	withClaim := func(key string, val interface{}) jwt.MapClaims {
+		claims := validClaims(ScopeOrdersRead)
+		if val == nil {
+			delete(claims, key)
+		} else {
+			claims[key] = val
+		}
+		return claims
+	}
+
+	hs256 := jwt.NewWithClaims(jwt.SigningMethodHS256, validClaims(ScopeOrdersRead))
+	hs256.Header["kid"] = "rsa-1"
+	hsToken, err := hs256.SignedString([]byte("secret"))
+	require.NoError(t, err)
+
+	valid := signer.sign(t, jwt.SigningMethodRS256, "rsa-1", validClaims(ScopeOrdersRead))
+	parts := strings.Split(valid, ".")
+
+	tokens := map[string]string{
+		"expired":         signer.sign(t, jwt.SigningMethodRS256, "rsa-1", withClaim("exp", time.Now().Add(-time.Hour).Unix())),
+		"no exp":          signer.sign(t, jwt.SigningMethodRS256, "rsa-1", withClaim("exp", nil)),
+		"not yet valid":   signer.sign(t, jwt.SigningMethodRS256, "rsa-1", withClaim("nbf", time.Now().Add(time.Hour).Unix())),
+		"wrong issuer":    signer.sign(t, jwt.SigningMethodRS256, "rsa-1", withClaim("iss", "https://evil.example.com")),
+		"wrong audience":  signer.sign(t, jwt.SigningMethodRS256, "rsa-1", withClaim("aud", "other-service")),
+		"unknown kid":     signer.sign(t, jwt.SigningMethodRS256, "rsa-2", validClaims(ScopeOrdersRead)),
+		"key type swap":   signer.sign(t, jwt.SigningMethodES256, "rsa-1", validClaims(ScopeOrdersRead)),
+		"hmac":            hsToken,
+		"bad signature":   parts[0] + "." + parts[1] + "." + b64([]byte("forged")),
+		"tampered claims": parts[0] + "." + b64([]byte(`{"sub":"admin","scope":"admin","exp":9999999999}`)) + "." + parts[2],
+	}
+	for name, token := range tokens {
+		w, _ := serveProtected(a, ScopeOrdersRead, http.Header{"Authorization": {"Bearer " + token}})
+		assert.Equal(t, http.StatusUnauthorized, w.Code, name)
+	}
+}
+
+func TestRequire_JWTWithoutJWKS(t *testing.T) {
+	a, err := New(config.AuthConfig{Enabled: true}, &fakeKeyStore{keys: map[string]*APIKey{}})
+	require.NoError(t, err)
+
+	w, _ := serveProtected(a, ScopeOrdersRead, http.Header{"Authorization": {"Bearer aaa.bbb.ccc"}})
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+func TestAPIKeyHelpers(t *testing.T) {
+	k1, err := GenerateAPIKey()
+	require.NoError(t, err)
+	k2, err := GenerateAPIKey()
+	require.NoError(t, err)
+
+	assert.True(t, strings.HasPrefix(k1, apiKeyPrefix))
+	assert.NotEqual(t, k1, k2)
+	assert.Equal(t, HashAPIKey(k1), HashAPIKey(k1))
+	assert.Len(t, HashAPIKey(k1), 64)
+	assert.NotEqual(t, HashAPIKey(k1), HashAPIKey(k2))
+
+	assert.Equal(t, []string{"orders:read", "admin"}, ParseScopes(" orders:read, admin ,"))
+	assert.Equal(t, []string{"orders:read", "orders:write"}, ParseScopes("orders:read orders:write"))
+	assert.Empty(t, ParseScopes(""))
+}
@@ -0,0 +1,163 @@
+// internal/auth/jwks.go
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// JWKS открытые ключи проверки JWT из локального файла, по kid
+type JWKS struct {
+	keys map[string]crypto.PublicKey
+}
+
+type jwk struct {
+	Kid string `json:"kid"`
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	// RSA
+	N string `json:"n"`
+	E string `json:"e"`
+	// EC и OKP
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// LoadJWKS читает JWKS (RFC 7517) из файла. Поддерживаются RSA, EC (P-256/384/521) и Ed25519
+func LoadJWKS(path string) (*JWKS, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось прочитать JWKS: %w", err)
+	}
+	return ParseJWKS(data)
+}
+
+// ParseJWKS разбирает JWKS из памяти, ключи шифрования (use=enc) пропускаются
+func ParseJWKS(data []byte) (*JWKS, error) {
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("ошибка разбора JWKS: %w", err)
+	}
+
+	jwks := &JWKS{keys: make(map[string]crypto.PublicKey, len(set.Keys))}
+	for i, k := range set.Keys {
+		// ключи шифрования для проверки подписи не подходят
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		if k.Kid == "" {
+			return nil, fmt.Errorf("JWKS: у ключа %d не задан kid", i)
+		}
+		key, err := k.publicKey()
+		if err != nil {
+			return nil, fmt.Errorf("JWKS: ключ %s: %w", k.Kid, err)
+		}
+		jwks.keys[k.Kid] = key
+	}
+	if len(jwks.keys) == 0 {
+		return nil, errors.New("JWKS: нет ключей подписи")
+	}
+	return jwks, nil
+}
+
+// Keyfunc выбирает ключ по kid из заголовка токена и сверяет его тип с алгоритмом
+func (j *JWKS) Keyfunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	key, ok := j.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("неизвестный kid %q", kid)
+	}
+
+	var match bool
+	switch token.Method.(type) {
+	case *jwt.SigningMethodRSA, *jwt.SigningMethodRSAPSS:
+		_, match = key.(*rsa.PublicKey)
+	case *jwt.SigningMethodECDSA:
+		_, match = key.(*ecdsa.PublicKey)
+	case *jwt.SigningMethodEd25519:
+		_, match = key.(ed25519.PublicKey)
+	}
+	if !match {
+		return nil, fmt.Errorf("алгоритм %s не подходит к ключу %q", token.Method.Alg(), kid)
+	}
+	return key, nil
+}
+
+func (k jwk) publicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+		if !e.IsInt64() || e.Int64() < 3 {
+			return nil, errors.New("некорректная экспонента RSA")
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("неподдерживаемая кривая %q", k.Crv)
+		}
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		if !curve.IsOnCurve(x, y) {
+			return nil, errors.New("точка не лежит на кривой")
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+
+	case "OKP":
+		if k.Crv != "Ed25519" {
+			return nil, fmt.Errorf("неподдерживаемая кривая %q", k.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil || len(x) != ed25519.PublicKeySize {
+			return nil, errors.New("некорректный ключ Ed25519")
+		}
+		return ed25519.PublicKey(x), nil
+	}
+	return nil, fmt.Errorf("неподдерживаемый тип ключа %q", k.Kty)
+}
+
+func decodeBigInt(val string) (*big.Int, error) {
+	if val == "" {
+		return nil, errors.New("пустой параметр ключа")
+	}
+	b, err := base64.RawURLEncoding.DecodeString(val)
+	if err != nil {
+		return nil, fmt.Errorf("некорректный base64url: %w", err)
+	}
+	return new(big.Int).SetBytes(b), nil
+}
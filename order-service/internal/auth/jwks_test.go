@@ -0,0 +1,122 @@
+// internal/auth/jwks_test.go
+package auth
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func b64(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func rsaJWK(kid string, key *rsa.PublicKey) map[string]string {
+	return map[string]string{
+		"kid": kid, "kty": "RSA", "use": "sig",
+		"n": b64(key.N.Bytes()), "e": b64(big.NewInt(int64(key.E)).Bytes()),
+	}
+}
+
+func ecJWK(kid string, key *ecdsa.PublicKey) map[string]string {
+	size := (key.Curve.Params().BitSize + 7) / 8
+	return map[string]string{
+		"kid": kid, "kty": "EC", "crv": key.Curve.Params().Name,
+		"x": b64(key.X.FillBytes(make([]byte, size))), "y": b64(key.Y.FillBytes(make([]byte, size))),
+	}
+}
+
+func jwksJSON(t *testing.T, keys ...map[string]string) []byte {
+	data, err := json.Marshal(map[string]interface{}{"keys": keys})
+	require.NoError(t, err)
+	return data
+}
+
+func TestParseJWKS_KeyTypes(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	edPub, _, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	jwks, err := ParseJWKS(jwksJSON(t,
+		rsaJWK("rsa", &rsaKey.PublicKey),
+		ecJWK("ec", &ecKey.PublicKey),
+		map[string]string{"kid": "ed", "kty": "OKP", "crv": "Ed25519", "x": b64(edPub)},
+		// ключ шифрования пропускается
+		map[string]string{"kid": "enc", "kty": "RSA", "use": "enc", "n": "AQAB", "e": "AQAB"},
+	))
+	require.NoError(t, err)
+	require.Len(t, jwks.keys, 3)
+
+	assert.True(t, rsaKey.PublicKey.Equal(jwks.keys["rsa"]))
+	assert.True(t, ecKey.PublicKey.Equal(jwks.keys["ec"]))
+	assert.True(t, edPub.Equal(jwks.keys["ed"]))
+}
+
+func TestParseJWKS_Errors(t *testing.T) {
+	tests := map[string]string{
+		"not json":      `{`,
+		"no keys":       `{"keys":[]}`,
+		"no kid":        `{"keys":[{"kty":"RSA","n":"AQAB","e":"AQAB"}]}`,
+		"unknown kty":   `{"keys":[{"kid":"a","kty":"oct","k":"c2VjcmV0"}]}`,
+		"bad curve":     `{"keys":[{"kid":"a","kty":"EC","crv":"P-192","x":"AQ","y":"AQ"}]}`,
+		"off curve":     `{"keys":[{"kid":"a","kty":"EC","crv":"P-256","x":"AQ","y":"AQ"}]}`,
+		"bad base64":    `{"keys":[{"kid":"a","kty":"RSA","n":"***","e":"AQAB"}]}`,
+		"bad exponent":  `{"keys":[{"kid":"a","kty":"RSA","n":"AQAB","e":"AQ"}]}`,
+		"short ed25519": `{"keys":[{"kid":"a","kty":"OKP","crv":"Ed25519","x":"AQ"}]}`,
+	}
+	for name, data := range tests {
+		_, err := ParseJWKS([]byte(data))
+		assert.Error(t, err, name)
+	}
+}
+
+func TestLoadJWKS_File(t *testing.T) {
+	ecKey, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	require.NoError(t, err)
+
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	require.NoError(t, os.WriteFile(path, jwksJSON(t, ecJWK("ec", &ecKey.PublicKey)), 0o644))
+
+	jwks, err := LoadJWKS(path)
+	require.NoError(t, err)
+	assert.Contains(t, jwks.keys, "ec")
+
+	_, err = LoadJWKS(filepath.Join(t.TempDir(), "missing.json"))
+	assert.Error(t, err)
+}
+
+func TestJWKS_KeyfuncChecksAlgorithm(t *testing.T) {
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	jwks, err := ParseJWKS(jwksJSON(t, ecJWK("ec", &ecKey.PublicKey)))
+	require.NoError(t, err)
+
+	token := &jwt.Token{Method: jwt.SigningMethodES256, Header: map[string]interface{}{"kid": "ec"}}
+	key, err := jwks.Keyfunc(token)
+	require.NoError(t, err)
+	assert.True(t, ecKey.PublicKey.Equal(key))
+
+	// ключ EC не должен использоваться для проверки RS256
+	token = &jwt.Token{Method: jwt.SigningMethodRS256, Header: map[string]interface{}{"kid": "ec"}}
+	_, err = jwks.Keyfunc(token)
+	assert.Error(t, err)
+
+	token = &jwt.Token{Method: jwt.SigningMethodES256, Header: map[string]interface{}{"kid": "other"}}
+	_, err = jwks.Keyfunc(token)
+	assert.Error(t, err)
+}
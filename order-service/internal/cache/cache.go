@@ -5,16 +5,40 @@ import (
 	"order-service/internal/metrics"
 	"order-service/models"
 	"sync"
+	"sync/atomic"
 	"time"
+
+	"golang.org/x/sync/singleflight"
 )
 
 var _ interfaces.Cache = (*Cache)(nil)
 
+const (
+	cleanupInterval    = time.Minute
+	defaultNegativeTTL = 30 * time.Second
+)
+
 type Cache struct {
 	mu      sync.RWMutex
 	orders  map[string]cacheItem
 	ttl     time.Duration
 	maxSize int
+
+	// отрицательный кэш: uid отсутствующих заказов и момент истечения записи
+	negative    map[string]time.Time
+	negativeTTL time.Duration
+	loads       singleflight.Group
+
+	snapshotPath     string
+	snapshotInterval time.Duration
+
+	hits      atomic.Uint64
+	misses    atomic.Uint64
+	evictions atomic.Uint64
+
+	stop      chan struct{}
+	stopOnce  sync.Once
+	cleanupWg sync.WaitGroup
 }
 
 type cacheItem struct {
@@ -22,25 +46,52 @@ type cacheItem struct {
 	createdAt time.Time
 }
 
-func New(ttl time.Duration, maxSize int) *Cache {
+// Option дополнительная настройка кэша
+type Option func(*Cache)
+
+// WithNegativeTTL задаёт время жизни записей о ненайденных заказах, 0 отключает отрицательный кэш
+func WithNegativeTTL(ttl time.Duration) Option {
+	return func(c *Cache) {
+		c.negativeTTL = ttl
+	}
+}
+
+func New(ttl time.Duration, maxSize int, opts ...Option) *Cache {
 	c := &Cache{
-		orders:  make(map[string]cacheItem),
-		ttl:     ttl,
-		maxSize: maxSize,
+		orders:      make(map[string]cacheItem),
+		ttl:         ttl,
+		maxSize:     maxSize,
+		negative:    make(map[string]time.Time),
+		negativeTTL: defaultNegativeTTL,
+		stop:        make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	c.cleanupWg.Add(1)
+	go c.cleanup(cleanupInterval)
+	if c.snapshotPath != "" && c.snapshotInterval > 0 {
+		c.cleanupWg.Add(1)
+		go c.snapshotLoop()
 	}
-	go c.cleanup()
 	return c
 }
 
+// BulkSet добавляет отсутствующие заказы; живые записи не трогает, они не старее пакета
 func (c *Cache) BulkSet(orders map[string]*models.Order) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 	for uid, order := range orders {
-		if len(c.orders) >= c.maxSize {
+		item, exists := c.orders[uid]
+		if exists && time.Since(item.createdAt) <= c.ttl {
+			continue
+		}
+		if !exists && len(c.orders) >= c.maxSize {
 			c.evictOldest()
 		}
 
 		c.orders[uid] = cacheItem{order: order, createdAt: time.Now()}
+		delete(c.negative, uid)
 	}
 }
 
@@ -49,10 +100,12 @@ func (c *Cache) Get(orderUID string) (*models.Order, bool) {
 	defer c.mu.RUnlock()
 	item, ok := c.orders[orderUID]
 	if ok && time.Since(item.createdAt) <= c.ttl {
+		c.hits.Add(1)
 		metrics.CacheOperations.WithLabelValues("get", "hit").Inc()
 		return item.order, true
 	}
 
+	c.misses.Add(1)
 	metrics.CacheOperations.WithLabelValues("get", "miss").Inc()
 	return nil, false
 }
@@ -61,15 +114,62 @@ func (c *Cache) Set(orderUID string, order *models.Order) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
-	if len(c.orders) >= c.maxSize {
+	if _, exists := c.orders[orderUID]; !exists && len(c.orders) >= c.maxSize {
 		c.evictOldest()
 	}
 
 	c.orders[orderUID] = cacheItem{order: order, createdAt: time.Now()}
+	delete(c.negative, orderUID)
 
 	metrics.CacheOperations.WithLabelValues("set", "success").Inc()
 }
 
+// Delete убирает заказ из кэша (например, после обновления или удаления)
+func (c *Cache) Delete(orderUID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.orders, orderUID)
+	delete(c.negative, orderUID)
+	metrics.CacheOperations.WithLabelValues("delete", "success").Inc()
+}
+
+// Clear полностью очищает кэш, счётчики статистики не сбрасываются
+func (c *Cache) Clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.orders = make(map[string]cacheItem)
+	c.negative = make(map[string]time.Time)
+	metrics.CacheOperations.WithLabelValues("clear", "success").Inc()
+}
+
+// Len количество записей в кэше, включая ещё не вычищенные просроченные
+func (c *Cache) Len() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return len(c.orders)
+}
+
+func (c *Cache) Stats() interfaces.CacheStats {
+	return interfaces.CacheStats{
+		Hits:      c.hits.Load(),
+		Misses:    c.misses.Load(),
+		Evictions: c.evictions.Load(),
+		Size:      c.Len(),
+	}
+}
+
+// Close останавливает фоновую очистку и снимки и дожидается их завершения.
+// Повторный вызов безопасен.
+func (c *Cache) Close() error {
+	c.stopOnce.Do(func() {
+		close(c.stop)
+	})
+	c.cleanupWg.Wait()
+	return nil
+}
+
 func (c *Cache) evictOldest() {
 	var oldestKey string
 	var oldestTime time.Time
@@ -83,19 +183,37 @@ func (c *Cache) evictOldest() {
 
 	if oldestKey != "" {
 		delete(c.orders, oldestKey)
+		c.evictions.Add(1)
 	}
 }
 
-func (c *Cache) cleanup() {
-	ticker := time.NewTicker(time.Minute)
+func (c *Cache) cleanup(interval time.Duration) {
+	defer c.cleanupWg.Done()
+
+	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
-	for range ticker.C {
-		c.mu.Lock()
-		for uid, item := range c.orders {
-			if time.Since(item.createdAt) > c.ttl {
-				delete(c.orders, uid)
-			}
+	for {
+		select {
+		case <-ticker.C:
+			c.removeExpired()
+		case <-c.stop:
+			return
+		}
+	}
+}
+
+func (c *Cache) removeExpired() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for uid, item := range c.orders {
+		if time.Since(item.createdAt) > c.ttl {
+			delete(c.orders, uid)
+			c.evictions.Add(1)
+		}
+	}
+	for uid, expiresAt := range c.negative {
+		if time.Now().After(expiresAt) {
+			delete(c.negative, uid)
 		}
-		c.mu.Unlock()
 	}
 }
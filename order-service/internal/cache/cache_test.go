@@ -2,16 +2,22 @@
 package cache
 
 import (
+	"database/sql"
+	"errors"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"order-service/models"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestCache_SetAndGet(t *testing.T) {
 	cache := New(5*time.Minute, 100)
+	defer cache.Close()
 	order := &models.Order{OrderUID: "test123", TrackNumber: "WBILMTEST"}
 
 	cache.Set("test123", order)
@@ -23,6 +29,7 @@ func TestCache_SetAndGet(t *testing.T) {
 
 func TestCache_Get_NotFound(t *testing.T) {
 	cache := New(5*time.Minute, 100)
+	defer cache.Close()
 
 	result, found := cache.Get("nonexistent")
 
@@ -32,6 +39,7 @@ func TestCache_Get_NotFound(t *testing.T) {
 
 func TestCache_Expiration(t *testing.T) {
 	cache := New(100*time.Millisecond, 100)
+	defer cache.Close()
 	order := &models.Order{OrderUID: "test123"}
 
 	cache.Set("test123", order)
@@ -45,6 +53,7 @@ func TestCache_Expiration(t *testing.T) {
 
 func TestCache_Eviction(t *testing.T) {
 	cache := New(5*time.Minute, 2)
+	defer cache.Close()
 
 	order1 := &models.Order{OrderUID: "test1"}
 	order2 := &models.Order{OrderUID: "test2"}
@@ -64,3 +73,247 @@ func TestCache_Eviction(t *testing.T) {
 	assert.True(t, found2, "test2 должен остаться")
 	assert.True(t, found3, "test3 должен остаться")
 }
+
+func TestCache_Delete(t *testing.T) {
+	cache := New(5*time.Minute, 100)
+	defer cache.Close()
+
+	cache.Set("test123", &models.Order{OrderUID: "test123"})
+	cache.Delete("test123")
+
+	_, found := cache.Get("test123")
+	assert.False(t, found)
+	assert.Equal(t, 0, cache.Len())
+
+	// удаление отсутствующего ключа не паникует
+	cache.Delete("nonexistent")
+}
+
+func TestCache_ClearAndLen(t *testing.T) {
+	cache := New(5*time.Minute, 100)
+	defer cache.Close()
+
+	cache.BulkSet(map[string]*models.Order{
+		"test1": {OrderUID: "test1"},
+		"test2": {OrderUID: "test2"},
+		"test3": {OrderUID: "test3"},
+	})
+	assert.Equal(t, 3, cache.Len())
+
+	cache.Clear()
+
+	assert.Equal(t, 0, cache.Len())
+	_, found := cache.Get("test1")
+	assert.False(t, found)
+}
+
+func TestCache_BulkSetKeepsExisting(t *testing.T) {
+	cache := New(5*time.Minute, 100)
+	defer cache.Close()
+
+	cache.Set("test1", &models.Order{OrderUID: "test1", TrackNumber: "fresh"})
+	cache.BulkSet(map[string]*models.Order{
+		"test1": {OrderUID: "test1", TrackNumber: "stale"},
+		"test2": {OrderUID: "test2"},
+	})
+
+	order, found := cache.Get("test1")
+	require.True(t, found)
+	assert.Equal(t, "fresh", order.TrackNumber, "BulkSet не перезаписывает имеющиеся записи")
+	assert.Equal(t, 2, cache.Len())
+}
+
+func TestCache_Stats(t *testing.T) {
+	cache := New(5*time.Minute, 1)
+	defer cache.Close()
+
+	cache.Set("test1", &models.Order{OrderUID: "test1"})
+	cache.Get("test1")
+	cache.Get("nonexistent")
+	cache.Set("test2", &models.Order{OrderUID: "test2"})
+
+	stats := cache.Stats()
+	assert.Equal(t, uint64(1), stats.Hits)
+	assert.Equal(t, uint64(1), stats.Misses)
+	assert.Equal(t, uint64(1), stats.Evictions)
+	assert.Equal(t, 1, stats.Size)
+}
+
+func TestCache_SetExistingKeyDoesNotEvict(t *testing.T) {
+	cache := New(5*time.Minute, 2)
+	defer cache.Close()
+
+	cache.Set("test1", &models.Order{OrderUID: "test1"})
+	cache.Set("test2", &models.Order{OrderUID: "test2"})
+	cache.Set("test2", &models.Order{OrderUID: "test2", TrackNumber: "UPDATED"})
+
+	_, found1 := cache.Get("test1")
+	result, found2 := cache.Get("test2")
+
+	assert.True(t, found1)
+	assert.True(t, found2)
+	assert.Equal(t, "UPDATED", result.TrackNumber)
+	assert.Equal(t, uint64(0), cache.Stats().Evictions)
+}
+
+func TestCache_RemoveExpired(t *testing.T) {
+	cache := New(50*time.Millisecond, 100)
+	defer cache.Close()
+
+	cache.Set("test1", &models.Order{OrderUID: "test1"})
+	time.Sleep(100 * time.Millisecond)
+	cache.Set("test2", &models.Order{OrderUID: "test2"})
+
+	cache.removeExpired()
+
+	assert.Equal(t, 1, cache.Len())
+	assert.Equal(t, uint64(1), cache.Stats().Evictions)
+}
+
+func TestCache_Close(t *testing.T) {
+	cache := New(5*time.Minute, 100)
+
+	done := make(chan struct{})
+	go func() {
+		assert.NoError(t, cache.Close())
+		// повторный вызов не должен паниковать на закрытом канале
+		assert.NoError(t, cache.Close())
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Close не дождался остановки горутины очистки")
+	}
+}
+
+func TestCache_GetOrLoad_LoadsAndCaches(t *testing.T) {
+	cache := New(5*time.Minute, 100)
+	defer cache.Close()
+
+	var calls atomic.Int32
+	load := func(uid string) (*models.Order, error) {
+		calls.Add(1)
+		return &models.Order{OrderUID: uid}, nil
+	}
+
+	order, err := cache.GetOrLoad("test123", load)
+	assert.NoError(t, err)
+	assert.Equal(t, "test123", order.OrderUID)
+
+	order, err = cache.GetOrLoad("test123", load)
+	assert.NoError(t, err)
+	assert.Equal(t, "test123", order.OrderUID)
+	assert.Equal(t, int32(1), calls.Load(), "второй запрос должен обслуживаться из кэша")
+}
+
+func TestCache_GetOrLoad_CoalescesConcurrentMisses(t *testing.T) {
+	cache := New(5*time.Minute, 100)
+	defer cache.Close()
+
+	var calls atomic.Int32
+	release := make(chan struct{})
+	load := func(uid string) (*models.Order, error) {
+		calls.Add(1)
+		<-release
+		return &models.Order{OrderUID: uid}, nil
+	}
+
+	const workers = 20
+	var started, wg sync.WaitGroup
+	started.Add(workers)
+	wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go func() {
+			defer wg.Done()
+			started.Done()
+			order, err := cache.GetOrLoad("popular", load)
+			assert.NoError(t, err)
+			assert.Equal(t, "popular", order.OrderUID)
+		}()
+	}
+	started.Wait()
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	assert.Equal(t, int32(1), calls.Load(), "загрузка должна выполниться один раз")
+}
+
+func TestCache_GetOrLoad_NegativeCaching(t *testing.T) {
+	cache := New(5*time.Minute, 100, WithNegativeTTL(100*time.Millisecond))
+	defer cache.Close()
+
+	var calls atomic.Int32
+	load := func(uid string) (*models.Order, error) {
+		calls.Add(1)
+		return nil, sql.ErrNoRows
+	}
+
+	_, err := cache.GetOrLoad("missing", load)
+	assert.True(t, errors.Is(err, sql.ErrNoRows))
+
+	_, err = cache.GetOrLoad("missing", load)
+	assert.True(t, errors.Is(err, sql.ErrNoRows))
+	assert.Equal(t, int32(1), calls.Load(), "повторный запрос должен отбиться отрицательным кэшем")
+
+	time.Sleep(150 * time.Millisecond)
+
+	_, err = cache.GetOrLoad("missing", load)
+	assert.True(t, errors.Is(err, sql.ErrNoRows))
+	assert.Equal(t, int32(2), calls.Load(), "после истечения TTL загрузка повторяется")
+}
+
+func TestCache_GetOrLoad_SetClearsNegative(t *testing.T) {
+	cache := New(5*time.Minute, 100)
+	defer cache.Close()
+
+	_, err := cache.GetOrLoad("late", func(uid string) (*models.Order, error) {
+		return nil, sql.ErrNoRows
+	})
+	assert.Error(t, err)
+
+	cache.Set("late", &models.Order{OrderUID: "late"})
+
+	order, err := cache.GetOrLoad("late", func(uid string) (*models.Order, error) {
+		t.Fatal("загрузчик не должен вызываться")
+		return nil, nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "late", order.OrderUID)
+}
+
+func TestCache_GetOrLoad_ErrorNotCached(t *testing.T) {
+	cache := New(5*time.Minute, 100)
+	defer cache.Close()
+
+	var calls atomic.Int32
+	load := func(uid string) (*models.Order, error) {
+		calls.Add(1)
+		return nil, errors.New("db connection failed")
+	}
+
+	_, err := cache.GetOrLoad("test123", load)
+	assert.Error(t, err)
+	_, err = cache.GetOrLoad("test123", load)
+	assert.Error(t, err)
+
+	assert.Equal(t, int32(2), calls.Load(), "прочие ошибки не должны кэшироваться")
+}
+
+func TestCache_GetOrLoad_NegativeDisabled(t *testing.T) {
+	cache := New(5*time.Minute, 100, WithNegativeTTL(0))
+	defer cache.Close()
+
+	var calls atomic.Int32
+	load := func(uid string) (*models.Order, error) {
+		calls.Add(1)
+		return nil, sql.ErrNoRows
+	}
+
+	cache.GetOrLoad("missing", load)
+	cache.GetOrLoad("missing", load)
+
+	assert.Equal(t, int32(2), calls.Load())
+}
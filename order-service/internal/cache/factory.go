@@ -0,0 +1,59 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"order-service/internal/config"
+	"order-service/internal/interfaces"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// NewFromConfig выбирает реализацию кэша по cfg.Backend. Вторым значением
+// возвращается локальный уровень кэша, который нужно инвалидировать при
+// изменениях на других репликах (nil, если кэш целиком общий).
+func NewFromConfig(ctx context.Context, cfg config.CacheConfig) (interfaces.Cache, interfaces.Cache, error) {
+	opts := []Option{WithNegativeTTL(cfg.NegativeTTL)}
+	if cfg.SnapshotPath != "" {
+		opts = append(opts, WithSnapshot(cfg.SnapshotPath, cfg.SnapshotInterval))
+	}
+
+	switch cfg.Backend {
+	case "memory":
+		c := New(cfg.TTL, cfg.MaxSize, opts...)
+		return c, c, nil
+	case "redis":
+		r, err := newRedisFromConfig(ctx, cfg)
+		if err != nil {
+			return nil, nil, err
+		}
+		return r, nil, nil
+	case "tiered":
+		r, err := newRedisFromConfig(ctx, cfg)
+		if err != nil {
+			return nil, nil, err
+		}
+		// L1 живёт меньше, чтобы реплики быстрее видели изменения из L2;
+		// отрицательный кэш ведёт L2, снимок L1 не нужен
+		l1 := New(cfg.TTL/5, cfg.MaxSize, WithNegativeTTL(0))
+		return NewTiered(l1, r), l1, nil
+	default:
+		return nil, nil, fmt.Errorf("неизвестный backend кэша %q", cfg.Backend)
+	}
+}
+
+func newRedisFromConfig(ctx context.Context, cfg config.CacheConfig) (*RedisCache, error) {
+	r := NewRedis(redis.NewClient(&redis.Options{
+		Addr:     cfg.RedisAddr,
+		Password: cfg.RedisPassword,
+	}), cfg.TTL, cfg.NegativeTTL)
+
+	pingCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	if err := r.Ping(pingCtx); err != nil {
+		r.Close()
+		return nil, fmt.Errorf("redis %s недоступен: %w", cfg.RedisAddr, err)
+	}
+	return r, nil
+}
@@ -0,0 +1,85 @@
+package cache
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"order-service/internal/interfaces"
+	"order-service/internal/metrics"
+	"order-service/models"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// negativeStore хранилище, умеющее запоминать отсутствующие заказы
+type negativeStore interface {
+	Get(orderUID string) (*models.Order, bool)
+	Set(orderUID string, order *models.Order)
+	isNegative(orderUID string) bool
+	setNegative(orderUID string)
+}
+
+// GetOrLoad отдаёт заказ из кэша, а при промахе загружает его через load.
+// Одновременные промахи по одному order_uid схлопываются в один вызов load,
+// ненайденные заказы (sql.ErrNoRows) запоминаются на negativeTTL.
+func (c *Cache) GetOrLoad(orderUID string, load interfaces.LoadFunc) (*models.Order, error) {
+	return getOrLoad(c, &c.loads, orderUID, load)
+}
+
+func getOrLoad(s negativeStore, loads *singleflight.Group, orderUID string, load interfaces.LoadFunc) (*models.Order, error) {
+	if order, ok := s.Get(orderUID); ok {
+		return order, nil
+	}
+
+	if s.isNegative(orderUID) {
+		metrics.CacheOperations.WithLabelValues("load", "negative_hit").Inc()
+		return nil, fmt.Errorf("заказ %s не найден (отрицательный кэш): %w", orderUID, sql.ErrNoRows)
+	}
+
+	v, err, shared := loads.Do(orderUID, func() (interface{}, error) {
+		order, err := load(orderUID)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				s.setNegative(orderUID)
+			}
+			metrics.CacheOperations.WithLabelValues("load", "error").Inc()
+			return nil, err
+		}
+		s.Set(orderUID, order)
+		metrics.CacheOperations.WithLabelValues("load", "success").Inc()
+		return order, nil
+	})
+	if shared {
+		metrics.CacheOperations.WithLabelValues("load", "shared").Inc()
+	}
+	if err != nil {
+		return nil, err
+	}
+	return v.(*models.Order), nil
+}
+
+func (c *Cache) isNegative(orderUID string) bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	expiresAt, ok := c.negative[orderUID]
+	return ok && time.Now().Before(expiresAt)
+}
+
+func (c *Cache) setNegative(orderUID string) {
+	if c.negativeTTL <= 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// при переборе uid отрицательный кэш не должен расти бесконечно
+	if _, exists := c.negative[orderUID]; !exists && len(c.negative) >= c.maxSize {
+		for uid := range c.negative {
+			delete(c.negative, uid)
+			break
+		}
+	}
+	c.negative[orderUID] = time.Now().Add(c.negativeTTL)
+}
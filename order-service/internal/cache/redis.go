@@ -0,0 +1,240 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"order-service/internal/interfaces"
+	"order-service/internal/logging"
+	"order-service/internal/metrics"
+	"order-service/models"
+	"sync/atomic"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"golang.org/x/sync/singleflight"
+)
+
+var _ interfaces.Cache = (*RedisCache)(nil)
+
+const (
+	redisOrderPrefix    = "order:"
+	redisNegativePrefix = "order_nf:"
+	redisOpTimeout      = time.Second
+	redisScanCount      = 500
+)
+
+// RedisCache распределённый кэш заказов, общий для всех реплик сервиса.
+// Заказы хранятся в JSON под ключами order:{uid} с TTL на стороне Redis.
+type RedisCache struct {
+	client      redis.UniversalClient
+	ttl         time.Duration
+	negativeTTL time.Duration
+	loads       singleflight.Group
+
+	hits   atomic.Uint64
+	misses atomic.Uint64
+}
+
+// NewRedis создаёт кэш поверх готового клиента, клиент закрывается в Close
+func NewRedis(client redis.UniversalClient, ttl, negativeTTL time.Duration) *RedisCache {
+	return &RedisCache{
+		client:      client,
+		ttl:         ttl,
+		negativeTTL: negativeTTL,
+	}
+}
+
+// Ping проверяет доступность Redis
+func (r *RedisCache) Ping(ctx context.Context) error {
+	return r.client.Ping(ctx).Err()
+}
+
+func (r *RedisCache) Get(orderUID string) (*models.Order, bool) {
+	ctx, cancel := context.WithTimeout(context.Background(), redisOpTimeout)
+	defer cancel()
+
+	data, err := r.client.Get(ctx, redisOrderPrefix+orderUID).Bytes()
+	if err != nil {
+		if !errors.Is(err, redis.Nil) {
+			slog.Error("Ошибка чтения заказа из Redis", slog.String("order_uid", orderUID), logging.Err(err))
+			metrics.CacheOperations.WithLabelValues("redis_get", "error").Inc()
+		}
+		r.misses.Add(1)
+		metrics.CacheOperations.WithLabelValues("redis_get", "miss").Inc()
+		return nil, false
+	}
+
+	var order models.Order
+	if err := json.Unmarshal(data, &order); err != nil {
+		slog.Error("Повреждённая запись заказа в Redis", slog.String("order_uid", orderUID), logging.Err(err))
+		r.misses.Add(1)
+		metrics.CacheOperations.WithLabelValues("redis_get", "error").Inc()
+		return nil, false
+	}
+
+	r.hits.Add(1)
+	metrics.CacheOperations.WithLabelValues("redis_get", "hit").Inc()
+	return &order, true
+}
+
+func (r *RedisCache) GetOrLoad(orderUID string, load interfaces.LoadFunc) (*models.Order, error) {
+	return getOrLoad(r, &r.loads, orderUID, load)
+}
+
+func (r *RedisCache) Set(orderUID string, order *models.Order) {
+	data, err := json.Marshal(order)
+	if err != nil {
+		slog.Error("Ошибка сериализации заказа", slog.String("order_uid", orderUID), logging.Err(err))
+		metrics.CacheOperations.WithLabelValues("redis_set", "error").Inc()
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), redisOpTimeout)
+	defer cancel()
+
+	_, err = r.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(ctx, redisOrderPrefix+orderUID, data, r.ttl)
+		pipe.Del(ctx, redisNegativePrefix+orderUID)
+		return nil
+	})
+	if err != nil {
+		slog.Error("Ошибка записи заказа в Redis", slog.String("order_uid", orderUID), logging.Err(err))
+		metrics.CacheOperations.WithLabelValues("redis_set", "error").Inc()
+		return
+	}
+	metrics.CacheOperations.WithLabelValues("redis_set", "success").Inc()
+}
+
+// BulkSet записывает отсутствующие в Redis заказы одним пайплайном (SET NX), используется для прогрева
+func (r *RedisCache) BulkSet(orders map[string]*models.Order) {
+	r.bulkAdd(orders)
+}
+
+// bulkAdd BulkSet, возвращающий заказы, которые действительно записаны
+func (r *RedisCache) bulkAdd(orders map[string]*models.Order) map[string]*models.Order {
+	if len(orders) == 0 {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), redisOpTimeout*time.Duration(1+len(orders)/redisScanCount))
+	defer cancel()
+
+	results := make(map[string]*redis.BoolCmd, len(orders))
+	_, err := r.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for uid, order := range orders {
+			data, err := json.Marshal(order)
+			if err != nil {
+				slog.Error("Ошибка сериализации заказа", slog.String("order_uid", uid), logging.Err(err))
+				continue
+			}
+			results[uid] = pipe.SetNX(ctx, redisOrderPrefix+uid, data, r.ttl)
+			pipe.Del(ctx, redisNegativePrefix+uid)
+		}
+		return nil
+	})
+	if err != nil {
+		slog.Error("Ошибка пакетной записи в Redis", logging.Err(err))
+		metrics.CacheOperations.WithLabelValues("redis_bulk_set", "error").Inc()
+		return nil
+	}
+	metrics.CacheOperations.WithLabelValues("redis_bulk_set", "success").Inc()
+
+	added := make(map[string]*models.Order, len(results))
+	for uid, cmd := range results {
+		if cmd.Val() {
+			added[uid] = orders[uid]
+		}
+	}
+	return added
+}
+
+func (r *RedisCache) Delete(orderUID string) {
+	ctx, cancel := context.WithTimeout(context.Background(), redisOpTimeout)
+	defer cancel()
+
+	if err := r.client.Del(ctx, redisOrderPrefix+orderUID, redisNegativePrefix+orderUID).Err(); err != nil {
+		slog.Error("Ошибка удаления заказа из Redis", slog.String("order_uid", orderUID), logging.Err(err))
+		metrics.CacheOperations.WithLabelValues("redis_delete", "error").Inc()
+		return
+	}
+	metrics.CacheOperations.WithLabelValues("redis_delete", "success").Inc()
+}
+
+// Clear удаляет только ключи заказов, остальные данные в Redis не трогаются
+func (r *RedisCache) Clear() {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*redisOpTimeout)
+	defer cancel()
+
+	for _, pattern := range []string{redisOrderPrefix + "*", redisNegativePrefix + "*"} {
+		iter := r.client.Scan(ctx, 0, pattern, redisScanCount).Iterator()
+		batch := make([]string, 0, redisScanCount)
+		for iter.Next(ctx) {
+			batch = append(batch, iter.Val())
+			if len(batch) == redisScanCount {
+				r.client.Del(ctx, batch...)
+				batch = batch[:0]
+			}
+		}
+		if len(batch) > 0 {
+			r.client.Del(ctx, batch...)
+		}
+		if err := iter.Err(); err != nil {
+			slog.Error("Ошибка очистки Redis", logging.Err(err))
+			metrics.CacheOperations.WithLabelValues("redis_clear", "error").Inc()
+			return
+		}
+	}
+	metrics.CacheOperations.WithLabelValues("redis_clear", "success").Inc()
+}
+
+// Len количество заказов в Redis, считается через SCAN по всей базе: дорого, не для частых снимков
+func (r *RedisCache) Len() int {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*redisOpTimeout)
+	defer cancel()
+
+	n := 0
+	iter := r.client.Scan(ctx, 0, redisOrderPrefix+"*", redisScanCount).Iterator()
+	for iter.Next(ctx) {
+		n++
+	}
+	if err := iter.Err(); err != nil {
+		slog.Error("Ошибка подсчёта ключей Redis", logging.Err(err))
+	}
+	return n
+}
+
+// Stats счётчики текущей реплики, вытеснение выполняет сам Redis и не учитывается.
+// Размер не считается: снимок не должен сканировать всю базу, точное число — Len
+func (r *RedisCache) Stats() interfaces.CacheStats {
+	return interfaces.CacheStats{
+		Hits:   r.hits.Load(),
+		Misses: r.misses.Load(),
+	}
+}
+
+func (r *RedisCache) Close() error {
+	return r.client.Close()
+}
+
+func (r *RedisCache) isNegative(orderUID string) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), redisOpTimeout)
+	defer cancel()
+
+	n, err := r.client.Exists(ctx, redisNegativePrefix+orderUID).Result()
+	return err == nil && n > 0
+}
+
+func (r *RedisCache) setNegative(orderUID string) {
+	if r.negativeTTL <= 0 {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), redisOpTimeout)
+	defer cancel()
+
+	if err := r.client.Set(ctx, redisNegativePrefix+orderUID, 1, r.negativeTTL).Err(); err != nil {
+		slog.Error("Ошибка записи отрицательного кэша в Redis", slog.String("order_uid", orderUID), logging.Err(err))
+	}
+}
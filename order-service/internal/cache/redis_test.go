@@ -0,0 +1,214 @@
+// internal/cache/redis_test.go
+package cache
+
+import (
+	"database/sql"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"order-service/models"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestRedis(t *testing.T, ttl time.Duration) (*RedisCache, *miniredis.Miniredis) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	r := NewRedis(client, ttl, 30*time.Second)
+	t.Cleanup(func() { r.Close() })
+	return r, mr
+}
+
+func TestRedisCache_SetAndGet(t *testing.T) {
+	r, _ := newTestRedis(t, 5*time.Minute)
+	created := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	order := &models.Order{
+		OrderUID:    "test123",
+		TrackNumber: "WBILMTEST",
+		DateCreated: created,
+		Items:       []models.Item{{ChrtID: 1, Name: "item"}},
+	}
+
+	r.Set("test123", order)
+	result, found := r.Get("test123")
+
+	require.True(t, found)
+	assert.Equal(t, order.TrackNumber, result.TrackNumber)
+	assert.True(t, created.Equal(result.DateCreated))
+	assert.Len(t, result.Items, 1)
+}
+
+func TestRedisCache_Get_NotFound(t *testing.T) {
+	r, _ := newTestRedis(t, 5*time.Minute)
+
+	result, found := r.Get("nonexistent")
+
+	assert.False(t, found)
+	assert.Nil(t, result)
+}
+
+func TestRedisCache_Expiration(t *testing.T) {
+	r, mr := newTestRedis(t, time.Minute)
+
+	r.Set("test123", &models.Order{OrderUID: "test123"})
+	mr.FastForward(2 * time.Minute)
+
+	_, found := r.Get("test123")
+	assert.False(t, found)
+}
+
+func TestRedisCache_BulkSetDeleteClearLen(t *testing.T) {
+	r, mr := newTestRedis(t, 5*time.Minute)
+	mr.Set("unrelated", "keep")
+
+	r.BulkSet(map[string]*models.Order{
+		"test1": {OrderUID: "test1"},
+		"test2": {OrderUID: "test2"},
+		"test3": {OrderUID: "test3"},
+	})
+	assert.Equal(t, 3, r.Len())
+
+	r.Delete("test2")
+	assert.Equal(t, 2, r.Len())
+
+	r.Clear()
+	assert.Equal(t, 0, r.Len())
+	assert.True(t, mr.Exists("unrelated"), "Clear не должен трогать чужие ключи")
+}
+
+func TestTiered_BulkSetKeepsFresherL2(t *testing.T) {
+	l2, _ := newTestRedis(t, 5*time.Minute)
+	l1 := New(5*time.Minute, 100)
+	tiered := NewTiered(l1, l2)
+	defer tiered.Close()
+
+	// свежая версия записана другой репликой только в L2
+	l2.Set("test1", &models.Order{OrderUID: "test1", TrackNumber: "fresh"})
+	tiered.BulkSet(map[string]*models.Order{
+		"test1": {OrderUID: "test1", TrackNumber: "stale"},
+		"test2": {OrderUID: "test2"},
+	})
+
+	_, inL1 := l1.Get("test1")
+	assert.False(t, inL1, "устаревшая версия не должна попасть в L1")
+	order, found := tiered.Get("test1")
+	require.True(t, found)
+	assert.Equal(t, "fresh", order.TrackNumber)
+	_, inL1 = l1.Get("test2")
+	assert.True(t, inL1)
+}
+
+func TestRedisCache_CorruptedEntry(t *testing.T) {
+	r, mr := newTestRedis(t, 5*time.Minute)
+	mr.Set(redisOrderPrefix+"broken", "{not json")
+
+	_, found := r.Get("broken")
+	assert.False(t, found)
+}
+
+func TestRedisCache_GetOrLoad_NegativeCaching(t *testing.T) {
+	r, mr := newTestRedis(t, 5*time.Minute)
+
+	var calls atomic.Int32
+	load := func(uid string) (*models.Order, error) {
+		calls.Add(1)
+		return nil, sql.ErrNoRows
+	}
+
+	_, err := r.GetOrLoad("missing", load)
+	assert.True(t, errors.Is(err, sql.ErrNoRows))
+	_, err = r.GetOrLoad("missing", load)
+	assert.True(t, errors.Is(err, sql.ErrNoRows))
+	assert.Equal(t, int32(1), calls.Load())
+
+	mr.FastForward(time.Minute)
+	r.GetOrLoad("missing", load)
+	assert.Equal(t, int32(2), calls.Load())
+}
+
+func TestRedisCache_Stats(t *testing.T) {
+	r, _ := newTestRedis(t, 5*time.Minute)
+
+	r.Set("test1", &models.Order{OrderUID: "test1"})
+	r.Get("test1")
+	r.Get("nonexistent")
+
+	stats := r.Stats()
+	assert.Equal(t, uint64(1), stats.Hits)
+	assert.Equal(t, uint64(1), stats.Misses)
+	// размер только по Len, снимок не сканирует базу
+	assert.Zero(t, stats.Size)
+	assert.Equal(t, 1, r.Len())
+}
+
+func TestTiered_StatsSizeFromL1(t *testing.T) {
+	l2, _ := newTestRedis(t, 5*time.Minute)
+	l1 := New(5*time.Minute, 100)
+	tiered := NewTiered(l1, l2)
+
+	tiered.Set("a", &models.Order{OrderUID: "a"})
+	l2.Set("b", &models.Order{OrderUID: "b"})
+
+	assert.Equal(t, 1, tiered.Stats().Size)
+	assert.Equal(t, 2, tiered.Len())
+}
+
+func TestTiered_ReadThroughL2WarmsL1(t *testing.T) {
+	l2, _ := newTestRedis(t, 5*time.Minute)
+	l1 := New(5*time.Minute, 100)
+	tiered := NewTiered(l1, l2)
+	defer tiered.Close()
+
+	// заказ записан другой репликой только в L2
+	l2.Set("test123", &models.Order{OrderUID: "test123"})
+
+	result, found := tiered.Get("test123")
+	require.True(t, found)
+	assert.Equal(t, "test123", result.OrderUID)
+
+	_, inL1 := l1.Get("test123")
+	assert.True(t, inL1, "после чтения из L2 заказ должен попасть в L1")
+}
+
+func TestTiered_SetAndDeleteBothLevels(t *testing.T) {
+	l2, _ := newTestRedis(t, 5*time.Minute)
+	l1 := New(5*time.Minute, 100)
+	tiered := NewTiered(l1, l2)
+	defer tiered.Close()
+
+	tiered.Set("test123", &models.Order{OrderUID: "test123"})
+	assert.Equal(t, 1, l1.Len())
+	assert.Equal(t, 1, l2.Len())
+
+	tiered.Delete("test123")
+	assert.Equal(t, 0, l1.Len())
+	assert.Equal(t, 0, l2.Len())
+}
+
+func TestTiered_GetOrLoad(t *testing.T) {
+	l2, _ := newTestRedis(t, 5*time.Minute)
+	l1 := New(5*time.Minute, 100)
+	tiered := NewTiered(l1, l2)
+	defer tiered.Close()
+
+	var calls atomic.Int32
+	load := func(uid string) (*models.Order, error) {
+		calls.Add(1)
+		return &models.Order{OrderUID: uid}, nil
+	}
+
+	order, err := tiered.GetOrLoad("test123", load)
+	require.NoError(t, err)
+	assert.Equal(t, "test123", order.OrderUID)
+
+	_, err = tiered.GetOrLoad("test123", load)
+	require.NoError(t, err)
+	assert.Equal(t, int32(1), calls.Load())
+	assert.Equal(t, 1, l1.Len())
+	assert.Equal(t, 1, l2.Len())
+}
@@ -0,0 +1,155 @@
+package cache
+
+import (
+	"bufio"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"log/slog"
+	"order-service/internal/logging"
+	"order-service/models"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const snapshotVersion = 1
+
+// ErrSnapshotStale снимок старше допустимого возраста
+var ErrSnapshotStale = errors.New("снимок кэша устарел")
+
+type snapshotHeader struct {
+	Version int
+	SavedAt time.Time
+	Count   int
+}
+
+type snapshotEntry struct {
+	OrderUID  string
+	Order     *models.Order
+	CreatedAt time.Time
+}
+
+// WithSnapshot включает периодическое сохранение кэша в файл path.
+// Последний снимок пишется при Close.
+func WithSnapshot(path string, interval time.Duration) Option {
+	return func(c *Cache) {
+		c.snapshotPath = path
+		c.snapshotInterval = interval
+	}
+}
+
+// SaveSnapshot атомарно записывает текущее содержимое кэша в gob-файл,
+// время помещения в кэш сохраняется, чтобы TTL не продлевался после рестарта
+func (c *Cache) SaveSnapshot(path string) error {
+	c.mu.RLock()
+	entries := make([]snapshotEntry, 0, len(c.orders))
+	for uid, item := range c.orders {
+		if time.Since(item.createdAt) > c.ttl {
+			continue
+		}
+		entries = append(entries, snapshotEntry{OrderUID: uid, Order: item.order, CreatedAt: item.createdAt})
+	}
+	c.mu.RUnlock()
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	w := bufio.NewWriter(tmp)
+	enc := gob.NewEncoder(w)
+	if err := enc.Encode(snapshotHeader{Version: snapshotVersion, SavedAt: time.Now(), Count: len(entries)}); err != nil {
+		tmp.Close()
+		return err
+	}
+	for _, e := range entries {
+		if err := enc.Encode(e); err != nil {
+			tmp.Close()
+			return err
+		}
+	}
+	if err := w.Flush(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// LoadSnapshot восстанавливает кэш из файла. Возвращает ошибку, если файл
+// отсутствует, повреждён или старше maxAge (0 — без ограничения), в этом
+// случае кэш не изменяется и прогрев нужно выполнить из БД.
+func (c *Cache) LoadSnapshot(path string, maxAge time.Duration) (int, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	dec := gob.NewDecoder(bufio.NewReader(f))
+	var header snapshotHeader
+	if err := dec.Decode(&header); err != nil {
+		return 0, fmt.Errorf("повреждён заголовок снимка: %w", err)
+	}
+	if header.Version != snapshotVersion {
+		return 0, fmt.Errorf("неподдерживаемая версия снимка %d", header.Version)
+	}
+	if maxAge > 0 && time.Since(header.SavedAt) > maxAge {
+		return 0, fmt.Errorf("%w: сохранён %s назад", ErrSnapshotStale, time.Since(header.SavedAt).Round(time.Second))
+	}
+
+	entries := make([]snapshotEntry, 0, header.Count)
+	for i := 0; i < header.Count; i++ {
+		var e snapshotEntry
+		if err := dec.Decode(&e); err != nil {
+			return 0, fmt.Errorf("повреждена запись %d снимка: %w", i, err)
+		}
+		entries = append(entries, e)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	restored := 0
+	for _, e := range entries {
+		if e.Order == nil || time.Since(e.CreatedAt) > c.ttl {
+			continue
+		}
+		if _, exists := c.orders[e.OrderUID]; !exists && len(c.orders) >= c.maxSize {
+			c.evictOldest()
+		}
+		c.orders[e.OrderUID] = cacheItem{order: e.Order, createdAt: e.CreatedAt}
+		restored++
+	}
+	return restored, nil
+}
+
+func (c *Cache) snapshotLoop() {
+	defer c.cleanupWg.Done()
+
+	ticker := time.NewTicker(c.snapshotInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := c.SaveSnapshot(c.snapshotPath); err != nil {
+				slog.Error("Ошибка сохранения снимка кэша", logging.Err(err))
+			}
+		case <-c.stop:
+			if err := c.SaveSnapshot(c.snapshotPath); err != nil {
+				slog.Error("Ошибка сохранения снимка кэша при остановке", logging.Err(err))
+			}
+			return
+		}
+	}
+}
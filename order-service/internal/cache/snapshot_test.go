@@ -0,0 +1,111 @@
+// internal/cache/snapshot_test.go
+package cache
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"order-service/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCache_SnapshotRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.snapshot")
+
+	src := New(5*time.Minute, 100)
+	defer src.Close()
+	src.Set("test1", &models.Order{OrderUID: "test1", Items: []models.Item{{ChrtID: 1}}})
+	src.Set("test2", &models.Order{OrderUID: "test2"})
+	require.NoError(t, src.SaveSnapshot(path))
+
+	dst := New(5*time.Minute, 100)
+	defer dst.Close()
+	n, err := dst.LoadSnapshot(path, time.Hour)
+	require.NoError(t, err)
+
+	assert.Equal(t, 2, n)
+	result, found := dst.Get("test1")
+	require.True(t, found)
+	assert.Len(t, result.Items, 1)
+
+	src.mu.RLock()
+	srcCreated := src.orders["test1"].createdAt
+	src.mu.RUnlock()
+	dst.mu.RLock()
+	dstCreated := dst.orders["test1"].createdAt
+	dst.mu.RUnlock()
+	assert.True(t, srcCreated.Equal(dstCreated), "время помещения в кэш должно сохраняться")
+}
+
+func TestCache_LoadSnapshot_SkipsExpired(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.snapshot")
+
+	src := New(5*time.Minute, 100)
+	defer src.Close()
+	src.Set("test1", &models.Order{OrderUID: "test1"})
+	require.NoError(t, src.SaveSnapshot(path))
+
+	time.Sleep(60 * time.Millisecond)
+
+	dst := New(50*time.Millisecond, 100)
+	defer dst.Close()
+	n, err := dst.LoadSnapshot(path, time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, 0, n)
+}
+
+func TestCache_LoadSnapshot_Missing(t *testing.T) {
+	c := New(5*time.Minute, 100)
+	defer c.Close()
+
+	_, err := c.LoadSnapshot(filepath.Join(t.TempDir(), "nope"), time.Hour)
+	assert.True(t, errors.Is(err, os.ErrNotExist))
+}
+
+func TestCache_LoadSnapshot_Corrupt(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.snapshot")
+	require.NoError(t, os.WriteFile(path, []byte("garbage"), 0o644))
+
+	c := New(5*time.Minute, 100)
+	defer c.Close()
+
+	_, err := c.LoadSnapshot(path, time.Hour)
+	assert.Error(t, err)
+	assert.Equal(t, 0, c.Len())
+}
+
+func TestCache_LoadSnapshot_Stale(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.snapshot")
+
+	src := New(5*time.Minute, 100)
+	defer src.Close()
+	src.Set("test1", &models.Order{OrderUID: "test1"})
+	require.NoError(t, src.SaveSnapshot(path))
+
+	time.Sleep(20 * time.Millisecond)
+
+	dst := New(5*time.Minute, 100)
+	defer dst.Close()
+	_, err := dst.LoadSnapshot(path, 10*time.Millisecond)
+	assert.True(t, errors.Is(err, ErrSnapshotStale))
+	assert.Equal(t, 0, dst.Len())
+}
+
+func TestCache_SnapshotWrittenOnClose(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.snapshot")
+
+	c := New(5*time.Minute, 100, WithSnapshot(path, time.Hour))
+	c.Set("test1", &models.Order{OrderUID: "test1"})
+	require.NoError(t, c.Close())
+
+	restored := New(5*time.Minute, 100)
+	defer restored.Close()
+	n, err := restored.LoadSnapshot(path, time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+}
@@ -0,0 +1,102 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"order-service/internal/interfaces"
+	"order-service/models"
+)
+
+var _ interfaces.Cache = (*Tiered)(nil)
+
+// Tiered двухуровневый кэш: быстрый локальный L1 и общий для реплик L2.
+// Запись идёт в оба уровня, чтение из L2 прогревает L1.
+type Tiered struct {
+	l1 interfaces.Cache
+	l2 interfaces.Cache
+}
+
+func NewTiered(l1, l2 interfaces.Cache) *Tiered {
+	return &Tiered{l1: l1, l2: l2}
+}
+
+// Ping проверяет доступность L2, если он это поддерживает
+func (t *Tiered) Ping(ctx context.Context) error {
+	if p, ok := t.l2.(interface{ Ping(context.Context) error }); ok {
+		return p.Ping(ctx)
+	}
+	return nil
+}
+
+func (t *Tiered) Get(orderUID string) (*models.Order, bool) {
+	if order, ok := t.l1.Get(orderUID); ok {
+		return order, true
+	}
+	order, ok := t.l2.Get(orderUID)
+	if ok {
+		t.l1.Set(orderUID, order)
+	}
+	return order, ok
+}
+
+// GetOrLoad схлопывание промахов и отрицательный кэш обеспечивает L2
+func (t *Tiered) GetOrLoad(orderUID string, load interfaces.LoadFunc) (*models.Order, error) {
+	if order, ok := t.l1.Get(orderUID); ok {
+		return order, nil
+	}
+	order, err := t.l2.GetOrLoad(orderUID, load)
+	if err != nil {
+		return nil, err
+	}
+	t.l1.Set(orderUID, order)
+	return order, nil
+}
+
+func (t *Tiered) Set(orderUID string, order *models.Order) {
+	t.l2.Set(orderUID, order)
+	t.l1.Set(orderUID, order)
+}
+
+// BulkSet в L1 попадают только заказы, записанные в L2: если в L2 уже лежит более
+// свежая версия, L1 получит её при чтении, а не устаревшую из пакета
+func (t *Tiered) BulkSet(orders map[string]*models.Order) {
+	if l2, ok := t.l2.(interface {
+		bulkAdd(map[string]*models.Order) map[string]*models.Order
+	}); ok {
+		orders = l2.bulkAdd(orders)
+	} else {
+		t.l2.BulkSet(orders)
+	}
+	t.l1.BulkSet(orders)
+}
+
+func (t *Tiered) Delete(orderUID string) {
+	t.l2.Delete(orderUID)
+	t.l1.Delete(orderUID)
+}
+
+func (t *Tiered) Clear() {
+	t.l2.Clear()
+	t.l1.Clear()
+}
+
+// Len размер L2, он содержит все заказы L1. У Redis это SCAN по всей базе
+func (t *Tiered) Len() int {
+	return t.l2.Len()
+}
+
+// Stats попадания суммируются по уровням, промахом считается только промах L2.
+// Размер — только L1 этой реплики: размер L2 потребовал бы SCAN на каждый снимок
+func (t *Tiered) Stats() interfaces.CacheStats {
+	s1, s2 := t.l1.Stats(), t.l2.Stats()
+	return interfaces.CacheStats{
+		Hits:      s1.Hits + s2.Hits,
+		Misses:    s2.Misses,
+		Evictions: s1.Evictions + s2.Evictions,
+		Size:      s1.Size,
+	}
+}
+
+func (t *Tiered) Close() error {
+	return errors.Join(t.l1.Close(), t.l2.Close())
+}
@@ -0,0 +1,909 @@
+// internal/config/config.go
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Config настройки сервиса. Источники в порядке приоритета:
+// флаги командной строки, переменные окружения, YAML файл, значения по умолчанию.
+type Config struct {
+	HTTP      HTTPConfig      `yaml:"http"`
+	GRPC      GRPCConfig      `yaml:"grpc"`
+	Postgres  PostgresConfig  `yaml:"postgres"`
+	Kafka     KafkaConfig     `yaml:"kafka"`
+	Cache     CacheConfig     `yaml:"cache"`
+	Tracing   TracingConfig   `yaml:"tracing"`
+	Logging   LoggingConfig   `yaml:"logging"`
+	Auth      AuthConfig      `yaml:"auth"`
+	PII       PIIConfig       `yaml:"pii"`
+	RateLimit RateLimitConfig `yaml:"rate_limit"`
+	Audit     AuditConfig     `yaml:"audit"`
+	Stream    StreamConfig    `yaml:"stream"`
+	Webhook   WebhookConfig   `yaml:"webhook"`
+	GraphQL   GraphQLConfig   `yaml:"graphql"`
+}
+
+type HTTPConfig struct {
+	Addr            string        `yaml:"addr"`
+	ReadTimeout     time.Duration `yaml:"read_timeout"`
+	WriteTimeout    time.Duration `yaml:"write_timeout"`
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout"`
+	// DrainDelay сколько /readyz отвечает 503 перед остановкой сервера,
+	// чтобы балансировщик успел убрать реплику
+	DrainDelay time.Duration `yaml:"drain_delay"`
+	WebDir     string        `yaml:"web_dir"`
+	// SwaggerUIDir файлы Swagger UI для /static/swagger-ui/; отдельно от web_dir, чтобы их не закрывал
+	// смонтированный поверх web каталог
+	SwaggerUIDir string `yaml:"swagger_ui_dir"`
+	// TLS при заданных cert_file и key_file сервер принимает только HTTPS
+	TLS ServerTLSConfig `yaml:"tls"`
+	// OpsAddr отдельный листенер без TLS для проб, /health и /metrics: оркестратору и Prometheus
+	// не нужен сертификат сервиса. Пустой — служебные маршруты только на addr
+	OpsAddr string `yaml:"ops_addr"`
+}
+
+type ServerTLSConfig struct {
+	// CertFile и KeyFile перечитываются по SIGHUP без перезапуска
+	CertFile string `yaml:"cert_file"`
+	KeyFile  string `yaml:"key_file"`
+	// ClientCAFile CA для проверки клиентских сертификатов (mTLS)
+	ClientCAFile string `yaml:"client_ca_file"`
+	ClientAuth   string `yaml:"client_auth"` // none, request, require
+	MinVersion   string `yaml:"min_version"` // 1.2 или 1.3
+}
+
+// Enabled включён ли HTTPS
+func (t ServerTLSConfig) Enabled() bool {
+	return t.CertFile != "" || t.KeyFile != ""
+}
+
+type PostgresConfig struct {
+	DSN string `yaml:"dsn"`
+	// SSLMode и файлы сертификатов заменяют одноимённые параметры DSN
+	SSLMode     string `yaml:"sslmode"` // disable, require, verify-ca, verify-full
+	SSLRootCert string `yaml:"sslrootcert"`
+	SSLCert     string `yaml:"sslcert"`
+	SSLKey      string `yaml:"sslkey"`
+}
+
+// ConnString DSN с параметрами TLS из конфигурации. Поддерживаются обе формы DSN:
+// URL postgres://... и key=value; в key=value последнее значение параметра перекрывает прежние
+func (p PostgresConfig) ConnString() string {
+	params := [][2]string{
+		{"sslmode", p.SSLMode},
+		{"sslrootcert", p.SSLRootCert},
+		{"sslcert", p.SSLCert},
+		{"sslkey", p.SSLKey},
+	}
+
+	if strings.HasPrefix(p.DSN, "postgres://") || strings.HasPrefix(p.DSN, "postgresql://") {
+		u, err := url.Parse(p.DSN)
+		if err != nil {
+			return p.DSN
+		}
+		q := u.Query()
+		for _, kv := range params {
+			if kv[1] != "" {
+				q.Set(kv[0], kv[1])
+			}
+		}
+		u.RawQuery = q.Encode()
+		return u.String()
+	}
+
+	dsn := p.DSN
+	for _, kv := range params {
+		if kv[1] != "" {
+			dsn += " " + kv[0] + "='" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(kv[1]) + "'"
+		}
+	}
+	return dsn
+}
+
+type KafkaConfig struct {
+	Brokers     []string      `yaml:"brokers"`
+	Topic       string        `yaml:"topic"`
+	GroupID     string        `yaml:"group_id"`
+	DLQTopic    string        `yaml:"dlq_topic"`
+	MaxRetries  int           `yaml:"max_retries"`
+	RetryDelay  time.Duration `yaml:"retry_delay"`
+	BackoffMode string        `yaml:"backoff_mode"` // fixed или exponential
+
+	TLS  KafkaTLSConfig  `yaml:"tls"`
+	SASL KafkaSASLConfig `yaml:"sasl"`
+}
+
+type KafkaTLSConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// CAFile CA брокеров, пусто — системные корневые сертификаты
+	CAFile string `yaml:"ca_file"`
+	// CertFile и KeyFile клиентский сертификат, если брокер требует mTLS
+	CertFile           string `yaml:"cert_file"`
+	KeyFile            string `yaml:"key_file"`
+	InsecureSkipVerify bool   `yaml:"insecure_skip_verify"`
+}
+
+type KafkaSASLConfig struct {
+	Mechanism string `yaml:"mechanism"` // пусто, plain, scram-sha-256, scram-sha-512
+	Username  string `yaml:"username"`
+	Password  string `yaml:"password"`
+}
+
+type CacheConfig struct {
+	Backend     string        `yaml:"backend"` // memory, redis, tiered
+	TTL         time.Duration `yaml:"ttl"`
+	MaxSize     int           `yaml:"max_size"`
+	NegativeTTL time.Duration `yaml:"negative_ttl"`
+
+	RedisAddr     string `yaml:"redis_addr"`
+	RedisPassword string `yaml:"redis_password"`
+
+	Invalidation string `yaml:"invalidation"` // evict, refresh, off
+
+	SnapshotPath     string        `yaml:"snapshot_path"`
+	SnapshotInterval time.Duration `yaml:"snapshot_interval"`
+	SnapshotMaxAge   time.Duration `yaml:"snapshot_max_age"`
+
+	WarmupStrategy      string        `yaml:"warmup_strategy"` // recent, frequent, none
+	WarmupLimit         int           `yaml:"warmup_limit"`
+	AccessFlushInterval time.Duration `yaml:"access_flush_interval"`
+}
+
+type TracingConfig struct {
+	ServiceName    string `yaml:"service_name"`
+	ServiceVersion string `yaml:"service_version"`
+	Environment    string `yaml:"environment"`
+
+	Exporter       string `yaml:"exporter"` // otlp-grpc, otlp-http, jaeger, stdout, none
+	OTLPEndpoint   string `yaml:"otlp_endpoint"`
+	OTLPInsecure   bool   `yaml:"otlp_insecure"`
+	JaegerEndpoint string `yaml:"jaeger_endpoint"`
+
+	// SampleRatio доля трейсов, начинаемых сервисом; решение родителя из traceparent уважается
+	SampleRatio float64 `yaml:"sample_ratio"`
+}
+
+type LoggingConfig struct {
+	Level  string `yaml:"level"`  // debug, info, warn, error
+	Format string `yaml:"format"` // json, text
+}
+
+type AuthConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// JWKSFile локальный JWKS для проверки JWT, пустой путь — только API ключи
+	JWKSFile    string `yaml:"jwks_file"`
+	JWTIssuer   string `yaml:"jwt_issuer"`
+	JWTAudience string `yaml:"jwt_audience"`
+	// APIKeyCacheTTL сколько найденный ключ не перечитывается из БД; столько же живёт отозванный ключ
+	APIKeyCacheTTL time.Duration `yaml:"api_key_cache_ttl"`
+}
+
+type PIIConfig struct {
+	// KeyFile файл мастер-ключей шифрования персональных данных в БД, пустой путь — без шифрования
+	KeyFile string `yaml:"key_file"`
+	// KeyRefreshInterval как часто реплика перечитывает ключи данных: после pii rotate
+	// новым ключом она начинает писать не позже чем через этот интервал
+	KeyRefreshInterval time.Duration `yaml:"key_refresh_interval"`
+}
+
+type RateLimitConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// Default корзина клиента на маршрут, если для маршрута нет своей
+	Default RateLimit `yaml:"default"`
+	// Routes лимиты по шаблону маршрута ServeMux, например "GET /api/v1/orders/{uid}",
+	// и по полному имени метода gRPC, например "/order.v1.OrderService/ListOrders"
+	Routes map[string]RateLimit `yaml:"routes"`
+	// AuthFailures корзина ответов 401 на IP перед аутентификацией: перебор учётных данных
+	AuthFailures RateLimit `yaml:"auth_failures"`
+	// TrustForwardedFor IP клиента из последнего X-Forwarded-For; включать только за своим прокси
+	TrustForwardedFor bool `yaml:"trust_forwarded_for"`
+}
+
+// GRPCConfig gRPC API рядом с HTTP; TLS и аутентификация общие с HTTP
+type GRPCConfig struct {
+	Enabled bool   `yaml:"enabled"`
+	Addr    string `yaml:"addr"`
+}
+
+type AuditConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// BufferSize сколько событий ждут записи в памяти; при переполнении новые отбрасываются
+	BufferSize int `yaml:"buffer_size"`
+	// FlushInterval как часто неполная пачка пишется в БД
+	FlushInterval time.Duration `yaml:"flush_interval"`
+}
+
+// StreamConfig потоки новых заказов: SSE, WebSocket и gRPC WatchOrders
+type StreamConfig struct {
+	// BufferSize сколько заказов ждут отправки клиенту; не поместившиеся пропускаются
+	BufferSize int `yaml:"buffer_size"`
+	// Heartbeat как часто слать пустое событие, чтобы прокси не закрывали соединение
+	Heartbeat time.Duration `yaml:"heartbeat"`
+	// WriteTimeout сколько ждать записи события; не успевший клиент отключается
+	WriteTimeout time.Duration `yaml:"write_timeout"`
+}
+
+// WebhookConfig рассылка заказов подписчикам из таблицы webhooks
+type WebhookConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// QueueSize сколько заказов ждут рассылки; при переполнении новые не рассылаются
+	QueueSize int `yaml:"queue_size"`
+	// Workers сколько доставок выполняется одновременно
+	Workers int `yaml:"workers"`
+	// Timeout ожидание ответа подписчика на одну попытку
+	Timeout time.Duration `yaml:"timeout"`
+	// MaxAttempts попыток доставки одного события, паузы между ними растут вдвое от InitialBackoff до MaxBackoff
+	MaxAttempts    int           `yaml:"max_attempts"`
+	InitialBackoff time.Duration `yaml:"initial_backoff"`
+	MaxBackoff     time.Duration `yaml:"max_backoff"`
+	// MaxFailures после стольких недоставленных подряд событий подписка выключается; 0 — не выключать
+	MaxFailures int `yaml:"max_failures"`
+}
+
+// GraphQLConfig эндпоинт /graphql
+type GraphQLConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// MaxDepth наибольшая вложенность полей запроса, глубже запрос отклоняется до выполнения
+	MaxDepth int `yaml:"max_depth"`
+	// MaxComplexity бюджет запроса: каждое поле стоит 1, поля внутри списка — столько раз,
+	// сколько элементов он может вернуть (first или его значение по умолчанию). Дороже — отказ до выполнения
+	MaxComplexity int `yaml:"max_complexity"`
+	// MaxFields наибольшее число полей в запросе после раскрытия фрагментов, псевдонимы считаются отдельно
+	MaxFields int `yaml:"max_fields"`
+	// Introspection отвечать на запросы схемы (__schema, __type) для IDE и генераторов клиентов
+	Introspection bool `yaml:"introspection"`
+}
+
+// RateLimit token bucket: Burst запросов подряд, дальше RPS в секунду
+type RateLimit struct {
+	RPS   float64 `yaml:"rps"`
+	Burst int     `yaml:"burst"`
+}
+
+// Default значения по умолчанию, совпадающие с docker-compose окружением
+func Default() *Config {
+	return &Config{
+		HTTP: HTTPConfig{
+			Addr:            ":8081",
+			ReadTimeout:     5 * time.Second,
+			WriteTimeout:    10 * time.Second,
+			ShutdownTimeout: 10 * time.Second,
+			DrainDelay:      5 * time.Second,
+			WebDir:          "web",
+			SwaggerUIDir:    "web/swagger-ui",
+			TLS: ServerTLSConfig{
+				ClientAuth: "none",
+				MinVersion: "1.2",
+			},
+		},
+		GRPC: GRPCConfig{
+			Enabled: true,
+			Addr:    ":9090",
+		},
+		Kafka: KafkaConfig{
+			Brokers:     []string{"kafka:9092"},
+			Topic:       "orders",
+			GroupID:     "order_service_group",
+			DLQTopic:    "orders_dlq",
+			MaxRetries:  3,
+			RetryDelay:  2 * time.Second,
+			BackoffMode: "exponential",
+		},
+		Cache: CacheConfig{
+			Backend:             "memory",
+			TTL:                 5 * time.Minute,
+			MaxSize:             1000,
+			NegativeTTL:         30 * time.Second,
+			RedisAddr:           "redis:6379",
+			Invalidation:        "evict",
+			SnapshotInterval:    time.Minute,
+			SnapshotMaxAge:      10 * time.Minute,
+			WarmupStrategy:      "recent",
+			WarmupLimit:         1000,
+			AccessFlushInterval: 30 * time.Second,
+		},
+		Tracing: TracingConfig{
+			ServiceName:    "order-service",
+			ServiceVersion: "dev",
+			Environment:    "development",
+			Exporter:       "otlp-grpc",
+			OTLPEndpoint:   "jaeger:4317",
+			OTLPInsecure:   true,
+			JaegerEndpoint: "http://jaeger:14268/api/traces",
+			SampleRatio:    1,
+		},
+		Logging: LoggingConfig{
+			Level:  "info",
+			Format: "json",
+		},
+		Auth: AuthConfig{
+			Enabled:        true,
+			APIKeyCacheTTL: time.Minute,
+		},
+		PII: PIIConfig{
+			KeyRefreshInterval: 30 * time.Second,
+		},
+		RateLimit: RateLimitConfig{
+			Enabled: true,
+			Default: RateLimit{RPS: 10, Burst: 20},
+			// поиск по uid строже: перебором uid угадывают чужие заказы
+			Routes: map[string]RateLimit{
+				"GET /api/v1/orders/{uid}": {RPS: 2, Burst: 10},
+				"GET /order/{uid}":         {RPS: 2, Burst: 10},
+			},
+			AuthFailures: RateLimit{RPS: 0.1, Burst: 10},
+		},
+		Audit: AuditConfig{
+			Enabled:       true,
+			BufferSize:    10000,
+			FlushInterval: time.Second,
+		},
+		Stream: StreamConfig{
+			BufferSize:   64,
+			Heartbeat:    15 * time.Second,
+			WriteTimeout: 10 * time.Second,
+		},
+		Webhook: WebhookConfig{
+			Enabled:        true,
+			QueueSize:      1000,
+			Workers:        8,
+			Timeout:        5 * time.Second,
+			MaxAttempts:    5,
+			InitialBackoff: time.Second,
+			MaxBackoff:     time.Minute,
+			MaxFailures:    10,
+		},
+		GraphQL: GraphQLConfig{
+			Enabled:       true,
+			MaxDepth:      10,
+			MaxComplexity: 5000,
+			MaxFields:     300,
+			Introspection: true,
+		},
+	}
+}
+
+// Load собирает конфигурацию из всех источников и проверяет её
+func Load(args []string) (*Config, error) {
+	cfg := Default()
+
+	fs := flag.NewFlagSet("order-service", flag.ContinueOnError)
+	configFile := fs.String("config", os.Getenv("CONFIG_FILE"), "путь к YAML файлу конфигурации")
+	flags := registerFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	if *configFile != "" {
+		if err := loadFile(cfg, *configFile); err != nil {
+			return nil, err
+		}
+	}
+	if err := applyEnv(cfg); err != nil {
+		return nil, err
+	}
+	flags.apply(fs, cfg)
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+func loadFile(cfg *Config, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("не удалось прочитать файл конфигурации: %w", err)
+	}
+	defer f.Close()
+
+	dec := yaml.NewDecoder(f)
+	dec.KnownFields(true)
+	if err := dec.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("ошибка разбора %s: %w", path, err)
+	}
+	return nil
+}
+
+// envReader накапливает ошибки разбора переменных окружения
+type envReader struct {
+	errs []error
+}
+
+func (e *envReader) string(dst *string, key string) {
+	if val, ok := os.LookupEnv(key); ok && val != "" {
+		*dst = val
+	}
+}
+
+func (e *envReader) list(dst *[]string, key string) {
+	if val, ok := os.LookupEnv(key); ok && val != "" {
+		*dst = splitList(val)
+	}
+}
+
+func (e *envReader) int(dst *int, key string) {
+	if val, ok := os.LookupEnv(key); ok && val != "" {
+		n, err := strconv.Atoi(val)
+		if err != nil {
+			e.errs = append(e.errs, fmt.Errorf("%s: ожидается целое число, получено %q", key, val))
+			return
+		}
+		*dst = n
+	}
+}
+
+func (e *envReader) duration(dst *time.Duration, key string) {
+	if val, ok := os.LookupEnv(key); ok && val != "" {
+		d, err := time.ParseDuration(val)
+		if err != nil {
+			e.errs = append(e.errs, fmt.Errorf("%s: ожидается длительность вида 30s или 5m, получено %q", key, val))
+			return
+		}
+		*dst = d
+	}
+}
+
+func (e *envReader) float(dst *float64, key string) {
+	if val, ok := os.LookupEnv(key); ok && val != "" {
+		f, err := strconv.ParseFloat(val, 64)
+		if err != nil {
+			e.errs = append(e.errs, fmt.Errorf("%s: ожидается число, получено %q", key, val))
+			return
+		}
+		*dst = f
+	}
+}
+
+func (e *envReader) bool(dst *bool, key string) {
+	if val, ok := os.LookupEnv(key); ok && val != "" {
+		b, err := strconv.ParseBool(val)
+		if err != nil {
+			e.errs = append(e.errs, fmt.Errorf("%s: ожидается true или false, получено %q", key, val))
+			return
+		}
+		*dst = b
+	}
+}
+
+func applyEnv(cfg *Config) error {
+	e := &envReader{}
+
+	e.string(&cfg.HTTP.Addr, "HTTP_ADDR")
+	e.duration(&cfg.HTTP.ReadTimeout, "HTTP_READ_TIMEOUT")
+	e.duration(&cfg.HTTP.WriteTimeout, "HTTP_WRITE_TIMEOUT")
+	e.duration(&cfg.HTTP.ShutdownTimeout, "HTTP_SHUTDOWN_TIMEOUT")
+	e.duration(&cfg.HTTP.DrainDelay, "HTTP_DRAIN_DELAY")
+	e.string(&cfg.HTTP.WebDir, "HTTP_WEB_DIR")
+	e.string(&cfg.HTTP.SwaggerUIDir, "HTTP_SWAGGER_UI_DIR")
+	e.string(&cfg.HTTP.TLS.CertFile, "HTTP_TLS_CERT_FILE")
+	e.string(&cfg.HTTP.TLS.KeyFile, "HTTP_TLS_KEY_FILE")
+	e.string(&cfg.HTTP.TLS.ClientCAFile, "HTTP_TLS_CLIENT_CA_FILE")
+	e.string(&cfg.HTTP.TLS.ClientAuth, "HTTP_TLS_CLIENT_AUTH")
+	e.string(&cfg.HTTP.OpsAddr, "HTTP_OPS_ADDR")
+	e.string(&cfg.HTTP.TLS.MinVersion, "HTTP_TLS_MIN_VERSION")
+
+	e.bool(&cfg.GRPC.Enabled, "GRPC_ENABLED")
+	e.string(&cfg.GRPC.Addr, "GRPC_ADDR")
+
+	e.string(&cfg.Postgres.DSN, "POSTGRES_DSN")
+	e.string(&cfg.Postgres.SSLMode, "POSTGRES_SSLMODE")
+	e.string(&cfg.Postgres.SSLRootCert, "POSTGRES_SSLROOTCERT")
+	e.string(&cfg.Postgres.SSLCert, "POSTGRES_SSLCERT")
+	e.string(&cfg.Postgres.SSLKey, "POSTGRES_SSLKEY")
+
+	e.list(&cfg.Kafka.Brokers, "KAFKA_BROKERS")
+	e.string(&cfg.Kafka.Topic, "KAFKA_TOPIC")
+	e.string(&cfg.Kafka.GroupID, "KAFKA_GROUP_ID")
+	e.string(&cfg.Kafka.DLQTopic, "KAFKA_DLQ_TOPIC")
+	e.int(&cfg.Kafka.MaxRetries, "KAFKA_MAX_RETRIES")
+	e.duration(&cfg.Kafka.RetryDelay, "KAFKA_RETRY_DELAY")
+	e.string(&cfg.Kafka.BackoffMode, "KAFKA_BACKOFF_MODE")
+	e.bool(&cfg.Kafka.TLS.Enabled, "KAFKA_TLS_ENABLED")
+	e.string(&cfg.Kafka.TLS.CAFile, "KAFKA_TLS_CA_FILE")
+	e.string(&cfg.Kafka.TLS.CertFile, "KAFKA_TLS_CERT_FILE")
+	e.string(&cfg.Kafka.TLS.KeyFile, "KAFKA_TLS_KEY_FILE")
+	e.bool(&cfg.Kafka.TLS.InsecureSkipVerify, "KAFKA_TLS_INSECURE_SKIP_VERIFY")
+	e.string(&cfg.Kafka.SASL.Mechanism, "KAFKA_SASL_MECHANISM")
+	e.string(&cfg.Kafka.SASL.Username, "KAFKA_SASL_USERNAME")
+	e.string(&cfg.Kafka.SASL.Password, "KAFKA_SASL_PASSWORD")
+
+	e.string(&cfg.Cache.Backend, "CACHE_BACKEND")
+	e.duration(&cfg.Cache.TTL, "CACHE_TTL")
+	e.int(&cfg.Cache.MaxSize, "CACHE_MAX_SIZE")
+	e.duration(&cfg.Cache.NegativeTTL, "CACHE_NEGATIVE_TTL")
+	e.string(&cfg.Cache.RedisAddr, "REDIS_ADDR")
+	e.string(&cfg.Cache.RedisPassword, "REDIS_PASSWORD")
+	e.string(&cfg.Cache.Invalidation, "CACHE_INVALIDATION")
+	e.string(&cfg.Cache.SnapshotPath, "CACHE_SNAPSHOT_PATH")
+	e.duration(&cfg.Cache.SnapshotInterval, "CACHE_SNAPSHOT_INTERVAL")
+	e.duration(&cfg.Cache.SnapshotMaxAge, "CACHE_SNAPSHOT_MAX_AGE")
+	e.string(&cfg.Cache.WarmupStrategy, "CACHE_WARMUP_STRATEGY")
+	e.int(&cfg.Cache.WarmupLimit, "CACHE_WARMUP_LIMIT")
+	e.duration(&cfg.Cache.AccessFlushInterval, "CACHE_ACCESS_FLUSH_INTERVAL")
+
+	e.string(&cfg.Tracing.ServiceName, "TRACING_SERVICE_NAME")
+	e.string(&cfg.Tracing.ServiceVersion, "TRACING_SERVICE_VERSION")
+	e.string(&cfg.Tracing.Environment, "TRACING_ENVIRONMENT")
+	e.string(&cfg.Tracing.Exporter, "TRACING_EXPORTER")
+	e.string(&cfg.Tracing.OTLPEndpoint, "TRACING_OTLP_ENDPOINT")
+	e.bool(&cfg.Tracing.OTLPInsecure, "TRACING_OTLP_INSECURE")
+	e.string(&cfg.Tracing.JaegerEndpoint, "JAEGER_ENDPOINT")
+	e.float(&cfg.Tracing.SampleRatio, "TRACING_SAMPLE_RATIO")
+
+	e.string(&cfg.Logging.Level, "LOG_LEVEL")
+	e.string(&cfg.Logging.Format, "LOG_FORMAT")
+
+	e.bool(&cfg.Auth.Enabled, "AUTH_ENABLED")
+	e.string(&cfg.Auth.JWKSFile, "AUTH_JWKS_FILE")
+	e.string(&cfg.Auth.JWTIssuer, "AUTH_JWT_ISSUER")
+	e.string(&cfg.Auth.JWTAudience, "AUTH_JWT_AUDIENCE")
+	e.duration(&cfg.Auth.APIKeyCacheTTL, "AUTH_API_KEY_CACHE_TTL")
+
+	e.string(&cfg.PII.KeyFile, "PII_KEY_FILE")
+	e.duration(&cfg.PII.KeyRefreshInterval, "PII_KEY_REFRESH_INTERVAL")
+
+	e.bool(&cfg.RateLimit.Enabled, "RATE_LIMIT_ENABLED")
+	e.float(&cfg.RateLimit.Default.RPS, "RATE_LIMIT_RPS")
+	e.int(&cfg.RateLimit.Default.Burst, "RATE_LIMIT_BURST")
+	e.float(&cfg.RateLimit.AuthFailures.RPS, "RATE_LIMIT_AUTH_FAILURES_RPS")
+	e.int(&cfg.RateLimit.AuthFailures.Burst, "RATE_LIMIT_AUTH_FAILURES_BURST")
+	e.bool(&cfg.RateLimit.TrustForwardedFor, "RATE_LIMIT_TRUST_FORWARDED_FOR")
+
+	e.bool(&cfg.Audit.Enabled, "AUDIT_ENABLED")
+	e.int(&cfg.Audit.BufferSize, "AUDIT_BUFFER_SIZE")
+	e.duration(&cfg.Audit.FlushInterval, "AUDIT_FLUSH_INTERVAL")
+
+	e.int(&cfg.Stream.BufferSize, "STREAM_BUFFER_SIZE")
+	e.duration(&cfg.Stream.Heartbeat, "STREAM_HEARTBEAT")
+	e.duration(&cfg.Stream.WriteTimeout, "STREAM_WRITE_TIMEOUT")
+
+	e.bool(&cfg.Webhook.Enabled, "WEBHOOK_ENABLED")
+	e.int(&cfg.Webhook.QueueSize, "WEBHOOK_QUEUE_SIZE")
+	e.int(&cfg.Webhook.Workers, "WEBHOOK_WORKERS")
+	e.duration(&cfg.Webhook.Timeout, "WEBHOOK_TIMEOUT")
+	e.int(&cfg.Webhook.MaxAttempts, "WEBHOOK_MAX_ATTEMPTS")
+	e.duration(&cfg.Webhook.InitialBackoff, "WEBHOOK_INITIAL_BACKOFF")
+	e.duration(&cfg.Webhook.MaxBackoff, "WEBHOOK_MAX_BACKOFF")
+	e.int(&cfg.Webhook.MaxFailures, "WEBHOOK_MAX_FAILURES")
+
+	e.bool(&cfg.GraphQL.Enabled, "GRAPHQL_ENABLED")
+	e.int(&cfg.GraphQL.MaxDepth, "GRAPHQL_MAX_DEPTH")
+	e.int(&cfg.GraphQL.MaxComplexity, "GRAPHQL_MAX_COMPLEXITY")
+	e.int(&cfg.GraphQL.MaxFields, "GRAPHQL_MAX_FIELDS")
+	e.bool(&cfg.GraphQL.Introspection, "GRAPHQL_INTROSPECTION")
+
+	return errors.Join(e.errs...)
+}
+
+// cliFlags флаги командной строки, применяются только явно заданные
+type cliFlags struct {
+	httpAddr       *string
+	grpcAddr       *string
+	postgresDSN    *string
+	kafkaBrokers   *string
+	kafkaTopic     *string
+	kafkaGroupID   *string
+	kafkaDLQTopic  *string
+	cacheBackend   *string
+	cacheTTL       *time.Duration
+	cacheMaxSize   *int
+	warmupStrategy *string
+	tracingExport  *string
+	otlpEndpoint   *string
+	jaegerEndpoint *string
+	sampleRatio    *float64
+	logLevel       *string
+}
+
+func registerFlags(fs *flag.FlagSet) *cliFlags {
+	return &cliFlags{
+		httpAddr:       fs.String("http-addr", "", "адрес HTTP сервера"),
+		grpcAddr:       fs.String("grpc-addr", "", "адрес gRPC сервера"),
+		postgresDSN:    fs.String("postgres-dsn", "", "строка подключения к PostgreSQL"),
+		kafkaBrokers:   fs.String("kafka-brokers", "", "брокеры Kafka через запятую"),
+		kafkaTopic:     fs.String("kafka-topic", "", "топик заказов"),
+		kafkaGroupID:   fs.String("kafka-group-id", "", "consumer group"),
+		kafkaDLQTopic:  fs.String("kafka-dlq-topic", "", "топик некорректных сообщений"),
+		cacheBackend:   fs.String("cache-backend", "", "memory, redis или tiered"),
+		cacheTTL:       fs.Duration("cache-ttl", 0, "время жизни записи кэша"),
+		cacheMaxSize:   fs.Int("cache-max-size", 0, "максимальное число заказов в кэше"),
+		warmupStrategy: fs.String("cache-warmup-strategy", "", "recent, frequent или none"),
+		tracingExport:  fs.String("tracing-exporter", "", "otlp-grpc, otlp-http, jaeger, stdout или none"),
+		otlpEndpoint:   fs.String("otlp-endpoint", "", "адрес OTLP коллектора"),
+		jaegerEndpoint: fs.String("jaeger-endpoint", "", "адрес коллектора Jaeger"),
+		sampleRatio:    fs.Float64("tracing-sample-ratio", 0, "доля сэмплируемых трейсов от 0 до 1"),
+		logLevel:       fs.String("log-level", "", "debug, info, warn или error"),
+	}
+}
+
+func (f *cliFlags) apply(fs *flag.FlagSet, cfg *Config) {
+	fs.Visit(func(fl *flag.Flag) {
+		switch fl.Name {
+		case "http-addr":
+			cfg.HTTP.Addr = *f.httpAddr
+		case "grpc-addr":
+			cfg.GRPC.Addr = *f.grpcAddr
+		case "postgres-dsn":
+			cfg.Postgres.DSN = *f.postgresDSN
+		case "kafka-brokers":
+			cfg.Kafka.Brokers = splitList(*f.kafkaBrokers)
+		case "kafka-topic":
+			cfg.Kafka.Topic = *f.kafkaTopic
+		case "kafka-group-id":
+			cfg.Kafka.GroupID = *f.kafkaGroupID
+		case "kafka-dlq-topic":
+			cfg.Kafka.DLQTopic = *f.kafkaDLQTopic
+		case "cache-backend":
+			cfg.Cache.Backend = *f.cacheBackend
+		case "cache-ttl":
+			cfg.Cache.TTL = *f.cacheTTL
+		case "cache-max-size":
+			cfg.Cache.MaxSize = *f.cacheMaxSize
+		case "cache-warmup-strategy":
+			cfg.Cache.WarmupStrategy = *f.warmupStrategy
+		case "tracing-exporter":
+			cfg.Tracing.Exporter = *f.tracingExport
+		case "otlp-endpoint":
+			cfg.Tracing.OTLPEndpoint = *f.otlpEndpoint
+		case "jaeger-endpoint":
+			cfg.Tracing.JaegerEndpoint = *f.jaegerEndpoint
+		case "tracing-sample-ratio":
+			cfg.Tracing.SampleRatio = *f.sampleRatio
+		case "log-level":
+			cfg.Logging.Level = *f.logLevel
+		}
+	})
+}
+
+func splitList(val string) []string {
+	parts := strings.Split(val, ",")
+	out := make([]string, 0, len(parts))
+	for _, p := range parts {
+		if p = strings.TrimSpace(p); p != "" {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// Validate проверяет конфигурацию целиком и возвращает все найденные ошибки
+func (c *Config) Validate() error {
+	var errs []error
+	fail := func(format string, args ...interface{}) {
+		errs = append(errs, fmt.Errorf(format, args...))
+	}
+
+	if c.HTTP.Addr == "" {
+		fail("http.addr: не задан адрес сервера")
+	}
+	if c.HTTP.ReadTimeout <= 0 || c.HTTP.WriteTimeout <= 0 || c.HTTP.ShutdownTimeout <= 0 {
+		fail("http: таймауты должны быть больше нуля")
+	}
+	if c.HTTP.DrainDelay < 0 {
+		fail("http.drain_delay: не может быть отрицательным")
+	}
+	if c.GRPC.Enabled {
+		if c.GRPC.Addr == "" {
+			fail("grpc.addr: не задан адрес сервера")
+		} else if c.GRPC.Addr == c.HTTP.Addr {
+			fail("grpc.addr: совпадает с http.addr")
+		}
+	}
+	if c.HTTP.OpsAddr != "" && (c.HTTP.OpsAddr == c.HTTP.Addr || (c.GRPC.Enabled && c.HTTP.OpsAddr == c.GRPC.Addr)) {
+		fail("http.ops_addr: совпадает с http.addr или grpc.addr")
+	}
+	if t := c.HTTP.TLS; t.Enabled() {
+		if t.CertFile == "" || t.KeyFile == "" {
+			fail("http.tls: cert_file и key_file задаются вместе")
+		}
+		switch t.ClientAuth {
+		case "none":
+		case "request", "require":
+			if t.ClientCAFile == "" {
+				fail("http.tls.client_ca_file: обязателен для client_auth %s", t.ClientAuth)
+			}
+		default:
+			fail("http.tls.client_auth: ожидается none, request или require, получено %q", t.ClientAuth)
+		}
+		if t.MinVersion != "1.2" && t.MinVersion != "1.3" {
+			fail("http.tls.min_version: ожидается 1.2 или 1.3, получено %q", t.MinVersion)
+		}
+	}
+
+	if c.Postgres.DSN == "" {
+		fail("postgres.dsn: не задан (POSTGRES_DSN)")
+	}
+	// allow и prefer lib/pq не поддерживает: подключение упало бы уже при старте
+	switch c.Postgres.SSLMode {
+	case "", "disable", "require", "verify-ca", "verify-full":
+	default:
+		fail("postgres.sslmode: ожидается disable, require, verify-ca или verify-full, получено %q", c.Postgres.SSLMode)
+	}
+	if (c.Postgres.SSLCert == "") != (c.Postgres.SSLKey == "") {
+		fail("postgres: sslcert и sslkey задаются вместе")
+	}
+
+	if len(c.Kafka.Brokers) == 0 {
+		fail("kafka.brokers: нужен хотя бы один брокер (KAFKA_BROKERS)")
+	}
+	if c.Kafka.Topic == "" || c.Kafka.GroupID == "" || c.Kafka.DLQTopic == "" {
+		fail("kafka: topic, group_id и dlq_topic обязательны")
+	}
+	if c.Kafka.MaxRetries < 0 {
+		fail("kafka.max_retries: не может быть отрицательным")
+	}
+	if c.Kafka.RetryDelay <= 0 {
+		fail("kafka.retry_delay: должна быть больше нуля")
+	}
+	if c.Kafka.BackoffMode != "fixed" && c.Kafka.BackoffMode != "exponential" {
+		fail("kafka.backoff_mode: ожидается fixed или exponential, получено %q", c.Kafka.BackoffMode)
+	}
+	if (c.Kafka.TLS.CertFile == "") != (c.Kafka.TLS.KeyFile == "") {
+		fail("kafka.tls: cert_file и key_file задаются вместе")
+	}
+	switch c.Kafka.SASL.Mechanism {
+	case "":
+	case "plain", "scram-sha-256", "scram-sha-512":
+		if c.Kafka.SASL.Username == "" {
+			fail("kafka.sasl.username: обязателен для механизма %s", c.Kafka.SASL.Mechanism)
+		}
+	default:
+		fail("kafka.sasl.mechanism: ожидается plain, scram-sha-256 или scram-sha-512, получено %q", c.Kafka.SASL.Mechanism)
+	}
+
+	switch c.Cache.Backend {
+	case "memory":
+	case "redis", "tiered":
+		if c.Cache.RedisAddr == "" {
+			fail("cache.redis_addr: обязателен для backend %s", c.Cache.Backend)
+		}
+	default:
+		fail("cache.backend: ожидается memory, redis или tiered, получено %q", c.Cache.Backend)
+	}
+	if c.Cache.TTL <= 0 {
+		fail("cache.ttl: должен быть больше нуля")
+	}
+	if c.Cache.MaxSize <= 0 {
+		fail("cache.max_size: должен быть больше нуля")
+	}
+	if c.Cache.NegativeTTL < 0 {
+		fail("cache.negative_ttl: не может быть отрицательным")
+	}
+	switch c.Cache.Invalidation {
+	case "evict", "refresh", "off":
+	default:
+		fail("cache.invalidation: ожидается evict, refresh или off, получено %q", c.Cache.Invalidation)
+	}
+	if c.Cache.SnapshotPath != "" && c.Cache.SnapshotInterval <= 0 {
+		fail("cache.snapshot_interval: должен быть больше нуля")
+	}
+	// снимок хранит расшифрованные заказы в открытом виде — с шифрованием PII в БД это утечка
+	if c.Cache.SnapshotPath != "" && c.PII.KeyFile != "" {
+		fail("cache.snapshot_path: снимок кэша пишет персональные данные в открытом виде, несовместим с pii.key_file")
+	}
+	switch c.Cache.WarmupStrategy {
+	case "recent", "frequent", "none":
+	default:
+		fail("cache.warmup_strategy: ожидается recent, frequent или none, получено %q", c.Cache.WarmupStrategy)
+	}
+	if c.Cache.WarmupLimit < 0 || c.Cache.WarmupLimit > c.Cache.MaxSize {
+		fail("cache.warmup_limit: должен быть от 0 до cache.max_size (%d)", c.Cache.MaxSize)
+	}
+	if c.Cache.AccessFlushInterval <= 0 {
+		fail("cache.access_flush_interval: должен быть больше нуля")
+	}
+
+	if c.Tracing.ServiceName == "" {
+		fail("tracing.service_name: не задан")
+	}
+	switch c.Tracing.Exporter {
+	case "otlp-grpc", "otlp-http":
+		if c.Tracing.OTLPEndpoint == "" {
+			fail("tracing.otlp_endpoint: обязателен для экспортера %s", c.Tracing.Exporter)
+		}
+	case "jaeger":
+		if c.Tracing.JaegerEndpoint == "" {
+			fail("tracing.jaeger_endpoint: обязателен для экспортера jaeger")
+		}
+	case "stdout", "none":
+	default:
+		fail("tracing.exporter: ожидается otlp-grpc, otlp-http, jaeger, stdout или none, получено %q", c.Tracing.Exporter)
+	}
+	if c.Tracing.SampleRatio < 0 || c.Tracing.SampleRatio > 1 {
+		fail("tracing.sample_ratio: должен быть от 0 до 1")
+	}
+
+	switch strings.ToLower(c.Logging.Level) {
+	case "debug", "info", "warn", "error":
+	default:
+		fail("logging.level: ожидается debug, info, warn или error, получено %q", c.Logging.Level)
+	}
+	if c.Logging.Format != "json" && c.Logging.Format != "text" {
+		fail("logging.format: ожидается json или text, получено %q", c.Logging.Format)
+	}
+
+	if c.Auth.APIKeyCacheTTL < 0 {
+		fail("auth.api_key_cache_ttl: не может быть отрицательным")
+	}
+	if c.Auth.JWKSFile == "" && (c.Auth.JWTIssuer != "" || c.Auth.JWTAudience != "") {
+		fail("auth.jwks_file: обязателен, если заданы jwt_issuer или jwt_audience")
+	}
+	if c.PII.KeyFile != "" && c.PII.KeyRefreshInterval <= 0 {
+		fail("pii.key_refresh_interval: должен быть больше нуля")
+	}
+
+	if c.RateLimit.Enabled {
+		if !c.RateLimit.Default.valid() {
+			fail("rate_limit.default: rps должен быть больше нуля, burst — не меньше 1")
+		}
+		if !c.RateLimit.AuthFailures.valid() {
+			fail("rate_limit.auth_failures: rps должен быть больше нуля, burst — не меньше 1")
+		}
+		for route, limit := range c.RateLimit.Routes {
+			if !limit.valid() {
+				fail("rate_limit.routes[%q]: rps должен быть больше нуля, burst — не меньше 1", route)
+			}
+		}
+	}
+
+	if c.Audit.Enabled {
+		if c.Audit.BufferSize < 1 {
+			fail("audit.buffer_size: должен быть больше нуля")
+		}
+		if c.Audit.FlushInterval <= 0 {
+			fail("audit.flush_interval: должен быть больше нуля")
+		}
+	}
+
+	if c.Stream.BufferSize < 1 {
+		fail("stream.buffer_size: должен быть больше нуля")
+	}
+	if c.Stream.Heartbeat <= 0 || c.Stream.WriteTimeout <= 0 {
+		fail("stream: heartbeat и write_timeout должны быть больше нуля")
+	}
+
+	if c.Webhook.Enabled {
+		if c.Webhook.QueueSize < 1 || c.Webhook.Workers < 1 || c.Webhook.MaxAttempts < 1 {
+			fail("webhook: queue_size, workers и max_attempts должны быть больше нуля")
+		}
+		if c.Webhook.Timeout <= 0 || c.Webhook.InitialBackoff <= 0 || c.Webhook.MaxBackoff < c.Webhook.InitialBackoff {
+			fail("webhook: timeout и initial_backoff должны быть больше нуля, max_backoff — не меньше initial_backoff")
+		}
+		if c.Webhook.MaxFailures < 0 {
+			fail("webhook.max_failures: не может быть отрицательным")
+		}
+	}
+
+	if c.GraphQL.Enabled {
+		if c.GraphQL.MaxDepth < 1 {
+			fail("graphql.max_depth: должен быть больше нуля")
+		}
+		if c.GraphQL.MaxComplexity < 1 {
+			fail("graphql.max_complexity: должен быть больше нуля")
+		}
+		if c.GraphQL.MaxFields < 1 {
+			fail("graphql.max_fields: должен быть больше нуля")
+		}
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("некорректная конфигурация: %w", errors.Join(errs...))
+	}
+	return nil
+}
+
+func (l RateLimit) valid() bool {
+	return l.RPS > 0 && l.Burst >= 1
+}
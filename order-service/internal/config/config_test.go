@@ -0,0 +1,442 @@
+// internal/config/config_test.go
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeConfigFile(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	return path
+}
+
+func TestLoad_Defaults(t *testing.T) {
+	t.Setenv("POSTGRES_DSN", "postgres://u:p@localhost/db")
+
+	cfg, err := Load(nil)
+	require.NoError(t, err)
+
+	assert.Equal(t, ":8081", cfg.HTTP.Addr)
+	assert.Equal(t, []string{"kafka:9092"}, cfg.Kafka.Brokers)
+	assert.Equal(t, "orders", cfg.Kafka.Topic)
+	assert.Equal(t, 5*time.Minute, cfg.Cache.TTL)
+	assert.Equal(t, 1000, cfg.Cache.MaxSize)
+	assert.Equal(t, "http://jaeger:14268/api/traces", cfg.Tracing.JaegerEndpoint)
+}
+
+func TestLoad_EnvOverrides(t *testing.T) {
+	t.Setenv("POSTGRES_DSN", "postgres://u:p@localhost/db")
+	t.Setenv("KAFKA_BROKERS", "kafka1:9092, kafka2:9092,,kafka3:9092")
+	t.Setenv("KAFKA_TOPIC", "orders_v2")
+	t.Setenv("CACHE_TTL", "1m")
+	t.Setenv("CACHE_MAX_SIZE", "500")
+	t.Setenv("CACHE_WARMUP_LIMIT", "100")
+	t.Setenv("JAEGER_ENDPOINT", "http://collector:14268/api/traces")
+
+	cfg, err := Load(nil)
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"kafka1:9092", "kafka2:9092", "kafka3:9092"}, cfg.Kafka.Brokers)
+	assert.Equal(t, "orders_v2", cfg.Kafka.Topic)
+	assert.Equal(t, time.Minute, cfg.Cache.TTL)
+	assert.Equal(t, 500, cfg.Cache.MaxSize)
+	assert.Equal(t, "http://collector:14268/api/traces", cfg.Tracing.JaegerEndpoint)
+}
+
+func TestLoad_FileThenEnvThenFlags(t *testing.T) {
+	path := writeConfigFile(t, `
+http:
+  addr: ":9000"
+postgres:
+  dsn: "postgres://file/db"
+kafka:
+  brokers: ["file1:9092", "file2:9092"]
+  group_id: file_group
+cache:
+  ttl: 2m
+  backend: memory
+`)
+	t.Setenv("POSTGRES_DSN", "")
+	t.Setenv("KAFKA_GROUP_ID", "env_group")
+	t.Setenv("HTTP_ADDR", ":9100")
+
+	cfg, err := Load([]string{"-config", path, "-http-addr", ":9200"})
+	require.NoError(t, err)
+
+	assert.Equal(t, ":9200", cfg.HTTP.Addr, "флаг приоритетнее окружения")
+	assert.Equal(t, "env_group", cfg.Kafka.GroupID, "окружение приоритетнее файла")
+	assert.Equal(t, "postgres://file/db", cfg.Postgres.DSN)
+	assert.Equal(t, []string{"file1:9092", "file2:9092"}, cfg.Kafka.Brokers)
+	assert.Equal(t, 2*time.Minute, cfg.Cache.TTL)
+	assert.Equal(t, "orders", cfg.Kafka.Topic, "незаданные поля берутся по умолчанию")
+}
+
+func TestLoad_ConfigFileFromEnv(t *testing.T) {
+	path := writeConfigFile(t, "postgres:\n  dsn: postgres://file/db\n")
+	t.Setenv("CONFIG_FILE", path)
+	t.Setenv("POSTGRES_DSN", "")
+
+	cfg, err := Load(nil)
+	require.NoError(t, err)
+	assert.Equal(t, "postgres://file/db", cfg.Postgres.DSN)
+}
+
+func TestLoad_UnknownFileField(t *testing.T) {
+	path := writeConfigFile(t, "cache:\n  tll: 5m\n")
+
+	_, err := Load([]string{"-config", path})
+	assert.Error(t, err)
+}
+
+func TestLoad_MissingFile(t *testing.T) {
+	_, err := Load([]string{"-config", filepath.Join(t.TempDir(), "nope.yaml")})
+	assert.Error(t, err)
+}
+
+func TestLoad_InvalidEnvValue(t *testing.T) {
+	t.Setenv("POSTGRES_DSN", "postgres://u:p@localhost/db")
+	t.Setenv("CACHE_TTL", "five minutes")
+	t.Setenv("CACHE_MAX_SIZE", "many")
+
+	_, err := Load(nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "CACHE_TTL")
+	assert.Contains(t, err.Error(), "CACHE_MAX_SIZE")
+}
+
+func TestLoad_MissingDSN(t *testing.T) {
+	t.Setenv("POSTGRES_DSN", "")
+
+	_, err := Load(nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "postgres.dsn")
+}
+
+func TestValidate_CollectsAllErrors(t *testing.T) {
+	cfg := Default()
+	cfg.Postgres.DSN = "postgres://u:p@localhost/db"
+	cfg.Kafka.Brokers = nil
+	cfg.Kafka.BackoffMode = "linear"
+	cfg.Cache.Backend = "memcached"
+	cfg.Cache.WarmupLimit = cfg.Cache.MaxSize + 1
+	cfg.Logging.Level = "verbose"
+
+	err := cfg.Validate()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "logging.level")
+	assert.Contains(t, err.Error(), "kafka.brokers")
+	assert.Contains(t, err.Error(), "kafka.backoff_mode")
+	assert.Contains(t, err.Error(), "cache.backend")
+	assert.Contains(t, err.Error(), "cache.warmup_limit")
+}
+
+func TestValidate_RedisAddrRequired(t *testing.T) {
+	cfg := Default()
+	cfg.Postgres.DSN = "postgres://u:p@localhost/db"
+	cfg.Cache.Backend = "tiered"
+	cfg.Cache.RedisAddr = ""
+
+	err := cfg.Validate()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "cache.redis_addr")
+}
+
+func TestLoad_AuthEnv(t *testing.T) {
+	t.Setenv("POSTGRES_DSN", "postgres://u:p@localhost/db")
+
+	cfg, err := Load(nil)
+	require.NoError(t, err)
+	assert.True(t, cfg.Auth.Enabled)
+	assert.Equal(t, time.Minute, cfg.Auth.APIKeyCacheTTL)
+
+	t.Setenv("AUTH_ENABLED", "false")
+	t.Setenv("AUTH_JWKS_FILE", "/etc/order-service/jwks.json")
+	t.Setenv("AUTH_JWT_ISSUER", "https://idp.example.com")
+	t.Setenv("AUTH_API_KEY_CACHE_TTL", "10s")
+
+	cfg, err = Load(nil)
+	require.NoError(t, err)
+	assert.False(t, cfg.Auth.Enabled)
+	assert.Equal(t, "/etc/order-service/jwks.json", cfg.Auth.JWKSFile)
+	assert.Equal(t, "https://idp.example.com", cfg.Auth.JWTIssuer)
+	assert.Equal(t, 10*time.Second, cfg.Auth.APIKeyCacheTTL)
+}
+
+func TestValidate_JWTClaimsRequireJWKS(t *testing.T) {
+	cfg := Default()
+	cfg.Postgres.DSN = "postgres://u:p@localhost/db"
+	cfg.Auth.JWTAudience = "order-service"
+
+	err := cfg.Validate()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "auth.jwks_file")
+}
+
+func TestValidate_SnapshotWithPIIKey(t *testing.T) {
+	cfg := Default()
+	cfg.Postgres.DSN = "postgres://u:p@localhost/db"
+	cfg.Cache.SnapshotPath = "/app/data/cache.snapshot"
+	require.NoError(t, cfg.Validate())
+
+	cfg.PII.KeyFile = "/run/secrets/pii.keys"
+	err := cfg.Validate()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "cache.snapshot_path")
+
+	cfg.Cache.SnapshotPath = ""
+	assert.NoError(t, cfg.Validate())
+}
+
+func TestLoad_PIIKeyFile(t *testing.T) {
+	t.Setenv("POSTGRES_DSN", "postgres://u:p@localhost/db")
+
+	cfg, err := Load(nil)
+	require.NoError(t, err)
+	assert.Empty(t, cfg.PII.KeyFile)
+
+	assert.Equal(t, 30*time.Second, cfg.PII.KeyRefreshInterval)
+
+	t.Setenv("PII_KEY_FILE", "/run/secrets/pii.keys")
+	t.Setenv("PII_KEY_REFRESH_INTERVAL", "5s")
+	cfg, err = Load(nil)
+	require.NoError(t, err)
+	assert.Equal(t, "/run/secrets/pii.keys", cfg.PII.KeyFile)
+	assert.Equal(t, 5*time.Second, cfg.PII.KeyRefreshInterval)
+
+	t.Setenv("PII_KEY_REFRESH_INTERVAL", "0s")
+	_, err = Load(nil)
+	assert.ErrorContains(t, err, "pii.key_refresh_interval")
+}
+
+func TestLoad_RateLimitEnv(t *testing.T) {
+	t.Setenv("POSTGRES_DSN", "postgres://u:p@localhost/db")
+
+	cfg, err := Load(nil)
+	require.NoError(t, err)
+	assert.True(t, cfg.RateLimit.Enabled)
+	assert.Equal(t, RateLimit{RPS: 10, Burst: 20}, cfg.RateLimit.Default)
+	assert.Equal(t, RateLimit{RPS: 2, Burst: 10}, cfg.RateLimit.Routes["GET /api/v1/orders/{uid}"])
+
+	t.Setenv("RATE_LIMIT_RPS", "0.5")
+	t.Setenv("RATE_LIMIT_BURST", "3")
+	t.Setenv("RATE_LIMIT_TRUST_FORWARDED_FOR", "true")
+	t.Setenv("RATE_LIMIT_AUTH_FAILURES_RPS", "0.05")
+	t.Setenv("RATE_LIMIT_AUTH_FAILURES_BURST", "5")
+
+	cfg, err = Load(nil)
+	require.NoError(t, err)
+	assert.Equal(t, RateLimit{RPS: 0.5, Burst: 3}, cfg.RateLimit.Default)
+	assert.Equal(t, RateLimit{RPS: 0.05, Burst: 5}, cfg.RateLimit.AuthFailures)
+	assert.True(t, cfg.RateLimit.TrustForwardedFor)
+}
+
+func TestValidate_RateLimit(t *testing.T) {
+	cfg := Default()
+	cfg.Postgres.DSN = "postgres://u:p@localhost/db"
+	cfg.RateLimit.Default.RPS = 0
+	cfg.RateLimit.Routes["GET /order/{uid}"] = RateLimit{RPS: 1}
+	cfg.RateLimit.AuthFailures = RateLimit{}
+
+	err := cfg.Validate()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "rate_limit.default")
+	assert.Contains(t, err.Error(), "rate_limit.auth_failures")
+	assert.Contains(t, err.Error(), `rate_limit.routes["GET /order/{uid}"]`)
+
+	// выключенный лимитер не проверяется
+	cfg.RateLimit.Enabled = false
+	assert.NoError(t, cfg.Validate())
+}
+
+func TestLoad_AuditEnv(t *testing.T) {
+	t.Setenv("POSTGRES_DSN", "postgres://u:p@localhost/db")
+	t.Setenv("AUDIT_BUFFER_SIZE", "50")
+	t.Setenv("AUDIT_FLUSH_INTERVAL", "200ms")
+
+	cfg, err := Load(nil)
+	require.NoError(t, err)
+	assert.True(t, cfg.Audit.Enabled)
+	assert.Equal(t, 50, cfg.Audit.BufferSize)
+	assert.Equal(t, 200*time.Millisecond, cfg.Audit.FlushInterval)
+
+	t.Setenv("AUDIT_BUFFER_SIZE", "0")
+	_, err = Load(nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "audit.buffer_size")
+
+	t.Setenv("AUDIT_ENABLED", "false")
+	cfg, err = Load(nil)
+	require.NoError(t, err)
+	assert.False(t, cfg.Audit.Enabled)
+}
+
+func TestLoad_GRPCAddr(t *testing.T) {
+	t.Setenv("POSTGRES_DSN", "postgres://u:p@localhost/db")
+
+	cfg, err := Load(nil)
+	require.NoError(t, err)
+	assert.Equal(t, ":9090", cfg.GRPC.Addr)
+
+	t.Setenv("GRPC_ADDR", ":9300")
+	cfg, err = Load([]string{"-grpc-addr", ":9400"})
+	require.NoError(t, err)
+	assert.Equal(t, ":9400", cfg.GRPC.Addr)
+
+	_, err = Load([]string{"-grpc-addr", ":8081"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "grpc.addr")
+
+	// выключенный сервер не проверяется
+	t.Setenv("GRPC_ENABLED", "false")
+	cfg, err = Load([]string{"-grpc-addr", ":8081"})
+	require.NoError(t, err)
+	assert.False(t, cfg.GRPC.Enabled)
+}
+
+func TestLoad_StreamEnv(t *testing.T) {
+	t.Setenv("POSTGRES_DSN", "postgres://u:p@localhost/db")
+	t.Setenv("STREAM_BUFFER_SIZE", "16")
+	t.Setenv("STREAM_HEARTBEAT", "5s")
+	t.Setenv("STREAM_WRITE_TIMEOUT", "2s")
+
+	cfg, err := Load(nil)
+	require.NoError(t, err)
+	assert.Equal(t, 16, cfg.Stream.BufferSize)
+	assert.Equal(t, 5*time.Second, cfg.Stream.Heartbeat)
+	assert.Equal(t, 2*time.Second, cfg.Stream.WriteTimeout)
+
+	t.Setenv("STREAM_BUFFER_SIZE", "0")
+	_, err = Load(nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "stream.buffer_size")
+}
+
+func TestLoad_WebhookEnv(t *testing.T) {
+	t.Setenv("POSTGRES_DSN", "postgres://u:p@localhost/db")
+	t.Setenv("WEBHOOK_WORKERS", "2")
+	t.Setenv("WEBHOOK_MAX_ATTEMPTS", "3")
+	t.Setenv("WEBHOOK_INITIAL_BACKOFF", "500ms")
+	t.Setenv("WEBHOOK_MAX_FAILURES", "0")
+
+	cfg, err := Load(nil)
+	require.NoError(t, err)
+	assert.True(t, cfg.Webhook.Enabled)
+	assert.Equal(t, 2, cfg.Webhook.Workers)
+	assert.Equal(t, 3, cfg.Webhook.MaxAttempts)
+	assert.Equal(t, 500*time.Millisecond, cfg.Webhook.InitialBackoff)
+	assert.Equal(t, time.Minute, cfg.Webhook.MaxBackoff)
+	assert.Zero(t, cfg.Webhook.MaxFailures)
+
+	t.Setenv("WEBHOOK_MAX_BACKOFF", "100ms")
+	_, err = Load(nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "max_backoff")
+
+	// выключенная рассылка не проверяется
+	t.Setenv("WEBHOOK_ENABLED", "false")
+	_, err = Load(nil)
+	require.NoError(t, err)
+}
+
+func TestLoad_GraphQLEnv(t *testing.T) {
+	t.Setenv("POSTGRES_DSN", "postgres://u:p@localhost/db")
+	t.Setenv("GRAPHQL_MAX_DEPTH", "6")
+	t.Setenv("GRAPHQL_INTROSPECTION", "false")
+	t.Setenv("GRAPHQL_MAX_COMPLEXITY", "1000")
+
+	cfg, err := Load(nil)
+	require.NoError(t, err)
+	assert.True(t, cfg.GraphQL.Enabled)
+	assert.Equal(t, 6, cfg.GraphQL.MaxDepth)
+	assert.Equal(t, 1000, cfg.GraphQL.MaxComplexity)
+	assert.Equal(t, 300, cfg.GraphQL.MaxFields)
+	assert.False(t, cfg.GraphQL.Introspection)
+
+	t.Setenv("GRAPHQL_MAX_DEPTH", "0")
+	t.Setenv("GRAPHQL_MAX_FIELDS", "0")
+	_, err = Load(nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "graphql.max_depth")
+	assert.Contains(t, err.Error(), "graphql.max_fields")
+}
+
+func TestLoad_TLSEnv(t *testing.T) {
+	t.Setenv("POSTGRES_DSN", "postgres://u:p@localhost/db")
+	t.Setenv("HTTP_TLS_CERT_FILE", "/etc/tls/server.crt")
+	t.Setenv("HTTP_TLS_KEY_FILE", "/etc/tls/server.key")
+	t.Setenv("HTTP_TLS_CLIENT_CA_FILE", "/etc/tls/clients.crt")
+	t.Setenv("HTTP_TLS_CLIENT_AUTH", "require")
+	t.Setenv("KAFKA_TLS_ENABLED", "true")
+	t.Setenv("KAFKA_SASL_MECHANISM", "scram-sha-512")
+	t.Setenv("KAFKA_SASL_USERNAME", "order-service")
+	t.Setenv("KAFKA_SASL_PASSWORD", "secret")
+
+	cfg, err := Load(nil)
+	require.NoError(t, err)
+	assert.True(t, cfg.HTTP.TLS.Enabled())
+	assert.Equal(t, "require", cfg.HTTP.TLS.ClientAuth)
+	assert.Equal(t, "1.2", cfg.HTTP.TLS.MinVersion)
+	assert.True(t, cfg.Kafka.TLS.Enabled)
+	assert.Equal(t, KafkaSASLConfig{Mechanism: "scram-sha-512", Username: "order-service", Password: "secret"}, cfg.Kafka.SASL)
+}
+
+func TestLoad_OpsAddrEnv(t *testing.T) {
+	t.Setenv("POSTGRES_DSN", "postgres://u:p@localhost/db")
+	t.Setenv("HTTP_OPS_ADDR", ":8082")
+
+	cfg, err := Load(nil)
+	require.NoError(t, err)
+	assert.Equal(t, ":8082", cfg.HTTP.OpsAddr)
+
+	t.Setenv("HTTP_OPS_ADDR", cfg.HTTP.Addr)
+	_, err = Load(nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "http.ops_addr")
+}
+
+func TestValidate_TLS(t *testing.T) {
+	cfg := Default()
+	cfg.Postgres.DSN = "postgres://u:p@localhost/db"
+	cfg.HTTP.TLS.CertFile = "/etc/tls/server.crt"
+	cfg.HTTP.TLS.ClientAuth = "require"
+	cfg.HTTP.TLS.MinVersion = "1.0"
+	cfg.Postgres.SSLMode = "on"
+	cfg.Postgres.SSLCert = "/etc/tls/pg.crt"
+	cfg.Kafka.TLS.KeyFile = "/etc/tls/kafka.key"
+	cfg.Kafka.SASL.Mechanism = "scram-sha-1"
+
+	err := cfg.Validate()
+	require.Error(t, err)
+	for _, field := range []string{"http.tls: cert_file и key_file", "http.tls.client_ca_file", "http.tls.min_version",
+		"postgres.sslmode", "postgres: sslcert", "kafka.tls", "kafka.sasl.mechanism"} {
+		assert.Contains(t, err.Error(), field)
+	}
+
+	cfg.Kafka.SASL.Mechanism = "plain"
+	assert.Contains(t, cfg.Validate().Error(), "kafka.sasl.username")
+
+	for _, mode := range []string{"allow", "prefer"} {
+		cfg.Postgres.SSLMode = mode
+		assert.Contains(t, cfg.Validate().Error(), "postgres.sslmode", mode)
+	}
+}
+
+func TestPostgresConfig_ConnString(t *testing.T) {
+	p := PostgresConfig{DSN: "postgres://u:p@db:5432/orders?sslmode=disable"}
+	assert.Equal(t, p.DSN, p.ConnString(), "без настроек TLS DSN не меняется")
+
+	p.SSLMode = "verify-full"
+	p.SSLRootCert = "/etc/tls/ca.crt"
+	assert.Equal(t, "postgres://u:p@db:5432/orders?sslmode=verify-full&sslrootcert=%2Fetc%2Ftls%2Fca.crt", p.ConnString())
+
+	p.DSN = "host=db user=u dbname=orders sslmode=disable"
+	p.SSLRootCert = `/etc/it's\ca.crt`
+	assert.Equal(t, `host=db user=u dbname=orders sslmode=disable sslmode='verify-full' sslrootcert='/etc/it\'s\\ca.crt'`, p.ConnString())
+}
@@ -0,0 +1,67 @@
+// internal/db/apikeys.go
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"order-service/internal/auth"
+	"order-service/internal/metrics"
+
+	"github.com/lib/pq"
+)
+
+var _ auth.KeyStore = (*PostgresDB)(nil)
+
+// LookupAPIKey действующий ключ по хэшу; отозванные и просроченные не находятся
+func (p *PostgresDB) LookupAPIKey(ctx context.Context, keyHash string) (*auth.APIKey, error) {
+	var key auth.APIKey
+	var expiresAt sql.NullTime
+	err := traced{q: p.Conn}.queryRow(ctx, "select_api_key", `
+        SELECT name, scopes, expires_at FROM api_keys
+        WHERE key_hash = $1 AND revoked_at IS NULL
+          AND (expires_at IS NULL OR expires_at > now())`,
+		[]interface{}{keyHash}, &key.Name, pq.Array(&key.Scopes), &expiresAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			metrics.DBOperations.WithLabelValues("api_key_lookup", "not_found").Inc()
+			return nil, err
+		}
+		metrics.DBOperations.WithLabelValues("api_key_lookup", "error").Inc()
+		return nil, fmt.Errorf("ошибка при поиске API ключа: %w", err)
+	}
+	if expiresAt.Valid {
+		key.ExpiresAt = &expiresAt.Time
+	}
+	metrics.DBOperations.WithLabelValues("api_key_lookup", "success").Inc()
+	return &key, nil
+}
+
+// CreateAPIKey сохраняет хэш нового ключа; expiresAt nil — бессрочный
+func (p *PostgresDB) CreateAPIKey(ctx context.Context, name, keyHash string, scopes []string, expiresAt *time.Time) error {
+	_, err := traced{q: p.Conn}.exec(ctx, "insert_api_key", `
+        INSERT INTO api_keys(name, key_hash, scopes, expires_at)
+        VALUES ($1, $2, $3, $4)`,
+		name, keyHash, pq.Array(scopes), expiresAt)
+	if err != nil {
+		return fmt.Errorf("ошибка при создании API ключа: %w", err)
+	}
+	return nil
+}
+
+// RevokeAPIKey отзывает ключ по имени, sql.ErrNoRows если действующего ключа с таким именем нет
+func (p *PostgresDB) RevokeAPIKey(ctx context.Context, name string) error {
+	res, err := traced{q: p.Conn}.exec(ctx, "revoke_api_key", `
+        UPDATE api_keys SET revoked_at = now()
+        WHERE name = $1 AND revoked_at IS NULL`, name)
+	if err != nil {
+		return fmt.Errorf("ошибка при отзыве API ключа: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+// internal/db/audit.go
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"order-service/internal/audit"
+	"order-service/internal/metrics"
+
+	"github.com/lib/pq"
+)
+
+var _ audit.Store = (*PostgresDB)(nil)
+
+// InsertAuditEvents пишет пачку событий журнала одним запросом
+func (p *PostgresDB) InsertAuditEvents(ctx context.Context, events []audit.Event) error {
+	if len(events) == 0 {
+		return nil
+	}
+	actors := make([]string, len(events))
+	actions := make([]string, len(events))
+	uids := make([]string, len(events))
+	times := make([]string, len(events))
+	ips := make([]string, len(events))
+	traceIDs := make([]string, len(events))
+	for i, e := range events {
+		actors[i], actions[i], uids[i] = e.Actor, e.Action, e.OrderUID
+		times[i] = e.At.Format(time.RFC3339Nano)
+		ips[i], traceIDs[i] = e.SourceIP, e.TraceID
+	}
+
+	// время передаётся текстом RFC 3339, пустые IP и trace_id хранятся как NULL
+	_, err := traced{q: p.Conn}.exec(ctx, "insert_audit_events", `
+        INSERT INTO audit_log(actor, action, order_uid, created_at, source_ip, trace_id)
+        SELECT actor, action, uid, at, NULLIF(ip, '')::inet, NULLIF(trace_id, '')
+        FROM unnest($1::text[], $2::text[], $3::text[], $4::timestamptz[], $5::text[], $6::text[])
+            AS t(actor, action, uid, at, ip, trace_id)`,
+		pq.Array(actors), pq.Array(actions), pq.Array(uids), pq.Array(times), pq.Array(ips), pq.Array(traceIDs))
+	if err != nil {
+		metrics.DBOperations.WithLabelValues("insert_audit", "error").Inc()
+		return fmt.Errorf("ошибка при записи журнала аудита: %w", err)
+	}
+	metrics.DBOperations.WithLabelValues("insert_audit", "success").Inc()
+	return nil
+}
+
+// ListAuditEvents события заказа, новые первыми
+func (p *PostgresDB) ListAuditEvents(ctx context.Context, orderUID string, limit int) ([]audit.Event, error) {
+	events := make([]audit.Event, 0)
+	err := traced{q: p.Conn}.query(ctx, "select_audit_events", `
+        SELECT id, actor, action, order_uid, created_at, COALESCE(host(source_ip), ''), COALESCE(trace_id, '')
+        FROM audit_log
+        WHERE order_uid = $1
+        ORDER BY created_at DESC, id DESC
+        LIMIT $2`, []interface{}{orderUID, limit}, func(rows *sql.Rows) error {
+		var e audit.Event
+		if err := rows.Scan(&e.ID, &e.Actor, &e.Action, &e.OrderUID, &e.At, &e.SourceIP, &e.TraceID); err != nil {
+			return err
+		}
+		e.At = e.At.UTC()
+		events = append(events, e)
+		return nil
+	})
+	if err != nil {
+		metrics.DBOperations.WithLabelValues("select_audit", "error").Inc()
+		return nil, fmt.Errorf("ошибка при чтении журнала аудита: %w", err)
+	}
+	metrics.DBOperations.WithLabelValues("select_audit", "success").Inc()
+	return events, nil
+}
@@ -0,0 +1,56 @@
+// internal/db/datakeys.go
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"order-service/internal/fieldcrypt"
+)
+
+var _ fieldcrypt.KeyStore = (*PostgresDB)(nil)
+
+// DataKeys все обёрнутые ключи данных по возрастанию id
+func (p *PostgresDB) DataKeys(ctx context.Context) ([]fieldcrypt.WrappedKey, error) {
+	var keys []fieldcrypt.WrappedKey
+	err := traced{q: p.Conn}.query(ctx, "select_data_keys", `
+        SELECT id, master_key_id, wrapped_key FROM data_keys ORDER BY id`, nil, func(rows *sql.Rows) error {
+		var k fieldcrypt.WrappedKey
+		if err := rows.Scan(&k.ID, &k.MasterKeyID, &k.Wrapped); err != nil {
+			return err
+		}
+		keys = append(keys, k)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при чтении ключей данных: %w", err)
+	}
+	return keys, nil
+}
+
+// CreateDataKey сохраняет новый обёрнутый ключ данных
+func (p *PostgresDB) CreateDataKey(ctx context.Context, masterKeyID string, wrapped []byte) (int64, error) {
+	var id int64
+	err := traced{q: p.Conn}.queryRow(ctx, "insert_data_key", `
+        INSERT INTO data_keys(master_key_id, wrapped_key) VALUES ($1, $2) RETURNING id`,
+		[]interface{}{masterKeyID, wrapped}, &id)
+	if err != nil {
+		return 0, fmt.Errorf("ошибка при создании ключа данных: %w", err)
+	}
+	return id, nil
+}
+
+// RewrapDataKey заменяет обёртку ключа данных после смены мастер-ключа
+func (p *PostgresDB) RewrapDataKey(ctx context.Context, id int64, masterKeyID string, wrapped []byte) error {
+	res, err := traced{q: p.Conn}.exec(ctx, "rewrap_data_key", `
+        UPDATE data_keys SET master_key_id = $2, wrapped_key = $3, rewrapped_at = now() WHERE id = $1`,
+		id, masterKeyID, wrapped)
+	if err != nil {
+		return fmt.Errorf("ошибка при обновлении ключа данных: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
@@ -0,0 +1,302 @@
+// internal/db/encryption.go
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"order-service/internal/metrics"
+	"order-service/models"
+)
+
+// ErrPIIKeysMissing запись зашифрована, а ключи шифрования не настроены
+var ErrPIIKeysMissing = errors.New("персональные данные зашифрованы, а файл ключей не задан (PII_KEY_FILE)")
+
+// deliveryPII персональные колонки deliveries: открытый текст до шифрования
+// или шифротекст с id ключа данных и слепыми индексами после
+type deliveryPII struct {
+	name, phone, address, email             sql.NullString
+	keyID                                   sql.NullInt64
+	nameEnc, phoneEnc, addressEnc, emailEnc []byte
+	phoneIdx, emailIdx                      sql.NullString
+}
+
+// scanDest порядок совпадает с колонками deliveryPIIColumns
+func (d *deliveryPII) scanDest() []interface{} {
+	return []interface{}{&d.name, &d.phone, &d.address, &d.email,
+		&d.keyID, &d.nameEnc, &d.phoneEnc, &d.addressEnc, &d.emailEnc}
+}
+
+const deliveryPIIColumns = `name, phone, address, email, data_key_id, name_enc, phone_enc, address_enc, email_enc`
+
+// sealDelivery персональные поля доставки для записи. Без PII пишутся как есть,
+// с PII — только шифротекст активным ключом данных, открытые колонки NULL
+func (p *PostgresDB) sealDelivery(orderUID string, d *models.Delivery) (*deliveryPII, error) {
+	if p.PII == nil {
+		return &deliveryPII{
+			name:    sql.NullString{String: d.Name, Valid: true},
+			phone:   sql.NullString{String: d.Phone, Valid: true},
+			address: sql.NullString{String: d.Address, Valid: true},
+			email:   sql.NullString{String: d.Email, Valid: true},
+		}, nil
+	}
+
+	key := p.PII.Active()
+	row := &deliveryPII{keyID: sql.NullInt64{Int64: key.ID, Valid: true}}
+	fields := []struct {
+		name  string
+		value string
+		dst   *[]byte
+	}{
+		{"name", d.Name, &row.nameEnc},
+		{"phone", d.Phone, &row.phoneEnc},
+		{"address", d.Address, &row.addressEnc},
+		{"email", d.Email, &row.emailEnc},
+	}
+	for _, f := range fields {
+		sealed, err := key.Seal(orderUID, f.name, f.value)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка шифрования %s: %w", f.name, err)
+		}
+		*f.dst = sealed
+	}
+
+	// пустое значение не индексируется, по нему ничего не ищут
+	if idx, err := p.PII.PhoneIndex(d.Phone); err == nil {
+		row.phoneIdx = sql.NullString{String: idx, Valid: true}
+	}
+	if idx, err := p.PII.EmailIndex(d.Email); err == nil {
+		row.emailIdx = sql.NullString{String: idx, Valid: true}
+	}
+	return row, nil
+}
+
+// openDelivery заполняет персональные поля доставки из прочитанной строки
+func (p *PostgresDB) openDelivery(ctx context.Context, orderUID string, row *deliveryPII, d *models.Delivery) error {
+	if !row.keyID.Valid {
+		d.Name, d.Phone, d.Address, d.Email = row.name.String, row.phone.String, row.address.String, row.email.String
+		return nil
+	}
+	if p.PII == nil {
+		return ErrPIIKeysMissing
+	}
+
+	key, err := p.PII.Key(ctx, row.keyID.Int64)
+	if err != nil {
+		return err
+	}
+	fields := []struct {
+		name   string
+		sealed []byte
+		dst    *string
+	}{
+		{"name", row.nameEnc, &d.Name},
+		{"phone", row.phoneEnc, &d.Phone},
+		{"address", row.addressEnc, &d.Address},
+		{"email", row.emailEnc, &d.Email},
+	}
+	for _, f := range fields {
+		if *f.dst, err = key.Open(orderUID, f.name, f.sealed); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ReencryptDeliveries шифрует активным ключом данных доставки, которые ещё хранятся открытым текстом
+// или зашифрованы другим ключом. Пачки по batchSize идут отдельными транзакциями:
+// команду можно прервать и запустить снова, уже обработанные строки не трогаются
+func (p *PostgresDB) ReencryptDeliveries(ctx context.Context, batchSize int) (int, error) {
+	if p.PII == nil {
+		return 0, ErrPIIKeysMissing
+	}
+	active := p.PII.Active().ID
+
+	total := 0
+	for {
+		n, err := p.reencryptBatch(ctx, active, batchSize)
+		total += n
+		if err != nil {
+			metrics.DBOperations.WithLabelValues("reencrypt", "error").Inc()
+			return total, err
+		}
+		if n == 0 {
+			metrics.DBOperations.WithLabelValues("reencrypt", "success").Inc()
+			return total, nil
+		}
+	}
+}
+
+func (p *PostgresDB) reencryptBatch(ctx context.Context, active int64, limit int) (int, error) {
+	tx, err := p.Conn.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+	q := traced{q: tx}
+
+	type pending struct {
+		orderUID string
+		row      deliveryPII
+	}
+	var batch []pending
+	// SKIP LOCKED: строки, которые сейчас пишет SaveOrder, попадут в следующую пачку
+	err = q.query(ctx, "select_deliveries_to_encrypt", `
+        SELECT order_uid, `+deliveryPIIColumns+` FROM deliveries
+        WHERE data_key_id IS DISTINCT FROM $1
+        ORDER BY id LIMIT $2 FOR UPDATE SKIP LOCKED`, []interface{}{active, limit}, func(rows *sql.Rows) error {
+		var r pending
+		if err := rows.Scan(append([]interface{}{&r.orderUID}, r.row.scanDest()...)...); err != nil {
+			return err
+		}
+		batch = append(batch, r)
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("ошибка при выборке доставок для шифрования: %w", err)
+	}
+
+	for _, r := range batch {
+		var d models.Delivery
+		if err := p.openDelivery(ctx, r.orderUID, &r.row, &d); err != nil {
+			return 0, fmt.Errorf("доставка заказа %s: %w", r.orderUID, err)
+		}
+		sealed, err := p.sealDelivery(r.orderUID, &d)
+		if err != nil {
+			return 0, fmt.Errorf("доставка заказа %s: %w", r.orderUID, err)
+		}
+		_, err = q.exec(ctx, "encrypt_delivery", `
+            UPDATE deliveries SET name = NULL, phone = NULL, address = NULL, email = NULL,
+                data_key_id = $2, name_enc = $3, phone_enc = $4, address_enc = $5, email_enc = $6,
+                phone_bidx = $7, email_bidx = $8
+            WHERE order_uid = $1`,
+			r.orderUID, sealed.keyID, sealed.nameEnc, sealed.phoneEnc, sealed.addressEnc, sealed.emailEnc,
+			sealed.phoneIdx, sealed.emailIdx)
+		if err != nil {
+			return 0, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return len(batch), nil
+}
+
+// DecryptDeliveries возвращает зашифрованные доставки в открытые колонки и стирает шифротекст.
+// Нужна перед откатом миграции 008, которая удаляет *_enc и data_keys. Пачки по batchSize
+// идут отдельными транзакциями, команду можно прервать и запустить снова
+func (p *PostgresDB) DecryptDeliveries(ctx context.Context, batchSize int) (int, error) {
+	if p.PII == nil {
+		return 0, ErrPIIKeysMissing
+	}
+
+	total := 0
+	for {
+		n, err := p.decryptBatch(ctx, batchSize)
+		total += n
+		if err != nil {
+			metrics.DBOperations.WithLabelValues("decrypt", "error").Inc()
+			return total, err
+		}
+		if n == 0 {
+			metrics.DBOperations.WithLabelValues("decrypt", "success").Inc()
+			return total, nil
+		}
+	}
+}
+
+func (p *PostgresDB) decryptBatch(ctx context.Context, limit int) (int, error) {
+	tx, err := p.Conn.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+	q := traced{q: tx}
+
+	type pending struct {
+		orderUID string
+		row      deliveryPII
+	}
+	var batch []pending
+	err = q.query(ctx, "select_deliveries_to_decrypt", `
+        SELECT order_uid, `+deliveryPIIColumns+` FROM deliveries
+        WHERE data_key_id IS NOT NULL
+        ORDER BY id LIMIT $1 FOR UPDATE SKIP LOCKED`, []interface{}{limit}, func(rows *sql.Rows) error {
+		var r pending
+		if err := rows.Scan(append([]interface{}{&r.orderUID}, r.row.scanDest()...)...); err != nil {
+			return err
+		}
+		batch = append(batch, r)
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("ошибка при выборке доставок для расшифровки: %w", err)
+	}
+
+	for _, r := range batch {
+		var d models.Delivery
+		if err := p.openDelivery(ctx, r.orderUID, &r.row, &d); err != nil {
+			return 0, fmt.Errorf("доставка заказа %s: %w", r.orderUID, err)
+		}
+		_, err = q.exec(ctx, "decrypt_delivery", `
+            UPDATE deliveries SET name = $2, phone = $3, address = $4, email = $5,
+                data_key_id = NULL, name_enc = NULL, phone_enc = NULL, address_enc = NULL, email_enc = NULL,
+                phone_bidx = NULL, email_bidx = NULL
+            WHERE order_uid = $1`,
+			r.orderUID, d.Name, d.Phone, d.Address, d.Email)
+		if err != nil {
+			return 0, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return len(batch), nil
+}
+
+// FindOrderUIDsByPhone заказы с точным совпадением телефона по слепому индексу.
+// Без ключей шифрования сравнивается открытая колонка
+func (p *PostgresDB) FindOrderUIDsByPhone(ctx context.Context, phone string) ([]string, error) {
+	if p.PII == nil {
+		return p.findOrderUIDs(ctx, "select_uids_by_phone", `SELECT order_uid FROM deliveries WHERE phone = $1 ORDER BY id`, phone)
+	}
+	idx, err := p.PII.PhoneIndex(phone)
+	if err != nil {
+		return nil, err
+	}
+	return p.findOrderUIDs(ctx, "select_uids_by_phone_bidx", `SELECT order_uid FROM deliveries WHERE phone_bidx = $1 ORDER BY id`, idx)
+}
+
+// FindOrderUIDsByEmail заказы с точным совпадением email (без учёта регистра при шифровании)
+func (p *PostgresDB) FindOrderUIDsByEmail(ctx context.Context, email string) ([]string, error) {
+	if p.PII == nil {
+		return p.findOrderUIDs(ctx, "select_uids_by_email", `SELECT order_uid FROM deliveries WHERE email = $1 ORDER BY id`, email)
+	}
+	idx, err := p.PII.EmailIndex(email)
+	if err != nil {
+		return nil, err
+	}
+	return p.findOrderUIDs(ctx, "select_uids_by_email_bidx", `SELECT order_uid FROM deliveries WHERE email_bidx = $1 ORDER BY id`, idx)
+}
+
+func (p *PostgresDB) findOrderUIDs(ctx context.Context, name, query, arg string) ([]string, error) {
+	var uids []string
+	err := traced{q: p.Conn}.query(ctx, name, query, []interface{}{arg}, func(rows *sql.Rows) error {
+		var uid string
+		if err := rows.Scan(&uid); err != nil {
+			return err
+		}
+		uids = append(uids, uid)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при поиске заказов: %w", err)
+	}
+	return uids, nil
+}
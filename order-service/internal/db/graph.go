@@ -0,0 +1,248 @@
+// internal/db/graph.go
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"order-service/internal/interfaces"
+	"order-service/internal/metrics"
+	"order-service/models"
+
+	"github.com/lib/pq"
+)
+
+var _ interfaces.OrderBatchStore = (*PostgresDB)(nil)
+
+// Пакетные выборки: каждая часть заказа читается одним запросом
+// на все заказы уровня запроса, а не запросом на заказ, как в GetOrder
+
+const orderColumns = `order_uid, track_number, entry, locale, internal_signature, customer_id,
+        delivery_service, shardkey, sm_id, date_created, oof_shard`
+
+func scanOrder(rows *sql.Rows) (*models.Order, error) {
+	o := &models.Order{}
+	err := rows.Scan(&o.OrderUID, &o.TrackNumber, &o.Entry, &o.Locale, &o.InternalSignature, &o.CustomerID,
+		&o.DeliveryService, &o.Shardkey, &o.SmID, &o.DateCreated, &o.OofShard)
+	return o, err
+}
+
+// ListOrderHeaders страница заказов по фильтру, новые первыми. Доставка, оплата и товары не заполняются
+func (p *PostgresDB) ListOrderHeaders(ctx context.Context, f interfaces.OrderFilter, limit, offset int) ([]*models.Order, error) {
+	var where []string
+	var args []interface{}
+	cond := func(expr string, arg interface{}) {
+		args = append(args, arg)
+		where = append(where, expr+" $"+strconv.Itoa(len(args)))
+	}
+	if f.CustomerID != "" {
+		cond("customer_id =", f.CustomerID)
+	}
+	if f.DeliveryService != "" {
+		cond("delivery_service =", f.DeliveryService)
+	}
+	if f.CreatedAfter != nil {
+		cond("date_created >=", *f.CreatedAfter)
+	}
+	if f.CreatedBefore != nil {
+		cond("date_created <", *f.CreatedBefore)
+	}
+	query := `SELECT ` + orderColumns + ` FROM orders`
+	if len(where) > 0 {
+		query += ` WHERE ` + strings.Join(where, " AND ")
+	}
+	args = append(args, limit, offset)
+	query += fmt.Sprintf(` ORDER BY date_created DESC, order_uid LIMIT $%d OFFSET $%d`, len(args)-1, len(args))
+
+	orders := make([]*models.Order, 0, limit)
+	err := traced{q: p.Conn}.query(ctx, "select_order_headers", query, args, func(rows *sql.Rows) error {
+		o, err := scanOrder(rows)
+		if err != nil {
+			return err
+		}
+		orders = append(orders, o)
+		return nil
+	})
+	if err != nil {
+		metrics.DBOperations.WithLabelValues("batch", "error").Inc()
+		return nil, fmt.Errorf("ошибка при выборке страницы заказов: %w", err)
+	}
+	metrics.DBOperations.WithLabelValues("batch", "success").Inc()
+	return orders, nil
+}
+
+// OrdersByCustomers последние limit заказов каждого клиента из ids, новые первыми
+func (p *PostgresDB) OrdersByCustomers(ctx context.Context, ids []string, limit int) (map[string][]*models.Order, error) {
+	byCustomer := make(map[string][]*models.Order, len(ids))
+	err := traced{q: p.Conn}.query(ctx, "select_customer_orders", `
+        SELECT `+orderColumns+` FROM (
+            SELECT `+orderColumns+`,
+                ROW_NUMBER() OVER (PARTITION BY customer_id ORDER BY date_created DESC, order_uid) AS n
+            FROM orders WHERE customer_id = ANY($1)
+        ) o
+        WHERE n <= $2
+        ORDER BY customer_id, n`, []interface{}{pq.Array(ids), limit}, func(rows *sql.Rows) error {
+		o, err := scanOrder(rows)
+		if err != nil {
+			return err
+		}
+		byCustomer[o.CustomerID] = append(byCustomer[o.CustomerID], o)
+		return nil
+	})
+	return byCustomer, batchResult(err, "заказов клиентов")
+}
+
+// OrderCountsByCustomer число заказов каждого клиента из ids; клиентов без заказов в ответе нет
+func (p *PostgresDB) OrderCountsByCustomer(ctx context.Context, ids []string) (map[string]int, error) {
+	counts := make(map[string]int, len(ids))
+	err := traced{q: p.Conn}.query(ctx, "count_customer_orders", `
+        SELECT customer_id, count(*) FROM orders
+        WHERE customer_id = ANY($1)
+        GROUP BY customer_id`, []interface{}{pq.Array(ids)}, func(rows *sql.Rows) error {
+		var id string
+		var n int
+		if err := rows.Scan(&id, &n); err != nil {
+			return err
+		}
+		counts[id] = n
+		return nil
+	})
+	return counts, batchResult(err, "числа заказов клиентов")
+}
+
+// DeliveriesByOrder доставки заказов uids с расшифрованными персональными данными
+func (p *PostgresDB) DeliveriesByOrder(ctx context.Context, uids []string) (map[string]models.Delivery, error) {
+	deliveries := make(map[string]models.Delivery, len(uids))
+	err := traced{q: p.Conn}.query(ctx, "select_deliveries", `
+        SELECT order_uid, zip, city, region, `+deliveryPIIColumns+`
+        FROM deliveries WHERE order_uid = ANY($1)`, []interface{}{pq.Array(uids)}, func(rows *sql.Rows) error {
+		var uid string
+		var d models.Delivery
+		var pii deliveryPII
+		if err := rows.Scan(append([]interface{}{&uid, &d.Zip, &d.City, &d.Region}, pii.scanDest()...)...); err != nil {
+			return err
+		}
+		if err := p.openDelivery(ctx, uid, &pii, &d); err != nil {
+			return fmt.Errorf("доставка заказа %s: %w", uid, err)
+		}
+		deliveries[uid] = d
+		return nil
+	})
+	return deliveries, batchResult(err, "доставок")
+}
+
+// PaymentsByOrder оплаты заказов uids
+func (p *PostgresDB) PaymentsByOrder(ctx context.Context, uids []string) (map[string]models.Payment, error) {
+	payments := make(map[string]models.Payment, len(uids))
+	err := traced{q: p.Conn}.query(ctx, "select_payments", `
+        SELECT order_uid, transaction, request_id, currency, provider, amount, payment_dt, bank, delivery_cost, goods_total, custom_fee
+        FROM payments WHERE order_uid = ANY($1)`, []interface{}{pq.Array(uids)}, func(rows *sql.Rows) error {
+		var uid string
+		var pmt models.Payment
+		if err := rows.Scan(&uid, &pmt.Transaction, &pmt.RequestID, &pmt.Currency, &pmt.Provider, &pmt.Amount,
+			&pmt.PaymentDt, &pmt.Bank, &pmt.DeliveryCost, &pmt.GoodsTotal, &pmt.CustomFee); err != nil {
+			return err
+		}
+		payments[uid] = pmt
+		return nil
+	})
+	return payments, batchResult(err, "оплат")
+}
+
+// ItemsByOrder товары заказов uids в порядке сохранения
+func (p *PostgresDB) ItemsByOrder(ctx context.Context, uids []string) (map[string][]models.Item, error) {
+	items := make(map[string][]models.Item, len(uids))
+	err := traced{q: p.Conn}.query(ctx, "select_order_items", `
+        SELECT order_uid, chrt_id, track_number, price, rid, name, sale, size, total_price, nm_id, brand, status
+        FROM items WHERE order_uid = ANY($1)
+        ORDER BY order_uid, id`, []interface{}{pq.Array(uids)}, func(rows *sql.Rows) error {
+		var uid string
+		var item models.Item
+		if err := rows.Scan(&uid, &item.ChrtID, &item.TrackNumber, &item.Price, &item.Rid, &item.Name, &item.Sale, &item.Size,
+			&item.TotalPrice, &item.NmID, &item.Brand, &item.Status); err != nil {
+			return err
+		}
+		items[uid] = append(items[uid], item)
+		return nil
+	})
+	return items, batchResult(err, "товаров")
+}
+
+// OrdersByUID полные заказы uids: заказы и каждая их часть читаются одним запросом на все uid.
+// Неизвестные uid в ответ не попадают
+func (p *PostgresDB) OrdersByUID(ctx context.Context, uids []string) (map[string]*models.Order, error) {
+	var orders []*models.Order
+	err := traced{q: p.Conn}.query(ctx, "select_orders_by_uid", `
+        SELECT `+orderColumns+` FROM orders WHERE order_uid = ANY($1)`, []interface{}{pq.Array(uids)}, func(rows *sql.Rows) error {
+		o, err := scanOrder(rows)
+		if err != nil {
+			return err
+		}
+		orders = append(orders, o)
+		return nil
+	})
+	if err = batchResult(err, "заказов"); err != nil {
+		return nil, err
+	}
+	if err := p.completeOrders(ctx, orders); err != nil {
+		return nil, err
+	}
+	byUID := make(map[string]*models.Order, len(orders))
+	for _, o := range orders {
+		byUID[o.OrderUID] = o
+	}
+	return byUID, nil
+}
+
+// completeOrders заполняет доставку, оплату и товары заказов тремя пакетными выборками.
+// Заказ без доставки или оплаты — ошибка, как в GetOrder
+func (p *PostgresDB) completeOrders(ctx context.Context, orders []*models.Order) error {
+	if len(orders) == 0 {
+		return nil
+	}
+	uids := make([]string, len(orders))
+	for i, o := range orders {
+		uids[i] = o.OrderUID
+	}
+	deliveries, err := p.DeliveriesByOrder(ctx, uids)
+	if err != nil {
+		return err
+	}
+	payments, err := p.PaymentsByOrder(ctx, uids)
+	if err != nil {
+		return err
+	}
+	items, err := p.ItemsByOrder(ctx, uids)
+	if err != nil {
+		return err
+	}
+	for _, o := range orders {
+		d, ok := deliveries[o.OrderUID]
+		if !ok {
+			return fmt.Errorf("доставка заказа %s не найдена: %w", o.OrderUID, sql.ErrNoRows)
+		}
+		pmt, ok := payments[o.OrderUID]
+		if !ok {
+			return fmt.Errorf("оплата заказа %s не найдена: %w", o.OrderUID, sql.ErrNoRows)
+		}
+		o.Delivery, o.Payment = d, pmt
+		o.Items = items[o.OrderUID]
+		if o.Items == nil {
+			o.Items = []models.Item{}
+		}
+	}
+	return nil
+}
+
+// batchResult учитывает пакетную выборку в метриках и оборачивает ошибку
+func batchResult(err error, what string) error {
+	if err != nil {
+		metrics.DBOperations.WithLabelValues("batch", "error").Inc()
+		return fmt.Errorf("ошибка при выборке %s: %w", what, err)
+	}
+	metrics.DBOperations.WithLabelValues("batch", "success").Inc()
+	return nil
+}
@@ -1,23 +1,34 @@
 package db
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"fmt"
-	"log"
+	"log/slog"
 	"order-service/internal/metrics"
 
+	"order-service/internal/fieldcrypt"
 	"order-service/internal/interfaces"
+	"order-service/internal/invalidation"
+	"order-service/internal/logging"
 	"order-service/models"
 	"time"
 
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 var _ interfaces.Database = (*PostgresDB)(nil)
 
 type PostgresDB struct {
 	Conn *sql.DB
+	// InvalidationOrigin если задан, SaveOrder в той же транзакции шлёт NOTIFY
+	// об изменении заказа, чтобы остальные реплики сбросили его из кэша
+	InvalidationOrigin string
+	// PII если задан, имя, телефон, адрес и email доставки хранятся зашифрованными
+	PII *fieldcrypt.Encryptor
 }
 
 func NewPostgresDB(dsn string) (*PostgresDB, error) {
@@ -31,28 +42,39 @@ func NewPostgresDB(dsn string) (*PostgresDB, error) {
 	return &PostgresDB{Conn: db}, nil
 }
 
+// Ping проверка доступности БД для health проверок
+func (p *PostgresDB) Ping(ctx context.Context) error {
+	return p.Conn.PingContext(ctx)
+}
+
 func (p *PostgresDB) Close() error {
 	return p.Conn.Close()
 }
 
-func (p *PostgresDB) SaveOrder(order *models.Order) error {
+// SaveOrder сохраняет заказ целиком в одной транзакции. statusChanged — у ранее сохранённого
+// заказа сменился статус хотя бы одного товара
+func (p *PostgresDB) SaveOrder(ctx context.Context, order *models.Order) (statusChanged bool, err error) {
 	start := time.Now()
 	defer func() {
 		duration := time.Since(start).Seconds()
 		metrics.OrderProcessingTime.WithLabelValues("db", "save_order").Observe(duration)
 	}()
 
-	tx, err := p.Conn.Begin()
+	ctx, span := tracer.Start(ctx, "db.save_order", trace.WithAttributes(attribute.String("order.uid", order.OrderUID)))
+	defer func() { endQuerySpan(span, err) }()
+
+	tx, err := p.Conn.BeginTx(ctx, nil)
 	if err != nil {
 		metrics.DBOperations.WithLabelValues("save", "error").Inc()
-		return err
+		return false, err
 	}
 	defer func() {
 		_ = tx.Rollback()
 	}()
+	q := traced{q: tx}
 
 	// orders
-	_, err = tx.Exec(`
+	_, err = q.exec(ctx, "insert_order", `
         INSERT INTO orders(order_uid, track_number, entry, locale, internal_signature, customer_id, delivery_service, shardkey, sm_id, date_created, oof_shard)
         VALUES($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11)
         ON CONFLICT (order_uid) DO UPDATE SET track_number=EXCLUDED.track_number, entry=EXCLUDED.entry`,
@@ -60,23 +82,33 @@ func (p *PostgresDB) SaveOrder(order *models.Order) error {
 		order.CustomerID, order.DeliveryService, order.Shardkey, order.SmID, order.DateCreated, order.OofShard)
 	if err != nil {
 		metrics.DBOperations.WithLabelValues("save", "error").Inc()
-		return err
+		return false, err
 	}
 
-	// deliveries
-	_, err = tx.Exec(`
-        INSERT INTO deliveries(order_uid, name, phone, zip, city, address, region, email)
-        VALUES($1,$2,$3,$4,$5,$6,$7,$8)
-        ON CONFLICT (order_uid) DO UPDATE SET name=EXCLUDED.name`,
-		order.OrderUID, order.Delivery.Name, order.Delivery.Phone, order.Delivery.Zip,
-		order.Delivery.City, order.Delivery.Address, order.Delivery.Region, order.Delivery.Email)
+	// deliveries: персональные колонки обновляются вместе, чтобы шифротекст и открытый текст не разошлись
+	pii, err := p.sealDelivery(order.OrderUID, &order.Delivery)
 	if err != nil {
 		metrics.DBOperations.WithLabelValues("save", "error").Inc()
-		return err
+		return false, err
+	}
+	_, err = q.exec(ctx, "insert_delivery", `
+        INSERT INTO deliveries(order_uid, name, phone, zip, city, address, region, email,
+            data_key_id, name_enc, phone_enc, address_enc, email_enc, phone_bidx, email_bidx)
+        VALUES($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13,$14,$15)
+        ON CONFLICT (order_uid) DO UPDATE SET name=EXCLUDED.name, phone=EXCLUDED.phone,
+            address=EXCLUDED.address, email=EXCLUDED.email, data_key_id=EXCLUDED.data_key_id,
+            name_enc=EXCLUDED.name_enc, phone_enc=EXCLUDED.phone_enc, address_enc=EXCLUDED.address_enc,
+            email_enc=EXCLUDED.email_enc, phone_bidx=EXCLUDED.phone_bidx, email_bidx=EXCLUDED.email_bidx`,
+		order.OrderUID, pii.name, pii.phone, order.Delivery.Zip, order.Delivery.City, pii.address,
+		order.Delivery.Region, pii.email, pii.keyID, pii.nameEnc, pii.phoneEnc, pii.addressEnc,
+		pii.emailEnc, pii.phoneIdx, pii.emailIdx)
+	if err != nil {
+		metrics.DBOperations.WithLabelValues("save", "error").Inc()
+		return false, err
 	}
 
 	// payments
-	_, err = tx.Exec(`
+	_, err = q.exec(ctx, "insert_payment", `
         INSERT INTO payments(order_uid, transaction, request_id, currency, provider, amount, payment_dt, bank, delivery_cost, goods_total, custom_fee)
         VALUES($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11)
         ON CONFLICT (order_uid) DO UPDATE SET transaction=EXCLUDED.transaction`,
@@ -85,17 +117,31 @@ func (p *PostgresDB) SaveOrder(order *models.Order) error {
 		order.Payment.DeliveryCost, order.Payment.GoodsTotal, order.Payment.CustomFee)
 	if err != nil {
 		metrics.DBOperations.WithLabelValues("save", "error").Inc()
-		return err
+		return false, err
 	}
 
-	_, err = tx.Exec(`DELETE FROM items WHERE order_uid = $1`, order.OrderUID)
+	// статусы товаров до сохранения: по ним видно, сменился ли статус ранее сохранённого заказа
+	prevStatus := make(map[int64]int)
+	err = q.query(ctx, "delete_items", `DELETE FROM items WHERE order_uid = $1 RETURNING chrt_id, status`,
+		[]interface{}{order.OrderUID}, func(rows *sql.Rows) error {
+			var chrtID int64
+			var status int
+			if err := rows.Scan(&chrtID, &status); err != nil {
+				return err
+			}
+			prevStatus[chrtID] = status
+			return nil
+		})
 	if err != nil {
 		metrics.DBOperations.WithLabelValues("save", "error").Inc()
-		return err
+		return false, err
 	}
 
 	for _, item := range order.Items {
-		_, err = tx.Exec(`
+		if prev, ok := prevStatus[item.ChrtID]; ok && prev != item.Status {
+			statusChanged = true
+		}
+		_, err = q.exec(ctx, "insert_item", `
             INSERT INTO items(chrt_id, order_uid, track_number, price, rid, name, sale, size, total_price, nm_id, brand, status)
             VALUES($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12)
             ON CONFLICT (chrt_id) DO UPDATE SET price=EXCLUDED.price`,
@@ -103,34 +149,50 @@ func (p *PostgresDB) SaveOrder(order *models.Order) error {
 			item.Sale, item.Size, item.TotalPrice, item.NmID, item.Brand, item.Status)
 		if err != nil {
 			metrics.DBOperations.WithLabelValues("save", "error").Inc()
-			return err
+			return false, err
+		}
+	}
+
+	// уведомление доставляется подписчикам только после коммита
+	if p.InvalidationOrigin != "" {
+		payload, err := invalidation.Payload(order.OrderUID, p.InvalidationOrigin)
+		if err != nil {
+			metrics.DBOperations.WithLabelValues("save", "error").Inc()
+			return false, err
+		}
+		if _, err := q.exec(ctx, "notify_invalidation", `SELECT pg_notify($1, $2)`, invalidation.Channel, payload); err != nil {
+			metrics.DBOperations.WithLabelValues("save", "error").Inc()
+			return false, err
 		}
 	}
 
 	err = tx.Commit()
 	if err != nil {
 		metrics.DBOperations.WithLabelValues("save", "error").Inc()
-		return err
+		return false, err
 	}
 	metrics.DBOperations.WithLabelValues("save", "success").Inc()
-	return nil
+	return statusChanged, nil
 }
 
-func (p *PostgresDB) GetOrder(orderUID string) (*models.Order, error) {
+func (p *PostgresDB) GetOrder(ctx context.Context, orderUID string) (order *models.Order, err error) {
 	start := time.Now()
 	defer func() {
 		duration := time.Since(start).Seconds()
 		metrics.OrderProcessingTime.WithLabelValues("db", "get_order").Observe(duration)
 	}()
 
-	order := &models.Order{}
+	ctx, span := tracer.Start(ctx, "db.get_order", trace.WithAttributes(attribute.String("order.uid", orderUID)))
+	defer func() { endQuerySpan(span, err) }()
 
-	row := p.Conn.QueryRow(`
-        SELECT order_uid, track_number, entry, locale, internal_signature, customer_id, delivery_service, shardkey, sm_id, date_created, oof_shard
-        FROM orders WHERE order_uid = $1`, orderUID)
+	q := traced{q: p.Conn}
+	order = &models.Order{}
 
 	var dateCreated time.Time
-	err := row.Scan(&order.OrderUID, &order.TrackNumber, &order.Entry, &order.Locale, &order.InternalSignature,
+	err = q.queryRow(ctx, "select_order", `
+        SELECT order_uid, track_number, entry, locale, internal_signature, customer_id, delivery_service, shardkey, sm_id, date_created, oof_shard
+        FROM orders WHERE order_uid = $1`, []interface{}{orderUID},
+		&order.OrderUID, &order.TrackNumber, &order.Entry, &order.Locale, &order.InternalSignature,
 		&order.CustomerID, &order.DeliveryService, &order.Shardkey, &order.SmID, &dateCreated, &order.OofShard)
 	if errors.Is(err, sql.ErrNoRows) {
 		metrics.DBOperations.WithLabelValues("get", "error").Inc()
@@ -142,21 +204,29 @@ func (p *PostgresDB) GetOrder(orderUID string) (*models.Order, error) {
 	order.DateCreated = dateCreated
 
 	d := models.Delivery{}
-	row = p.Conn.QueryRow(`
-        SELECT name, phone, zip, city, address, region, email
-        FROM deliveries WHERE order_uid = $1`, orderUID)
-	err = row.Scan(&d.Name, &d.Phone, &d.Zip, &d.City, &d.Address, &d.Region, &d.Email)
+	var pii deliveryPII
+	err = q.queryRow(ctx, "select_delivery", `
+        SELECT zip, city, region, `+deliveryPIIColumns+`
+        FROM deliveries WHERE order_uid = $1`, []interface{}{orderUID},
+		append([]interface{}{&d.Zip, &d.City, &d.Region}, pii.scanDest()...)...)
 	if errors.Is(err, sql.ErrNoRows) {
 		metrics.DBOperations.WithLabelValues("get", "error").Inc()
 		return nil, fmt.Errorf("доставка заказа %s не найдена: %w", orderUID, err)
 	}
+	if err == nil {
+		err = p.openDelivery(ctx, orderUID, &pii, &d)
+	}
+	if err != nil {
+		metrics.DBOperations.WithLabelValues("get", "error").Inc()
+		return nil, fmt.Errorf("ошибка чтения доставки заказа %s: %w", orderUID, err)
+	}
 	order.Delivery = d
 
 	pmt := models.Payment{}
-	row = p.Conn.QueryRow(`
+	err = q.queryRow(ctx, "select_payment", `
         SELECT transaction, request_id, currency, provider, amount, payment_dt, bank, delivery_cost, goods_total, custom_fee
-        FROM payments WHERE order_uid = $1`, orderUID)
-	err = row.Scan(&pmt.Transaction, &pmt.RequestID, &pmt.Currency, &pmt.Provider, &pmt.Amount,
+        FROM payments WHERE order_uid = $1`, []interface{}{orderUID},
+		&pmt.Transaction, &pmt.RequestID, &pmt.Currency, &pmt.Provider, &pmt.Amount,
 		&pmt.PaymentDt, &pmt.Bank, &pmt.DeliveryCost, &pmt.GoodsTotal, &pmt.CustomFee)
 	if errors.Is(err, sql.ErrNoRows) {
 		metrics.DBOperations.WithLabelValues("get", "error").Inc()
@@ -164,30 +234,19 @@ func (p *PostgresDB) GetOrder(orderUID string) (*models.Order, error) {
 	}
 	order.Payment = pmt
 
-	rows, err := p.Conn.Query(`
-        SELECT chrt_id, track_number, price, rid, name, sale, size, total_price, nm_id, brand, status
-        FROM items WHERE order_uid = $1`, orderUID)
-	if err != nil {
-		metrics.DBOperations.WithLabelValues("get", "error").Inc()
-		return nil, err
-	}
-	defer func() {
-		if cerr := rows.Close(); cerr != nil {
-			log.Printf("Ошибка закрытия rows: %v", cerr)
-		}
-	}()
-
 	items := []models.Item{}
-	for rows.Next() {
+	err = q.query(ctx, "select_items", `
+        SELECT chrt_id, track_number, price, rid, name, sale, size, total_price, nm_id, brand, status
+        FROM items WHERE order_uid = $1`, []interface{}{orderUID}, func(rows *sql.Rows) error {
 		var item models.Item
 		if err := rows.Scan(&item.ChrtID, &item.TrackNumber, &item.Price, &item.Rid, &item.Name, &item.Sale, &item.Size,
 			&item.TotalPrice, &item.NmID, &item.Brand, &item.Status); err != nil {
-			metrics.DBOperations.WithLabelValues("get", "error").Inc()
-			return nil, err
+			return err
 		}
 		items = append(items, item)
-	}
-	if err := rows.Err(); err != nil {
+		return nil
+	})
+	if err != nil {
 		metrics.DBOperations.WithLabelValues("get", "error").Inc()
 		return nil, fmt.Errorf("ошибка при переборе items заказа %s: %w", orderUID, err)
 	}
@@ -207,7 +266,7 @@ func (p *PostgresDB) GetRecentOrders(limit int) (map[string]*models.Order, error
 	}
 	defer func() {
 		if err := rows.Close(); err != nil {
-			log.Printf("Ошибка закрытия rows: %v", err)
+			slog.Error("Ошибка закрытия rows", logging.Err(err))
 		}
 	}()
 
@@ -219,9 +278,9 @@ func (p *PostgresDB) GetRecentOrders(limit int) (map[string]*models.Order, error
 			continue
 		}
 
-		order, err := p.GetOrder(uid)
+		order, err := p.GetOrder(context.Background(), uid)
 		if err != nil {
-			log.Printf("не удалось загрузить заказ %s: %v", uid, err)
+			slog.Warn("Не удалось загрузить заказ", slog.String("order_uid", uid), logging.Err(err))
 			continue
 		}
 
@@ -232,3 +291,78 @@ func (p *PostgresDB) GetRecentOrders(limit int) (map[string]*models.Order, error
 	}
 	return orderMap, nil
 }
+
+// ListOrders страница заказов по убыванию date_created. Части заказов страницы
+// читаются пакетными выборками, по запросу на таблицу, а не GetOrder на каждый заказ
+func (p *PostgresDB) ListOrders(ctx context.Context, limit, offset int) ([]*models.Order, error) {
+	orders, err := p.ListOrderHeaders(ctx, interfaces.OrderFilter{}, limit, offset)
+	if err == nil {
+		err = p.completeOrders(ctx, orders)
+	}
+	if err != nil {
+		metrics.DBOperations.WithLabelValues("list", "error").Inc()
+		return nil, err
+	}
+	metrics.DBOperations.WithLabelValues("list", "success").Inc()
+	return orders, nil
+}
+
+// GetRecentOrderUIDs uid последних по date_created заказов
+func (p *PostgresDB) GetRecentOrderUIDs(limit int) ([]string, error) {
+	return p.queryOrderUIDs("select_recent_uids", `
+        SELECT order_uid FROM orders
+        ORDER BY date_created DESC
+        LIMIT $1`, limit)
+}
+
+// GetMostAccessedOrderUIDs uid самых запрашиваемых за последнюю неделю заказов
+func (p *PostgresDB) GetMostAccessedOrderUIDs(limit int) ([]string, error) {
+	return p.queryOrderUIDs("select_most_accessed_uids", `
+        SELECT l.order_uid FROM order_access_log l
+        JOIN orders o ON o.order_uid = l.order_uid
+        WHERE l.last_accessed > now() - interval '7 days'
+        ORDER BY l.hits DESC
+        LIMIT $1`, limit)
+}
+
+// RecordOrderAccesses прибавляет счётчики обращений к заказам одним запросом
+func (p *PostgresDB) RecordOrderAccesses(hits map[string]int) error {
+	if len(hits) == 0 {
+		return nil
+	}
+	uids := make([]string, 0, len(hits))
+	counts := make([]int64, 0, len(hits))
+	for uid, n := range hits {
+		uids = append(uids, uid)
+		counts = append(counts, int64(n))
+	}
+
+	_, err := traced{q: p.Conn}.exec(context.Background(), "record_access", `
+        INSERT INTO order_access_log(order_uid, hits, last_accessed)
+        SELECT uid, n, now() FROM unnest($1::text[], $2::bigint[]) AS t(uid, n)
+        ON CONFLICT (order_uid) DO UPDATE
+        SET hits = order_access_log.hits + EXCLUDED.hits, last_accessed = EXCLUDED.last_accessed`,
+		pq.Array(uids), pq.Array(counts))
+	if err != nil {
+		metrics.DBOperations.WithLabelValues("record_access", "error").Inc()
+		return err
+	}
+	metrics.DBOperations.WithLabelValues("record_access", "success").Inc()
+	return nil
+}
+
+func (p *PostgresDB) queryOrderUIDs(name, query string, limit int) ([]string, error) {
+	uids := make([]string, 0, limit)
+	err := traced{q: p.Conn}.query(context.Background(), name, query, []interface{}{limit}, func(rows *sql.Rows) error {
+		var uid string
+		if err := rows.Scan(&uid); err != nil {
+			return err
+		}
+		uids = append(uids, uid)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при переборе заказов: %w", err)
+	}
+	return uids, nil
+}
@@ -11,6 +11,10 @@ import (
 	"testing"
 	"time"
 
+	"order-service/internal/audit"
+	"order-service/internal/fieldcrypt"
+	"order-service/internal/interfaces"
+	"order-service/internal/webhook"
 	"order-service/models"
 
 	"github.com/brianvoe/gofakeit/v6"
@@ -87,8 +91,22 @@ func createTestTables(db *sql.DB) error {
 			address TEXT,
 			region TEXT,
 			email TEXT,
+			data_key_id BIGINT,
+			name_enc BYTEA,
+			phone_enc BYTEA,
+			address_enc BYTEA,
+			email_enc BYTEA,
+			phone_bidx TEXT,
+			email_bidx TEXT,
 			CONSTRAINT deliveries_order_uid_key UNIQUE(order_uid)
 		)`,
+		`CREATE TABLE IF NOT EXISTS data_keys (
+			id BIGSERIAL PRIMARY KEY,
+			master_key_id TEXT NOT NULL,
+			wrapped_key BYTEA NOT NULL,
+			created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+			rewrapped_at TIMESTAMPTZ
+		)`,
 		`CREATE TABLE IF NOT EXISTS payments (
 			id SERIAL PRIMARY KEY,
 			order_uid TEXT NOT NULL REFERENCES orders(order_uid) ON DELETE CASCADE,
@@ -120,6 +138,38 @@ func createTestTables(db *sql.DB) error {
 			status BIGINT,
 			CONSTRAINT items_chrt_id_key UNIQUE(chrt_id)
 		)`,
+		`CREATE TABLE IF NOT EXISTS api_keys (
+			id BIGSERIAL PRIMARY KEY,
+			name TEXT NOT NULL UNIQUE,
+			key_hash TEXT NOT NULL UNIQUE,
+			scopes TEXT[] NOT NULL DEFAULT '{}',
+			created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+			expires_at TIMESTAMPTZ,
+			revoked_at TIMESTAMPTZ
+		)`,
+		`CREATE TABLE IF NOT EXISTS audit_log (
+			id BIGSERIAL PRIMARY KEY,
+			actor TEXT NOT NULL,
+			action TEXT NOT NULL,
+			order_uid TEXT NOT NULL,
+			created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+			source_ip INET,
+			trace_id TEXT
+		)`,
+		`CREATE TABLE IF NOT EXISTS webhooks (
+			id BIGSERIAL PRIMARY KEY,
+			url TEXT NOT NULL,
+			secret TEXT NOT NULL,
+			events TEXT[] NOT NULL DEFAULT '{}',
+			customer_id TEXT NOT NULL DEFAULT '',
+			delivery_service TEXT NOT NULL DEFAULT '',
+			include_pii BOOLEAN NOT NULL DEFAULT false,
+			failures INT NOT NULL DEFAULT 0,
+			last_error TEXT,
+			last_delivered_at TIMESTAMPTZ,
+			created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+			disabled_at TIMESTAMPTZ
+		)`,
 	}
 
 	for _, q := range queries {
@@ -176,10 +226,10 @@ func TestPostgresDB_SaveAndGetOrder_Integration(t *testing.T) {
 	order := createTestOrder()
 	order.OrderUID = "test-integration-" + gofakeit.UUID()
 
-	err := db.SaveOrder(order)
+	_, err := db.SaveOrder(context.Background(), order)
 	assert.NoError(t, err)
 
-	retrievedOrder, err := db.GetOrder(order.OrderUID)
+	retrievedOrder, err := db.GetOrder(context.Background(), order.OrderUID)
 	assert.NoError(t, err)
 
 	assert.Equal(t, order.OrderUID, retrievedOrder.OrderUID)
@@ -239,7 +289,7 @@ func TestPostgresDB_GetRecentOrders_Integration(t *testing.T) {
 		order := createTestOrder()
 		order.OrderUID = fmt.Sprintf("test-%d-", i) + gofakeit.UUID()
 		order.DateCreated = time.Now().Add(-time.Duration(i) * time.Hour)
-		err := db.SaveOrder(order)
+		_, err := db.SaveOrder(context.Background(), order)
 		assert.NoError(t, err)
 	}
 
@@ -274,7 +324,7 @@ func TestPostgresDB_GetOrder_NotFound(t *testing.T) {
 	db, cleanup := setupTestDB(t)
 	defer cleanup()
 
-	order, err := db.GetOrder("nonexistent-order-123")
+	order, err := db.GetOrder(context.Background(), "nonexistent-order-123")
 	assert.Error(t, err)
 	assert.Nil(t, order)
 	assert.Contains(t, err.Error(), "не найден")
@@ -291,13 +341,357 @@ func TestPostgresDB_SaveOrder_Duplicate(t *testing.T) {
 	order := createTestOrder()
 	order.OrderUID = "duplicate-test-123"
 
-	err := db.SaveOrder(order)
+	changed, err := db.SaveOrder(context.Background(), order)
 	assert.NoError(t, err)
+	assert.False(t, changed, "новый заказ")
 
-	err = db.SaveOrder(order)
+	changed, err = db.SaveOrder(context.Background(), order)
 	assert.NoError(t, err)
+	assert.False(t, changed, "статусы товаров те же")
 
-	retrievedOrder, err := db.GetOrder(order.OrderUID)
+	order.Items[0].Status++
+	changed, err = db.SaveOrder(context.Background(), order)
+	assert.NoError(t, err)
+	assert.True(t, changed)
+
+	retrievedOrder, err := db.GetOrder(context.Background(), order.OrderUID)
 	assert.NoError(t, err)
 	assert.Equal(t, order.OrderUID, retrievedOrder.OrderUID)
 }
+
+func TestPostgresDB_APIKeys_Integration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration test in short mode")
+	}
+
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	require.NoError(t, db.CreateAPIKey(ctx, "web", "hash-web", []string{"orders:read"}, nil))
+	expired := time.Now().Add(-time.Minute)
+	require.NoError(t, db.CreateAPIKey(ctx, "old", "hash-old", []string{"admin"}, &expired))
+
+	key, err := db.LookupAPIKey(ctx, "hash-web")
+	require.NoError(t, err)
+	assert.Equal(t, "web", key.Name)
+	assert.Equal(t, []string{"orders:read"}, key.Scopes)
+	assert.Nil(t, key.ExpiresAt)
+
+	// просроченный и неизвестный ключи не находятся
+	_, err = db.LookupAPIKey(ctx, "hash-old")
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+	_, err = db.LookupAPIKey(ctx, "hash-unknown")
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+
+	// имя уникально
+	assert.Error(t, db.CreateAPIKey(ctx, "web", "hash-web-2", []string{"orders:read"}, nil))
+
+	require.NoError(t, db.RevokeAPIKey(ctx, "web"))
+	_, err = db.LookupAPIKey(ctx, "hash-web")
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+	assert.ErrorIs(t, db.RevokeAPIKey(ctx, "web"), sql.ErrNoRows)
+}
+
+func enablePII(t *testing.T, db *PostgresDB) *fieldcrypt.Keyfile {
+	kf, err := fieldcrypt.NewKeyfile()
+	require.NoError(t, err)
+	db.PII, err = fieldcrypt.New(context.Background(), kf, db)
+	require.NoError(t, err)
+	return kf
+}
+
+func TestPostgresDB_EncryptedDelivery_Integration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration test in short mode")
+	}
+
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+	ctx := context.Background()
+	enablePII(t, db)
+
+	order := createTestOrder()
+	order.OrderUID = "encrypted-" + gofakeit.UUID()
+	order.Delivery.Phone = "+79991234567"
+	order.Delivery.Email = "Test@Mail.ru"
+	_, err := db.SaveOrder(ctx, order)
+	require.NoError(t, err)
+
+	// в открытых колонках ничего нет
+	var name, phone, email sql.NullString
+	var phoneEnc []byte
+	err = db.Conn.QueryRow(`SELECT name, phone, email, phone_enc FROM deliveries WHERE order_uid = $1`, order.OrderUID).
+		Scan(&name, &phone, &email, &phoneEnc)
+	require.NoError(t, err)
+	assert.False(t, name.Valid || phone.Valid || email.Valid)
+	assert.NotContains(t, string(phoneEnc), "79991234567")
+
+	got, err := db.GetOrder(ctx, order.OrderUID)
+	require.NoError(t, err)
+	assert.Equal(t, order.Delivery, got.Delivery)
+
+	uids, err := db.FindOrderUIDsByPhone(ctx, "+7 999 123-45-67")
+	require.NoError(t, err)
+	assert.Equal(t, []string{order.OrderUID}, uids)
+	uids, err = db.FindOrderUIDsByEmail(ctx, "test@mail.ru")
+	require.NoError(t, err)
+	assert.Equal(t, []string{order.OrderUID}, uids)
+
+	// без ключей зашифрованный заказ не читается
+	db.PII = nil
+	_, err = db.GetOrder(ctx, order.OrderUID)
+	assert.ErrorIs(t, err, ErrPIIKeysMissing)
+}
+
+func TestPostgresDB_ReencryptDeliveries_Integration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration test in short mode")
+	}
+
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	// заказы, сохранённые до включения шифрования
+	orders := make([]*models.Order, 5)
+	for i := range orders {
+		orders[i] = createTestOrder()
+		orders[i].OrderUID = fmt.Sprintf("plain-%d-", i) + gofakeit.UUID()
+		_, err := db.SaveOrder(ctx, orders[i])
+		require.NoError(t, err)
+	}
+
+	enablePII(t, db)
+	n, err := db.ReencryptDeliveries(ctx, 2)
+	require.NoError(t, err)
+	assert.Equal(t, len(orders), n)
+
+	// ротация: новый ключ данных, все строки перешифровываются им
+	id, err := db.PII.Rotate(ctx)
+	require.NoError(t, err)
+	n, err = db.ReencryptDeliveries(ctx, 2)
+	require.NoError(t, err)
+	assert.Equal(t, len(orders), n)
+
+	var stale int
+	require.NoError(t, db.Conn.QueryRow(`
+		SELECT count(*) FROM deliveries WHERE data_key_id IS DISTINCT FROM $1 OR phone IS NOT NULL`, id).Scan(&stale))
+	assert.Zero(t, stale)
+
+	for _, order := range orders {
+		got, err := db.GetOrder(ctx, order.OrderUID)
+		require.NoError(t, err)
+		assert.Equal(t, order.Delivery, got.Delivery)
+	}
+
+	n, err = db.ReencryptDeliveries(ctx, 2)
+	require.NoError(t, err)
+	assert.Zero(t, n)
+
+	// расшифровка перед откатом миграции: открытый текст на месте, шифротекста не осталось
+	n, err = db.DecryptDeliveries(ctx, 2)
+	require.NoError(t, err)
+	assert.Equal(t, len(orders), n)
+	var encrypted int
+	require.NoError(t, db.Conn.QueryRow(`
+		SELECT count(*) FROM deliveries WHERE data_key_id IS NOT NULL OR name_enc IS NOT NULL OR phone IS NULL`).Scan(&encrypted))
+	assert.Zero(t, encrypted)
+	db.PII = nil
+	got, err := db.GetOrder(ctx, orders[0].OrderUID)
+	require.NoError(t, err)
+	assert.Equal(t, orders[0].Delivery, got.Delivery)
+}
+
+func TestPostgresDB_AuditLog_Integration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration test in short mode")
+	}
+
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	at := time.Date(2024, 5, 1, 12, 0, 0, 123456000, time.UTC)
+	require.NoError(t, db.InsertAuditEvents(ctx, []audit.Event{
+		{Actor: "kafka", Action: audit.ActionWrite, OrderUID: "abc", At: at},
+		{Actor: "api_key:support", Action: audit.ActionRead, OrderUID: "abc", At: at.Add(time.Minute),
+			SourceIP: "2001:db8::1", TraceID: "4bf92f3577b34da6a3ce929d0e0e4736"},
+		{Actor: "anonymous", Action: audit.ActionRead, OrderUID: "other", At: at, SourceIP: "192.0.2.1"},
+	}))
+	require.NoError(t, db.InsertAuditEvents(ctx, nil))
+
+	events, err := db.ListAuditEvents(ctx, "abc", 10)
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+
+	// новые первыми, пустые IP и trace_id читаются пустыми строками
+	assert.Equal(t, "api_key:support", events[0].Actor)
+	assert.Equal(t, at.Add(time.Minute), events[0].At)
+	assert.Equal(t, "2001:db8::1", events[0].SourceIP)
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", events[0].TraceID)
+	assert.Equal(t, audit.ActionWrite, events[1].Action)
+	assert.Empty(t, events[1].SourceIP)
+	assert.Empty(t, events[1].TraceID)
+	assert.NotZero(t, events[1].ID)
+
+	events, err = db.ListAuditEvents(ctx, "abc", 1)
+	require.NoError(t, err)
+	assert.Len(t, events, 1)
+
+	events, err = db.ListAuditEvents(ctx, "missing", 10)
+	require.NoError(t, err)
+	assert.Empty(t, events)
+}
+
+func TestPostgresDB_Webhooks_Integration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration test in short mode")
+	}
+
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	id, err := db.CreateWebhook(ctx, &webhook.Subscription{URL: "https://partner.example/hook", Secret: "whsec_1",
+		Events: []string{webhook.EventOrderStored}, CustomerID: "alice"})
+	require.NoError(t, err)
+	other, err := db.CreateWebhook(ctx, &webhook.Subscription{URL: "https://other.example/hook", Secret: "whsec_2", IncludePII: true})
+	require.NoError(t, err)
+
+	subs, err := db.ActiveWebhooks(ctx)
+	require.NoError(t, err)
+	require.Len(t, subs, 2)
+	assert.Equal(t, "whsec_1", subs[0].Secret)
+	assert.Equal(t, []string{webhook.EventOrderStored}, subs[0].Events)
+	assert.Equal(t, "alice", subs[0].CustomerID)
+	assert.Empty(t, subs[1].Events)
+	assert.True(t, subs[1].IncludePII)
+
+	// вторая неудача подряд при max_failures 2 выключает подписку, но только один раз
+	disabled, err := db.WebhookFailed(ctx, id, "подписчик ответил 500", 2)
+	require.NoError(t, err)
+	assert.False(t, disabled)
+	disabled, err = db.WebhookFailed(ctx, id, "подписчик ответил 503", 2)
+	require.NoError(t, err)
+	assert.True(t, disabled)
+	disabled, err = db.WebhookFailed(ctx, id, "подписчик ответил 503", 2)
+	require.NoError(t, err)
+	assert.False(t, disabled)
+
+	subs, err = db.ActiveWebhooks(ctx)
+	require.NoError(t, err)
+	require.Len(t, subs, 1)
+	assert.Equal(t, other, subs[0].ID)
+
+	all, err := db.ListWebhooks(ctx)
+	require.NoError(t, err)
+	require.Len(t, all, 2)
+	assert.Equal(t, 3, all[0].Failures)
+	assert.Equal(t, "подписчик ответил 503", all[0].LastError)
+	assert.NotNil(t, all[0].DisabledAt)
+
+	// включение сбрасывает счётчик, доставка запоминает время
+	require.NoError(t, db.EnableWebhook(ctx, id))
+	require.NoError(t, db.WebhookDelivered(ctx, id))
+	all, err = db.ListWebhooks(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, all[0].Failures)
+	assert.Nil(t, all[0].DisabledAt)
+	assert.NotNil(t, all[0].LastDeliveredAt)
+
+	// max_failures 0 — не выключать
+	for i := 0; i < 5; i++ {
+		disabled, err = db.WebhookFailed(ctx, other, "timeout", 0)
+		require.NoError(t, err)
+		assert.False(t, disabled)
+	}
+
+	require.NoError(t, db.DeleteWebhook(ctx, other))
+	assert.ErrorIs(t, db.DeleteWebhook(ctx, other), sql.ErrNoRows)
+	// результат доставки удалённой подписке не ошибка
+	assert.NoError(t, db.WebhookDelivered(ctx, other))
+	disabled, err = db.WebhookFailed(ctx, other, "timeout", 1)
+	require.NoError(t, err)
+	assert.False(t, disabled)
+}
+
+func TestPostgresDB_GraphBatches_Integration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration test in short mode")
+	}
+
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	var uids []string
+	for i := 0; i < 4; i++ {
+		order := createTestOrder()
+		order.OrderUID = fmt.Sprintf("graph-%d-", i) + gofakeit.UUID()
+		order.CustomerID = []string{"alice", "bob"}[i%2]
+		order.DeliveryService = "meest"
+		order.DateCreated = time.Now().Add(-time.Duration(i) * time.Hour)
+		_, err := db.SaveOrder(ctx, order)
+		require.NoError(t, err)
+		uids = append(uids, order.OrderUID)
+	}
+
+	// фильтр по клиенту и времени, новые первыми
+	page, err := db.ListOrderHeaders(ctx, interfaces.OrderFilter{CustomerID: "alice"}, 10, 0)
+	require.NoError(t, err)
+	require.Len(t, page, 2)
+	assert.Equal(t, uids[0], page[0].OrderUID)
+	assert.Equal(t, uids[2], page[1].OrderUID)
+	assert.Empty(t, page[0].Items)
+
+	after := time.Now().Add(-90 * time.Minute)
+	page, err = db.ListOrderHeaders(ctx, interfaces.OrderFilter{DeliveryService: "meest", CreatedAfter: &after}, 1, 1)
+	require.NoError(t, err)
+	require.Len(t, page, 1)
+	assert.Equal(t, uids[1], page[0].OrderUID)
+
+	byCustomer, err := db.OrdersByCustomers(ctx, []string{"alice", "bob", "nobody"}, 1)
+	require.NoError(t, err)
+	require.Len(t, byCustomer, 2)
+	assert.Equal(t, uids[0], byCustomer["alice"][0].OrderUID)
+	assert.Equal(t, uids[1], byCustomer["bob"][0].OrderUID)
+
+	counts, err := db.OrderCountsByCustomer(ctx, []string{"alice", "nobody"})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int{"alice": 2}, counts)
+
+	// части заказов одним запросом на все uid, неизвестный uid пропускается
+	keys := append(uids, "missing")
+	deliveries, err := db.DeliveriesByOrder(ctx, keys)
+	require.NoError(t, err)
+	payments, err := db.PaymentsByOrder(ctx, keys)
+	require.NoError(t, err)
+	items, err := db.ItemsByOrder(ctx, keys)
+	require.NoError(t, err)
+	assert.Len(t, deliveries, 4)
+	assert.Len(t, payments, 4)
+	assert.Len(t, items, 4)
+
+	full, err := db.GetOrder(ctx, uids[3])
+	require.NoError(t, err)
+	assert.Equal(t, full.Delivery, deliveries[uids[3]])
+	assert.Equal(t, full.Payment, payments[uids[3]])
+	assert.ElementsMatch(t, full.Items, items[uids[3]])
+
+	// страница ListOrders собирается из тех же пакетных выборок
+	list, err := db.ListOrders(ctx, 2, 2)
+	require.NoError(t, err)
+	require.Len(t, list, 2)
+	assert.Equal(t, uids[2], list[0].OrderUID)
+	assert.Equal(t, full.OrderUID, list[1].OrderUID)
+	assert.Equal(t, full.Delivery, list[1].Delivery)
+	assert.Equal(t, full.Payment, list[1].Payment)
+	assert.ElementsMatch(t, full.Items, list[1].Items)
+
+	byUID, err := db.OrdersByUID(ctx, []string{uids[3], "missing"})
+	require.NoError(t, err)
+	require.Len(t, byUID, 1)
+	assert.Equal(t, full.Delivery, byUID[uids[3]].Delivery)
+	assert.ElementsMatch(t, full.Items, byUID[uids[3]].Items)
+}
@@ -0,0 +1,100 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.28.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var tracer = otel.Tracer("order-service/db")
+
+// querier общий интерфейс *sql.DB и *sql.Tx
+type querier interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// traced выполняет запросы, создавая на каждый дочерний спан с именем запроса и числом строк
+type traced struct {
+	q querier
+}
+
+func startQuerySpan(ctx context.Context, name, query string) (context.Context, trace.Span) {
+	return tracer.Start(ctx, "db."+name,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemPostgreSQL,
+			semconv.DBOperationName(name),
+			semconv.DBQueryText(query),
+		),
+	)
+}
+
+func endQuerySpan(span trace.Span, err error) {
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// exec INSERT/UPDATE/DELETE, в спан пишется число затронутых строк
+func (t traced) exec(ctx context.Context, name, query string, args ...interface{}) (sql.Result, error) {
+	ctx, span := startQuerySpan(ctx, name, query)
+
+	res, err := t.q.ExecContext(ctx, query, args...)
+	if err == nil {
+		if n, rerr := res.RowsAffected(); rerr == nil {
+			span.SetAttributes(attribute.Int64("db.rows_affected", n))
+		}
+	}
+	endQuerySpan(span, err)
+	return res, err
+}
+
+// queryRow выборка одной строки, sql.ErrNoRows не считается ошибкой спана
+func (t traced) queryRow(ctx context.Context, name, query string, args []interface{}, dest ...interface{}) error {
+	ctx, span := startQuerySpan(ctx, name, query)
+
+	err := t.q.QueryRowContext(ctx, query, args...).Scan(dest...)
+	returned := 1
+	if err != nil {
+		returned = 0
+	}
+	span.SetAttributes(attribute.Int("db.rows_returned", returned))
+	endQuerySpan(span, err)
+	return err
+}
+
+// query перебирает строки выборки, scan вызывается на каждую
+func (t traced) query(ctx context.Context, name, query string, args []interface{}, scan func(*sql.Rows) error) error {
+	ctx, span := startQuerySpan(ctx, name, query)
+
+	returned := 0
+	err := func() error {
+		rows, err := t.q.QueryContext(ctx, query, args...)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			if err := scan(rows); err != nil {
+				return err
+			}
+			returned++
+		}
+		return rows.Err()
+	}()
+
+	span.SetAttributes(attribute.Int("db.rows_returned", returned))
+	endQuerySpan(span, err)
+	return err
+}
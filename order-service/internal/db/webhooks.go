@@ -0,0 +1,133 @@
+// internal/db/webhooks.go
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"order-service/internal/metrics"
+	"order-service/internal/webhook"
+
+	"github.com/lib/pq"
+)
+
+var _ webhook.Store = (*PostgresDB)(nil)
+
+const webhookColumns = `id, url, secret, events, customer_id, delivery_service, include_pii,
+        failures, COALESCE(last_error, ''), last_delivered_at, created_at, disabled_at`
+
+// CreateWebhook сохраняет подписку и возвращает её id
+func (p *PostgresDB) CreateWebhook(ctx context.Context, s *webhook.Subscription) (int64, error) {
+	var id int64
+	err := traced{q: p.Conn}.queryRow(ctx, "insert_webhook", `
+        INSERT INTO webhooks(url, secret, events, customer_id, delivery_service, include_pii)
+        VALUES ($1, $2, COALESCE($3, '{}'::text[]), $4, $5, $6)
+        RETURNING id`,
+		[]interface{}{s.URL, s.Secret, pq.Array(s.Events), s.CustomerID, s.DeliveryService, s.IncludePII}, &id)
+	if err != nil {
+		return 0, fmt.Errorf("ошибка при создании подписки: %w", err)
+	}
+	return id, nil
+}
+
+// ListWebhooks все подписки, включая выключенные
+func (p *PostgresDB) ListWebhooks(ctx context.Context) ([]webhook.Subscription, error) {
+	return p.selectWebhooks(ctx, "select_webhooks", `SELECT `+webhookColumns+` FROM webhooks ORDER BY id`)
+}
+
+// ActiveWebhooks подписки, не выключенные после неудач
+func (p *PostgresDB) ActiveWebhooks(ctx context.Context) ([]webhook.Subscription, error) {
+	subs, err := p.selectWebhooks(ctx, "select_active_webhooks",
+		`SELECT `+webhookColumns+` FROM webhooks WHERE disabled_at IS NULL ORDER BY id`)
+	if err != nil {
+		metrics.DBOperations.WithLabelValues("select_webhooks", "error").Inc()
+		return nil, err
+	}
+	metrics.DBOperations.WithLabelValues("select_webhooks", "success").Inc()
+	return subs, nil
+}
+
+func (p *PostgresDB) selectWebhooks(ctx context.Context, name, query string) ([]webhook.Subscription, error) {
+	subs := make([]webhook.Subscription, 0)
+	err := traced{q: p.Conn}.query(ctx, name, query, nil, func(rows *sql.Rows) error {
+		var s webhook.Subscription
+		var lastDelivered, disabled sql.NullTime
+		if err := rows.Scan(&s.ID, &s.URL, &s.Secret, pq.Array(&s.Events), &s.CustomerID, &s.DeliveryService,
+			&s.IncludePII, &s.Failures, &s.LastError, &lastDelivered, &s.CreatedAt, &disabled); err != nil {
+			return err
+		}
+		if lastDelivered.Valid {
+			s.LastDeliveredAt = &lastDelivered.Time
+		}
+		if disabled.Valid {
+			s.DisabledAt = &disabled.Time
+		}
+		subs = append(subs, s)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при чтении подписок: %w", err)
+	}
+	return subs, nil
+}
+
+// DeleteWebhook удаляет подписку, sql.ErrNoRows если её нет
+func (p *PostgresDB) DeleteWebhook(ctx context.Context, id int64) error {
+	return p.execWebhook(ctx, "delete_webhook", `DELETE FROM webhooks WHERE id = $1`, id)
+}
+
+// EnableWebhook включает подписку обратно и сбрасывает счётчик неудач, sql.ErrNoRows если её нет
+func (p *PostgresDB) EnableWebhook(ctx context.Context, id int64) error {
+	return p.execWebhook(ctx, "enable_webhook", `
+        UPDATE webhooks SET disabled_at = NULL, failures = 0, last_error = NULL
+        WHERE id = $1`, id)
+}
+
+// WebhookDelivered сбрасывает счётчик неудач и запоминает время доставки
+func (p *PostgresDB) WebhookDelivered(ctx context.Context, id int64) error {
+	err := p.execWebhook(ctx, "webhook_delivered", `
+        UPDATE webhooks SET failures = 0, last_delivered_at = now()
+        WHERE id = $1`, id)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		metrics.DBOperations.WithLabelValues("update_webhook", "error").Inc()
+		return err
+	}
+	// подписку могли удалить, пока шла доставка
+	metrics.DBOperations.WithLabelValues("update_webhook", "success").Inc()
+	return nil
+}
+
+// WebhookFailed увеличивает счётчик неудач одним запросом, чтобы параллельные доставки
+// не теряли приращения, и выключает подписку на maxFailures-й неудаче подряд
+func (p *PostgresDB) WebhookFailed(ctx context.Context, id int64, lastError string, maxFailures int) (bool, error) {
+	var disabled bool
+	err := traced{q: p.Conn}.queryRow(ctx, "webhook_failed", `
+        UPDATE webhooks SET failures = failures + 1, last_error = $2,
+            disabled_at = CASE WHEN $3 > 0 AND failures + 1 >= $3 AND disabled_at IS NULL THEN now() ELSE disabled_at END
+        WHERE id = $1
+        RETURNING disabled_at IS NOT NULL AND failures = $3`,
+		[]interface{}{id, lastError, maxFailures}, &disabled)
+	if errors.Is(err, sql.ErrNoRows) {
+		metrics.DBOperations.WithLabelValues("update_webhook", "success").Inc()
+		return false, nil
+	}
+	if err != nil {
+		metrics.DBOperations.WithLabelValues("update_webhook", "error").Inc()
+		return false, fmt.Errorf("ошибка при записи неудачи вебхука: %w", err)
+	}
+	metrics.DBOperations.WithLabelValues("update_webhook", "success").Inc()
+	return disabled, nil
+}
+
+func (p *PostgresDB) execWebhook(ctx context.Context, name, query string, id int64) error {
+	res, err := traced{q: p.Conn}.exec(ctx, name, query, id)
+	if err != nil {
+		return fmt.Errorf("ошибка при изменении подписки %d: %w", id, err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
@@ -0,0 +1,252 @@
+// internal/fieldcrypt/encryptor.go
+package fieldcrypt
+
+import (
+	"context"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"order-service/internal/logging"
+)
+
+// WrappedKey ключ данных в том виде, в котором он хранится в БД
+type WrappedKey struct {
+	ID          int64
+	MasterKeyID string
+	Wrapped     []byte
+}
+
+// KeyStore хранилище обёрнутых ключей данных (таблица data_keys)
+type KeyStore interface {
+	DataKeys(ctx context.Context) ([]WrappedKey, error)
+	CreateDataKey(ctx context.Context, masterKeyID string, wrapped []byte) (int64, error)
+	RewrapDataKey(ctx context.Context, id int64, masterKeyID string, wrapped []byte) error
+}
+
+// Encryptor конвертное шифрование полей: поле шифруется AES-GCM ключом данных,
+// ключ данных хранится в БД обёрнутым мастер-ключом из файла
+type Encryptor struct {
+	keyfile *Keyfile
+	store   KeyStore
+
+	mu     sync.RWMutex
+	keys   map[int64]*DataKey
+	active int64
+}
+
+// DataKey развёрнутый ключ данных
+type DataKey struct {
+	ID   int64
+	aead cipher.AEAD
+}
+
+// New загружает ключи данных из хранилища; если их ещё нет, создаёт первый.
+// Активным считается последний созданный ключ
+func New(ctx context.Context, kf *Keyfile, store KeyStore) (*Encryptor, error) {
+	e := &Encryptor{keyfile: kf, store: store, keys: make(map[int64]*DataKey)}
+	if err := e.reload(ctx); err != nil {
+		return nil, err
+	}
+	if e.active == 0 {
+		if _, err := e.Rotate(ctx); err != nil {
+			return nil, err
+		}
+	}
+	return e, nil
+}
+
+// reload разворачивает ключи данных, которых ещё нет в памяти
+func (e *Encryptor) reload(ctx context.Context) error {
+	wrapped, err := e.store.DataKeys(ctx)
+	if err != nil {
+		return fmt.Errorf("ошибка загрузки ключей данных: %w", err)
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for _, w := range wrapped {
+		if _, ok := e.keys[w.ID]; !ok {
+			key, err := e.unwrap(w)
+			if err != nil {
+				return fmt.Errorf("ключ данных %d: %w", w.ID, err)
+			}
+			e.keys[w.ID] = key
+		}
+		if w.ID > e.active {
+			e.active = w.ID
+		}
+	}
+	return nil
+}
+
+func (e *Encryptor) unwrap(w WrappedKey) (*DataKey, error) {
+	raw, err := e.keyfile.unwrap(w.MasterKeyID, w.Wrapped)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := newAEAD(raw)
+	if err != nil {
+		return nil, err
+	}
+	return &DataKey{ID: w.ID, aead: aead}, nil
+}
+
+// Active ключ данных для новых записей
+func (e *Encryptor) Active() *DataKey {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.keys[e.active]
+}
+
+// Refresh перечитывает ключи данных каждые interval до отмены ctx: ключ, созданный pii rotate
+// на другой реплике или командой, становится активным без перезапуска
+func (e *Encryptor) Refresh(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			prev := e.Active().ID
+			if err := e.reload(ctx); err != nil {
+				slog.WarnContext(ctx, "Не удалось перечитать ключи данных", logging.Err(err))
+				continue
+			}
+			if id := e.Active().ID; id != prev {
+				slog.InfoContext(ctx, "Активный ключ данных сменился", slog.Int64("data_key_id", id))
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// Key ключ данных по id. Неизвестный ключ мог создать pii rotate на другой реплике — тогда ключи перечитываются
+func (e *Encryptor) Key(ctx context.Context, id int64) (*DataKey, error) {
+	e.mu.RLock()
+	key, ok := e.keys[id]
+	e.mu.RUnlock()
+	if ok {
+		return key, nil
+	}
+
+	if err := e.reload(ctx); err != nil {
+		return nil, err
+	}
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	if key, ok := e.keys[id]; ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("ключ данных %d не найден", id)
+}
+
+// Rotate создаёт новый ключ данных и делает его активным. Старые ключи остаются для чтения
+func (e *Encryptor) Rotate(ctx context.Context) (int64, error) {
+	raw, err := randomKey()
+	if err != nil {
+		return 0, err
+	}
+	masterKeyID, wrapped, err := e.keyfile.wrap(raw)
+	if err != nil {
+		return 0, err
+	}
+	id, err := e.store.CreateDataKey(ctx, masterKeyID, wrapped)
+	if err != nil {
+		return 0, fmt.Errorf("ошибка сохранения ключа данных: %w", err)
+	}
+	aead, err := newAEAD(raw)
+	if err != nil {
+		return 0, err
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.keys[id] = &DataKey{ID: id, aead: aead}
+	e.active = id
+	return id, nil
+}
+
+// Rewrap переоборачивает основным мастер-ключом ключи данных, обёрнутые другими.
+// Сами ключи данных не меняются, поэтому записи не перешифровываются. После этого старые мастер-ключи можно удалить из файла
+func (e *Encryptor) Rewrap(ctx context.Context) (int, error) {
+	wrapped, err := e.store.DataKeys(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("ошибка загрузки ключей данных: %w", err)
+	}
+
+	n := 0
+	for _, w := range wrapped {
+		if w.MasterKeyID == e.keyfile.Primary {
+			continue
+		}
+		raw, err := e.keyfile.unwrap(w.MasterKeyID, w.Wrapped)
+		if err != nil {
+			return n, fmt.Errorf("ключ данных %d: %w", w.ID, err)
+		}
+		masterKeyID, rewrapped, err := e.keyfile.wrap(raw)
+		if err != nil {
+			return n, err
+		}
+		if err := e.store.RewrapDataKey(ctx, w.ID, masterKeyID, rewrapped); err != nil {
+			return n, fmt.Errorf("ошибка сохранения ключа данных %d: %w", w.ID, err)
+		}
+		n++
+	}
+	return n, nil
+}
+
+// Seal шифрует значение поля. recordID и field входят в AAD: шифротекст нельзя переставить в другую запись или колонку
+func (k *DataKey) Seal(recordID, field, plaintext string) ([]byte, error) {
+	return seal(k.aead, []byte(plaintext), fieldAAD(recordID, field))
+}
+
+// Open расшифровывает значение поля, зашифрованное Seal
+func (k *DataKey) Open(recordID, field string, sealed []byte) (string, error) {
+	plaintext, err := open(k.aead, sealed, fieldAAD(recordID, field))
+	if err != nil {
+		return "", fmt.Errorf("не удалось расшифровать %s: %w", field, err)
+	}
+	return string(plaintext), nil
+}
+
+func fieldAAD(recordID, field string) []byte {
+	return []byte(recordID + "\x00" + field)
+}
+
+// ErrEmptyIndex значение без значимых символов не индексируется
+var ErrEmptyIndex = errors.New("пустое значение для слепого индекса")
+
+// PhoneIndex слепой индекс телефона: HMAC от цифр, +7 (999) 123-45-67 и +79991234567 совпадают
+func (e *Encryptor) PhoneIndex(phone string) (string, error) {
+	digits := strings.Map(func(r rune) rune {
+		if unicode.IsDigit(r) {
+			return r
+		}
+		return -1
+	}, phone)
+	return e.blindIndex("phone", digits)
+}
+
+// EmailIndex слепой индекс email без учёта регистра и пробелов по краям
+func (e *Encryptor) EmailIndex(email string) (string, error) {
+	return e.blindIndex("email", strings.ToLower(strings.TrimSpace(email)))
+}
+
+// blindIndex HMAC-SHA256 ключом индексов; поле входит в MAC, чтобы индексы разных колонок не совпадали
+func (e *Encryptor) blindIndex(field, normalized string) (string, error) {
+	if normalized == "" {
+		return "", ErrEmptyIndex
+	}
+	mac := hmac.New(sha256.New, e.keyfile.indexKey)
+	mac.Write([]byte(field + "\x00" + normalized))
+	return hex.EncodeToString(mac.Sum(nil)), nil
+}
@@ -0,0 +1,198 @@
+// internal/fieldcrypt/encryptor_test.go
+package fieldcrypt
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memKeyStore таблица data_keys в памяти
+type memKeyStore struct {
+	mu   sync.Mutex
+	keys []WrappedKey
+}
+
+func (m *memKeyStore) DataKeys(ctx context.Context) ([]WrappedKey, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]WrappedKey(nil), m.keys...), nil
+}
+
+func (m *memKeyStore) CreateDataKey(ctx context.Context, masterKeyID string, wrapped []byte) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	id := int64(len(m.keys) + 1)
+	m.keys = append(m.keys, WrappedKey{ID: id, MasterKeyID: masterKeyID, Wrapped: wrapped})
+	return id, nil
+}
+
+func (m *memKeyStore) RewrapDataKey(ctx context.Context, id int64, masterKeyID string, wrapped []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.keys[id-1] = WrappedKey{ID: id, MasterKeyID: masterKeyID, Wrapped: wrapped}
+	return nil
+}
+
+func newTestEncryptor(t *testing.T) (*Encryptor, *Keyfile, *memKeyStore) {
+	kf, err := NewKeyfile()
+	require.NoError(t, err)
+	store := &memKeyStore{}
+	e, err := New(context.Background(), kf, store)
+	require.NoError(t, err)
+	return e, kf, store
+}
+
+func TestEncryptor_CreatesFirstKey(t *testing.T) {
+	e, kf, store := newTestEncryptor(t)
+
+	require.Len(t, store.keys, 1)
+	assert.Equal(t, kf.Primary, store.keys[0].MasterKeyID)
+	assert.Equal(t, int64(1), e.Active().ID)
+
+	// повторный старт берёт существующий ключ
+	again, err := New(context.Background(), kf, store)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), again.Active().ID)
+	assert.Len(t, store.keys, 1)
+}
+
+func TestDataKey_SealOpen(t *testing.T) {
+	e, _, _ := newTestEncryptor(t)
+	key := e.Active()
+
+	sealed, err := key.Seal("order-1", "phone", "+79991234567")
+	require.NoError(t, err)
+	assert.NotContains(t, string(sealed), "79991234567")
+
+	got, err := key.Open("order-1", "phone", sealed)
+	require.NoError(t, err)
+	assert.Equal(t, "+79991234567", got)
+
+	// одинаковые значения шифруются по-разному
+	again, err := key.Seal("order-1", "phone", "+79991234567")
+	require.NoError(t, err)
+	assert.NotEqual(t, sealed, again)
+
+	// шифротекст нельзя перенести в другую запись, колонку или испортить
+	_, err = key.Open("order-2", "phone", sealed)
+	assert.Error(t, err)
+	_, err = key.Open("order-1", "email", sealed)
+	assert.Error(t, err)
+	sealed[len(sealed)-1] ^= 1
+	_, err = key.Open("order-1", "phone", sealed)
+	assert.Error(t, err)
+	_, err = key.Open("order-1", "phone", []byte("short"))
+	assert.Error(t, err)
+}
+
+func TestEncryptor_RotateKeepsOldKeys(t *testing.T) {
+	e, kf, store := newTestEncryptor(t)
+	ctx := context.Background()
+
+	old := e.Active()
+	sealed, err := old.Seal("order-1", "name", "Test Testov")
+	require.NoError(t, err)
+
+	id, err := e.Rotate(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), id)
+	assert.Equal(t, id, e.Active().ID)
+
+	key, err := e.Key(ctx, old.ID)
+	require.NoError(t, err)
+	got, err := key.Open("order-1", "name", sealed)
+	require.NoError(t, err)
+	assert.Equal(t, "Test Testov", got)
+
+	// реплика, запущенная до ротации, подхватывает новый ключ при первом чтении
+	replica, err := New(ctx, kf, &memKeyStore{keys: store.keys[:1]})
+	require.NoError(t, err)
+	replica.store = store
+	_, err = replica.Key(ctx, id)
+	assert.NoError(t, err)
+
+	_, err = e.Key(ctx, 42)
+	assert.Error(t, err)
+}
+
+func TestEncryptor_RefreshPicksUpRotation(t *testing.T) {
+	e, kf, store := newTestEncryptor(t)
+
+	// реплика, запущенная до ротации, ничего не читает, но должна начать писать новым ключом
+	replica, err := New(context.Background(), kf, &memKeyStore{keys: store.keys[:1]})
+	require.NoError(t, err)
+	replica.store = store
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go replica.Refresh(ctx, 10*time.Millisecond)
+
+	id, err := e.Rotate(context.Background())
+	require.NoError(t, err)
+	assert.Eventually(t, func() bool { return replica.Active().ID == id }, time.Second, 10*time.Millisecond)
+}
+
+func TestEncryptor_RewrapWithNewMasterKey(t *testing.T) {
+	e, kf, store := newTestEncryptor(t)
+	ctx := context.Background()
+	sealed, err := e.Active().Seal("order-1", "email", "test@gmail.com")
+	require.NoError(t, err)
+
+	// в файл добавлен новый мастер-ключ, старый пока на месте
+	oldMaster := kf.Primary
+	kf.masterKeys["zz-new"] = append([]byte(nil), kf.masterKeys[oldMaster]...)
+	kf.masterKeys["zz-new"][0] ^= 1
+	kf.Primary = "zz-new"
+
+	n, err := e.Rewrap(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	assert.Equal(t, "zz-new", store.keys[0].MasterKeyID)
+
+	// старый мастер-ключ больше не нужен, ключ данных тот же
+	delete(kf.masterKeys, oldMaster)
+	restarted, err := New(ctx, kf, store)
+	require.NoError(t, err)
+	got, err := restarted.Active().Open("order-1", "email", sealed)
+	require.NoError(t, err)
+	assert.Equal(t, "test@gmail.com", got)
+
+	n, err = e.Rewrap(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, n)
+}
+
+func TestEncryptor_BlindIndex(t *testing.T) {
+	e, _, _ := newTestEncryptor(t)
+
+	a, err := e.PhoneIndex("+7 (999) 123-45-67")
+	require.NoError(t, err)
+	b, err := e.PhoneIndex("+79991234567")
+	require.NoError(t, err)
+	assert.Equal(t, a, b)
+	assert.Len(t, a, 64)
+
+	c, err := e.EmailIndex(" Test@Gmail.com ")
+	require.NoError(t, err)
+	d, err := e.EmailIndex("test@gmail.com")
+	require.NoError(t, err)
+	assert.Equal(t, c, d)
+
+	// одно значение в разных колонках даёт разные индексы
+	phoneAsEmail, err := e.EmailIndex("79991234567")
+	require.NoError(t, err)
+	assert.NotEqual(t, a, phoneAsEmail)
+
+	_, err = e.PhoneIndex("+")
+	assert.ErrorIs(t, err, ErrEmptyIndex)
+
+	// другой ключ индексов — другие значения
+	other, _, _ := newTestEncryptor(t)
+	otherIdx, err := other.PhoneIndex("+79991234567")
+	require.NoError(t, err)
+	assert.NotEqual(t, a, otherIdx)
+}
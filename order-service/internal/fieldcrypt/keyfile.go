@@ -0,0 +1,205 @@
+// internal/fieldcrypt/keyfile.go
+package fieldcrypt
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+)
+
+// keySize AES-256
+const keySize = 32
+
+// Keyfile мастер-ключи и ключ слепых индексов из локального файла.
+// Мастер-ключи только оборачивают ключи данных, сами поля ими не шифруются
+type Keyfile struct {
+	// Primary мастер-ключ для новых и переобёрнутых ключей данных
+	Primary    string
+	masterKeys map[string][]byte
+	indexKey   []byte
+}
+
+type keyfileJSON struct {
+	Primary    string            `json:"primary"`
+	MasterKeys map[string]string `json:"master_keys"`
+	IndexKey   string            `json:"index_key"`
+}
+
+// LoadKeyfile читает файл ключей. Формат:
+//
+//	{"primary": "20261018T120000", "master_keys": {"20261018T120000": "<base64>"}, "index_key": "<base64>"}
+func LoadKeyfile(path string) (*Keyfile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось прочитать файл ключей: %w", err)
+	}
+	return ParseKeyfile(data)
+}
+
+// ParseKeyfile разбирает файл ключей из памяти, все ключи по 32 байта
+func ParseKeyfile(data []byte) (*Keyfile, error) {
+	var raw keyfileJSON
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("ошибка разбора файла ключей: %w", err)
+	}
+
+	kf := &Keyfile{Primary: raw.Primary, masterKeys: make(map[string][]byte, len(raw.MasterKeys))}
+	for id, val := range raw.MasterKeys {
+		key, err := decodeKey(val)
+		if err != nil {
+			return nil, fmt.Errorf("мастер-ключ %s: %w", id, err)
+		}
+		kf.masterKeys[id] = key
+	}
+	if _, ok := kf.masterKeys[kf.Primary]; !ok {
+		return nil, fmt.Errorf("основной мастер-ключ %q не найден в master_keys", kf.Primary)
+	}
+
+	key, err := decodeKey(raw.IndexKey)
+	if err != nil {
+		return nil, fmt.Errorf("index_key: %w", err)
+	}
+	kf.indexKey = key
+	return kf, nil
+}
+
+// NewKeyfile файл с одним мастер-ключом и новым ключом индексов
+func NewKeyfile() (*Keyfile, error) {
+	indexKey, err := randomKey()
+	if err != nil {
+		return nil, err
+	}
+	kf := &Keyfile{masterKeys: make(map[string][]byte), indexKey: indexKey}
+	if _, err := kf.AddMasterKey(); err != nil {
+		return nil, err
+	}
+	return kf, nil
+}
+
+// AddMasterKey добавляет новый мастер-ключ и делает его основным. Старые остаются,
+// пока ключи данных не переобёрнуты командой pii rotate
+func (k *Keyfile) AddMasterKey() (string, error) {
+	key, err := randomKey()
+	if err != nil {
+		return "", err
+	}
+	id := time.Now().UTC().Format("20060102T150405")
+	if _, ok := k.masterKeys[id]; ok {
+		return "", fmt.Errorf("мастер-ключ %s уже есть", id)
+	}
+	k.masterKeys[id] = key
+	k.Primary = id
+	return id, nil
+}
+
+// Save записывает файл ключей с правами 0600
+func (k *Keyfile) Save(path string) error {
+	raw := keyfileJSON{
+		Primary:    k.Primary,
+		MasterKeys: make(map[string]string, len(k.masterKeys)),
+		IndexKey:   base64.StdEncoding.EncodeToString(k.indexKey),
+	}
+	for id, key := range k.masterKeys {
+		raw.MasterKeys[id] = base64.StdEncoding.EncodeToString(key)
+	}
+	data, err := json.MarshalIndent(raw, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o600)
+}
+
+// MasterKeyIDs идентификаторы мастер-ключей по возрастанию
+func (k *Keyfile) MasterKeyIDs() []string {
+	ids := make([]string, 0, len(k.masterKeys))
+	for id := range k.masterKeys {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// wrap оборачивает ключ данных основным мастер-ключом
+func (k *Keyfile) wrap(dataKey []byte) (string, []byte, error) {
+	aead, err := newAEAD(k.masterKeys[k.Primary])
+	if err != nil {
+		return "", nil, err
+	}
+	wrapped, err := seal(aead, dataKey, wrapAAD(k.Primary))
+	if err != nil {
+		return "", nil, err
+	}
+	return k.Primary, wrapped, nil
+}
+
+// unwrap снимает обёртку мастер-ключом, которым ключ данных был обёрнут
+func (k *Keyfile) unwrap(masterKeyID string, wrapped []byte) ([]byte, error) {
+	master, ok := k.masterKeys[masterKeyID]
+	if !ok {
+		return nil, fmt.Errorf("мастер-ключа %q нет в файле ключей", masterKeyID)
+	}
+	aead, err := newAEAD(master)
+	if err != nil {
+		return nil, err
+	}
+	dataKey, err := open(aead, wrapped, wrapAAD(masterKeyID))
+	if err != nil {
+		return nil, fmt.Errorf("не удалось развернуть ключ данных мастер-ключом %s: %w", masterKeyID, err)
+	}
+	return dataKey, nil
+}
+
+func wrapAAD(masterKeyID string) []byte {
+	return []byte("order-service/data-key/" + masterKeyID)
+}
+
+func decodeKey(val string) ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(val)
+	if err != nil {
+		return nil, fmt.Errorf("некорректный base64: %w", err)
+	}
+	if len(key) != keySize {
+		return nil, fmt.Errorf("ожидается ключ %d байт, получено %d", keySize, len(key))
+	}
+	return key, nil
+}
+
+func randomKey() ([]byte, error) {
+	key := make([]byte, keySize)
+	if _, err := rand.Read(key); err != nil {
+		return nil, fmt.Errorf("не удалось сгенерировать ключ: %w", err)
+	}
+	return key, nil
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// seal nonce || шифротекст с тегом
+func seal(aead cipher.AEAD, plaintext, aad []byte) ([]byte, error) {
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plaintext)+aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(nonce, nonce, plaintext, aad), nil
+}
+
+func open(aead cipher.AEAD, sealed, aad []byte) ([]byte, error) {
+	if len(sealed) < aead.NonceSize()+aead.Overhead() {
+		return nil, errors.New("шифротекст короче nonce и тега")
+	}
+	n := aead.NonceSize()
+	return aead.Open(nil, sealed[:n], sealed[n:], aad)
+}
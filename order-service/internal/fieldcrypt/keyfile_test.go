@@ -0,0 +1,79 @@
+// internal/fieldcrypt/keyfile_test.go
+package fieldcrypt
+
+import (
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKeyfile_SaveLoad(t *testing.T) {
+	kf, err := NewKeyfile()
+	require.NoError(t, err)
+
+	path := filepath.Join(t.TempDir(), "pii.keys")
+	require.NoError(t, kf.Save(path))
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+
+	loaded, err := LoadKeyfile(path)
+	require.NoError(t, err)
+	assert.Equal(t, kf.Primary, loaded.Primary)
+	assert.Equal(t, kf.masterKeys, loaded.masterKeys)
+	assert.Equal(t, kf.indexKey, loaded.indexKey)
+
+	_, err = LoadKeyfile(filepath.Join(t.TempDir(), "missing"))
+	assert.Error(t, err)
+}
+
+func TestKeyfile_WrapUnwrap(t *testing.T) {
+	kf, err := NewKeyfile()
+	require.NoError(t, err)
+	dataKey, err := randomKey()
+	require.NoError(t, err)
+
+	masterKeyID, wrapped, err := kf.wrap(dataKey)
+	require.NoError(t, err)
+	assert.Equal(t, kf.Primary, masterKeyID)
+	assert.NotContains(t, string(wrapped), string(dataKey))
+
+	got, err := kf.unwrap(masterKeyID, wrapped)
+	require.NoError(t, err)
+	assert.Equal(t, dataKey, got)
+
+	// обёртка привязана к id мастер-ключа
+	kf.masterKeys["other"] = kf.masterKeys[masterKeyID]
+	_, err = kf.unwrap("other", wrapped)
+	assert.Error(t, err)
+
+	_, err = kf.unwrap("missing", wrapped)
+	assert.Error(t, err)
+}
+
+func TestParseKeyfile_Errors(t *testing.T) {
+	key := base64.StdEncoding.EncodeToString([]byte(strings.Repeat("k", keySize)))
+	short := base64.StdEncoding.EncodeToString([]byte("short"))
+
+	tests := map[string]string{
+		"not json":        `{`,
+		"no primary":      `{"master_keys":{"a":"` + key + `"},"index_key":"` + key + `"}`,
+		"unknown primary": `{"primary":"b","master_keys":{"a":"` + key + `"},"index_key":"` + key + `"}`,
+		"short master":    `{"primary":"a","master_keys":{"a":"` + short + `"},"index_key":"` + key + `"}`,
+		"bad base64":      `{"primary":"a","master_keys":{"a":"***"},"index_key":"` + key + `"}`,
+		"no index key":    `{"primary":"a","master_keys":{"a":"` + key + `"}}`,
+	}
+	for name, data := range tests {
+		_, err := ParseKeyfile([]byte(data))
+		assert.Error(t, err, name)
+	}
+
+	kf, err := ParseKeyfile([]byte(`{"primary":"a","master_keys":{"a":"` + key + `"},"index_key":"` + key + `"}`))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a"}, kf.MasterKeyIDs())
+}
@@ -0,0 +1,643 @@
+// internal/graphapi/complexity.go
+package graphapi
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+// Оценка стоимости запроса до выполнения. graphql-go не отдаёт разобранный запрос наружу,
+// поэтому текст разбирается здесь ровно настолько, насколько нужно для оценки:
+// поля, псевдонимы, аргумент first, фрагменты и значения переменных
+
+const (
+	// maxNesting предел вложенности скобок при разборе, с запасом выше graphql.max_depth:
+	// глубже запрос всё равно отклонится, а разбор не должен уходить в рекурсию без конца
+	maxNesting = 64
+	// itemsEstimate ожидаемое число товаров заказа: список items не ограничен аргументом
+	itemsEstimate = 10
+	// orders у Query и у Customer отдают по first элементов, по умолчанию — как в schema.graphql
+	defaultOrdersFirst         = 20
+	defaultCustomerOrdersFirst = 10
+)
+
+// queryCost стоимость и число полей запроса
+type queryCost struct {
+	complexity float64
+	fields     float64
+}
+
+// estimateCost оценивает операцию operationName (или все операции документа, если имя не задано)
+// с учётом переменных. Ошибка — запрос не удалось разобрать
+func estimateCost(query, operationName string, variables map[string]interface{}) (queryCost, error) {
+	tokens, err := lex(query)
+	if err != nil {
+		return queryCost{}, err
+	}
+	p := &parser{tokens: tokens}
+	doc, err := p.document()
+	if err != nil {
+		return queryCost{}, err
+	}
+
+	var total queryCost
+	for _, op := range doc.operations {
+		if operationName != "" && op.name != operationName {
+			continue
+		}
+		e := &estimator{doc: doc, op: op, variables: variables, memo: make(map[fragmentKey]queryCost), visiting: make(map[string]bool)}
+		c, err := e.selections(op.selections, true)
+		if err != nil {
+			return queryCost{}, err
+		}
+		total.complexity = max(total.complexity, c.complexity)
+		total.fields = max(total.fields, c.fields)
+	}
+	return total, nil
+}
+
+type document struct {
+	operations []*operation
+	fragments  map[string][]selection
+}
+
+type operation struct {
+	name string
+	// defaults значения переменных по умолчанию из объявления операции
+	defaults   map[string]value
+	selections []selection
+}
+
+// selection поле, раскрытие фрагмента spread или встроенный фрагмент inline
+type selection struct {
+	name     string
+	first    *value
+	children []selection
+	spread   string
+	inline   []selection
+}
+
+// value нужное для оценки от значения аргумента: целое число или имя переменной
+type value struct {
+	isInt    bool
+	n        int64
+	variable string
+}
+
+type fragmentKey struct {
+	name string
+	root bool
+}
+
+type estimator struct {
+	doc       *document
+	op        *operation
+	variables map[string]interface{}
+	memo      map[fragmentKey]queryCost
+	visiting  map[string]bool
+}
+
+// selections стоимость набора полей; root — поля Query, у них свои значения first по умолчанию.
+// Стоимость линейна по множителю списка, поэтому фрагмент считается один раз на уровень
+func (e *estimator) selections(sels []selection, root bool) (queryCost, error) {
+	var total queryCost
+	for _, sel := range sels {
+		var c queryCost
+		var err error
+		switch {
+		case sel.spread != "":
+			c, err = e.fragment(sel.spread, root)
+		case sel.inline != nil:
+			c, err = e.selections(sel.inline, root)
+		default:
+			c, err = e.field(sel, root)
+		}
+		if err != nil {
+			return queryCost{}, err
+		}
+		total.complexity += c.complexity
+		total.fields += c.fields
+	}
+	return total, nil
+}
+
+func (e *estimator) field(sel selection, root bool) (queryCost, error) {
+	children, err := e.selections(sel.children, false)
+	if err != nil {
+		return queryCost{}, err
+	}
+	return queryCost{
+		complexity: 1 + e.listSize(sel, root)*children.complexity,
+		fields:     1 + children.fields,
+	}, nil
+}
+
+func (e *estimator) fragment(name string, root bool) (queryCost, error) {
+	key := fragmentKey{name: name, root: root}
+	if c, ok := e.memo[key]; ok {
+		return c, nil
+	}
+	sels, ok := e.doc.fragments[name]
+	if !ok {
+		return queryCost{}, fmt.Errorf("фрагмент %s не объявлен", name)
+	}
+	if e.visiting[name] {
+		return queryCost{}, fmt.Errorf("фрагмент %s раскрывает сам себя", name)
+	}
+	e.visiting[name] = true
+	c, err := e.selections(sels, root)
+	delete(e.visiting, name)
+	if err != nil {
+		return queryCost{}, err
+	}
+	e.memo[key] = c
+	return c, nil
+}
+
+// listSize сколько элементов может вернуть поле: first из аргумента или переменной,
+// иначе значение по умолчанию из схемы
+func (e *estimator) listSize(sel selection, root bool) float64 {
+	switch sel.name {
+	case "orders":
+		def := int64(defaultCustomerOrdersFirst)
+		if root {
+			def = defaultOrdersFirst
+		}
+		return float64(max(e.intArg(sel.first, def), 1))
+	case "items":
+		return itemsEstimate
+	}
+	return 1
+}
+
+func (e *estimator) intArg(v *value, def int64) int64 {
+	if v == nil {
+		return def
+	}
+	if v.isInt {
+		return v.n
+	}
+	if v.variable == "" {
+		return def
+	}
+	if raw, ok := e.variables[v.variable]; ok {
+		// переменные приходят из JSON: числа декодированы в float64
+		if f, ok := raw.(float64); ok {
+			return int64(f)
+		}
+		return def
+	}
+	if d, ok := e.op.defaults[v.variable]; ok && d.isInt {
+		return d.n
+	}
+	return def
+}
+
+// Разбор
+
+type tokenKind int
+
+const (
+	tokPunct tokenKind = iota
+	tokName
+	tokInt
+	tokFloat
+	tokString
+)
+
+type token struct {
+	kind tokenKind
+	text string
+}
+
+var errUnexpectedEnd = errors.New("неожиданный конец запроса")
+
+// lex разбивает текст на лексемы GraphQL, пропуская пробелы, запятые и комментарии
+func lex(src string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(src); {
+		c := src[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == ',':
+			i++
+		case c == '#':
+			for i < len(src) && src[i] != '\n' && src[i] != '\r' {
+				i++
+			}
+		case c == 0xEF && len(src) >= i+3 && src[i:i+3] == "\xEF\xBB\xBF":
+			i += 3
+		case c == '.':
+			if len(src) < i+3 || src[i:i+3] != "..." {
+				return nil, fmt.Errorf("неожиданный символ %q", c)
+			}
+			tokens = append(tokens, token{tokPunct, "..."})
+			i += 3
+		case isPunct(c):
+			tokens = append(tokens, token{tokPunct, string(c)})
+			i++
+		case isNameStart(c):
+			j := i + 1
+			for j < len(src) && (isNameStart(src[j]) || isDigit(src[j])) {
+				j++
+			}
+			tokens = append(tokens, token{tokName, src[i:j]})
+			i = j
+		case c == '-' || isDigit(c):
+			j := i + 1
+			kind := tokInt
+			for j < len(src) && (isDigit(src[j]) || src[j] == '.' || src[j] == 'e' || src[j] == 'E' ||
+				((src[j] == '+' || src[j] == '-') && (src[j-1] == 'e' || src[j-1] == 'E'))) {
+				if !isDigit(src[j]) {
+					kind = tokFloat
+				}
+				j++
+			}
+			tokens = append(tokens, token{kind, src[i:j]})
+			i = j
+		case c == '"':
+			j, err := skipString(src, i)
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, token{tokString, src[i:j]})
+			i = j
+		default:
+			return nil, fmt.Errorf("неожиданный символ %q", c)
+		}
+	}
+	return tokens, nil
+}
+
+// skipString конец строки, начатой в src[i]: обычной или блочной """
+func skipString(src string, i int) (int, error) {
+	if len(src) >= i+3 && src[i:i+3] == `"""` {
+		for j := i + 3; j+3 <= len(src); j++ {
+			if src[j] == '\\' && len(src) >= j+4 && src[j+1:j+4] == `"""` {
+				j += 3
+				continue
+			}
+			if src[j:j+3] == `"""` {
+				return j + 3, nil
+			}
+		}
+		return 0, errUnexpectedEnd
+	}
+	for j := i + 1; j < len(src); j++ {
+		switch src[j] {
+		case '\\':
+			j++
+		case '"':
+			return j + 1, nil
+		case '\n', '\r':
+			return 0, errors.New("перевод строки внутри строки")
+		}
+	}
+	return 0, errUnexpectedEnd
+}
+
+func isPunct(c byte) bool {
+	switch c {
+	case '!', '$', '&', '(', ')', ':', '=', '@', '[', ']', '{', '|', '}':
+		return true
+	}
+	return false
+}
+
+func isNameStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+	depth  int
+}
+
+func (p *parser) peek() (token, bool) {
+	if p.pos >= len(p.tokens) {
+		return token{}, false
+	}
+	return p.tokens[p.pos], true
+}
+
+func (p *parser) peekIs(kind tokenKind, text string) bool {
+	t, ok := p.peek()
+	return ok && t.kind == kind && t.text == text
+}
+
+func (p *parser) next() (token, error) {
+	t, ok := p.peek()
+	if !ok {
+		return token{}, errUnexpectedEnd
+	}
+	p.pos++
+	return t, nil
+}
+
+func (p *parser) expect(kind tokenKind, text string) error {
+	t, err := p.next()
+	if err != nil {
+		return err
+	}
+	if t.kind != kind || (text != "" && t.text != text) {
+		return fmt.Errorf("ожидалось %q, получено %q", text, t.text)
+	}
+	return nil
+}
+
+func (p *parser) name() (string, error) {
+	t, err := p.next()
+	if err != nil {
+		return "", err
+	}
+	if t.kind != tokName {
+		return "", fmt.Errorf("ожидалось имя, получено %q", t.text)
+	}
+	return t.text, nil
+}
+
+// nest учитывает вход в скобки; unnest вызывается на выходе
+func (p *parser) nest() error {
+	p.depth++
+	if p.depth > maxNesting {
+		return errors.New("слишком глубокая вложенность запроса")
+	}
+	return nil
+}
+
+func (p *parser) unnest() { p.depth-- }
+
+func (p *parser) document() (*document, error) {
+	doc := &document{fragments: make(map[string][]selection)}
+	for p.pos < len(p.tokens) {
+		switch {
+		case p.peekIs(tokPunct, "{"):
+			sels, err := p.selectionSet()
+			if err != nil {
+				return nil, err
+			}
+			doc.operations = append(doc.operations, &operation{selections: sels})
+		case p.peekIs(tokName, "fragment"):
+			p.pos++
+			name, err := p.name()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect(tokName, "on"); err != nil {
+				return nil, err
+			}
+			if _, err := p.name(); err != nil {
+				return nil, err
+			}
+			if err := p.directives(); err != nil {
+				return nil, err
+			}
+			sels, err := p.selectionSet()
+			if err != nil {
+				return nil, err
+			}
+			doc.fragments[name] = sels
+		case p.peekIs(tokName, "query"), p.peekIs(tokName, "mutation"), p.peekIs(tokName, "subscription"):
+			op, err := p.operation()
+			if err != nil {
+				return nil, err
+			}
+			doc.operations = append(doc.operations, op)
+		default:
+			t, _ := p.peek()
+			return nil, fmt.Errorf("неожиданное %q в начале определения", t.text)
+		}
+	}
+	if len(doc.operations) == 0 {
+		return nil, errors.New("в запросе нет операций")
+	}
+	return doc, nil
+}
+
+func (p *parser) operation() (*operation, error) {
+	p.pos++ // query, mutation или subscription
+	op := &operation{defaults: make(map[string]value)}
+	if t, ok := p.peek(); ok && t.kind == tokName {
+		op.name = t.text
+		p.pos++
+	}
+	if p.peekIs(tokPunct, "(") {
+		p.pos++
+		for !p.peekIs(tokPunct, ")") {
+			if err := p.expect(tokPunct, "$"); err != nil {
+				return nil, err
+			}
+			name, err := p.name()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect(tokPunct, ":"); err != nil {
+				return nil, err
+			}
+			if err := p.typeRef(); err != nil {
+				return nil, err
+			}
+			if p.peekIs(tokPunct, "=") {
+				p.pos++
+				v, err := p.value()
+				if err != nil {
+					return nil, err
+				}
+				op.defaults[name] = v
+			}
+			if err := p.directives(); err != nil {
+				return nil, err
+			}
+		}
+		p.pos++
+	}
+	if err := p.directives(); err != nil {
+		return nil, err
+	}
+	sels, err := p.selectionSet()
+	if err != nil {
+		return nil, err
+	}
+	op.selections = sels
+	return op, nil
+}
+
+func (p *parser) typeRef() error {
+	if p.peekIs(tokPunct, "[") {
+		p.pos++
+		if err := p.nest(); err != nil {
+			return err
+		}
+		if err := p.typeRef(); err != nil {
+			return err
+		}
+		p.unnest()
+		if err := p.expect(tokPunct, "]"); err != nil {
+			return err
+		}
+	} else if _, err := p.name(); err != nil {
+		return err
+	}
+	if p.peekIs(tokPunct, "!") {
+		p.pos++
+	}
+	return nil
+}
+
+func (p *parser) selectionSet() ([]selection, error) {
+	if err := p.expect(tokPunct, "{"); err != nil {
+		return nil, err
+	}
+	if err := p.nest(); err != nil {
+		return nil, err
+	}
+	defer p.unnest()
+
+	sels := []selection{}
+	for !p.peekIs(tokPunct, "}") {
+		sel, err := p.selection()
+		if err != nil {
+			return nil, err
+		}
+		sels = append(sels, sel)
+	}
+	p.pos++
+	if len(sels) == 0 {
+		return nil, errors.New("пустой набор полей")
+	}
+	return sels, nil
+}
+
+func (p *parser) selection() (selection, error) {
+	if p.peekIs(tokPunct, "...") {
+		p.pos++
+		if t, ok := p.peek(); ok && t.kind == tokName && t.text != "on" {
+			p.pos++
+			return selection{spread: t.text}, p.directives()
+		}
+		if p.peekIs(tokName, "on") {
+			p.pos += 2
+		}
+		if err := p.directives(); err != nil {
+			return selection{}, err
+		}
+		inline, err := p.selectionSet()
+		return selection{inline: inline}, err
+	}
+
+	name, err := p.name()
+	if err != nil {
+		return selection{}, err
+	}
+	if p.peekIs(tokPunct, ":") {
+		p.pos++
+		if name, err = p.name(); err != nil {
+			return selection{}, err
+		}
+	}
+	sel := selection{name: name}
+	args, err := p.arguments()
+	if err != nil {
+		return selection{}, err
+	}
+	if v, ok := args["first"]; ok {
+		sel.first = &v
+	}
+	if err := p.directives(); err != nil {
+		return selection{}, err
+	}
+	if p.peekIs(tokPunct, "{") {
+		if sel.children, err = p.selectionSet(); err != nil {
+			return selection{}, err
+		}
+	}
+	return sel, nil
+}
+
+func (p *parser) arguments() (map[string]value, error) {
+	if !p.peekIs(tokPunct, "(") {
+		return nil, nil
+	}
+	p.pos++
+	args := make(map[string]value)
+	for !p.peekIs(tokPunct, ")") {
+		name, err := p.name()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(tokPunct, ":"); err != nil {
+			return nil, err
+		}
+		v, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		args[name] = v
+	}
+	p.pos++
+	return args, nil
+}
+
+func (p *parser) directives() error {
+	for p.peekIs(tokPunct, "@") {
+		p.pos++
+		if _, err := p.name(); err != nil {
+			return err
+		}
+		if _, err := p.arguments(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// value разбирает значение; запоминаются только целые числа и переменные, остальное пропускается
+func (p *parser) value() (value, error) {
+	t, err := p.next()
+	if err != nil {
+		return value{}, err
+	}
+	switch {
+	case t.kind == tokPunct && t.text == "$":
+		name, err := p.name()
+		return value{variable: name}, err
+	case t.kind == tokInt:
+		n, err := strconv.ParseInt(t.text, 10, 64)
+		if err != nil {
+			return value{}, fmt.Errorf("некорректное число %q", t.text)
+		}
+		return value{isInt: true, n: n}, nil
+	case t.kind == tokFloat, t.kind == tokString, t.kind == tokName:
+		return value{}, nil
+	case t.kind == tokPunct && (t.text == "[" || t.text == "{"):
+		if err := p.nest(); err != nil {
+			return value{}, err
+		}
+		defer p.unnest()
+		closing := "]"
+		if t.text == "{" {
+			closing = "}"
+		}
+		for !p.peekIs(tokPunct, closing) {
+			if closing == "}" {
+				if _, err := p.name(); err != nil {
+					return value{}, err
+				}
+				if err := p.expect(tokPunct, ":"); err != nil {
+					return value{}, err
+				}
+			}
+			if _, err := p.value(); err != nil {
+				return value{}, err
+			}
+		}
+		p.pos++
+		return value{}, nil
+	}
+	return value{}, fmt.Errorf("неожиданное %q вместо значения", t.text)
+}
@@ -0,0 +1,64 @@
+// internal/graphapi/complexity_test.go
+package graphapi
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEstimateCost(t *testing.T) {
+	tests := []struct {
+		name       string
+		query      string
+		operation  string
+		vars       map[string]interface{}
+		complexity float64
+		fields     float64
+	}{
+		{"одно поле", `{ order(uid: "1") { uid } }`, "", nil, 2, 2},
+		// orders(1) + 20 * (nodes(1) + uid(1))
+		{"first по умолчанию", `{ orders { nodes { uid } } }`, "", nil, 41, 3},
+		{"first из аргумента", `{ orders(first: 5) { nodes { uid } } }`, "", nil, 11, 3},
+		{"first из переменной", `query Q($n: Int) { orders(first: $n) { nodes { uid } } }`, "",
+			map[string]interface{}{"n": float64(3)}, 7, 3},
+		{"значение переменной по умолчанию", `query Q($n: Int = 2) { orders(first: $n) { nodes { uid } } }`, "", nil, 5, 3},
+		// order(1) + customer(1) + orders(1) + 10 * (uid(1) + items(1) + 10 * name(1))
+		{"вложенные списки", `{ order(uid: "1") { customer { orders { uid items { name } } } } }`, "", nil, 123, 6},
+		{"псевдонимы", `{ a: order(uid: "1") { uid } b: order(uid: "2") { uid } }`, "", nil, 4, 4},
+		{"фрагменты раскрываются", `
+			query { orders(first: 2) { nodes { ...F ... on Order { entry } } } }
+			fragment F on Order { uid trackNumber }`, "", nil, 9, 5},
+		{"выбранная операция", `query A { order(uid: "1") { uid } } query B { orders { nodes { uid } } }`, "A", nil, 2, 2},
+		{"без имени — самая дорогая", `query A { order(uid: "1") { uid } } query B { orders { nodes { uid } } }`, "", nil, 41, 3},
+		{"строки, комментарии и директивы", `
+			# комментарий
+			query($f: OrderFilter = {customerId: "a, b"}, $skip: Boolean!) {
+				orders(filter: $f, first: 1) @include(if: true) { nodes { uid @skip(if: $skip) } }
+				order(uid: """блок "" строка""") { uid }
+			}`, "", nil, 5, 5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cost, err := estimateCost(tt.query, tt.operation, tt.vars)
+			require.NoError(t, err)
+			assert.Equal(t, tt.complexity, cost.complexity)
+			assert.Equal(t, tt.fields, cost.fields)
+		})
+	}
+}
+
+func TestEstimateCost_Invalid(t *testing.T) {
+	for _, q := range []string{
+		`{ order(uid: "1") { uid }`,
+		`{ order(uid: "1) { uid } }`,
+		`{ ...F } fragment F on Query { ...F }`,
+		`{ ...Missing }`,
+		`{ }`,
+		`query($x: [[[[[[[[[[[[[[[[[[[[[[[[[[[[[[[[[[[[[[[[[[[[[[[[[[[[[[[[[[[[[[[[[[Int]]]]]]]]]]]]]]]]]]]]]]]]]]]]]]]]]]]]]]]]]]]]]]]]]]]]]]]]]]]]]]]]]]) { order { uid } }`,
+	} {
+		_, err := estimateCost(q, "", nil)
+		assert.Error(t, err, q)
+	}
+}
@@ -0,0 +1,169 @@
+// internal/graphapi/graphapi.go
+package graphapi
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"order-service/internal/audit"
+	"order-service/internal/auth"
+	"order-service/internal/config"
+	"order-service/internal/handlers"
+	"order-service/internal/interfaces"
+	"order-service/internal/logging"
+	"order-service/internal/metrics"
+	"order-service/internal/pii"
+	"order-service/models"
+
+	"github.com/graph-gophers/graphql-go"
+	gqlerrors "github.com/graph-gophers/graphql-go/errors"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+//go:embed schema.graphql
+var schemaSDL string
+
+// maxRequestBody тело запроса с текстом GraphQL и переменными
+const maxRequestBody = 1 << 20
+
+// errInternal ответ клиенту вместо ошибки БД, подробности в логе и трейсе
+var errInternal = errors.New("внутренняя ошибка сервера")
+
+// Handler POST /graphql. Заказ по uid читается через кэш, как в REST,
+// списки и вложенные части — пакетными запросами к store
+type Handler struct {
+	schema *graphql.Schema
+	cache  interfaces.Cache
+	db     interfaces.Database
+	store  interfaces.OrderBatchStore
+	tracer trace.Tracer
+	access interfaces.AccessRecorder
+	audit  *audit.Logger
+
+	maxComplexity int
+	maxFields     int
+}
+
+// NewHandler эндпоинт с зависимостями HTTP обработчика h и ограничениями из cfg
+func NewHandler(h *handlers.Handler, store interfaces.OrderBatchStore, cfg config.GraphQLConfig) *Handler {
+	gh := &Handler{
+		cache:  h.Cache,
+		db:     h.DB,
+		store:  store,
+		tracer: h.Tracer,
+		access: h.Access,
+		audit:  h.Audit,
+
+		maxComplexity: cfg.MaxComplexity,
+		maxFields:     cfg.MaxFields,
+	}
+	opts := []graphql.SchemaOpt{graphql.UseFieldResolvers(), graphql.MaxDepth(cfg.MaxDepth)}
+	if !cfg.Introspection {
+		opts = append(opts, graphql.DisableIntrospection())
+	}
+	gh.schema = graphql.MustParseSchema(schemaSDL, &queryResolver{h: gh}, opts...)
+	return gh
+}
+
+type gqlRequest struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx, span := h.tracer.Start(r.Context(), "http.graphql")
+	defer span.End()
+
+	var req gqlRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBody)).Decode(&req); err != nil || req.Query == "" {
+		errMsg := "ожидается JSON с полем query"
+		http.Error(w, errMsg, http.StatusBadRequest)
+		span.SetStatus(codes.Error, errMsg)
+		metrics.GraphQLRequests.WithLabelValues("bad_request").Inc()
+		return
+	}
+	if req.OperationName != "" {
+		span.SetAttributes(attribute.String("graphql.operation", req.OperationName))
+	}
+
+	if err := h.checkCost(req); err != nil {
+		metrics.GraphQLRequests.WithLabelValues("too_complex").Inc()
+		span.SetStatus(codes.Error, err.Message)
+		h.writeResponse(ctx, w, &graphql.Response{Errors: []*gqlerrors.QueryError{err}})
+		return
+	}
+
+	if !revealPII(ctx) {
+		w.Header().Set("X-PII-Masked", "true")
+	}
+	ctx = withRequest(ctx, newRequest(h.store, h.audit, r))
+	resp := h.schema.Exec(ctx, req.Query, req.OperationName, req.Variables)
+
+	if len(resp.Errors) > 0 {
+		metrics.GraphQLRequests.WithLabelValues("error").Inc()
+		span.SetStatus(codes.Error, resp.Errors[0].Message)
+	} else {
+		metrics.GraphQLRequests.WithLabelValues("success").Inc()
+		span.SetStatus(codes.Ok, "запрос выполнен")
+	}
+
+	h.writeResponse(ctx, w, resp)
+}
+
+// checkCost отклоняет запрос дороже бюджета до выполнения. Запрос, который не удалось разобрать,
+// отклоняется с ошибками проверки по схеме
+func (h *Handler) checkCost(req gqlRequest) *gqlerrors.QueryError {
+	cost, err := estimateCost(req.Query, req.OperationName, req.Variables)
+	if err != nil {
+		if errs := h.schema.Validate(req.Query); len(errs) > 0 {
+			return errs[0]
+		}
+		return gqlerrors.Errorf("не удалось оценить стоимость запроса: %v", err)
+	}
+	if cost.fields > float64(h.maxFields) {
+		return gqlerrors.Errorf("запрос выбирает %.0f полей, допустимо не больше %d", cost.fields, h.maxFields)
+	}
+	if cost.complexity > float64(h.maxComplexity) {
+		return gqlerrors.Errorf("стоимость запроса %.0f превышает допустимую %d: уменьшите first или число вложенных полей",
+			cost.complexity, h.maxComplexity)
+	}
+	return nil
+}
+
+// writeResponse ошибки GraphQL, в том числе частичные, отдаются в теле с кодом 200
+func (h *Handler) writeResponse(ctx context.Context, w http.ResponseWriter, resp *graphql.Response) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		trace.SpanFromContext(ctx).RecordError(err)
+		slog.ErrorContext(ctx, "Ошибка кодирования ответа GraphQL", logging.Err(err))
+	}
+}
+
+// revealPII видит ли клиент персональные данные: нужна область pii:read.
+// Клиента нет в контексте только при выключенной аутентификации, тогда данные отдаются как есть
+func revealPII(ctx context.Context) bool {
+	p := auth.FromContext(ctx)
+	return p == nil || p.HasScope(auth.ScopePIIRead)
+}
+
+// present заказ, каким его видит клиент запроса: без pii:read персональные данные скрыты
+func present(ctx context.Context, o *models.Order) *models.Order {
+	if revealPII(ctx) {
+		return o
+	}
+	return pii.Mask(o)
+}
+
+// internalError пишет ошибку БД в лог и трейс, клиенту уходит errInternal
+func internalError(ctx context.Context, err error) error {
+	trace.SpanFromContext(ctx).RecordError(err)
+	slog.ErrorContext(ctx, "Ошибка выполнения запроса GraphQL", logging.Err(err))
+	return errInternal
+}
@@ -0,0 +1,454 @@
+// internal/graphapi/graphapi_test.go
+package graphapi
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"order-service/internal/audit"
+	"order-service/internal/auth"
+	"order-service/internal/config"
+	"order-service/internal/handlers"
+	"order-service/internal/interfaces"
+	"order-service/internal/mocks"
+	"order-service/models"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+// fakeStore заказы в памяти; calls считает выборки каждого метода, по ним видно отсутствие N+1
+type fakeStore struct {
+	orders []*models.Order
+
+	mu    sync.Mutex
+	calls map[string]int
+	last  interfaces.OrderFilter
+}
+
+func newFakeStore(orders ...*models.Order) *fakeStore {
+	// как в БД: новые первыми
+	sort.Slice(orders, func(i, j int) bool { return orders[i].DateCreated.After(orders[j].DateCreated) })
+	return &fakeStore{orders: orders, calls: make(map[string]int)}
+}
+
+func (s *fakeStore) count(method string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.calls[method]++
+}
+
+func (s *fakeStore) called(method string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.calls[method]
+}
+
+// header заказ без доставки, оплаты и товаров, как из ListOrderHeaders
+func header(o *models.Order) *models.Order {
+	h := *o
+	h.Delivery, h.Payment, h.Items = models.Delivery{}, models.Payment{}, nil
+	return &h
+}
+
+func (s *fakeStore) ListOrderHeaders(ctx context.Context, f interfaces.OrderFilter, limit, offset int) ([]*models.Order, error) {
+	s.count("ListOrderHeaders")
+	s.mu.Lock()
+	s.last = f
+	s.mu.Unlock()
+	var page []*models.Order
+	for _, o := range s.orders {
+		if (f.CustomerID != "" && o.CustomerID != f.CustomerID) ||
+			(f.DeliveryService != "" && o.DeliveryService != f.DeliveryService) ||
+			(f.CreatedAfter != nil && o.DateCreated.Before(*f.CreatedAfter)) ||
+			(f.CreatedBefore != nil && !o.DateCreated.Before(*f.CreatedBefore)) {
+			continue
+		}
+		if offset > 0 {
+			offset--
+			continue
+		}
+		if len(page) < limit {
+			page = append(page, header(o))
+		}
+	}
+	return page, nil
+}
+
+func (s *fakeStore) OrdersByCustomers(ctx context.Context, ids []string, limit int) (map[string][]*models.Order, error) {
+	s.count("OrdersByCustomers")
+	res := make(map[string][]*models.Order)
+	for _, o := range s.orders {
+		for _, id := range ids {
+			if o.CustomerID == id && len(res[id]) < limit {
+				res[id] = append(res[id], header(o))
+			}
+		}
+	}
+	return res, nil
+}
+
+func (s *fakeStore) OrderCountsByCustomer(ctx context.Context, ids []string) (map[string]int, error) {
+	s.count("OrderCountsByCustomer")
+	res := make(map[string]int)
+	for _, o := range s.orders {
+		for _, id := range ids {
+			if o.CustomerID == id {
+				res[id]++
+			}
+		}
+	}
+	return res, nil
+}
+
+func (s *fakeStore) DeliveriesByOrder(ctx context.Context, uids []string) (map[string]models.Delivery, error) {
+	s.count("DeliveriesByOrder")
+	res := make(map[string]models.Delivery)
+	for _, o := range s.find(uids) {
+		res[o.OrderUID] = o.Delivery
+	}
+	return res, nil
+}
+
+func (s *fakeStore) PaymentsByOrder(ctx context.Context, uids []string) (map[string]models.Payment, error) {
+	s.count("PaymentsByOrder")
+	res := make(map[string]models.Payment)
+	for _, o := range s.find(uids) {
+		res[o.OrderUID] = o.Payment
+	}
+	return res, nil
+}
+
+func (s *fakeStore) ItemsByOrder(ctx context.Context, uids []string) (map[string][]models.Item, error) {
+	s.count("ItemsByOrder")
+	res := make(map[string][]models.Item)
+	for _, o := range s.find(uids) {
+		res[o.OrderUID] = o.Items
+	}
+	return res, nil
+}
+
+func (s *fakeStore) find(uids []string) []*models.Order {
+	var found []*models.Order
+	for _, o := range s.orders {
+		for _, uid := range uids {
+			if o.OrderUID == uid {
+				found = append(found, o)
+			}
+		}
+	}
+	return found
+}
+
+func testOrder(n int, customer string) *models.Order {
+	uid := fmt.Sprintf("order-%d", n)
+	return &models.Order{
+		OrderUID:        uid,
+		TrackNumber:     "TRACK" + uid,
+		Entry:           "WBIL",
+		CustomerID:      customer,
+		DeliveryService: "meest",
+		Locale:          "en",
+		Shardkey:        "9",
+		SmID:            99,
+		OofShard:        "1",
+		DateCreated:     time.Date(2024, 1, n, 0, 0, 0, 0, time.UTC),
+		Delivery: models.Delivery{
+			Name: "Test Testov", Phone: "+9720000000", Zip: "2639809", City: "Kiryat Mozkin",
+			Address: "Ploshad Mira 15", Region: "Kraiot", Email: "test@gmail.com",
+		},
+		Payment: models.Payment{Transaction: "real-transaction-" + uid, Currency: "USD", Amount: 100 * n, PaymentDt: 1637907727},
+		Items:   []models.Item{{ChrtID: int64(n), Name: "item-" + uid, NmID: 2389212, Price: 453}},
+	}
+}
+
+func newTestHandler(t *testing.T, store interfaces.OrderBatchStore) (*Handler, *mocks.MockCache, *mocks.MockDatabase) {
+	ctrl := gomock.NewController(t)
+	mockCache := mocks.NewMockCache(ctrl)
+	mockDB := mocks.NewMockDatabase(ctrl)
+	h := handlers.NewHandler(mockCache, mockDB, noop.NewTracerProvider().Tracer("test"))
+	return NewHandler(h, store, config.Default().GraphQL), mockCache, mockDB
+}
+
+type gqlResponse struct {
+	Data   map[string]interface{} `json:"data"`
+	Errors []struct {
+		Message string `json:"message"`
+	} `json:"errors"`
+}
+
+// query выполняет запрос от имени p; nil — аутентификация выключена
+func query(t *testing.T, h http.Handler, p *auth.Principal, q string, vars map[string]interface{}) (gqlResponse, *httptest.ResponseRecorder) {
+	t.Helper()
+	body, err := json.Marshal(map[string]interface{}{"query": q, "variables": vars})
+	require.NoError(t, err)
+	req := httptest.NewRequest(http.MethodPost, "/graphql", bytes.NewReader(body))
+	if p != nil {
+		req = req.WithContext(auth.WithPrincipal(req.Context(), p))
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	var resp gqlResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	return resp, rec
+}
+
+// path значение по пути из объектов и индексов списков
+func path(v interface{}, keys ...interface{}) interface{} {
+	for _, k := range keys {
+		switch k := k.(type) {
+		case string:
+			v = v.(map[string]interface{})[k]
+		case int:
+			v = v.([]interface{})[k]
+		}
+	}
+	return v
+}
+
+func TestOrders_Batched(t *testing.T) {
+	var orders []*models.Order
+	for i := 1; i <= 10; i++ {
+		orders = append(orders, testOrder(i, []string{"alice", "bob", "carol"}[i%3]))
+	}
+	store := newFakeStore(orders...)
+	h, _, _ := newTestHandler(t, store)
+
+	resp, _ := query(t, h, nil, `{
+		orders(first: 10) {
+			nodes {
+				uid
+				delivery { city name }
+				payment { amount }
+				items { name chrtId }
+				customer {
+					id
+					orderCount
+					orders(first: 2) { uid payment { amount } items { name } }
+				}
+			}
+		}
+	}`, nil)
+	require.Empty(t, resp.Errors)
+	nodes := path(resp.Data, "orders", "nodes").([]interface{})
+	require.Len(t, nodes, 10)
+
+	// новые первыми, части заказа на месте
+	first := nodes[0]
+	assert.Equal(t, "order-10", path(first, "uid"))
+	assert.Equal(t, "Test Testov", path(first, "delivery", "name"))
+	assert.EqualValues(t, 1000, path(first, "payment", "amount"))
+	assert.Equal(t, "10", path(first, "items", 0, "chrtId"))
+	assert.Equal(t, "bob", path(first, "customer", "id"))
+	assert.EqualValues(t, 4, path(first, "customer", "orderCount"))
+	assert.Len(t, path(first, "customer", "orders"), 2)
+
+	// по одной выборке на каждую часть, сколько бы заказов ни было на странице
+	for _, method := range []string{"ListOrderHeaders", "DeliveriesByOrder", "PaymentsByOrder",
+		"ItemsByOrder", "OrderCountsByCustomer", "OrdersByCustomers"} {
+		assert.Equal(t, 1, store.called(method), method)
+	}
+}
+
+func TestOrders_OnlyRequestedParts(t *testing.T) {
+	store := newFakeStore(testOrder(1, "alice"), testOrder(2, "alice"))
+	h, _, _ := newTestHandler(t, store)
+
+	resp, _ := query(t, h, nil, `{ orders { nodes { uid trackNumber } } }`, nil)
+	require.Empty(t, resp.Errors)
+	assert.Len(t, path(resp.Data, "orders", "nodes"), 2)
+
+	// доставка, оплата и товары не запрошены — и не выбираются
+	assert.Equal(t, 1, store.called("ListOrderHeaders"))
+	assert.Zero(t, store.called("DeliveriesByOrder"))
+	assert.Zero(t, store.called("PaymentsByOrder"))
+	assert.Zero(t, store.called("ItemsByOrder"))
+}
+
+func TestOrders_FilterAndPagination(t *testing.T) {
+	store := newFakeStore(testOrder(1, "alice"), testOrder(2, "bob"), testOrder(3, "alice"), testOrder(4, "alice"))
+	h, _, _ := newTestHandler(t, store)
+	q := `query($filter: OrderFilter, $first: Int = 20, $offset: Int = 0) {
+		orders(filter: $filter, first: $first, offset: $offset) { nodes { uid } hasNextPage }
+	}`
+
+	resp, _ := query(t, h, nil, q, map[string]interface{}{
+		"filter": map[string]interface{}{"customerId": "alice", "createdAfter": "2024-01-02T00:00:00Z"},
+		"first":  1,
+	})
+	require.Empty(t, resp.Errors)
+	assert.Equal(t, "order-4", path(resp.Data, "orders", "nodes", 0, "uid"))
+	assert.Equal(t, true, path(resp.Data, "orders", "hasNextPage"))
+	assert.Equal(t, "alice", store.last.CustomerID)
+	require.NotNil(t, store.last.CreatedAfter)
+	assert.True(t, store.last.CreatedAfter.Equal(time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)))
+
+	resp, _ = query(t, h, nil, q, map[string]interface{}{
+		"filter": map[string]interface{}{"customerId": "alice", "createdAfter": "2024-01-02T00:00:00Z"},
+		"first":  1,
+		"offset": 1,
+	})
+	require.Empty(t, resp.Errors)
+	assert.Equal(t, "order-3", path(resp.Data, "orders", "nodes", 0, "uid"))
+	assert.Equal(t, false, path(resp.Data, "orders", "hasNextPage"))
+
+	for _, vars := range []map[string]interface{}{{"first": 0}, {"first": 101}, {"offset": -1}} {
+		resp, _ = query(t, h, nil, q, vars)
+		require.NotEmpty(t, resp.Errors, vars)
+	}
+}
+
+func TestOrder_FromCache(t *testing.T) {
+	store := newFakeStore(testOrder(1, "alice"))
+	h, mockCache, mockDB := newTestHandler(t, store)
+	order := testOrder(1, "alice")
+
+	for _, uid := range []string{order.OrderUID, "missing"} {
+		mockCache.EXPECT().GetOrLoad(uid, gomock.Any()).DoAndReturn(
+			func(uid string, load interfaces.LoadFunc) (*models.Order, error) {
+				return load(uid)
+			})
+	}
+	mockDB.EXPECT().GetOrder(gomock.Any(), order.OrderUID).Return(order, nil)
+	mockDB.EXPECT().GetOrder(gomock.Any(), "missing").Return(nil, sql.ErrNoRows)
+
+	resp, _ := query(t, h, nil, `{
+		order(uid: "order-1") { uid delivery { city } items { name } customer { orderCount } }
+		missing: order(uid: "missing") { uid }
+	}`, nil)
+	require.Empty(t, resp.Errors)
+	assert.Equal(t, "Kiryat Mozkin", path(resp.Data, "order", "delivery", "city"))
+	assert.Equal(t, "item-order-1", path(resp.Data, "order", "items", 0, "name"))
+	assert.EqualValues(t, 1, path(resp.Data, "order", "customer", "orderCount"))
+	assert.Nil(t, resp.Data["missing"])
+
+	// заказ из кэша уже целый, части не догружаются
+	assert.Zero(t, store.called("DeliveriesByOrder"))
+	assert.Zero(t, store.called("ItemsByOrder"))
+}
+
+func TestCustomer(t *testing.T) {
+	store := newFakeStore(testOrder(1, "alice"), testOrder(2, "alice"), testOrder(3, "bob"))
+	h, _, _ := newTestHandler(t, store)
+
+	resp, _ := query(t, h, nil, `{
+		alice: customer(id: "alice") { orderCount orders { uid } }
+		nobody: customer(id: "nobody") { id }
+	}`, nil)
+	require.Empty(t, resp.Errors)
+	assert.EqualValues(t, 2, path(resp.Data, "alice", "orderCount"))
+	assert.Equal(t, "order-2", path(resp.Data, "alice", "orders", 0, "uid"))
+	assert.Nil(t, resp.Data["nobody"])
+}
+
+func TestPIIMasked(t *testing.T) {
+	store := newFakeStore(testOrder(1, "alice"))
+	h, _, _ := newTestHandler(t, store)
+	q := `{ orders { nodes { delivery { name city } payment { transaction } } } }`
+
+	reader := &auth.Principal{Subject: "frontend", Scopes: []string{auth.ScopeOrdersRead}}
+	resp, rec := query(t, h, reader, q, nil)
+	require.Empty(t, resp.Errors)
+	node := path(resp.Data, "orders", "nodes", 0)
+	assert.NotEqual(t, "Test Testov", path(node, "delivery", "name"))
+	assert.Equal(t, "Kiryat Mozkin", path(node, "delivery", "city"))
+	assert.NotEqual(t, "real-transaction-order-1", path(node, "payment", "transaction"))
+	assert.Equal(t, "true", rec.Header().Get("X-PII-Masked"))
+
+	support := &auth.Principal{Subject: "support", Scopes: []string{auth.ScopeOrdersRead, auth.ScopePIIRead}}
+	resp, rec = query(t, h, support, q, nil)
+	require.Empty(t, resp.Errors)
+	assert.Equal(t, "Test Testov", path(resp.Data, "orders", "nodes", 0, "delivery", "name"))
+	assert.Empty(t, rec.Header().Get("X-PII-Masked"))
+}
+
+func TestAudit(t *testing.T) {
+	store := newFakeStore(testOrder(1, "alice"), testOrder(2, "alice"))
+	h, _, _ := newTestHandler(t, store)
+	auditStore := &memAuditStore{}
+	h.audit = audit.New(auditStore, 10, time.Hour)
+
+	// заказы страницы и те же заказы клиента — по одному событию на заказ
+	resp, _ := query(t, h, nil, `{ orders { nodes { uid customer { orders { uid } } } } }`, nil)
+	require.Empty(t, resp.Errors)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	h.audit.Run(ctx)
+	require.Len(t, auditStore.events, 2)
+	for _, e := range auditStore.events {
+		assert.Equal(t, audit.ActionRead, e.Action)
+	}
+}
+
+type memAuditStore struct {
+	events []audit.Event
+}
+
+func (s *memAuditStore) InsertAuditEvents(ctx context.Context, events []audit.Event) error {
+	s.events = append(s.events, events...)
+	return nil
+}
+
+func (s *memAuditStore) ListAuditEvents(ctx context.Context, orderUID string, limit int) ([]audit.Event, error) {
+	return s.events, nil
+}
+
+func TestServeHTTP_BadRequest(t *testing.T) {
+	store := newFakeStore()
+	h, _, _ := newTestHandler(t, store)
+
+	for _, body := range []string{"", "not json", `{"query": ""}`} {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(body)))
+		assert.Equal(t, http.StatusBadRequest, rec.Code, body)
+	}
+
+	// вложенность сверх graphql.max_depth отклоняется до выполнения
+	deep := `{ orders { nodes { customer { orders { customer { orders { customer { orders { customer { orders { uid } } } } } } } } } } }`
+	resp, _ := query(t, h, nil, deep, nil)
+	require.NotEmpty(t, resp.Errors)
+	assert.Zero(t, store.called("ListOrderHeaders"))
+}
+
+func TestServeHTTP_TooComplex(t *testing.T) {
+	store := newFakeStore(testOrder(1, "alice"))
+	h, _, _ := newTestHandler(t, store)
+
+	// 100 заказов по 100 заказов клиента с товарами — далеко за бюджетом
+	wide := `query($n: Int, $m: Int) { orders(first: $n) { nodes { customer { orders(first: $m) { uid items { name } } } } } }`
+	resp, _ := query(t, h, nil, wide, map[string]interface{}{"n": 100, "m": 100})
+	require.Len(t, resp.Errors, 1)
+	assert.Contains(t, resp.Errors[0].Message, "стоимость запроса")
+	assert.Nil(t, resp.Data)
+	assert.Zero(t, store.called("ListOrderHeaders"))
+
+	// псевдонимы одного поля считаются отдельно
+	var b strings.Builder
+	b.WriteString("{")
+	for i := 0; i < 301; i++ {
+		fmt.Fprintf(&b, " a%d: order(uid: \"order-1\") { uid }", i)
+	}
+	b.WriteString(" }")
+	resp, _ = query(t, h, nil, b.String(), nil)
+	require.Len(t, resp.Errors, 1)
+	assert.Contains(t, resp.Errors[0].Message, "полей")
+
+	// тот же запрос с небольшими first укладывается в бюджет
+	resp, _ = query(t, h, nil, wide, map[string]interface{}{"n": 5, "m": 5})
+	assert.Empty(t, resp.Errors)
+}
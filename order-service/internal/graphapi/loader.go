@@ -0,0 +1,81 @@
+// internal/graphapi/loader.go
+package graphapi
+
+import (
+	"context"
+	"sync"
+
+	"order-service/internal/metrics"
+)
+
+// batch одна пакетная выборка: все ключи, отправленные вместе, ждут общего результата
+type batch[V any] struct {
+	done   chan struct{}
+	values map[string]V
+	err    error
+}
+
+// loader пакетная загрузка по ключу в пределах одного запроса.
+// Резолвер списка заранее сообщает ключи всех элементов через Prime, и первый Load
+// выбирает их одним запросом; остальные Load ждут тот же результат. Окна ожидания
+// нет, поэтому число запросов в БД зависит только от формы запроса, а не от времени
+type loader[V any] struct {
+	name  string
+	fetch func(ctx context.Context, keys []string) (map[string]V, error)
+
+	mu      sync.Mutex
+	pending []string
+	batches map[string]*batch[V]
+}
+
+func newLoader[V any](name string, fetch func(ctx context.Context, keys []string) (map[string]V, error)) *loader[V] {
+	return &loader[V]{name: name, fetch: fetch, batches: make(map[string]*batch[V])}
+}
+
+// Prime запоминает ключи для следующей выборки; уже известные пропускаются
+func (l *loader[V]) Prime(keys ...string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, k := range keys {
+		if _, ok := l.batches[k]; ok {
+			continue
+		}
+		l.batches[k] = nil
+		l.pending = append(l.pending, k)
+	}
+}
+
+// Load значение по ключу; ok ложно, если в БД его нет
+func (l *loader[V]) Load(ctx context.Context, key string) (v V, ok bool, err error) {
+	l.mu.Lock()
+	b := l.batches[key]
+	if b != nil {
+		l.mu.Unlock()
+		select {
+		case <-b.done:
+		case <-ctx.Done():
+			return v, false, ctx.Err()
+		}
+		v, ok = b.values[key]
+		return v, ok, b.err
+	}
+
+	// ключ не загружен: выбираются он и все ожидающие
+	keys := l.pending
+	if _, primed := l.batches[key]; !primed {
+		keys = append(keys, key)
+	}
+	l.pending = nil
+	b = &batch[V]{done: make(chan struct{})}
+	for _, k := range keys {
+		l.batches[k] = b
+	}
+	l.mu.Unlock()
+
+	metrics.GraphQLBatchSize.WithLabelValues(l.name).Observe(float64(len(keys)))
+	b.values, b.err = l.fetch(ctx, keys)
+	close(b.done)
+
+	v, ok = b.values[key]
+	return v, ok, b.err
+}
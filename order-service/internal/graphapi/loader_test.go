@@ -0,0 +1,79 @@
+// internal/graphapi/loader_test.go
+package graphapi
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// countingFetch запоминает ключи каждой выборки и отвечает значениями для всех, кроме missing
+func countingFetch(missing string) (func(context.Context, []string) (map[string]string, error), *[][]string) {
+	var mu sync.Mutex
+	var calls [][]string
+	return func(ctx context.Context, keys []string) (map[string]string, error) {
+		mu.Lock()
+		calls = append(calls, keys)
+		mu.Unlock()
+		values := make(map[string]string)
+		for _, k := range keys {
+			if k != missing {
+				values[k] = "v-" + k
+			}
+		}
+		return values, nil
+	}, &calls
+}
+
+func TestLoader_BatchesPrimedKeys(t *testing.T) {
+	fetch, calls := countingFetch("c")
+	l := newLoader("test", fetch)
+	l.Prime("a", "b", "c", "a")
+
+	var wg sync.WaitGroup
+	for _, k := range []string{"a", "b", "c"} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			v, ok, err := l.Load(context.Background(), k)
+			require.NoError(t, err)
+			assert.Equal(t, k != "c", ok)
+			if ok {
+				assert.Equal(t, "v-"+k, v)
+			}
+		}()
+	}
+	wg.Wait()
+
+	// три загрузки — одна выборка без повторов ключей
+	require.Len(t, *calls, 1)
+	assert.ElementsMatch(t, []string{"a", "b", "c"}, (*calls)[0])
+
+	// загруженный ключ берётся из результата, неизвестный выбирается отдельно
+	_, _, err := l.Load(context.Background(), "a")
+	require.NoError(t, err)
+	v, ok, err := l.Load(context.Background(), "d")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "v-d", v)
+	require.Len(t, *calls, 2)
+	assert.Equal(t, []string{"d"}, (*calls)[1])
+}
+
+func TestLoader_Error(t *testing.T) {
+	l := newLoader("test", func(ctx context.Context, keys []string) (map[string]int, error) {
+		return nil, errors.New("db down")
+	})
+	l.Prime("a", "b")
+
+	_, _, err := l.Load(context.Background(), "a")
+	assert.EqualError(t, err, "db down")
+	// ошибка общая для всех ключей выборки, повтора нет
+	_, ok, err := l.Load(context.Background(), "b")
+	assert.False(t, ok)
+	assert.EqualError(t, err, "db down")
+}
@@ -0,0 +1,126 @@
+// internal/graphapi/request.go
+package graphapi
+
+import (
+	"context"
+	"net/http"
+	"sync"
+
+	"order-service/internal/audit"
+	"order-service/internal/interfaces"
+	"order-service/models"
+)
+
+type requestKey struct{}
+
+// request загрузчики и учёт одного запроса /graphql. Загрузчики живут только в пределах запроса:
+// между запросами данные не переиспользуются, кэшем заказов остаётся interfaces.Cache
+type request struct {
+	store interfaces.OrderBatchStore
+	audit *audit.Logger
+	r     *http.Request
+
+	deliveries *loader[models.Delivery]
+	payments   *loader[models.Payment]
+	items      *loader[[]models.Item]
+	counts     *loader[int]
+
+	mu sync.Mutex
+	// customers все клиенты, встреченные в запросе: ими заполняется загрузчик заказов клиентов
+	customers []string
+	// customerOrders загрузчик на каждое значение first у Customer.orders
+	customerOrders map[int]*loader[[]*models.Order]
+	audited        map[string]bool
+}
+
+func newRequest(store interfaces.OrderBatchStore, auditLog *audit.Logger, r *http.Request) *request {
+	return &request{
+		store:          store,
+		audit:          auditLog,
+		r:              r,
+		deliveries:     newLoader("deliveries", store.DeliveriesByOrder),
+		payments:       newLoader("payments", store.PaymentsByOrder),
+		items:          newLoader("items", store.ItemsByOrder),
+		counts:         newLoader("customer_counts", store.OrderCountsByCustomer),
+		customerOrders: make(map[int]*loader[[]*models.Order]),
+		audited:        make(map[string]bool),
+	}
+}
+
+func withRequest(ctx context.Context, q *request) context.Context {
+	return context.WithValue(ctx, requestKey{}, q)
+}
+
+func requestFrom(ctx context.Context) *request {
+	return ctx.Value(requestKey{}).(*request)
+}
+
+// prime заранее сообщает загрузчикам ключи всех заказов уровня: вложенные поля
+// любого из них выберут части сразу для всех одним запросом
+func (q *request) prime(orders []*models.Order) {
+	uids := make([]string, 0, len(orders))
+	customers := make([]string, 0, len(orders))
+	for _, o := range orders {
+		uids = append(uids, o.OrderUID)
+		if o.CustomerID != "" {
+			customers = append(customers, o.CustomerID)
+		}
+	}
+	q.deliveries.Prime(uids...)
+	q.payments.Prime(uids...)
+	q.items.Prime(uids...)
+	q.primeCustomers(customers...)
+}
+
+func (q *request) primeCustomers(ids ...string) {
+	q.counts.Prime(ids...)
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.customers = append(q.customers, ids...)
+	for _, l := range q.customerOrders {
+		l.Prime(ids...)
+	}
+}
+
+// customerOrdersLoader загрузчик последних first заказов клиентов. Выбранные заказы
+// сообщаются загрузчикам частей до того, как их получит хоть один резолвер
+func (q *request) customerOrdersLoader(first int) *loader[[]*models.Order] {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if l, ok := q.customerOrders[first]; ok {
+		return l
+	}
+	l := newLoader("customer_orders", func(ctx context.Context, ids []string) (map[string][]*models.Order, error) {
+		byCustomer, err := q.store.OrdersByCustomers(ctx, ids, first)
+		if err != nil {
+			return nil, err
+		}
+		for _, orders := range byCustomer {
+			q.prime(orders)
+		}
+		return byCustomer, nil
+	})
+	l.Prime(q.customers...)
+	q.customerOrders[first] = l
+	return l
+}
+
+// orders резолверы заказов; чтение каждого заказа попадает в журнал аудита один раз за запрос
+func (q *request) orders(ctx context.Context, orders []*models.Order, full bool) []*orderResolver {
+	resolvers := make([]*orderResolver, len(orders))
+	for i, o := range orders {
+		q.record(ctx, o.OrderUID)
+		resolvers[i] = &orderResolver{o: o, full: full}
+	}
+	return resolvers
+}
+
+func (q *request) record(ctx context.Context, orderUID string) {
+	q.mu.Lock()
+	seen := q.audited[orderUID]
+	q.audited[orderUID] = true
+	q.mu.Unlock()
+	if !seen {
+		q.audit.Record(ctx, audit.ActionRead, orderUID, q.r)
+	}
+}
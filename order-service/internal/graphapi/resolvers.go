@@ -0,0 +1,253 @@
+// internal/graphapi/resolvers.go
+package graphapi
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"order-service/internal/handlers"
+	"order-service/internal/interfaces"
+	"order-service/internal/logging"
+	"order-service/models"
+
+	"github.com/graph-gophers/graphql-go"
+)
+
+const maxPageLimit = 100
+
+type queryResolver struct {
+	h *Handler
+}
+
+// Order заказ из кэша, промах загружается из БД целиком, как в GET /api/v1/orders/{uid}
+func (r *queryResolver) Order(ctx context.Context, args struct{ UID string }) (*orderResolver, error) {
+	ctx = logging.WithOrderUID(ctx, args.UID)
+	order, err := handlers.LoadOrder(ctx, r.h.cache, r.h.db, args.UID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, internalError(ctx, err)
+	}
+	if r.h.access != nil {
+		r.h.access.RecordAccess(args.UID)
+	}
+
+	q := requestFrom(ctx)
+	q.primeCustomers(order.CustomerID)
+	return q.orders(ctx, []*models.Order{order}, true)[0], nil
+}
+
+type filterInput struct {
+	CustomerID      *string
+	DeliveryService *string
+	CreatedAfter    *graphql.Time
+	CreatedBefore   *graphql.Time
+}
+
+func (f *filterInput) filter() interfaces.OrderFilter {
+	var of interfaces.OrderFilter
+	if f == nil {
+		return of
+	}
+	if f.CustomerID != nil {
+		of.CustomerID = *f.CustomerID
+	}
+	if f.DeliveryService != nil {
+		of.DeliveryService = *f.DeliveryService
+	}
+	if f.CreatedAfter != nil {
+		of.CreatedAfter = &f.CreatedAfter.Time
+	}
+	if f.CreatedBefore != nil {
+		of.CreatedBefore = &f.CreatedBefore.Time
+	}
+	return of
+}
+
+type pageResolver struct {
+	Nodes       []*orderResolver
+	HasNextPage bool
+}
+
+// Orders страница заказов: лишний заказ сверх first выбирается только чтобы узнать, есть ли следующая
+func (r *queryResolver) Orders(ctx context.Context, args struct {
+	Filter *filterInput
+	First  int32
+	Offset int32
+}) (*pageResolver, error) {
+	if err := checkFirst(args.First); err != nil {
+		return nil, err
+	}
+	if args.Offset < 0 {
+		return nil, errors.New("offset должен быть неотрицательным числом")
+	}
+
+	orders, err := r.h.store.ListOrderHeaders(ctx, args.Filter.filter(), int(args.First)+1, int(args.Offset))
+	if err != nil {
+		return nil, internalError(ctx, err)
+	}
+	page := &pageResolver{HasNextPage: len(orders) > int(args.First)}
+	if page.HasNextPage {
+		orders = orders[:args.First]
+	}
+
+	q := requestFrom(ctx)
+	q.prime(orders)
+	page.Nodes = q.orders(ctx, orders, false)
+	return page, nil
+}
+
+// Customer клиент по id; без заказов клиент не существует
+func (r *queryResolver) Customer(ctx context.Context, args struct{ ID string }) (*customerResolver, error) {
+	q := requestFrom(ctx)
+	q.primeCustomers(args.ID)
+	n, _, err := q.counts.Load(ctx, args.ID)
+	if err != nil {
+		return nil, internalError(ctx, err)
+	}
+	if n == 0 {
+		return nil, nil
+	}
+	return &customerResolver{id: args.ID}, nil
+}
+
+func checkFirst(first int32) error {
+	if first < 1 || first > maxPageLimit {
+		return fmt.Errorf("first должен быть от 1 до %d", maxPageLimit)
+	}
+	return nil
+}
+
+// orderResolver заказ; full — доставка, оплата и товары уже заполнены (заказ из кэша),
+// иначе они выбираются загрузчиками запроса
+type orderResolver struct {
+	o    *models.Order
+	full bool
+}
+
+func (r *orderResolver) UID() string               { return r.o.OrderUID }
+func (r *orderResolver) TrackNumber() string       { return r.o.TrackNumber }
+func (r *orderResolver) Entry() string             { return r.o.Entry }
+func (r *orderResolver) Locale() string            { return r.o.Locale }
+func (r *orderResolver) InternalSignature() string { return r.o.InternalSignature }
+func (r *orderResolver) CustomerID() string        { return r.o.CustomerID }
+func (r *orderResolver) DeliveryService() string   { return r.o.DeliveryService }
+func (r *orderResolver) Shardkey() string          { return r.o.Shardkey }
+func (r *orderResolver) SmID() int32               { return int32(r.o.SmID) }
+func (r *orderResolver) DateCreated() graphql.Time { return graphql.Time{Time: r.o.DateCreated} }
+func (r *orderResolver) OofShard() string          { return r.o.OofShard }
+
+// Delivery отдаётся структурой модели: поля схемы совпадают с её полями (UseFieldResolvers)
+func (r *orderResolver) Delivery(ctx context.Context) (*models.Delivery, error) {
+	d := r.o.Delivery
+	if !r.full {
+		var ok bool
+		var err error
+		if d, ok, err = requestFrom(ctx).deliveries.Load(ctx, r.o.OrderUID); err != nil {
+			return nil, internalError(ctx, err)
+		} else if !ok {
+			return nil, nil
+		}
+	}
+	return &present(ctx, &models.Order{Delivery: d}).Delivery, nil
+}
+
+func (r *orderResolver) Payment(ctx context.Context) (*paymentResolver, error) {
+	p := r.o.Payment
+	if !r.full {
+		var ok bool
+		var err error
+		if p, ok, err = requestFrom(ctx).payments.Load(ctx, r.o.OrderUID); err != nil {
+			return nil, internalError(ctx, err)
+		} else if !ok {
+			return nil, nil
+		}
+	}
+	return &paymentResolver{present(ctx, &models.Order{Payment: p}).Payment}, nil
+}
+
+func (r *orderResolver) Items(ctx context.Context) ([]*itemResolver, error) {
+	items := r.o.Items
+	if !r.full {
+		var err error
+		if items, _, err = requestFrom(ctx).items.Load(ctx, r.o.OrderUID); err != nil {
+			return nil, internalError(ctx, err)
+		}
+	}
+	resolvers := make([]*itemResolver, len(items))
+	for i := range items {
+		resolvers[i] = &itemResolver{items[i]}
+	}
+	return resolvers, nil
+}
+
+func (r *orderResolver) Customer() *customerResolver {
+	if r.o.CustomerID == "" {
+		return nil
+	}
+	return &customerResolver{id: r.o.CustomerID}
+}
+
+type paymentResolver struct {
+	p models.Payment
+}
+
+func (r *paymentResolver) Transaction() string { return r.p.Transaction }
+func (r *paymentResolver) RequestID() string   { return r.p.RequestID }
+func (r *paymentResolver) Currency() string    { return r.p.Currency }
+func (r *paymentResolver) Provider() string    { return r.p.Provider }
+func (r *paymentResolver) Amount() int32       { return int32(r.p.Amount) }
+func (r *paymentResolver) PaymentDt() graphql.Time {
+	return graphql.Time{Time: time.Unix(r.p.PaymentDt, 0)}
+}
+func (r *paymentResolver) Bank() string        { return r.p.Bank }
+func (r *paymentResolver) DeliveryCost() int32 { return int32(r.p.DeliveryCost) }
+func (r *paymentResolver) GoodsTotal() int32   { return int32(r.p.GoodsTotal) }
+func (r *paymentResolver) CustomFee() int32    { return int32(r.p.CustomFee) }
+
+type itemResolver struct {
+	i models.Item
+}
+
+func (r *itemResolver) ChrtID() graphql.ID  { return graphql.ID(strconv.FormatInt(r.i.ChrtID, 10)) }
+func (r *itemResolver) TrackNumber() string { return r.i.TrackNumber }
+func (r *itemResolver) Price() int32        { return int32(r.i.Price) }
+func (r *itemResolver) Rid() string         { return r.i.Rid }
+func (r *itemResolver) Name() string        { return r.i.Name }
+func (r *itemResolver) Sale() int32         { return int32(r.i.Sale) }
+func (r *itemResolver) Size() string        { return r.i.Size }
+func (r *itemResolver) TotalPrice() int32   { return int32(r.i.TotalPrice) }
+func (r *itemResolver) NmID() graphql.ID    { return graphql.ID(strconv.FormatInt(r.i.NmID, 10)) }
+func (r *itemResolver) Brand() string       { return r.i.Brand }
+func (r *itemResolver) Status() int32       { return int32(r.i.Status) }
+
+type customerResolver struct {
+	id string
+}
+
+func (r *customerResolver) ID() string { return r.id }
+
+func (r *customerResolver) OrderCount(ctx context.Context) (int32, error) {
+	n, _, err := requestFrom(ctx).counts.Load(ctx, r.id)
+	if err != nil {
+		return 0, internalError(ctx, err)
+	}
+	return int32(n), nil
+}
+
+func (r *customerResolver) Orders(ctx context.Context, args struct{ First int32 }) ([]*orderResolver, error) {
+	if err := checkFirst(args.First); err != nil {
+		return nil, err
+	}
+	q := requestFrom(ctx)
+	orders, _, err := q.customerOrdersLoader(int(args.First)).Load(ctx, r.id)
+	if err != nil {
+		return nil, internalError(ctx, err)
+	}
+	return q.orders(ctx, orders, false), nil
+}
@@ -0,0 +1,123 @@
+// internal/grpcapi/convert.go
+package grpcapi
+
+import (
+	orderv1 "order-service/api/order/v1"
+	"order-service/models"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// toProto заказ в сообщение ответа
+func toProto(o *models.Order) *orderv1.Order {
+	items := make([]*orderv1.Item, len(o.Items))
+	for i, it := range o.Items {
+		items[i] = &orderv1.Item{
+			ChrtId:      it.ChrtID,
+			TrackNumber: it.TrackNumber,
+			Price:       int64(it.Price),
+			Rid:         it.Rid,
+			Name:        it.Name,
+			Sale:        int32(it.Sale),
+			Size:        it.Size,
+			TotalPrice:  int64(it.TotalPrice),
+			NmId:        it.NmID,
+			Brand:       it.Brand,
+			Status:      int32(it.Status),
+		}
+	}
+	return &orderv1.Order{
+		OrderUid:    o.OrderUID,
+		TrackNumber: o.TrackNumber,
+		Entry:       o.Entry,
+		Delivery: &orderv1.Delivery{
+			Name:    o.Delivery.Name,
+			Phone:   o.Delivery.Phone,
+			Zip:     o.Delivery.Zip,
+			City:    o.Delivery.City,
+			Address: o.Delivery.Address,
+			Region:  o.Delivery.Region,
+			Email:   o.Delivery.Email,
+		},
+		Payment: &orderv1.Payment{
+			Transaction:  o.Payment.Transaction,
+			RequestId:    o.Payment.RequestID,
+			Currency:     o.Payment.Currency,
+			Provider:     o.Payment.Provider,
+			Amount:       int64(o.Payment.Amount),
+			PaymentDt:    o.Payment.PaymentDt,
+			Bank:         o.Payment.Bank,
+			DeliveryCost: int64(o.Payment.DeliveryCost),
+			GoodsTotal:   int64(o.Payment.GoodsTotal),
+			CustomFee:    int64(o.Payment.CustomFee),
+		},
+		Items:             items,
+		Locale:            o.Locale,
+		InternalSignature: o.InternalSignature,
+		CustomerId:        o.CustomerID,
+		DeliveryService:   o.DeliveryService,
+		Shardkey:          o.Shardkey,
+		SmId:              int32(o.SmID),
+		DateCreated:       timestamppb.New(o.DateCreated),
+		OofShard:          o.OofShard,
+	}
+}
+
+// fromProto заказ из запроса. Отсутствующие вложенные сообщения дают пустые поля,
+// их отсекает валидация так же, как пропущенные поля JSON
+func fromProto(o *orderv1.Order) *models.Order {
+	d, p := o.GetDelivery(), o.GetPayment()
+	order := &models.Order{
+		OrderUID:    o.GetOrderUid(),
+		TrackNumber: o.GetTrackNumber(),
+		Entry:       o.GetEntry(),
+		Delivery: models.Delivery{
+			Name:    d.GetName(),
+			Phone:   d.GetPhone(),
+			Zip:     d.GetZip(),
+			City:    d.GetCity(),
+			Address: d.GetAddress(),
+			Region:  d.GetRegion(),
+			Email:   d.GetEmail(),
+		},
+		Payment: models.Payment{
+			Transaction:  p.GetTransaction(),
+			RequestID:    p.GetRequestId(),
+			Currency:     p.GetCurrency(),
+			Provider:     p.GetProvider(),
+			Amount:       int(p.GetAmount()),
+			PaymentDt:    p.GetPaymentDt(),
+			Bank:         p.GetBank(),
+			DeliveryCost: int(p.GetDeliveryCost()),
+			GoodsTotal:   int(p.GetGoodsTotal()),
+			CustomFee:    int(p.GetCustomFee()),
+		},
+		Items:             make([]models.Item, len(o.GetItems())),
+		Locale:            o.GetLocale(),
+		InternalSignature: o.GetInternalSignature(),
+		CustomerID:        o.GetCustomerId(),
+		DeliveryService:   o.GetDeliveryService(),
+		Shardkey:          o.GetShardkey(),
+		SmID:              int(o.GetSmId()),
+		OofShard:          o.GetOofShard(),
+	}
+	if o.GetDateCreated() != nil {
+		order.DateCreated = o.GetDateCreated().AsTime()
+	}
+	for i, it := range o.GetItems() {
+		order.Items[i] = models.Item{
+			ChrtID:      it.GetChrtId(),
+			TrackNumber: it.GetTrackNumber(),
+			Price:       int(it.GetPrice()),
+			Rid:         it.GetRid(),
+			Name:        it.GetName(),
+			Sale:        int(it.GetSale()),
+			Size:        it.GetSize(),
+			TotalPrice:  int(it.GetTotalPrice()),
+			NmID:        it.GetNmId(),
+			Brand:       it.GetBrand(),
+			Status:      int(it.GetStatus()),
+		}
+	}
+	return order
+}
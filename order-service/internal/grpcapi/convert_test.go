@@ -0,0 +1,84 @@
+// internal/grpcapi/convert_test.go
+package grpcapi
+
+import (
+	"testing"
+	"time"
+
+	orderv1 "order-service/api/order/v1"
+	"order-service/models"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/proto"
+)
+
+func validTestOrder() *models.Order {
+	return &models.Order{
+		OrderUID:    "b563feb7b2b84b6test123",
+		TrackNumber: "WBILMTESTTRACK123",
+		Entry:       "WBIL",
+		Delivery: models.Delivery{
+			Name:    "Test Testov",
+			Phone:   "+9720000000",
+			Zip:     "2639809",
+			City:    "Kiryat Mozkin",
+			Address: "Ploshad Mira 15",
+			Region:  "Kraiot",
+			Email:   "test@gmail.com",
+		},
+		Payment: models.Payment{
+			Transaction:  "real-transaction-12345",
+			RequestID:    "req-1",
+			Currency:     "USD",
+			Provider:     "wbpay",
+			Amount:       1817,
+			PaymentDt:    time.Now().Unix(),
+			Bank:         "alpha",
+			DeliveryCost: 1500,
+			GoodsTotal:   317,
+			CustomFee:    3,
+		},
+		Items: []models.Item{{
+			ChrtID:      9934930,
+			TrackNumber: "WBILMTESTTRACK123",
+			Price:       453,
+			Rid:         "ab4219087a764ae0btest",
+			Name:        "Mascaras",
+			Sale:        30,
+			Size:        "0",
+			TotalPrice:  317,
+			NmID:        2389212,
+			Brand:       "Vivienne Sabo",
+			Status:      202,
+		}},
+		Locale:            "en",
+		InternalSignature: "sig",
+		CustomerID:        "test",
+		DeliveryService:   "meest",
+		Shardkey:          "9",
+		SmID:              99,
+		DateCreated:       time.Now().Add(-24 * time.Hour).UTC().Truncate(time.Microsecond),
+		OofShard:          "1",
+	}
+}
+
+func TestConvert_RoundTrip(t *testing.T) {
+	order := validTestOrder()
+
+	// через проводной формат, как между клиентом и сервером
+	data, err := proto.Marshal(toProto(order))
+	assert.NoError(t, err)
+	var msg orderv1.Order
+	assert.NoError(t, proto.Unmarshal(data, &msg))
+
+	assert.Equal(t, order, fromProto(&msg))
+}
+
+func TestFromProto_Empty(t *testing.T) {
+	// без вложенных сообщений и даты получаются нулевые значения, а не паника
+	order := fromProto(&orderv1.Order{OrderUid: "abc"})
+	assert.Equal(t, "abc", order.OrderUID)
+	assert.True(t, order.DateCreated.IsZero())
+	assert.Empty(t, order.Items)
+	assert.Empty(t, order.Delivery.Name)
+}
@@ -0,0 +1,231 @@
+// internal/grpcapi/interceptors.go
+package grpcapi
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	orderv1 "order-service/api/order/v1"
+	"order-service/internal/auth"
+	"order-service/internal/logging"
+	"order-service/internal/metrics"
+	"order-service/internal/middleware"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.28.0"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	grpccodes "google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+var tracer = otel.Tracer("order-service/grpc")
+
+// methodScopes область доступа каждого метода; метод без области не вызвать при включённой аутентификации
+var methodScopes = map[string]string{
+	orderv1.OrderService_GetOrder_FullMethodName:    auth.ScopeOrdersRead,
+	orderv1.OrderService_ListOrders_FullMethodName:  auth.ScopeOrdersRead,
+	orderv1.OrderService_WatchOrders_FullMethodName: auth.ScopeOrdersRead,
+	orderv1.OrderService_CreateOrder_FullMethodName: auth.ScopeOrdersWrite,
+}
+
+// New gRPC сервер с OrderService, трейсингом, метриками, проверкой учётных данных и ограничением частоты.
+// authn nil — аутентификация выключена, limiter nil — частота не ограничивается, как у HTTP
+func New(s *Server, authn *auth.Authenticator, limiter *middleware.RateLimiter, opts ...grpc.ServerOption) *grpc.Server {
+	i := interceptors{authn: authn, limiter: limiter}
+	opts = append(opts,
+		grpc.ChainUnaryInterceptor(i.unary),
+		grpc.ChainStreamInterceptor(i.stream),
+	)
+	gs := grpc.NewServer(opts...)
+	orderv1.RegisterOrderServiceServer(gs, s)
+	return gs
+}
+
+type interceptors struct {
+	authn   *auth.Authenticator
+	limiter *middleware.RateLimiter
+}
+
+func (i interceptors) unary(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	start := time.Now()
+	ctx, span := startSpan(ctx, info.FullMethod)
+	defer span.End()
+
+	ctx, err := i.authenticate(ctx, info.FullMethod)
+	if err == nil {
+		err = i.limit(ctx, info.FullMethod)
+	}
+	var resp any
+	if err == nil {
+		resp, err = handler(ctx, req)
+	}
+
+	code := finishSpan(span, err)
+	metrics.GRPCRequests.WithLabelValues(info.FullMethod, code.String()).Inc()
+	metrics.GRPCResponseTime.WithLabelValues(info.FullMethod).Observe(time.Since(start).Seconds())
+	return resp, err
+}
+
+func (i interceptors) stream(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx, span := startSpan(ss.Context(), info.FullMethod)
+	defer span.End()
+
+	ctx, err := i.authenticate(ctx, info.FullMethod)
+	if err == nil {
+		err = i.limit(ctx, info.FullMethod)
+	}
+	if err == nil {
+		err = handler(srv, &contextStream{ServerStream: ss, ctx: ctx})
+	}
+
+	// у потоков время ответа — это время подписки, в гистограмму оно не пишется
+	code := finishSpan(span, err)
+	metrics.GRPCRequests.WithLabelValues(info.FullMethod, code.String()).Inc()
+	return err
+}
+
+// authenticate кладёт клиента в контекст: ключ из x-api-key или authorization метаданных,
+// как заголовки HTTP. Unauthenticated без учётных данных, PermissionDenied без области метода.
+// Каждый Unauthenticated тратит корзину неудач IP, с пустой корзиной — ResourceExhausted до проверки
+func (i interceptors) authenticate(ctx context.Context, method string) (context.Context, error) {
+	if i.authn == nil {
+		return ctx, nil
+	}
+
+	// перебор ключей: IP с пустой корзиной неудач не доходит до проверки, как у HTTP
+	peerAddr := remoteAddr(ctx)
+	if ok, retryAfter := i.limiter.AllowAuthAttempt(peerAddr); !ok {
+		metrics.GRPCRateLimited.WithLabelValues(method, "auth_failures").Inc()
+		return ctx, exhausted(ctx, retryAfter, "слишком много неудачных попыток аутентификации, повторите через ")
+	}
+
+	md, _ := metadata.FromIncomingContext(ctx)
+	principal, err := i.authn.AuthenticateCredentials(ctx, first(md, "x-api-key"), first(md, "authorization"))
+	if err != nil {
+		switch {
+		case errors.Is(err, auth.ErrNoCredentials):
+			i.limiter.AuthFailed(peerAddr)
+			metrics.AuthRequests.WithLabelValues("none", "unauthenticated").Inc()
+			return ctx, status.Error(grpccodes.Unauthenticated, "требуется аутентификация")
+		case errors.Is(err, auth.ErrInvalidCredentials):
+			i.limiter.AuthFailed(peerAddr)
+			metrics.AuthRequests.WithLabelValues("none", "invalid").Inc()
+			return ctx, status.Error(grpccodes.Unauthenticated, "неверные учётные данные")
+		default:
+			// хранилище ключей недоступно — это не вина клиента
+			slog.ErrorContext(ctx, "Ошибка проверки учётных данных", logging.Err(err))
+			metrics.AuthRequests.WithLabelValues("none", "error").Inc()
+			return ctx, status.Error(grpccodes.Internal, "внутренняя ошибка сервера")
+		}
+	}
+
+	trace.SpanFromContext(ctx).SetAttributes(
+		attribute.String("auth.subject", principal.Subject),
+		attribute.String("auth.method", principal.Method),
+	)
+
+	scope, ok := methodScopes[method]
+	if !ok || !principal.HasScope(scope) {
+		slog.WarnContext(ctx, "Недостаточно прав",
+			slog.String("subject", principal.Subject), slog.String("scope", scope))
+		metrics.AuthRequests.WithLabelValues(principal.Method, "forbidden").Inc()
+		return ctx, status.Error(grpccodes.PermissionDenied, "недостаточно прав: нужна область "+scope)
+	}
+
+	metrics.AuthRequests.WithLabelValues(principal.Method, "success").Inc()
+	return auth.WithPrincipal(ctx, principal), nil
+}
+
+// limit списывает токен из корзины метода и клиента в тех же корзинах, что у HTTP: клиент — API ключ
+// или sub JWT, без аутентификации — IP соединения. Поток тратит токен при подписке.
+// Превышение — RESOURCE_EXHAUSTED и retry-after в секундах в заголовке ответа
+func (i interceptors) limit(ctx context.Context, method string) error {
+	allowed, retryAfter, kind := i.limiter.Allow(ctx, method, remoteAddr(ctx))
+	if allowed {
+		return nil
+	}
+	metrics.GRPCRateLimited.WithLabelValues(method, kind).Inc()
+	return exhausted(ctx, retryAfter, "слишком много запросов, повторите через ")
+}
+
+// exhausted RESOURCE_EXHAUSTED с retry-after в секундах в заголовке ответа и в тексте после msg
+func exhausted(ctx context.Context, retryAfter time.Duration, msg string) error {
+	secs := strconv.FormatInt(int64(math.Ceil(retryAfter.Seconds())), 10)
+	if err := grpc.SetHeader(ctx, metadata.Pairs("retry-after", secs)); err != nil {
+		slog.WarnContext(ctx, "Не удалось отправить retry-after", logging.Err(err))
+	}
+	return status.Error(grpccodes.ResourceExhausted, msg+secs+" с")
+}
+
+// startSpan серверный спан вызова, продолжает трейс из traceparent в метаданных
+func startSpan(ctx context.Context, fullMethod string) (context.Context, trace.Span) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	ctx = otel.GetTextMapPropagator().Extract(ctx, metadataCarrier(md))
+
+	service, method, _ := strings.Cut(strings.TrimPrefix(fullMethod, "/"), "/")
+	return tracer.Start(ctx, service+"/"+method,
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(
+			semconv.RPCSystemGRPC,
+			semconv.RPCService(service),
+			semconv.RPCMethod(method),
+		),
+	)
+}
+
+// finishSpan код статуса вызова в спан; ошибкой считаются коды, в которых виноват сервер
+func finishSpan(span trace.Span, err error) grpccodes.Code {
+	code := status.Code(err)
+	span.SetAttributes(semconv.RPCGRPCStatusCodeKey.Int(int(code)))
+	switch code {
+	case grpccodes.Unknown, grpccodes.DeadlineExceeded, grpccodes.Unimplemented,
+		grpccodes.Internal, grpccodes.Unavailable, grpccodes.DataLoss:
+		span.SetStatus(codes.Error, code.String())
+	}
+	return code
+}
+
+func first(md metadata.MD, key string) string {
+	if vals := md.Get(key); len(vals) > 0 {
+		return vals[0]
+	}
+	return ""
+}
+
+// metadataCarrier метаданные gRPC для propagation.TextMapPropagator
+type metadataCarrier metadata.MD
+
+func (c metadataCarrier) Get(key string) string {
+	return first(metadata.MD(c), key)
+}
+
+func (c metadataCarrier) Set(key, value string) {
+	metadata.MD(c).Set(key, value)
+}
+
+func (c metadataCarrier) Keys() []string {
+	keys := make([]string, 0, len(c))
+	for k := range c {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+// contextStream поток с контекстом, в который положен клиент
+type contextStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *contextStream) Context() context.Context {
+	return s.ctx
+}
@@ -0,0 +1,222 @@
+// internal/grpcapi/server.go
+package grpcapi
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"strconv"
+
+	orderv1 "order-service/api/order/v1"
+	"order-service/internal/audit"
+	"order-service/internal/auth"
+	"order-service/internal/handlers"
+	"order-service/internal/interfaces"
+	"order-service/internal/logging"
+	"order-service/internal/metrics"
+	"order-service/internal/pii"
+	"order-service/internal/pubsub"
+	"order-service/internal/validation"
+	"order-service/internal/webhook"
+	"order-service/models"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	grpccodes "google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	defaultPageLimit = 20
+	maxPageLimit     = 100
+)
+
+// Server OrderService поверх тех же кэша, БД, журнала и хаба, что и HTTP обработчики
+type Server struct {
+	orderv1.UnimplementedOrderServiceServer
+
+	cache  interfaces.Cache
+	db     interfaces.Database
+	tracer trace.Tracer
+	access interfaces.AccessRecorder
+	audit  *audit.Logger
+	hub    *pubsub.Hub
+	hooks  *webhook.Dispatcher
+	// watchBuffer сколько заказов ждёт отправки медленному подписчику WatchOrders
+	watchBuffer int
+}
+
+// NewServer сервис с зависимостями HTTP обработчика h
+func NewServer(h *handlers.Handler) *Server {
+	return &Server{
+		cache:  h.Cache,
+		db:     h.DB,
+		tracer: h.Tracer,
+		access: h.Access,
+		audit:  h.Audit,
+		hub:    h.Hub,
+		hooks:  h.Webhooks,
+
+		watchBuffer: h.Stream.BufferSize,
+	}
+}
+
+func (s *Server) GetOrder(ctx context.Context, req *orderv1.GetOrderRequest) (*orderv1.Order, error) {
+	ctx, span := s.tracer.Start(ctx, "grpc.get_order")
+	defer span.End()
+
+	orderUID := req.GetOrderUid()
+	if orderUID == "" {
+		return nil, spanError(span, grpccodes.InvalidArgument, "не указан order_uid")
+	}
+	span.SetAttributes(attribute.String("order.uid", orderUID))
+	ctx = logging.WithOrderUID(ctx, orderUID)
+
+	order, err := handlers.LoadOrder(ctx, s.cache, s.db, orderUID)
+	if err != nil {
+		span.RecordError(err)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, spanError(span, grpccodes.NotFound, "заказ не найден")
+		}
+		return nil, spanError(span, grpccodes.Internal, "внутренняя ошибка сервера DB error")
+	}
+	if s.access != nil {
+		s.access.RecordAccess(orderUID)
+	}
+	s.audit.RecordRemote(ctx, audit.ActionRead, orderUID, remoteAddr(ctx))
+
+	span.SetStatus(codes.Ok, "заказ получен")
+	return present(ctx, order), nil
+}
+
+func (s *Server) ListOrders(ctx context.Context, req *orderv1.ListOrdersRequest) (*orderv1.ListOrdersResponse, error) {
+	ctx, span := s.tracer.Start(ctx, "grpc.list_orders")
+	defer span.End()
+
+	limit, offset := int(req.GetLimit()), int(req.GetOffset())
+	if limit == 0 {
+		limit = defaultPageLimit
+	}
+	if limit < 1 || limit > maxPageLimit {
+		return nil, spanError(span, grpccodes.InvalidArgument, "limit должен быть от 1 до "+strconv.Itoa(maxPageLimit))
+	}
+	if offset < 0 {
+		return nil, spanError(span, grpccodes.InvalidArgument, "offset должен быть неотрицательным числом")
+	}
+	span.SetAttributes(attribute.Int("page.limit", limit), attribute.Int("page.offset", offset))
+
+	orders, err := s.db.ListOrders(ctx, limit, offset)
+	if err != nil {
+		span.RecordError(err)
+		return nil, spanError(span, grpccodes.Internal, "внутренняя ошибка сервера DB error")
+	}
+
+	resp := &orderv1.ListOrdersResponse{
+		Orders: make([]*orderv1.Order, len(orders)),
+		Limit:  int32(limit),
+		Offset: int32(offset),
+	}
+	for i, o := range orders {
+		resp.Orders[i] = present(ctx, o)
+		s.audit.RecordRemote(ctx, audit.ActionRead, o.OrderUID, remoteAddr(ctx))
+	}
+	span.SetStatus(codes.Ok, "список заказов получен")
+	return resp, nil
+}
+
+// CreateOrder приём заказа, как CreateOrderHandler: валидация, БД, кэш, журнал, подписчики
+func (s *Server) CreateOrder(ctx context.Context, req *orderv1.CreateOrderRequest) (*orderv1.Order, error) {
+	ctx, span := s.tracer.Start(ctx, "grpc.create_order")
+	defer span.End()
+
+	if req.GetOrder() == nil {
+		metrics.OrdersProcessed.WithLabelValues("grpc", "error").Inc()
+		return nil, spanError(span, grpccodes.InvalidArgument, "не передан заказ")
+	}
+	order := fromProto(req.GetOrder())
+	span.SetAttributes(attribute.String("order.uid", order.OrderUID))
+	ctx = logging.WithOrderUID(ctx, order.OrderUID)
+
+	if err := validation.ValidateOrder(order); err != nil {
+		span.SetStatus(codes.Error, "невалидные данные заказа")
+		metrics.OrdersProcessed.WithLabelValues("grpc", "validation_error").Inc()
+		return nil, status.Error(grpccodes.InvalidArgument, "невалидные данные заказа: "+err.Error())
+	}
+
+	statusChanged, err := s.db.SaveOrder(ctx, order)
+	if err != nil {
+		span.RecordError(err)
+		slog.ErrorContext(ctx, "Ошибка сохранения заказа из gRPC", logging.Err(err))
+		metrics.OrdersProcessed.WithLabelValues("grpc", "error").Inc()
+		return nil, spanError(span, grpccodes.Internal, "внутренняя ошибка сервера DB error")
+	}
+	s.cache.Set(order.OrderUID, order)
+	s.audit.RecordRemote(ctx, audit.ActionWrite, order.OrderUID, remoteAddr(ctx))
+	s.hub.Publish(order)
+	s.hooks.NotifyStored(ctx, order, statusChanged)
+	slog.InfoContext(ctx, "Заказ принят через gRPC")
+	metrics.OrdersProcessed.WithLabelValues("grpc", "success").Inc()
+
+	span.SetStatus(codes.Ok, "заказ сохранен")
+	return present(ctx, order), nil
+}
+
+// WatchOrders отправляет заказы, принятые после подписки, пока клиент не отменит вызов.
+// Медленный клиент пропускает заказы, не поместившиеся в буфер, остальных он не задерживает
+func (s *Server) WatchOrders(req *orderv1.WatchOrdersRequest, stream grpc.ServerStreamingServer[orderv1.Order]) error {
+	if s.hub == nil {
+		return status.Error(grpccodes.Unimplemented, "рассылка заказов выключена")
+	}
+	ctx := stream.Context()
+	sub := s.hub.Subscribe(pubsub.Filter{
+		CustomerID:      req.GetCustomerId(),
+		DeliveryService: req.GetDeliveryService(),
+	}, s.watchBuffer)
+	defer func() {
+		sub.Close()
+		if n := sub.Dropped(); n > 0 {
+			slog.WarnContext(ctx, "Подписчику WatchOrders не досталась часть заказов", slog.Uint64("dropped", n))
+		}
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case order, ok := <-sub.C:
+			if !ok {
+				// хаб закрыт при остановке сервиса, клиенту стоит переподключиться
+				return status.Error(grpccodes.Unavailable, "сервер останавливается")
+			}
+			if err := stream.Send(present(ctx, order)); err != nil {
+				return err
+			}
+			s.audit.RecordRemote(ctx, audit.ActionRead, order.OrderUID, remoteAddr(ctx))
+		}
+	}
+}
+
+// present заказ в том виде, в котором его видит клиент: без pii:read персональные данные скрыты.
+// Клиента нет в контексте только при выключенной аутентификации, тогда данные отдаются как есть
+func present(ctx context.Context, o *models.Order) *orderv1.Order {
+	if p := auth.FromContext(ctx); p != nil && !p.HasScope(auth.ScopePIIRead) {
+		o = pii.Mask(o)
+	}
+	return toProto(o)
+}
+
+func spanError(span trace.Span, code grpccodes.Code, msg string) error {
+	span.SetStatus(codes.Error, msg)
+	return status.Error(code, msg)
+}
+
+func remoteAddr(ctx context.Context) string {
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		return p.Addr.String()
+	}
+	return ""
+}
@@ -0,0 +1,384 @@
+// internal/grpcapi/server_test.go
+package grpcapi
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	orderv1 "order-service/api/order/v1"
+	"order-service/internal/audit"
+	"order-service/internal/auth"
+	"order-service/internal/config"
+	"order-service/internal/handlers"
+	"order-service/internal/interfaces"
+	"order-service/internal/middleware"
+	"order-service/internal/mocks"
+	"order-service/internal/pubsub"
+	"order-service/models"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/trace/noop"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// startServer gRPC сервер в памяти поверх h и клиент к нему
+func startServer(t *testing.T, h *handlers.Handler, authn *auth.Authenticator) orderv1.OrderServiceClient {
+	t.Helper()
+	return serve(t, New(NewServer(h), authn, nil))
+}
+
+// serve запускает gs в памяти и возвращает клиент к нему
+func serve(t *testing.T, gs *grpc.Server) orderv1.OrderServiceClient {
+	t.Helper()
+	lis := bufconn.Listen(1 << 20)
+	go gs.Serve(lis)
+	t.Cleanup(gs.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return orderv1.NewOrderServiceClient(conn)
+}
+
+func newTestHandler(mockCache *mocks.MockCache, mockDB *mocks.MockDatabase) *handlers.Handler {
+	return handlers.NewHandler(mockCache, mockDB, noop.NewTracerProvider().Tracer("test"))
+}
+
+// passThroughLoad настраивает мок кэша так, чтобы GetOrLoad вызывал переданный загрузчик
+func passThroughLoad(mockCache *mocks.MockCache, orderUID string) {
+	mockCache.EXPECT().GetOrLoad(orderUID, gomock.Any()).DoAndReturn(
+		func(uid string, load interfaces.LoadFunc) (*models.Order, error) {
+			return load(uid)
+		})
+}
+
+type memAuditStore struct {
+	events []audit.Event
+}
+
+func (s *memAuditStore) InsertAuditEvents(ctx context.Context, events []audit.Event) error {
+	s.events = append(s.events, events...)
+	return nil
+}
+
+func (s *memAuditStore) ListAuditEvents(ctx context.Context, orderUID string, limit int) ([]audit.Event, error) {
+	return s.events, nil
+}
+
+func TestGetOrder(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mockDB := mocks.NewMockDatabase(ctrl)
+	mockCache := mocks.NewMockCache(ctrl)
+
+	order := validTestOrder()
+	passThroughLoad(mockCache, order.OrderUID)
+	mockDB.EXPECT().GetOrder(gomock.Any(), order.OrderUID).Return(order, nil)
+	passThroughLoad(mockCache, "notfound")
+	mockDB.EXPECT().GetOrder(gomock.Any(), "notfound").Return(nil, sql.ErrNoRows)
+	passThroughLoad(mockCache, "broken")
+	mockDB.EXPECT().GetOrder(gomock.Any(), "broken").Return(nil, errors.New("db down"))
+
+	store := &memAuditStore{}
+	h := newTestHandler(mockCache, mockDB)
+	h.Audit = audit.New(store, 10, time.Hour)
+	client := startServer(t, h, nil)
+	ctx := context.Background()
+
+	got, err := client.GetOrder(ctx, &orderv1.GetOrderRequest{OrderUid: order.OrderUID})
+	require.NoError(t, err)
+	assert.Equal(t, order, fromProto(got))
+
+	_, err = client.GetOrder(ctx, &orderv1.GetOrderRequest{OrderUid: "notfound"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+	_, err = client.GetOrder(ctx, &orderv1.GetOrderRequest{OrderUid: "broken"})
+	assert.Equal(t, codes.Internal, status.Code(err))
+	_, err = client.GetOrder(ctx, &orderv1.GetOrderRequest{})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	drainAudit(h.Audit)
+	require.Len(t, store.events, 1, "пишутся только выданные заказы")
+	assert.Equal(t, audit.ActionRead, store.events[0].Action)
+	assert.Equal(t, audit.ActorAnonymous, store.events[0].Actor)
+}
+
+func TestListOrders(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mockDB := mocks.NewMockDatabase(ctrl)
+
+	order := validTestOrder()
+	mockDB.EXPECT().ListOrders(gomock.Any(), 20, 0).Return([]*models.Order{order}, nil)
+	mockDB.EXPECT().ListOrders(gomock.Any(), 5, 10).Return([]*models.Order{}, nil)
+
+	store := &memAuditStore{}
+	h := newTestHandler(mocks.NewMockCache(ctrl), mockDB)
+	h.Audit = audit.New(store, 10, time.Hour)
+	client := startServer(t, h, nil)
+	ctx := context.Background()
+
+	// limit 0 — значение по умолчанию, как пропущенный параметр HTTP
+	resp, err := client.ListOrders(ctx, &orderv1.ListOrdersRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, int32(20), resp.Limit)
+	require.Len(t, resp.Orders, 1)
+	assert.Equal(t, order.OrderUID, resp.Orders[0].OrderUid)
+
+	resp, err = client.ListOrders(ctx, &orderv1.ListOrdersRequest{Limit: 5, Offset: 10})
+	require.NoError(t, err)
+	assert.Empty(t, resp.Orders)
+	assert.Equal(t, int32(10), resp.Offset)
+
+	for _, req := range []*orderv1.ListOrdersRequest{{Limit: 101}, {Limit: -1}, {Offset: -1}} {
+		_, err := client.ListOrders(ctx, req)
+		assert.Equal(t, codes.InvalidArgument, status.Code(err), req.String())
+	}
+
+	// каждый выданный заказ попадает в журнал
+	drainAudit(h.Audit)
+	require.Len(t, store.events, 1)
+	assert.Equal(t, audit.ActionRead, store.events[0].Action)
+	assert.Equal(t, order.OrderUID, store.events[0].OrderUID)
+}
+
+func TestCreateOrder(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mockDB := mocks.NewMockDatabase(ctrl)
+	mockCache := mocks.NewMockCache(ctrl)
+
+	order := validTestOrder()
+	mockDB.EXPECT().SaveOrder(gomock.Any(), order).Return(false, nil)
+	mockCache.EXPECT().Set(order.OrderUID, order)
+
+	store := &memAuditStore{}
+	h := newTestHandler(mockCache, mockDB)
+	h.Audit = audit.New(store, 10, time.Hour)
+	h.Hub = pubsub.NewHub()
+	sub := h.Hub.Subscribe(pubsub.Filter{}, 10)
+	defer sub.Close()
+	client := startServer(t, h, nil)
+	ctx := context.Background()
+
+	saved, err := client.CreateOrder(ctx, &orderv1.CreateOrderRequest{Order: toProto(order)})
+	require.NoError(t, err)
+	assert.Equal(t, order.OrderUID, saved.OrderUid)
+
+	// невалидный и пустой заказы не доходят до БД
+	invalid := toProto(order)
+	invalid.Delivery.Email = "not-an-email"
+	_, err = client.CreateOrder(ctx, &orderv1.CreateOrderRequest{Order: invalid})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	assert.Contains(t, status.Convert(err).Message(), "невалидные данные заказа")
+	_, err = client.CreateOrder(ctx, &orderv1.CreateOrderRequest{})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	require.Len(t, sub.C, 1)
+	assert.Equal(t, order.OrderUID, (<-sub.C).OrderUID)
+	drainAudit(h.Audit)
+	require.Len(t, store.events, 1)
+	assert.Equal(t, audit.ActionWrite, store.events[0].Action)
+}
+
+func TestCreateOrder_DatabaseError(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mockDB := mocks.NewMockDatabase(ctrl)
+	mockDB.EXPECT().SaveOrder(gomock.Any(), gomock.Any()).Return(false, errors.New("db down"))
+
+	client := startServer(t, newTestHandler(mocks.NewMockCache(ctrl), mockDB), nil)
+	_, err := client.CreateOrder(context.Background(), &orderv1.CreateOrderRequest{Order: toProto(validTestOrder())})
+	assert.Equal(t, codes.Internal, status.Code(err))
+}
+
+func TestWatchOrders(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	h := newTestHandler(mocks.NewMockCache(ctrl), mocks.NewMockDatabase(ctrl))
+	h.Hub = pubsub.NewHub()
+	client := startServer(t, h, nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	stream, err := client.WatchOrders(ctx, &orderv1.WatchOrdersRequest{DeliveryService: "meest"})
+	require.NoError(t, err)
+
+	// подписка оформляется на сервере уже после открытия потока: пробный заказ
+	// публикуется, пока не дойдёт до клиента
+	probing := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(10 * time.Millisecond)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-probing:
+				return
+			case <-ticker.C:
+				h.Hub.Publish(&models.Order{OrderUID: "probe", DeliveryService: "meest"})
+			}
+		}
+	}()
+	got, err := stream.Recv()
+	close(probing)
+	require.NoError(t, err)
+	assert.Equal(t, "probe", got.OrderUid)
+
+	h.Hub.Publish(&models.Order{OrderUID: "other", DeliveryService: "dhl"})
+	h.Hub.Publish(&models.Order{OrderUID: "wanted", DeliveryService: "meest"})
+
+	// до wanted могут прийти оставшиеся пробные, но не заказ другой службы доставки
+	for got.OrderUid != "wanted" {
+		got, err = stream.Recv()
+		require.NoError(t, err)
+		assert.Equal(t, "meest", got.DeliveryService)
+	}
+}
+
+func TestWatchOrders_HubDisabled(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	client := startServer(t, newTestHandler(mocks.NewMockCache(ctrl), mocks.NewMockDatabase(ctrl)), nil)
+
+	stream, err := client.WatchOrders(context.Background(), &orderv1.WatchOrdersRequest{})
+	require.NoError(t, err)
+	_, err = stream.Recv()
+	assert.Equal(t, codes.Unimplemented, status.Code(err))
+}
+
+type fakeKeyStore map[string]*auth.APIKey
+
+func (f fakeKeyStore) LookupAPIKey(ctx context.Context, keyHash string) (*auth.APIKey, error) {
+	if key, ok := f[keyHash]; ok {
+		return key, nil
+	}
+	return nil, sql.ErrNoRows
+}
+
+func TestAuth(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mockCache := mocks.NewMockCache(ctrl)
+	order := validTestOrder()
+	mockCache.EXPECT().GetOrLoad(order.OrderUID, gomock.Any()).Return(order, nil).Times(2)
+
+	authn, err := auth.New(config.AuthConfig{Enabled: true}, fakeKeyStore{
+		auth.HashAPIKey("osk_reader"):  {Name: "reader", Scopes: []string{auth.ScopeOrdersRead}},
+		auth.HashAPIKey("osk_support"): {Name: "support", Scopes: []string{auth.ScopeOrdersRead, auth.ScopePIIRead}},
+	})
+	require.NoError(t, err)
+	client := startServer(t, newTestHandler(mockCache, mocks.NewMockDatabase(ctrl)), authn)
+
+	withKey := func(header, value string) context.Context {
+		return metadata.AppendToOutgoingContext(context.Background(), header, value)
+	}
+	req := &orderv1.GetOrderRequest{OrderUid: order.OrderUID}
+
+	_, err = client.GetOrder(context.Background(), req)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	_, err = client.GetOrder(withKey("x-api-key", "osk_unknown"), req)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	// без pii:read персональные данные скрыты, как в HTTP ответе
+	got, err := client.GetOrder(withKey("x-api-key", "osk_reader"), req)
+	require.NoError(t, err)
+	assert.NotEqual(t, order.Delivery.Phone, got.Delivery.Phone)
+	assert.Equal(t, order.Delivery.City, got.Delivery.City)
+
+	got, err = client.GetOrder(withKey("authorization", "Bearer osk_support"), req)
+	require.NoError(t, err)
+	assert.Equal(t, order.Delivery.Phone, got.Delivery.Phone)
+
+	// приём заказов требует orders:write
+	_, err = client.CreateOrder(withKey("x-api-key", "osk_reader"), &orderv1.CreateOrderRequest{Order: toProto(order)})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	// поток проверяется так же
+	stream, err := client.WatchOrders(context.Background(), &orderv1.WatchOrdersRequest{})
+	require.NoError(t, err)
+	_, err = stream.Recv()
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+}
+
+func TestRateLimit(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mockCache := mocks.NewMockCache(ctrl)
+	order := validTestOrder()
+	mockCache.EXPECT().GetOrLoad(order.OrderUID, gomock.Any()).Return(order, nil).Times(2)
+
+	limiter := middleware.NewRateLimiter(config.RateLimitConfig{
+		Enabled: true,
+		Default: config.RateLimit{RPS: 0.001, Burst: 5},
+		Routes: map[string]config.RateLimit{
+			orderv1.OrderService_GetOrder_FullMethodName: {RPS: 0.001, Burst: 2},
+		},
+	})
+	client := serve(t, New(NewServer(newTestHandler(mockCache, mocks.NewMockDatabase(ctrl))), nil, limiter))
+	req := &orderv1.GetOrderRequest{OrderUid: order.OrderUID}
+
+	for i := 0; i < 2; i++ {
+		_, err := client.GetOrder(context.Background(), req)
+		require.NoError(t, err)
+	}
+	var header metadata.MD
+	_, err := client.GetOrder(context.Background(), req, grpc.Header(&header))
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+	assert.NotEmpty(t, header.Get("retry-after"))
+
+	// у другого метода своя корзина
+	stream, err := client.WatchOrders(context.Background(), &orderv1.WatchOrdersRequest{})
+	require.NoError(t, err)
+	_, err = stream.Recv()
+	assert.NotEqual(t, codes.ResourceExhausted, status.Code(err))
+}
+
+func TestRateLimit_AuthFailures(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mockCache := mocks.NewMockCache(ctrl)
+	order := validTestOrder()
+	mockCache.EXPECT().GetOrLoad(order.OrderUID, gomock.Any()).Return(order, nil)
+
+	authn, err := auth.New(config.AuthConfig{Enabled: true}, fakeKeyStore{
+		auth.HashAPIKey("osk_reader"): {Name: "reader", Scopes: []string{auth.ScopeOrdersRead}},
+	})
+	require.NoError(t, err)
+	limiter := middleware.NewRateLimiter(config.RateLimitConfig{
+		Enabled:      true,
+		Default:      config.RateLimit{RPS: 10, Burst: 20},
+		AuthFailures: config.RateLimit{RPS: 0.001, Burst: 2},
+	})
+	client := serve(t, New(NewServer(newTestHandler(mockCache, mocks.NewMockDatabase(ctrl))), authn, limiter))
+	withKey := func(key string) context.Context {
+		return metadata.AppendToOutgoingContext(context.Background(), "x-api-key", key)
+	}
+	req := &orderv1.GetOrderRequest{OrderUid: order.OrderUID}
+
+	// успешный вызов корзину неудач не тратит
+	_, err = client.GetOrder(withKey("osk_reader"), req)
+	require.NoError(t, err)
+	for i := 0; i < 2; i++ {
+		_, err = client.GetOrder(withKey("osk_guess"), req)
+		assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	}
+
+	// после burst неудач IP не доходит до проверки ключа, даже верного
+	var header metadata.MD
+	_, err = client.GetOrder(withKey("osk_reader"), req, grpc.Header(&header))
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+	assert.NotEmpty(t, header.Get("retry-after"))
+}
+
+// drainAudit дописывает события из буфера журнала в хранилище
+func drainAudit(l *audit.Logger) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	l.Run(ctx)
+}
@@ -0,0 +1,67 @@
+// internal/handlers/audit.go
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"order-service/internal/audit"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+)
+
+const (
+	defaultAuditLimit = 100
+	maxAuditLimit     = 1000
+)
+
+// AuditPage ответ GET /admin/audit
+type AuditPage struct {
+	OrderUID string        `json:"order_uid"`
+	Events   []audit.Event `json:"events"`
+}
+
+// AuditHandler журнал обращений к заказу, новые события первыми.
+// Последние события могут появиться с задержкой до audit.flush_interval
+func (h *Handler) AuditHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, span := h.Tracer.Start(r.Context(), "http.get_audit")
+	defer span.End()
+
+	if h.Audit == nil {
+		errMsg := "журнал аудита выключен"
+		http.Error(w, errMsg, http.StatusNotFound)
+		span.SetStatus(codes.Error, errMsg)
+		return
+	}
+
+	orderUID := r.URL.Query().Get("order_uid")
+	if orderUID == "" {
+		errMsg := "не задан order_uid"
+		http.Error(w, errMsg, http.StatusBadRequest)
+		span.SetStatus(codes.Error, errMsg)
+		return
+	}
+	limit, err := queryInt(r, "limit", defaultAuditLimit)
+	if err != nil || limit < 1 || limit > maxAuditLimit {
+		errMsg := "limit должен быть от 1 до " + strconv.Itoa(maxAuditLimit)
+		http.Error(w, errMsg, http.StatusBadRequest)
+		span.SetStatus(codes.Error, errMsg)
+		return
+	}
+	span.SetAttributes(attribute.String("order.uid", orderUID), attribute.Int("page.limit", limit))
+
+	events, err := h.Audit.Query(ctx, orderUID, limit)
+	if err != nil {
+		span.RecordError(err)
+		errMsg := "внутренняя ошибка сервера DB error"
+		http.Error(w, errMsg, http.StatusInternalServerError)
+		span.SetStatus(codes.Error, errMsg)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(AuditPage{OrderUID: orderUID, Events: events})
+	span.SetStatus(codes.Ok, "журнал аудита получен")
+}
@@ -0,0 +1,180 @@
+// internal/handlers/audit_test.go
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"order-service/internal/audit"
+	"order-service/internal/mocks"
+	"order-service/models"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memAuditStore журнал в памяти, ListAuditEvents отдаёт события в обратном порядке, как БД
+type memAuditStore struct {
+	mu     sync.Mutex
+	events []audit.Event
+}
+
+func (s *memAuditStore) InsertAuditEvents(ctx context.Context, events []audit.Event) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.events = append(s.events, events...)
+	return nil
+}
+
+func (s *memAuditStore) ListAuditEvents(ctx context.Context, orderUID string, limit int) ([]audit.Event, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make([]audit.Event, 0)
+	for i := len(s.events) - 1; i >= 0 && len(out) < limit; i-- {
+		if s.events[i].OrderUID == orderUID {
+			out = append(out, s.events[i])
+		}
+	}
+	return out, nil
+}
+
+// flushAudit дописывает события из буфера журнала в хранилище
+func flushAudit(l *audit.Logger) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	l.Run(ctx)
+}
+
+func TestOrderHandler_RecordsAudit(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mockDB := mocks.NewMockDatabase(ctrl)
+	mockCache := mocks.NewMockCache(ctrl)
+
+	mockCache.EXPECT().GetOrLoad("test123", gomock.Any()).Return(&models.Order{OrderUID: "test123"}, nil)
+	passThroughLoad(mockCache, "notfound")
+	mockDB.EXPECT().GetOrder(gomock.Any(), "notfound").Return(nil, sql.ErrNoRows)
+
+	store := &memAuditStore{}
+	handler := createTestHandler(mockCache, mockDB)
+	handler.Audit = audit.New(store, 10, time.Hour)
+
+	req := orderRequest("test123")
+	req.RemoteAddr = "192.0.2.1:1234"
+	handler.OrderHandler(httptest.NewRecorder(), req)
+	handler.OrderHandler(httptest.NewRecorder(), orderRequest("notfound"))
+	flushAudit(handler.Audit)
+
+	require.Len(t, store.events, 1, "пишутся только выданные заказы")
+	assert.Equal(t, audit.ActionRead, store.events[0].Action)
+	assert.Equal(t, "test123", store.events[0].OrderUID)
+	assert.Equal(t, audit.ActorAnonymous, store.events[0].Actor)
+	assert.Equal(t, "192.0.2.1", store.events[0].SourceIP)
+}
+
+func TestListOrdersHandler_RecordsAudit(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mockDB := mocks.NewMockDatabase(ctrl)
+
+	orders := []*models.Order{{OrderUID: "a"}, {OrderUID: "b"}}
+	mockDB.EXPECT().ListOrders(gomock.Any(), 20, 0).Return(orders, nil).Times(2)
+
+	store := &memAuditStore{}
+	handler := createTestHandler(mocks.NewMockCache(ctrl), mockDB)
+	handler.Audit = audit.New(store, 10, time.Hour)
+
+	handler.ListOrdersHandler(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/api/v1/orders", nil))
+	// выгрузка в CSV — тоже чтение
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/orders", nil)
+	req.Header.Set("Accept", "text/csv")
+	handler.ListOrdersHandler(httptest.NewRecorder(), req)
+	flushAudit(handler.Audit)
+
+	require.Len(t, store.events, 4)
+	for i, uid := range []string{"a", "b", "a", "b"} {
+		assert.Equal(t, audit.ActionRead, store.events[i].Action)
+		assert.Equal(t, uid, store.events[i].OrderUID)
+	}
+}
+
+func TestCreateOrderHandler_RecordsAudit(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mockDB := mocks.NewMockDatabase(ctrl)
+	mockCache := mocks.NewMockCache(ctrl)
+
+	order := validTestOrder()
+	mockDB.EXPECT().SaveOrder(gomock.Any(), gomock.Any()).Return(false, nil)
+	mockCache.EXPECT().Set(order.OrderUID, gomock.Any())
+
+	store := &memAuditStore{}
+	handler := createTestHandler(mockCache, mockDB)
+	handler.Audit = audit.New(store, 10, time.Hour)
+
+	body, _ := json.Marshal(order)
+	require.Equal(t, http.StatusCreated, postOrder(t, handler, body).Code)
+	// невалидный заказ не сохраняется и в журнал не попадает
+	postOrder(t, handler, []byte(`{"order_uid":"bad"}`))
+	flushAudit(handler.Audit)
+
+	require.Len(t, store.events, 1)
+	assert.Equal(t, audit.ActionWrite, store.events[0].Action)
+	assert.Equal(t, order.OrderUID, store.events[0].OrderUID)
+}
+
+func TestAuditHandler(t *testing.T) {
+	store := &memAuditStore{}
+	handler := createTestHandler(nil, nil)
+	handler.Audit = audit.New(store, 10, time.Hour)
+
+	at := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	store.events = []audit.Event{
+		{ID: 1, Actor: "kafka", Action: audit.ActionWrite, OrderUID: "abc", At: at},
+		{ID: 2, Actor: "api_key:support", Action: audit.ActionRead, OrderUID: "abc", At: at.Add(time.Minute), SourceIP: "192.0.2.1"},
+		{ID: 3, Actor: "kafka", Action: audit.ActionWrite, OrderUID: "other", At: at},
+	}
+
+	w := httptest.NewRecorder()
+	handler.AuditHandler(w, httptest.NewRequest(http.MethodGet, "/admin/audit?order_uid=abc", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var page AuditPage
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&page))
+	assert.Equal(t, "abc", page.OrderUID)
+	require.Len(t, page.Events, 2)
+	assert.Equal(t, int64(2), page.Events[0].ID)
+	assert.Equal(t, "api_key:support", page.Events[0].Actor)
+	assert.Equal(t, int64(1), page.Events[1].ID)
+
+	w = httptest.NewRecorder()
+	handler.AuditHandler(w, httptest.NewRequest(http.MethodGet, "/admin/audit?order_uid=abc&limit=1", nil))
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&page))
+	assert.Len(t, page.Events, 1)
+
+	// нет событий — пустой массив, а не null
+	w = httptest.NewRecorder()
+	handler.AuditHandler(w, httptest.NewRequest(http.MethodGet, "/admin/audit?order_uid=none", nil))
+	assert.JSONEq(t, `{"order_uid":"none","events":[]}`, w.Body.String())
+}
+
+func TestAuditHandler_BadRequest(t *testing.T) {
+	handler := createTestHandler(nil, nil)
+	handler.Audit = audit.New(&memAuditStore{}, 10, time.Hour)
+
+	for _, target := range []string{"/admin/audit", "/admin/audit?order_uid=abc&limit=0", "/admin/audit?order_uid=abc&limit=5000"} {
+		w := httptest.NewRecorder()
+		handler.AuditHandler(w, httptest.NewRequest(http.MethodGet, target, nil))
+		assert.Equal(t, http.StatusBadRequest, w.Code, target)
+	}
+}
+
+func TestAuditHandler_Disabled(t *testing.T) {
+	w := httptest.NewRecorder()
+	createTestHandler(nil, nil).AuditHandler(w, httptest.NewRequest(http.MethodGet, "/admin/audit?order_uid=abc", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
@@ -0,0 +1,34 @@
+// internal/handlers/conditional.go
+package handlers
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"time"
+)
+
+// orderCacheControl заказ содержит персональные данные, поэтому только private.
+// no-cache: клиент хранит копию, но перед использованием сверяет ETag — заказ может быть перезаписан upsert'ом
+const orderCacheControl = "private, no-cache"
+
+// orderETag слабый ETag от содержимого JSON: одинаков для gzip/br/несжатого представления
+func orderETag(body []byte) string {
+	sum := sha256.Sum256(body)
+	return `W/"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// writeCacheable отдаёт JSON с ETag и Cache-Control.
+// If-None-Match и HEAD обрабатывает http.ServeContent: при совпадении 304 без тела.
+// Last-Modified не отдаётся: date_created не меняется при upsert'е, и клиент с одним
+// If-Modified-Since получил бы 304 на перезаписанный заказ. Свежесть определяет только ETag
+func writeCacheable(w http.ResponseWriter, r *http.Request, body []byte) {
+	h := w.Header()
+	h.Set("Content-Type", "application/json")
+	h.Set("ETag", orderETag(body))
+	h.Set("Cache-Control", orderCacheControl)
+
+	// нулевое время: ServeContent не ставит Last-Modified и не смотрит If-Modified-Since
+	http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(body))
+}
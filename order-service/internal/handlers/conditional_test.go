@@ -0,0 +1,95 @@
+// internal/handlers/conditional_test.go
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"order-service/internal/mocks"
+	"order-service/models"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func serveCachedOrder(t *testing.T, order *models.Order, header http.Header) *httptest.ResponseRecorder {
+	ctrl := gomock.NewController(t)
+	mockCache := mocks.NewMockCache(ctrl)
+	mockCache.EXPECT().GetOrLoad(order.OrderUID, gomock.Any()).Return(order, nil)
+
+	req := orderRequest(order.OrderUID)
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	w := httptest.NewRecorder()
+	createTestHandler(mockCache, mocks.NewMockDatabase(ctrl)).OrderHandler(w, req)
+	return w
+}
+
+func TestOrderHandler_CacheHeaders(t *testing.T) {
+	order := validTestOrder()
+	w := serveCachedOrder(t, order, nil)
+
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+	assert.Equal(t, orderCacheControl, w.Header().Get("Cache-Control"))
+	assert.Empty(t, w.Header().Get("Last-Modified"))
+	assert.Regexp(t, `^W/"[0-9a-f]{32}"$`, w.Header().Get("ETag"))
+
+	// ETag стабилен для одного и того же содержимого
+	again := serveCachedOrder(t, order, nil)
+	assert.Equal(t, w.Header().Get("ETag"), again.Header().Get("ETag"))
+	assert.Equal(t, w.Body.String(), again.Body.String())
+
+	changed := validTestOrder()
+	changed.DateCreated = order.DateCreated
+	changed.Payment.PaymentDt = order.Payment.PaymentDt
+	changed.TrackNumber = "WBILMCHANGED"
+	assert.NotEqual(t, w.Header().Get("ETag"), serveCachedOrder(t, changed, nil).Header().Get("ETag"))
+}
+
+func TestOrderHandler_IfNoneMatch(t *testing.T) {
+	order := validTestOrder()
+	etag := serveCachedOrder(t, order, nil).Header().Get("ETag")
+
+	w := serveCachedOrder(t, order, http.Header{"If-None-Match": {etag}})
+	assert.Equal(t, http.StatusNotModified, w.Code)
+	assert.Empty(t, w.Body.String())
+	assert.Equal(t, etag, w.Header().Get("ETag"))
+
+	w = serveCachedOrder(t, order, http.Header{"If-None-Match": {`W/"stale", ` + etag}})
+	assert.Equal(t, http.StatusNotModified, w.Code)
+
+	w = serveCachedOrder(t, order, http.Header{"If-None-Match": {`W/"stale"`}})
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), order.OrderUID)
+}
+
+func TestOrderHandler_IfModifiedSinceIgnored(t *testing.T) {
+	order := validTestOrder()
+	since := http.Header{"If-Modified-Since": {time.Now().UTC().Format(http.TimeFormat)}}
+
+	w := serveCachedOrder(t, order, since)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), order.OrderUID)
+
+	// заказ перезаписан upsert'ом с тем же date_created: клиент с одним If-Modified-Since получает новое тело
+	resaved := validTestOrder()
+	resaved.DateCreated = order.DateCreated
+	resaved.Payment.PaymentDt = order.Payment.PaymentDt
+	resaved.Items[0].Status = 303
+	w = serveCachedOrder(t, resaved, since)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"status":303`)
+
+	// If-None-Match по-прежнему даёт 304
+	etag := w.Header().Get("ETag")
+	w = serveCachedOrder(t, resaved, http.Header{
+		"If-None-Match":     {etag},
+		"If-Modified-Since": since["If-Modified-Since"],
+	})
+	assert.Equal(t, http.StatusNotModified, w.Code)
+}
@@ -2,13 +2,22 @@
 package handlers
 
 import (
+	"bytes"
+	"context"
 	"database/sql"
 	"encoding/json"
 	"errors"
-	"log"
+	"log/slog"
 	"net/http"
+	"order-service/internal/audit"
+	"order-service/internal/config"
 	"order-service/internal/interfaces"
-	"strings"
+	"order-service/internal/logging"
+	"order-service/internal/pubsub"
+	"order-service/internal/webhook"
+	"order-service/models"
+	"path/filepath"
+	"time"
 
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 
@@ -17,10 +26,30 @@ import (
 	"go.opentelemetry.io/otel/trace"
 )
 
+// orderLoadTimeout предел загрузки заказа из БД при промахе кэша. Загрузка общая для всех
+// ждущих этот uid, поэтому ограничена своим таймаутом, а не контекстом одного из запросов
+const orderLoadTimeout = 5 * time.Second
+
 type Handler struct {
 	Cache  interfaces.Cache
 	DB     interfaces.Database
 	Tracer trace.Tracer
+	// Access необязательный учёт обращений для стратегии прогрева frequent
+	Access interfaces.AccessRecorder
+	// Audit журнал чтений и записей заказов; nil — журнал выключен
+	Audit *audit.Logger
+	// Hub подписчики на новые заказы; nil — рассылки нет
+	Hub *pubsub.Hub
+	// Webhooks рассылка сохранённых заказов партнёрам; nil — вебхуки выключены
+	Webhooks *webhook.Dispatcher
+	// GraphQL эндпоинт /graphql; nil — маршрут не регистрируется
+	GraphQL http.Handler
+	// Stream буфер, пульс и таймаут записи потоков новых заказов
+	Stream config.StreamConfig
+	// WebDir каталог веб-интерфейса, по умолчанию web
+	WebDir string
+	// SwaggerUIDir каталог файлов Swagger UI, по умолчанию web/swagger-ui
+	SwaggerUIDir string
 }
 
 func NewHandler(c interfaces.Cache, db interfaces.Database, tracer trace.Tracer) *Handler {
@@ -28,57 +57,85 @@ func NewHandler(c interfaces.Cache, db interfaces.Database, tracer trace.Tracer)
 		Cache:  c,
 		DB:     db,
 		Tracer: tracer,
+		Stream: config.Default().Stream,
 	}
 }
 
 func (h *Handler) OrderHandler(w http.ResponseWriter, r *http.Request) {
-	_, span := h.Tracer.Start(r.Context(), "http.get_order")
+	ctx, span := h.Tracer.Start(r.Context(), "http.get_order")
 	defer span.End()
 
-	parts := strings.Split(r.URL.Path, "/")
-	if len(parts) != 3 {
+	// uid из шаблона маршрута GET /api/v1/orders/{uid} или GET /order/{uid}
+	orderUID := r.PathValue("uid")
+	if orderUID == "" {
 		errMsg := "Плохой запрос"
 		http.Error(w, errMsg, http.StatusBadRequest)
 		span.SetStatus(codes.Error, errMsg)
 		return
 	}
-	orderUID := parts[2]
 	span.SetAttributes(attribute.String("order.uid", orderUID))
-	log.Printf("Поиск заказа: %s", orderUID)
+	ctx = logging.WithOrderUID(ctx, orderUID)
+	slog.DebugContext(ctx, "Поиск заказа")
 
-	order, ok := h.Cache.Get(orderUID)
-	if !ok {
-		dbOrder, err := h.DB.GetOrder(orderUID)
-		if err != nil {
-			span.RecordError(err)
-			if errors.Is(err, sql.ErrNoRows) {
-				errMsg := "заказ не найден"
-				http.Error(w, errMsg, http.StatusNotFound)
-				span.SetStatus(codes.Error, errMsg)
-			} else {
-				errMsg := "внутренняя ошибка сервера DB error"
-				http.Error(w, errMsg, http.StatusInternalServerError)
-				span.SetStatus(codes.Error, errMsg)
-			}
-			return
+	// промах кэша загружается из БД, параллельные запросы одного uid схлопываются
+	order, err := LoadOrder(ctx, h.Cache, h.DB, orderUID)
+	if err != nil {
+		span.RecordError(err)
+		if errors.Is(err, sql.ErrNoRows) {
+			errMsg := "заказ не найден"
+			http.Error(w, errMsg, http.StatusNotFound)
+			span.SetStatus(codes.Error, errMsg)
+		} else {
+			errMsg := "внутренняя ошибка сервера DB error"
+			http.Error(w, errMsg, http.StatusInternalServerError)
+			span.SetStatus(codes.Error, errMsg)
 		}
-		order = dbOrder
-		h.Cache.Set(orderUID, dbOrder)
-	} else {
-		log.Printf("Заказ %s найден в кэше", orderUID)
+		return
 	}
+	if h.Access != nil {
+		h.Access.RecordAccess(orderUID)
+	}
+	h.Audit.Record(ctx, audit.ActionRead, orderUID, r)
+
+	// без pii:read персональные данные скрыты, ETag считается уже от того, что видит клиент
+	order = presentOrders(w, r, order)[0]
 
-	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(order); err != nil {
+	var body bytes.Buffer
+	if err := json.NewEncoder(&body).Encode(order); err != nil {
 		span.RecordError(err)
-		span.SetStatus(codes.Error, "ошибка распарсивания JSON")
+		errMsg := "ошибка распарсивания JSON"
+		http.Error(w, errMsg, http.StatusInternalServerError)
+		span.SetStatus(codes.Error, errMsg)
 		return
 	}
+	writeCacheable(w, r, body.Bytes())
 	span.SetStatus(codes.Ok, "заказ получен")
 }
 
+// LoadOrder заказ из кэша, при промахе — из БД; параллельные промахи одного uid схлопываются.
+// Отмена запроса, начавшего загрузку, не обрывает её для остальных ждущих: БД читается
+// с контекстом без отмены и с таймаутом orderLoadTimeout. Если общая загрузка всё же
+// завершилась ошибкой контекста, а наш запрос жив, загрузка повторяется один раз
+func LoadOrder(ctx context.Context, c interfaces.Cache, db interfaces.Database, orderUID string) (*models.Order, error) {
+	load := func(uid string) (*models.Order, error) {
+		loadCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), orderLoadTimeout)
+		defer cancel()
+		return db.GetOrder(loadCtx, uid)
+	}
+	order, err := c.GetOrLoad(orderUID, load)
+	if err != nil && ctx.Err() == nil && (errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)) {
+		slog.DebugContext(ctx, "Общая загрузка заказа прервана, повтор", logging.Err(err))
+		order, err = c.GetOrLoad(orderUID, load)
+	}
+	return order, err
+}
+
 func (h *Handler) WebInterfaceHandler(w http.ResponseWriter, r *http.Request) {
-	http.ServeFile(w, r, "./web/index.html")
+	dir := h.WebDir
+	if dir == "" {
+		dir = "web"
+	}
+	http.ServeFile(w, r, filepath.Join(dir, "index.html"))
 }
 
 var promHandler = promhttp.Handler()
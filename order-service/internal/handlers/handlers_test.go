@@ -2,11 +2,13 @@
 package handlers
 
 import (
+	"context"
 	"database/sql"
 	"encoding/json"
 	"errors"
 	"net/http"
 	"net/http/httptest"
+	"order-service/internal/interfaces"
 	"order-service/internal/mocks"
 	"order-service/models"
 	"os"
@@ -26,6 +28,21 @@ func createTestHandler(mockCache *mocks.MockCache, mockDB *mocks.MockDatabase) *
 	return NewHandler(mockCache, mockDB, tracer)
 }
 
+// orderRequest запрос с uid, как его заполняет ServeMux по шаблону /order/{uid}
+func orderRequest(orderUID string) *http.Request {
+	req := httptest.NewRequest("GET", "/order/"+orderUID, nil)
+	req.SetPathValue("uid", orderUID)
+	return req
+}
+
+// passThroughLoad настраивает мок кэша так, чтобы GetOrLoad вызывал переданный загрузчик
+func passThroughLoad(mockCache *mocks.MockCache, orderUID string) {
+	mockCache.EXPECT().GetOrLoad(orderUID, gomock.Any()).DoAndReturn(
+		func(uid string, load interfaces.LoadFunc) (*models.Order, error) {
+			return load(uid)
+		})
+}
+
 func TestOrderHandler_OrderFoundInCache(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
@@ -38,11 +55,11 @@ func TestOrderHandler_OrderFoundInCache(t *testing.T) {
 		TrackNumber: "WBILMTESTTRACK",
 	}
 
-	mockCache.EXPECT().Get("test123").Return(expectedOrder, true)
+	mockCache.EXPECT().GetOrLoad("test123", gomock.Any()).Return(expectedOrder, nil)
 
 	handler := createTestHandler(mockCache, mockDB)
 
-	req := httptest.NewRequest("GET", "/order/test123", nil)
+	req := orderRequest("test123")
 	w := httptest.NewRecorder()
 
 	handler.OrderHandler(w, req)
@@ -55,6 +72,33 @@ func TestOrderHandler_OrderFoundInCache(t *testing.T) {
 	assert.Equal(t, "test123", response.OrderUID)
 }
 
+type recordedAccess []string
+
+func (r *recordedAccess) RecordAccess(orderUID string) {
+	*r = append(*r, orderUID)
+}
+
+func TestOrderHandler_RecordsAccess(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockDB := mocks.NewMockDatabase(ctrl)
+	mockCache := mocks.NewMockCache(ctrl)
+
+	mockCache.EXPECT().GetOrLoad("test123", gomock.Any()).Return(&models.Order{OrderUID: "test123"}, nil)
+	passThroughLoad(mockCache, "notfound")
+	mockDB.EXPECT().GetOrder(gomock.Any(), "notfound").Return(nil, sql.ErrNoRows)
+
+	handler := createTestHandler(mockCache, mockDB)
+	var access recordedAccess
+	handler.Access = &access
+
+	handler.OrderHandler(httptest.NewRecorder(), orderRequest("test123"))
+	handler.OrderHandler(httptest.NewRecorder(), orderRequest("notfound"))
+
+	assert.Equal(t, recordedAccess{"test123"}, access, "учитываются только найденные заказы")
+}
+
 func TestOrderHandler_OrderNotFoundInCacheButFoundInDB(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
@@ -68,13 +112,12 @@ func TestOrderHandler_OrderNotFoundInCacheButFoundInDB(t *testing.T) {
 		DateCreated: time.Now(),
 	}
 
-	mockCache.EXPECT().Get("test123").Return(nil, false)
-	mockDB.EXPECT().GetOrder("test123").Return(expectedOrder, nil)
-	mockCache.EXPECT().Set("test123", expectedOrder)
+	passThroughLoad(mockCache, "test123")
+	mockDB.EXPECT().GetOrder(gomock.Any(), "test123").Return(expectedOrder, nil)
 
 	handler := createTestHandler(mockCache, mockDB)
 
-	req := httptest.NewRequest("GET", "/order/test123", nil)
+	req := orderRequest("test123")
 	w := httptest.NewRecorder()
 
 	handler.OrderHandler(w, req)
@@ -90,12 +133,12 @@ func TestOrderHandler_OrderNotFound(t *testing.T) {
 	mockDB := mocks.NewMockDatabase(ctrl)
 	mockCache := mocks.NewMockCache(ctrl)
 
-	mockCache.EXPECT().Get("notfound").Return(nil, false)
-	mockDB.EXPECT().GetOrder("notfound").Return(nil, sql.ErrNoRows)
+	passThroughLoad(mockCache, "notfound")
+	mockDB.EXPECT().GetOrder(gomock.Any(), "notfound").Return(nil, sql.ErrNoRows)
 
 	handler := createTestHandler(mockCache, mockDB)
 
-	req := httptest.NewRequest("GET", "/order/notfound", nil)
+	req := orderRequest("notfound")
 	w := httptest.NewRecorder()
 
 	handler.OrderHandler(w, req)
@@ -110,12 +153,12 @@ func TestOrderHandler_DatabaseError(t *testing.T) {
 	mockDB := mocks.NewMockDatabase(ctrl)
 	mockCache := mocks.NewMockCache(ctrl)
 
-	mockCache.EXPECT().Get("test123").Return(nil, false)
-	mockDB.EXPECT().GetOrder("test123").Return(nil, errors.New("db connection failed"))
+	passThroughLoad(mockCache, "test123")
+	mockDB.EXPECT().GetOrder(gomock.Any(), "test123").Return(nil, errors.New("db connection failed"))
 
 	handler := createTestHandler(mockCache, mockDB)
 
-	req := httptest.NewRequest("GET", "/order/test123", nil)
+	req := orderRequest("test123")
 	w := httptest.NewRecorder()
 
 	handler.OrderHandler(w, req)
@@ -140,6 +183,47 @@ func TestOrderHandler_BadRequest(t *testing.T) {
 	assert.Equal(t, http.StatusBadRequest, w.Code)
 }
 
+func TestLoadOrder_DetachedFromCallerCancel(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockDB := mocks.NewMockDatabase(ctrl)
+	mockCache := mocks.NewMockCache(ctrl)
+
+	passThroughLoad(mockCache, "test123")
+	mockDB.EXPECT().GetOrder(gomock.Any(), "test123").DoAndReturn(
+		func(ctx context.Context, uid string) (*models.Order, error) {
+			// загрузка общая для всех ждущих uid: отмена начавшего её запроса не должна её обрывать
+			assert.NoError(t, ctx.Err())
+			_, hasDeadline := ctx.Deadline()
+			assert.True(t, hasDeadline)
+			return &models.Order{OrderUID: uid}, nil
+		})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	order, err := LoadOrder(ctx, mockCache, mockDB, "test123")
+	require.NoError(t, err)
+	assert.Equal(t, "test123", order.OrderUID)
+}
+
+func TestLoadOrder_RetriesSharedContextError(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockDB := mocks.NewMockDatabase(ctrl)
+	mockCache := mocks.NewMockCache(ctrl)
+
+	// загрузку начал другой запрос и она оборвалась по его контексту
+	mockCache.EXPECT().GetOrLoad("test123", gomock.Any()).Return(nil, context.Canceled)
+	passThroughLoad(mockCache, "test123")
+	mockDB.EXPECT().GetOrder(gomock.Any(), "test123").Return(&models.Order{OrderUID: "test123"}, nil)
+
+	order, err := LoadOrder(context.Background(), mockCache, mockDB, "test123")
+	require.NoError(t, err)
+	assert.Equal(t, "test123", order.OrderUID)
+}
+
 func TestWebInterfaceHandler(t *testing.T) {
 	handler := &Handler{}
 
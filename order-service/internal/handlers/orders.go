@@ -0,0 +1,147 @@
+// internal/handlers/orders.go
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"order-service/internal/audit"
+	"order-service/internal/logging"
+	"order-service/internal/metrics"
+	"order-service/internal/validation"
+	"order-service/models"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+)
+
+const (
+	defaultPageLimit = 20
+	maxPageLimit     = 100
+	maxOrderBodySize = 1 << 20
+)
+
+// OrdersPage ответ GET /api/v1/orders
+type OrdersPage struct {
+	Orders []*models.Order `json:"orders"`
+	Limit  int             `json:"limit"`
+	Offset int             `json:"offset"`
+}
+
+// ListOrdersHandler постраничный список заказов, новые первыми
+func (h *Handler) ListOrdersHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, span := h.Tracer.Start(r.Context(), "http.list_orders")
+	defer span.End()
+
+	limit, err := queryInt(r, "limit", defaultPageLimit)
+	if err != nil || limit < 1 || limit > maxPageLimit {
+		errMsg := "limit должен быть от 1 до " + strconv.Itoa(maxPageLimit)
+		http.Error(w, errMsg, http.StatusBadRequest)
+		span.SetStatus(codes.Error, errMsg)
+		return
+	}
+	offset, err := queryInt(r, "offset", 0)
+	if err != nil || offset < 0 {
+		errMsg := "offset должен быть неотрицательным числом"
+		http.Error(w, errMsg, http.StatusBadRequest)
+		span.SetStatus(codes.Error, errMsg)
+		return
+	}
+	span.SetAttributes(attribute.Int("page.limit", limit), attribute.Int("page.offset", offset))
+
+	orders, err := h.DB.ListOrders(ctx, limit, offset)
+	if err != nil {
+		span.RecordError(err)
+		errMsg := "внутренняя ошибка сервера DB error"
+		http.Error(w, errMsg, http.StatusInternalServerError)
+		span.SetStatus(codes.Error, errMsg)
+		return
+	}
+
+	// каждый выданный заказ попадает в журнал, как при чтении по uid
+	for _, o := range orders {
+		h.Audit.Record(ctx, audit.ActionRead, o.OrderUID, r)
+	}
+	orders = presentOrders(w, r, orders...)
+	if acceptsCSV(r) {
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		w.Header().Set("Content-Disposition", `attachment; filename="orders.csv"`)
+		if err := writeOrdersCSV(w, orders); err != nil {
+			span.RecordError(err)
+			slog.WarnContext(ctx, "Ошибка записи CSV", logging.Err(err))
+		}
+		span.SetStatus(codes.Ok, "список заказов выгружен в CSV")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(OrdersPage{Orders: orders, Limit: limit, Offset: offset})
+	span.SetStatus(codes.Ok, "список заказов получен")
+}
+
+// CreateOrderHandler приём заказа через API, как и из Kafka: валидация, БД, кэш
+func (h *Handler) CreateOrderHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, span := h.Tracer.Start(r.Context(), "http.create_order")
+	defer span.End()
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxOrderBodySize)
+	var order models.Order
+	if err := json.NewDecoder(r.Body).Decode(&order); err != nil {
+		errMsg := "ошибка при преобразовании JSON"
+		status := http.StatusBadRequest
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			errMsg = "слишком большой заказ"
+			status = http.StatusRequestEntityTooLarge
+		}
+		http.Error(w, errMsg, status)
+		span.SetStatus(codes.Error, errMsg)
+		metrics.OrdersProcessed.WithLabelValues("api", "error").Inc()
+		return
+	}
+
+	span.SetAttributes(attribute.String("order.uid", order.OrderUID))
+	ctx = logging.WithOrderUID(ctx, order.OrderUID)
+
+	if err := validation.ValidateOrder(&order); err != nil {
+		http.Error(w, "невалидные данные заказа: "+err.Error(), http.StatusUnprocessableEntity)
+		span.SetStatus(codes.Error, "невалидные данные заказа")
+		metrics.OrdersProcessed.WithLabelValues("api", "validation_error").Inc()
+		return
+	}
+
+	statusChanged, err := h.DB.SaveOrder(ctx, &order)
+	if err != nil {
+		span.RecordError(err)
+		slog.ErrorContext(ctx, "Ошибка сохранения заказа из API", logging.Err(err))
+		errMsg := "внутренняя ошибка сервера DB error"
+		http.Error(w, errMsg, http.StatusInternalServerError)
+		span.SetStatus(codes.Error, errMsg)
+		metrics.OrdersProcessed.WithLabelValues("api", "error").Inc()
+		return
+	}
+	h.Cache.Set(order.OrderUID, &order)
+	h.Audit.Record(ctx, audit.ActionWrite, order.OrderUID, r)
+	h.Hub.Publish(&order)
+	h.Webhooks.NotifyStored(ctx, &order, statusChanged)
+	slog.InfoContext(ctx, "Заказ принят через API")
+	metrics.OrdersProcessed.WithLabelValues("api", "success").Inc()
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Location", "/api/v1/orders/"+order.OrderUID)
+	saved := presentOrders(w, r, &order)[0]
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(saved)
+	span.SetStatus(codes.Ok, "заказ сохранен")
+}
+
+func queryInt(r *http.Request, key string, def int) (int, error) {
+	val := r.URL.Query().Get(key)
+	if val == "" {
+		return def, nil
+	}
+	return strconv.Atoi(val)
+}
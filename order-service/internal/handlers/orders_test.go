@@ -0,0 +1,214 @@
+// internal/handlers/orders_test.go
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"order-service/internal/mocks"
+	"order-service/internal/pubsub"
+	"order-service/models"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func validTestOrder() *models.Order {
+	return &models.Order{
+		OrderUID:    "b563feb7b2b84b6test123",
+		TrackNumber: "WBILMTESTTRACK123",
+		Entry:       "WBIL",
+		Delivery: models.Delivery{
+			Name:    "Test Testov",
+			Phone:   "+9720000000",
+			Zip:     "2639809",
+			City:    "Kiryat Mozkin",
+			Address: "Ploshad Mira 15",
+			Region:  "Kraiot",
+			Email:   "test@gmail.com",
+		},
+		Payment: models.Payment{
+			Transaction:  "real-transaction-12345",
+			Currency:     "USD",
+			Provider:     "wbpay",
+			Amount:       1817,
+			PaymentDt:    time.Now().Unix(),
+			Bank:         "alpha",
+			DeliveryCost: 1500,
+			GoodsTotal:   317,
+		},
+		Items: []models.Item{{
+			ChrtID:      9934930,
+			TrackNumber: "WBILMTESTTRACK123",
+			Price:       453,
+			Rid:         "ab4219087a764ae0btest",
+			Name:        "Mascaras",
+			Sale:        30,
+			Size:        "0",
+			TotalPrice:  317,
+			NmID:        2389212,
+			Brand:       "Vivienne Sabo",
+			Status:      202,
+		}},
+		Locale:          "en",
+		CustomerID:      "test",
+		DeliveryService: "meest",
+		Shardkey:        "9",
+		SmID:            99,
+		DateCreated:     time.Now().Add(-24 * time.Hour),
+		OofShard:        "1",
+	}
+}
+
+func postOrder(t *testing.T, handler *Handler, body []byte) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/orders", bytes.NewReader(body))
+	w := httptest.NewRecorder()
+	handler.CreateOrderHandler(w, req)
+	return w
+}
+
+func TestCreateOrderHandler_Created(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockDB := mocks.NewMockDatabase(ctrl)
+	mockCache := mocks.NewMockCache(ctrl)
+
+	order := validTestOrder()
+	mockDB.EXPECT().SaveOrder(gomock.Any(), gomock.Any()).Return(false, nil)
+	mockCache.EXPECT().Set(order.OrderUID, gomock.Any())
+
+	body, _ := json.Marshal(order)
+	w := postOrder(t, createTestHandler(mockCache, mockDB), body)
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, "/api/v1/orders/"+order.OrderUID, w.Header().Get("Location"))
+
+	var response models.Order
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&response))
+	assert.Equal(t, order.OrderUID, response.OrderUID)
+}
+
+func TestCreateOrderHandler_PublishesOrder(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockDB := mocks.NewMockDatabase(ctrl)
+	mockCache := mocks.NewMockCache(ctrl)
+
+	order := validTestOrder()
+	mockDB.EXPECT().SaveOrder(gomock.Any(), gomock.Any()).Return(false, nil)
+	mockCache.EXPECT().Set(order.OrderUID, gomock.Any())
+
+	handler := createTestHandler(mockCache, mockDB)
+	handler.Hub = pubsub.NewHub()
+	sub := handler.Hub.Subscribe(pubsub.Filter{}, 10)
+	defer sub.Close()
+
+	body, _ := json.Marshal(order)
+	require.Equal(t, http.StatusCreated, postOrder(t, handler, body).Code)
+	postOrder(t, handler, []byte("{not json"))
+
+	require.Len(t, sub.C, 1)
+	assert.Equal(t, order.OrderUID, (<-sub.C).OrderUID)
+}
+
+func TestCreateOrderHandler_InvalidJSON(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	w := postOrder(t, createTestHandler(mocks.NewMockCache(ctrl), mocks.NewMockDatabase(ctrl)), []byte("{not json"))
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestCreateOrderHandler_ValidationError(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	order := validTestOrder()
+	order.Delivery.Email = "not-an-email"
+	body, _ := json.Marshal(order)
+
+	w := postOrder(t, createTestHandler(mocks.NewMockCache(ctrl), mocks.NewMockDatabase(ctrl)), body)
+
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	assert.Contains(t, w.Body.String(), "невалидные данные заказа")
+}
+
+func TestCreateOrderHandler_TooLarge(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	body := []byte(`{"order_uid":"` + strings.Repeat("a", maxOrderBodySize) + `"}`)
+	w := postOrder(t, createTestHandler(mocks.NewMockCache(ctrl), mocks.NewMockDatabase(ctrl)), body)
+
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+}
+
+func TestCreateOrderHandler_DatabaseError(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockDB := mocks.NewMockDatabase(ctrl)
+	mockDB.EXPECT().SaveOrder(gomock.Any(), gomock.Any()).Return(false, errors.New("db connection failed"))
+
+	body, _ := json.Marshal(validTestOrder())
+	w := postOrder(t, createTestHandler(mocks.NewMockCache(ctrl), mockDB), body)
+
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+}
+
+func TestListOrdersHandler(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockDB := mocks.NewMockDatabase(ctrl)
+	mockDB.EXPECT().ListOrders(gomock.Any(), 2, 4).Return([]*models.Order{{OrderUID: "a"}, {OrderUID: "b"}}, nil)
+
+	handler := createTestHandler(mocks.NewMockCache(ctrl), mockDB)
+	w := httptest.NewRecorder()
+	handler.ListOrdersHandler(w, httptest.NewRequest(http.MethodGet, "/api/v1/orders?limit=2&offset=4", nil))
+
+	require.Equal(t, http.StatusOK, w.Code)
+	var page OrdersPage
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&page))
+	assert.Equal(t, 2, page.Limit)
+	assert.Equal(t, 4, page.Offset)
+	require.Len(t, page.Orders, 2)
+	assert.Equal(t, "a", page.Orders[0].OrderUID)
+}
+
+func TestListOrdersHandler_Defaults(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockDB := mocks.NewMockDatabase(ctrl)
+	mockDB.EXPECT().ListOrders(gomock.Any(), defaultPageLimit, 0).Return([]*models.Order{}, nil)
+
+	handler := createTestHandler(mocks.NewMockCache(ctrl), mockDB)
+	w := httptest.NewRecorder()
+	handler.ListOrdersHandler(w, httptest.NewRequest(http.MethodGet, "/api/v1/orders", nil))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"orders":[],"limit":20,"offset":0}`, w.Body.String())
+}
+
+func TestListOrdersHandler_BadParams(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	handler := createTestHandler(mocks.NewMockCache(ctrl), mocks.NewMockDatabase(ctrl))
+	for _, query := range []string{"limit=0", "limit=1000", "limit=abc", "offset=-1", "offset=x"} {
+		w := httptest.NewRecorder()
+		handler.ListOrdersHandler(w, httptest.NewRequest(http.MethodGet, "/api/v1/orders?"+query, nil))
+		assert.Equal(t, http.StatusBadRequest, w.Code, query)
+	}
+}
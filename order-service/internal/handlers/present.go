@@ -0,0 +1,105 @@
+// internal/handlers/present.go
+package handlers
+
+import (
+	"context"
+	"encoding/csv"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"order-service/internal/auth"
+	"order-service/internal/pii"
+	"order-service/models"
+)
+
+// piiMaskedHeader выставляется, если персональные данные в ответе скрыты
+const piiMaskedHeader = "X-PII-Masked"
+
+// revealPII видит ли клиент персональные данные: нужна область pii:read.
+// Клиента нет в контексте только при выключенной аутентификации, тогда данные отдаются как есть
+func revealPII(ctx context.Context) bool {
+	p := auth.FromContext(ctx)
+	return p == nil || p.HasScope(auth.ScopePIIRead)
+}
+
+// presentOrders заказы в том виде, в котором их видит клиент запроса.
+// Все представления (JSON, CSV, веб-интерфейс поверх JSON) строятся из результата
+func presentOrders(w http.ResponseWriter, r *http.Request, orders ...*models.Order) []*models.Order {
+	if revealPII(r.Context()) {
+		return orders
+	}
+	w.Header().Set(piiMaskedHeader, "true")
+	masked := make([]*models.Order, len(orders))
+	for i, o := range orders {
+		masked[i] = pii.Mask(o)
+	}
+	return masked
+}
+
+// csvColumn колонка выгрузки заказов
+type csvColumn struct {
+	name  string
+	value func(o *models.Order) string
+}
+
+// csvColumns одна строка на заказ, товары только количеством
+var csvColumns = []csvColumn{
+	{"order_uid", func(o *models.Order) string { return o.OrderUID }},
+	{"track_number", func(o *models.Order) string { return o.TrackNumber }},
+	{"date_created", func(o *models.Order) string { return o.DateCreated.UTC().Format(time.RFC3339) }},
+	{"customer_id", func(o *models.Order) string { return o.CustomerID }},
+	{"delivery_service", func(o *models.Order) string { return o.DeliveryService }},
+	{"delivery.name", func(o *models.Order) string { return o.Delivery.Name }},
+	{"delivery.phone", func(o *models.Order) string { return o.Delivery.Phone }},
+	{"delivery.email", func(o *models.Order) string { return o.Delivery.Email }},
+	{"delivery.city", func(o *models.Order) string { return o.Delivery.City }},
+	{"delivery.address", func(o *models.Order) string { return o.Delivery.Address }},
+	{"payment.transaction", func(o *models.Order) string { return o.Payment.Transaction }},
+	{"payment.amount", func(o *models.Order) string { return strconv.Itoa(o.Payment.Amount) }},
+	{"payment.currency", func(o *models.Order) string { return o.Payment.Currency }},
+	{"items", func(o *models.Order) string { return strconv.Itoa(len(o.Items)) }},
+}
+
+// acceptsCSV просит ли клиент выгрузку в CSV вместо JSON
+func acceptsCSV(r *http.Request) bool {
+	for _, part := range strings.Split(r.Header.Get("Accept"), ",") {
+		if mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(part)); err == nil && mediaType == "text/csv" {
+			return true
+		}
+	}
+	return false
+}
+
+// writeOrdersCSV выгрузка заказов с заголовком из csvColumns
+func writeOrdersCSV(w io.Writer, orders []*models.Order) error {
+	cw := csv.NewWriter(w)
+	row := make([]string, len(csvColumns))
+	for i, c := range csvColumns {
+		row[i] = c.name
+	}
+	if err := cw.Write(row); err != nil {
+		return err
+	}
+	for _, o := range orders {
+		for i, c := range csvColumns {
+			row[i] = csvCell(c.value(o))
+		}
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// csvCell экранирует значения, которые табличный редактор принял бы за формулу (+7999..., =HYPERLINK(...))
+func csvCell(val string) string {
+	if val != "" && strings.ContainsRune("=+-@\t\r", rune(val[0])) {
+		return "'" + val
+	}
+	return val
+}
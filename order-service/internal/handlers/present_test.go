@@ -0,0 +1,157 @@
+// internal/handlers/present_test.go
+package handlers
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"order-service/internal/auth"
+	"order-service/internal/mocks"
+	"order-service/models"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// asPrincipal запрос от клиента с областями, как после auth.Require
+func asPrincipal(req *http.Request, scopes ...string) *http.Request {
+	p := &auth.Principal{Subject: "test", Method: auth.MethodAPIKey, Scopes: scopes}
+	return req.WithContext(auth.WithPrincipal(req.Context(), p))
+}
+
+func getOrderAs(t *testing.T, order *models.Order, req *http.Request) (*httptest.ResponseRecorder, models.Order) {
+	ctrl := gomock.NewController(t)
+	mockCache := mocks.NewMockCache(ctrl)
+	mockCache.EXPECT().GetOrLoad(order.OrderUID, gomock.Any()).Return(order, nil)
+
+	w := httptest.NewRecorder()
+	createTestHandler(mockCache, mocks.NewMockDatabase(ctrl)).OrderHandler(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var got models.Order
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&got))
+	return w, got
+}
+
+func TestOrderHandler_MasksPIIWithoutScope(t *testing.T) {
+	order := validTestOrder()
+	original := *order
+
+	w, got := getOrderAs(t, order, asPrincipal(orderRequest(order.OrderUID), auth.ScopeOrdersRead))
+
+	assert.Equal(t, "true", w.Header().Get(piiMaskedHeader))
+	assert.Equal(t, "T*** T***", got.Delivery.Name)
+	assert.Equal(t, "+9*****0000", got.Delivery.Phone)
+	assert.Equal(t, "***", got.Delivery.Address)
+	assert.Equal(t, "t***@gmail.com", got.Delivery.Email)
+	assert.Equal(t, "***2345", got.Payment.Transaction)
+	assert.Equal(t, order.Delivery.City, got.Delivery.City)
+	// заказ в кэше остаётся полным
+	assert.Equal(t, original, *order)
+}
+
+func TestOrderHandler_RevealsPII(t *testing.T) {
+	order := validTestOrder()
+	requests := map[string]*http.Request{
+		"pii:read":      asPrincipal(orderRequest(order.OrderUID), auth.ScopeOrdersRead, auth.ScopePIIRead),
+		"admin":         asPrincipal(orderRequest(order.OrderUID), auth.ScopeAdmin),
+		"auth disabled": orderRequest(order.OrderUID),
+	}
+	for name, req := range requests {
+		t.Run(name, func(t *testing.T) {
+			w, got := getOrderAs(t, order, req)
+			assert.Empty(t, w.Header().Get(piiMaskedHeader))
+			assert.Equal(t, order.Delivery, got.Delivery)
+			assert.Equal(t, order.Payment.Transaction, got.Payment.Transaction)
+		})
+	}
+}
+
+func TestOrderHandler_ETagDependsOnMasking(t *testing.T) {
+	order := validTestOrder()
+	masked, _ := getOrderAs(t, order, asPrincipal(orderRequest(order.OrderUID), auth.ScopeOrdersRead))
+	full, _ := getOrderAs(t, order, asPrincipal(orderRequest(order.OrderUID), auth.ScopePIIRead))
+
+	assert.NotEqual(t, masked.Header().Get("ETag"), full.Header().Get("ETag"))
+}
+
+func TestListOrdersHandler_MasksPII(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	order := validTestOrder()
+	mockDB := mocks.NewMockDatabase(ctrl)
+	mockDB.EXPECT().ListOrders(gomock.Any(), defaultPageLimit, 0).Return([]*models.Order{order}, nil)
+
+	w := httptest.NewRecorder()
+	req := asPrincipal(httptest.NewRequest(http.MethodGet, "/api/v1/orders", nil), auth.ScopeOrdersRead)
+	createTestHandler(mocks.NewMockCache(ctrl), mockDB).ListOrdersHandler(w, req)
+
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "true", w.Header().Get(piiMaskedHeader))
+	var page OrdersPage
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&page))
+	require.Len(t, page.Orders, 1)
+	assert.Equal(t, "t***@gmail.com", page.Orders[0].Delivery.Email)
+	assert.Equal(t, "test@gmail.com", order.Delivery.Email)
+}
+
+func TestListOrdersHandler_CSV(t *testing.T) {
+	tests := []struct {
+		name   string
+		scopes []string
+		phone  string
+		email  string
+	}{
+		{"masked", []string{auth.ScopeOrdersRead}, "'+9*****0000", "t***@gmail.com"},
+		{"pii:read", []string{auth.ScopeOrdersRead, auth.ScopePIIRead}, "'+9720000000", "test@gmail.com"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			order := validTestOrder()
+			mockDB := mocks.NewMockDatabase(ctrl)
+			mockDB.EXPECT().ListOrders(gomock.Any(), defaultPageLimit, 0).Return([]*models.Order{order}, nil)
+
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/orders", nil)
+			req.Header.Set("Accept", "text/csv;q=0.9, application/json;q=0.5")
+			w := httptest.NewRecorder()
+			createTestHandler(mocks.NewMockCache(ctrl), mockDB).ListOrdersHandler(w, asPrincipal(req, tt.scopes...))
+
+			require.Equal(t, http.StatusOK, w.Code)
+			assert.Equal(t, "text/csv; charset=utf-8", w.Header().Get("Content-Type"))
+
+			rows, err := csv.NewReader(w.Body).ReadAll()
+			require.NoError(t, err)
+			require.Len(t, rows, 2)
+			record := make(map[string]string)
+			for i, name := range rows[0] {
+				record[name] = rows[1][i]
+			}
+			assert.Equal(t, order.OrderUID, record["order_uid"])
+			// телефон начинается с + и экранируется от формул
+			assert.Equal(t, tt.phone, record["delivery.phone"])
+			assert.Equal(t, tt.email, record["delivery.email"])
+			assert.Equal(t, "1", record["items"])
+		})
+	}
+}
+
+func TestAcceptsCSV(t *testing.T) {
+	tests := map[string]bool{
+		"":                               false,
+		"application/json":               false,
+		"text/csv":                       true,
+		"application/json, text/csv;q=1": true,
+		"text/csvx":                      false,
+	}
+	for accept, want := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/orders", nil)
+		req.Header.Set("Accept", accept)
+		assert.Equal(t, want, acceptsCSV(req), accept)
+	}
+}
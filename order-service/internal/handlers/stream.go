@@ -0,0 +1,269 @@
+// internal/handlers/stream.go
+package handlers
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+
+	"order-service/internal/audit"
+	"order-service/internal/logging"
+	"order-service/internal/metrics"
+	"order-service/internal/pii"
+	"order-service/internal/pubsub"
+	"order-service/models"
+
+	"go.opentelemetry.io/otel/codes"
+	"golang.org/x/net/websocket"
+)
+
+const (
+	transportSSE       = "sse"
+	transportWebSocket = "websocket"
+)
+
+// streamSender доставка событий потока клиенту: SSE или WebSocket
+type streamSender interface {
+	order(o *models.Order) error
+	// dropped сколько заказов пропущено с прошлого уведомления
+	dropped(n uint64) error
+	heartbeat() error
+}
+
+// OrdersStreamHandler GET /orders/stream: новые заказы из Kafka и API как Server-Sent Events.
+// Фильтры customer_id и delivery_service в параметрах запроса
+func (h *Handler) OrdersStreamHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, span := h.Tracer.Start(r.Context(), "http.orders_stream")
+	defer span.End()
+
+	if h.Hub == nil {
+		http.Error(w, "рассылка заказов выключена", http.StatusNotFound)
+		span.SetStatus(codes.Error, "рассылка заказов выключена")
+		return
+	}
+
+	rc := http.NewResponseController(w)
+	// поток живёт дольше WriteTimeout сервера, дедлайн ставится на каждое событие
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		span.RecordError(err)
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	// nginx не буферизует ответ
+	w.Header().Set("X-Accel-Buffering", "no")
+	if !revealPII(ctx) {
+		w.Header().Set(piiMaskedHeader, "true")
+	}
+	w.WriteHeader(http.StatusOK)
+	if err := rc.Flush(); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "поток не поддерживается")
+		return
+	}
+
+	h.streamOrders(ctx, r, &sseSender{w: w, rc: rc, timeout: h.Stream.WriteTimeout}, transportSSE)
+	span.SetStatus(codes.Ok, "поток закрыт")
+}
+
+// OrdersWebSocketHandler GET /orders/stream/ws: те же события JSON сообщениями WebSocket
+func (h *Handler) OrdersWebSocketHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, span := h.Tracer.Start(r.Context(), "http.orders_websocket")
+	defer span.End()
+
+	if h.Hub == nil {
+		http.Error(w, "рассылка заказов выключена", http.StatusNotFound)
+		span.SetStatus(codes.Error, "рассылка заказов выключена")
+		return
+	}
+
+	srv := websocket.Server{
+		Handshake: checkOrigin,
+		Handler: func(conn *websocket.Conn) {
+			defer conn.Close()
+			ctx, cancel := context.WithCancel(ctx)
+			defer cancel()
+			// сообщения клиента не нужны, чтение только замечает закрытие соединения
+			go func() {
+				io.Copy(io.Discard, conn)
+				cancel()
+			}()
+			h.streamOrders(ctx, r, &wsSender{conn: conn, timeout: h.Stream.WriteTimeout}, transportWebSocket)
+		},
+	}
+	srv.ServeHTTP(hijackWriter{w}, r)
+	span.SetStatus(codes.Ok, "поток закрыт")
+}
+
+// streamOrders общий цикл потоков: заказы подписки, пульс и уведомления о пропусках.
+// Медленный клиент не тормозит приём заказов: то, что не влезло в его буфер, пропускается,
+// а клиент получает событие dropped и может дочитать пропущенное через GET /api/v1/orders.
+// Запись, не уложившаяся в WriteTimeout, закрывает поток
+func (h *Handler) streamOrders(ctx context.Context, r *http.Request, send streamSender, transport string) {
+	sub := h.Hub.Subscribe(streamFilter(r), h.Stream.BufferSize)
+	defer sub.Close()
+
+	reveal := revealPII(ctx)
+	heartbeat := time.NewTicker(h.Stream.Heartbeat)
+	defer heartbeat.Stop()
+
+	var reported uint64
+	reportDropped := func() error {
+		n := sub.Dropped()
+		if n == reported {
+			return nil
+		}
+		err := send.dropped(n - reported)
+		reported = n
+		return err
+	}
+
+	reason := "client"
+	defer func() {
+		metrics.StreamDisconnects.WithLabelValues(transport, reason).Inc()
+		slog.DebugContext(ctx, "Поток заказов закрыт", slog.String("transport", transport),
+			slog.String("reason", reason), slog.Uint64("dropped", sub.Dropped()))
+	}()
+
+	for {
+		var err error
+		select {
+		case <-ctx.Done():
+			return
+		case order, ok := <-sub.C:
+			if !ok {
+				reason = "shutdown"
+				return
+			}
+			if err = reportDropped(); err != nil {
+				break
+			}
+			if !reveal {
+				order = pii.Mask(order)
+			}
+			if err = send.order(order); err == nil {
+				h.Audit.Record(ctx, audit.ActionRead, order.OrderUID, r)
+			}
+		case <-heartbeat.C:
+			if err = reportDropped(); err == nil {
+				err = send.heartbeat()
+			}
+		}
+		if err != nil {
+			switch {
+			case ctx.Err() != nil:
+			case errors.Is(err, os.ErrDeadlineExceeded):
+				reason = "write_timeout"
+				slog.WarnContext(ctx, "Клиент не успевает читать поток заказов, соединение закрыто",
+					slog.String("transport", transport))
+			default:
+				reason = "error"
+				slog.WarnContext(ctx, "Ошибка записи в поток заказов", slog.String("transport", transport), logging.Err(err))
+			}
+			return
+		}
+	}
+}
+
+func streamFilter(r *http.Request) pubsub.Filter {
+	q := r.URL.Query()
+	return pubsub.Filter{
+		CustomerID:      q.Get("customer_id"),
+		DeliveryService: q.Get("delivery_service"),
+	}
+}
+
+// sseSender события text/event-stream: order и dropped с JSON в data, пульс комментарием
+type sseSender struct {
+	w       http.ResponseWriter
+	rc      *http.ResponseController
+	timeout time.Duration
+}
+
+func (s *sseSender) order(o *models.Order) error {
+	data, err := json.Marshal(o)
+	if err != nil {
+		return err
+	}
+	return s.write("event: order\ndata: %s\n\n", data)
+}
+
+func (s *sseSender) dropped(n uint64) error {
+	return s.write("event: dropped\ndata: {\"count\":%d}\n\n", n)
+}
+
+func (s *sseSender) heartbeat() error {
+	return s.write(": heartbeat\n\n")
+}
+
+func (s *sseSender) write(format string, args ...any) error {
+	if err := s.rc.SetWriteDeadline(time.Now().Add(s.timeout)); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		return err
+	}
+	if _, err := fmt.Fprintf(s.w, format, args...); err != nil {
+		return err
+	}
+	return s.rc.Flush()
+}
+
+// StreamMessage сообщение WebSocket потока: type order с заказом, dropped с числом пропущенных или heartbeat
+type StreamMessage struct {
+	Type  string        `json:"type"`
+	Order *models.Order `json:"order,omitempty"`
+	Count uint64        `json:"count,omitempty"`
+}
+
+type wsSender struct {
+	conn    *websocket.Conn
+	timeout time.Duration
+}
+
+func (s *wsSender) order(o *models.Order) error {
+	return s.send(StreamMessage{Type: "order", Order: o})
+}
+
+func (s *wsSender) dropped(n uint64) error {
+	return s.send(StreamMessage{Type: "dropped", Count: n})
+}
+
+func (s *wsSender) heartbeat() error {
+	return s.send(StreamMessage{Type: "heartbeat"})
+}
+
+func (s *wsSender) send(m StreamMessage) error {
+	if err := s.conn.SetWriteDeadline(time.Now().Add(s.timeout)); err != nil {
+		return err
+	}
+	return websocket.JSON.Send(s.conn, m)
+}
+
+// checkOrigin пускает клиентов без Origin (не браузер) и страницы этого же сервиса:
+// чужой сайт не откроет поток из браузера пользователя
+func checkOrigin(_ *websocket.Config, r *http.Request) error {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return nil
+	}
+	u, err := url.Parse(origin)
+	if err != nil || u.Host != r.Host {
+		return errors.New("недопустимый Origin: " + origin)
+	}
+	return nil
+}
+
+// hijackWriter даёт websocket.Server захватить соединение сквозь обёртки мидлвэров
+type hijackWriter struct {
+	http.ResponseWriter
+}
+
+func (w hijackWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return http.NewResponseController(w.ResponseWriter).Hijack()
+}
@@ -0,0 +1,255 @@
+// internal/handlers/stream_test.go
+package handlers
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"order-service/internal/auth"
+	"order-service/internal/mocks"
+	"order-service/internal/pubsub"
+	"order-service/models"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/websocket"
+)
+
+func newStreamHandler(t *testing.T) *Handler {
+	ctrl := gomock.NewController(t)
+	h := createTestHandler(mocks.NewMockCache(ctrl), mocks.NewMockDatabase(ctrl))
+	h.Hub = pubsub.NewHub()
+	t.Cleanup(h.Hub.Close)
+	return h
+}
+
+func streamOrder(uid, customer string) *models.Order {
+	order := validTestOrder()
+	order.OrderUID = uid
+	order.CustomerID = customer
+	return order
+}
+
+// probe публикует заказ, пока подписчик не получит первый: подписка создаётся уже после ответа клиенту
+func probe(h *Handler, order *models.Order) (stop func()) {
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		ticker := time.NewTicker(10 * time.Millisecond)
+		defer ticker.Stop()
+		for {
+			h.Hub.Publish(order)
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+	return func() {
+		close(done)
+		wg.Wait()
+	}
+}
+
+type sseEvent struct {
+	name string
+	data string
+}
+
+// readEvent следующее событие потока; комментарии пульса пропускаются
+func readEvent(t *testing.T, r *bufio.Reader) sseEvent {
+	t.Helper()
+	var ev sseEvent
+	for {
+		line, err := r.ReadString('\n')
+		require.NoError(t, err)
+		line = strings.TrimSuffix(line, "\n")
+		switch {
+		case line == "" && ev.name != "":
+			return ev
+		case strings.HasPrefix(line, "event: "):
+			ev.name = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			ev.data = strings.TrimPrefix(line, "data: ")
+		}
+	}
+}
+
+func TestOrdersStreamHandler_Events(t *testing.T) {
+	h := newStreamHandler(t)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h.OrdersStreamHandler(w, asPrincipal(r, auth.ScopeOrdersRead))
+	}))
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/orders/stream?customer_id=alice")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+	assert.Equal(t, "true", resp.Header.Get(piiMaskedHeader))
+
+	body := bufio.NewReader(resp.Body)
+	stop := probe(h, streamOrder("probe", "alice"))
+	ev := readEvent(t, body)
+	stop()
+	assert.Equal(t, "order", ev.name)
+
+	// заказ другого клиента в поток не попадает
+	h.Hub.Publish(streamOrder("other", "bob"))
+	h.Hub.Publish(streamOrder("wanted", "alice"))
+	for ev.name == "order" && strings.Contains(ev.data, `"order_uid":"probe"`) {
+		ev = readEvent(t, body)
+	}
+	require.Equal(t, "order", ev.name)
+
+	var got models.Order
+	require.NoError(t, json.Unmarshal([]byte(ev.data), &got))
+	assert.Equal(t, "wanted", got.OrderUID)
+	// без pii:read персональные данные скрыты
+	assert.NotEqual(t, "Test Testov", got.Delivery.Name)
+}
+
+func TestOrdersStreamHandler_Shutdown(t *testing.T) {
+	h := newStreamHandler(t)
+	srv := httptest.NewServer(http.HandlerFunc(h.OrdersStreamHandler))
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/orders/stream")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	body := bufio.NewReader(resp.Body)
+	stop := probe(h, streamOrder("probe", "alice"))
+	readEvent(t, body)
+	stop()
+
+	// остановка хаба завершает ответ, клиент видит конец потока
+	h.Hub.Close()
+	done := make(chan error, 1)
+	go func() {
+		_, err := body.ReadString(0)
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		assert.Error(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("поток не закрылся после остановки хаба")
+	}
+}
+
+func TestOrdersStreamHandler_HubDisabled(t *testing.T) {
+	h := newStreamHandler(t)
+	h.Hub = nil
+
+	w := httptest.NewRecorder()
+	h.OrdersStreamHandler(w, httptest.NewRequest("GET", "/orders/stream", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w = httptest.NewRecorder()
+	h.OrdersWebSocketHandler(w, httptest.NewRequest("GET", "/orders/stream/ws", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+// blockingSender задерживает первую отправку заказа, как клиент, который перестал читать
+type blockingSender struct {
+	started chan struct{}
+	release chan struct{}
+	once    sync.Once
+	events  chan string
+}
+
+func (s *blockingSender) order(o *models.Order) error {
+	s.once.Do(func() {
+		close(s.started)
+		<-s.release
+	})
+	s.events <- "order:" + o.OrderUID
+	return nil
+}
+
+func (s *blockingSender) dropped(n uint64) error {
+	s.events <- fmt.Sprintf("dropped:%d", n)
+	return nil
+}
+
+func (s *blockingSender) heartbeat() error { return nil }
+
+func TestStreamOrders_Backpressure(t *testing.T) {
+	h := newStreamHandler(t)
+	h.Stream.BufferSize = 1
+
+	send := &blockingSender{started: make(chan struct{}), release: make(chan struct{}), events: make(chan string, 10)}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		h.streamOrders(ctx, httptest.NewRequest("GET", "/orders/stream", nil), send, transportSSE)
+	}()
+
+	stop := probe(h, streamOrder("probe", "alice"))
+	<-send.started
+	stop()
+	// буфер не пуст после проб; дальше всё, что не влезло в него, пропускается
+	for _, uid := range []string{"a", "b", "c"} {
+		h.Hub.Publish(streamOrder(uid, "alice"))
+	}
+	close(send.release)
+
+	assert.Equal(t, "order:probe", <-send.events)
+	// клиент узнаёт о пропуске раньше следующего заказа
+	assert.Regexp(t, `^dropped:[1-9]`, <-send.events)
+	assert.Regexp(t, `^order:`, <-send.events)
+
+	cancel()
+	<-done
+}
+
+func TestOrdersWebSocketHandler(t *testing.T) {
+	h := newStreamHandler(t)
+	srv := httptest.NewServer(http.HandlerFunc(h.OrdersWebSocketHandler))
+	defer srv.Close()
+	wsURL := "ws" + strings.TrimPrefix(srv.URL, "http") + "/orders/stream/ws?delivery_service=meest"
+
+	conn, err := websocket.Dial(wsURL, "", srv.URL)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	stop := probe(h, streamOrder("probe", "alice"))
+	var msg StreamMessage
+	require.NoError(t, websocket.JSON.Receive(conn, &msg))
+	stop()
+	assert.Equal(t, "order", msg.Type)
+	require.NotNil(t, msg.Order)
+	assert.Equal(t, "meest", msg.Order.DeliveryService)
+
+	// остановка хаба закрывает соединение
+	h.Hub.Close()
+	for err == nil {
+		err = websocket.JSON.Receive(conn, &msg)
+	}
+	assert.Error(t, err)
+}
+
+func TestOrdersWebSocketHandler_ForeignOrigin(t *testing.T) {
+	h := newStreamHandler(t)
+	srv := httptest.NewServer(http.HandlerFunc(h.OrdersWebSocketHandler))
+	defer srv.Close()
+	wsURL := "ws" + strings.TrimPrefix(srv.URL, "http") + "/orders/stream/ws"
+
+	_, err := websocket.Dial(wsURL, "", "https://evil.example")
+	assert.Error(t, err)
+}
@@ -0,0 +1,87 @@
+// internal/health/checks.go
+package health
+
+import (
+	"context"
+	"time"
+)
+
+// Pinger зависимость, доступность которой проверяется ping-запросом
+type Pinger interface {
+	Ping(ctx context.Context) error
+}
+
+// PingCheck критичная проверка БД, Redis и т.п.
+func PingCheck(p Pinger) CheckFunc {
+	return func(ctx context.Context) Result {
+		if err := p.Ping(ctx); err != nil {
+			return Result{Status: StatusDown, Error: err.Error()}
+		}
+		return Result{Status: StatusUp}
+	}
+}
+
+// KafkaStatus состояние Kafka консюмера
+type KafkaStatus struct {
+	Running   bool
+	Lag       int64
+	LastError error
+	LastFetch time.Time
+}
+
+// KafkaCheck консюмер остановлен — down, последняя выборка с ошибкой — degraded
+func KafkaCheck(status func() KafkaStatus) CheckFunc {
+	return func(ctx context.Context) Result {
+		s := status()
+		res := Result{
+			Status: StatusUp,
+			Details: map[string]interface{}{
+				"running": s.Running,
+				"lag":     s.Lag,
+			},
+		}
+		if !s.LastFetch.IsZero() {
+			res.Details["last_fetch"] = s.LastFetch.UTC().Format(time.RFC3339)
+		}
+		switch {
+		case !s.Running:
+			res.Status = StatusDown
+		case s.LastError != nil:
+			res.Status = StatusDegraded
+			res.Error = s.LastError.Error()
+		}
+		return res
+	}
+}
+
+// WarmupCheck готовность после прогрева кэша
+func WarmupCheck(ready func() bool) CheckFunc {
+	return func(ctx context.Context) Result {
+		if !ready() {
+			return Result{Status: StatusDown, Details: map[string]interface{}{"warmed_up": false}}
+		}
+		return Result{Status: StatusUp, Details: map[string]interface{}{"warmed_up": true}}
+	}
+}
+
+// ExporterStatus состояние экспорта трейсов
+type ExporterStatus struct {
+	LastExport time.Time
+	LastError  error
+}
+
+// TracerCheck ошибка последней отправки спанов — degraded, трассировка не критична
+func TracerCheck(status func() ExporterStatus) CheckFunc {
+	return func(ctx context.Context) Result {
+		s := status()
+		res := Result{Status: StatusUp, Details: map[string]interface{}{}}
+		if !s.LastExport.IsZero() {
+			res.Details["last_export"] = s.LastExport.UTC().Format(time.RFC3339)
+		}
+		if s.LastError != nil {
+			res.Status = StatusDegraded
+			res.Error = s.LastError.Error()
+		}
+		return res
+	}
+}
@@ -0,0 +1,163 @@
+// internal/health/health.go
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	StatusUp       = "up"
+	StatusDegraded = "degraded"
+	StatusDown     = "down"
+
+	checkTimeout = 2 * time.Second
+)
+
+// Result состояние одной зависимости
+type Result struct {
+	Status    string                 `json:"status"`
+	LatencyMs float64                `json:"latency_ms"`
+	Critical  bool                   `json:"critical"`
+	Error     string                 `json:"error,omitempty"`
+	Details   map[string]interface{} `json:"details,omitempty"`
+}
+
+// CheckFunc проверка зависимости, Status и Details заполняет сама проверка
+type CheckFunc func(ctx context.Context) Result
+
+// Report ответ /health
+type Report struct {
+	Status   string            `json:"status"`
+	Draining bool              `json:"draining"`
+	Checks   map[string]Result `json:"checks"`
+}
+
+type check struct {
+	name     string
+	critical bool
+	fn       CheckFunc
+}
+
+// Health реестр проверок для /livez, /readyz и /health.
+// Критичные проверки влияют на готовность, остальные только на /health.
+type Health struct {
+	mu       sync.RWMutex
+	checks   []check
+	draining atomic.Bool
+}
+
+func New() *Health {
+	return &Health{}
+}
+
+func (h *Health) Register(name string, critical bool, fn CheckFunc) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.checks = append(h.checks, check{name: name, critical: critical, fn: fn})
+}
+
+// SetDraining переводит сервис в режим остановки: /readyz начинает отвечать 503,
+// чтобы балансировщик перестал слать новые запросы до Shutdown
+func (h *Health) SetDraining() {
+	h.draining.Store(true)
+}
+
+// Run выполняет все проверки параллельно
+func (h *Health) Run(ctx context.Context) Report {
+	h.mu.RLock()
+	checks := append([]check(nil), h.checks...)
+	h.mu.RUnlock()
+
+	ctx, cancel := context.WithTimeout(ctx, checkTimeout)
+	defer cancel()
+
+	results := make([]Result, len(checks))
+	var wg sync.WaitGroup
+	for i, c := range checks {
+		wg.Add(1)
+		go func(i int, c check) {
+			defer wg.Done()
+			start := time.Now()
+			res := c.fn(ctx)
+			res.LatencyMs = float64(time.Since(start).Microseconds()) / 1000
+			res.Critical = c.critical
+			if res.Status == "" {
+				res.Status = StatusUp
+			}
+			results[i] = res
+		}(i, c)
+	}
+	wg.Wait()
+
+	report := Report{
+		Status:   StatusUp,
+		Draining: h.draining.Load(),
+		Checks:   make(map[string]Result, len(checks)),
+	}
+	for i, c := range checks {
+		res := results[i]
+		report.Checks[c.name] = res
+		switch {
+		case res.Status == StatusDown && c.critical:
+			report.Status = StatusDown
+		case res.Status != StatusUp && report.Status == StatusUp:
+			report.Status = StatusDegraded
+		}
+	}
+	if report.Draining {
+		report.Status = StatusDown
+	}
+	return report
+}
+
+// LivezHandler процесс жив и обслуживает HTTP, зависимости не проверяются
+func (h *Health) LivezHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("ok"))
+}
+
+// ReadyzHandler готовность принимать трафик: нет остановки и критичные зависимости доступны
+func (h *Health) ReadyzHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	if h.draining.Load() {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		w.Write([]byte("draining"))
+		return
+	}
+
+	report := h.Run(r.Context())
+	if report.Status == StatusDown {
+		var failed []string
+		for name, res := range report.Checks {
+			if res.Critical && res.Status == StatusDown {
+				failed = append(failed, name)
+			}
+		}
+		sort.Strings(failed)
+		w.WriteHeader(http.StatusServiceUnavailable)
+		for _, name := range failed {
+			w.Write([]byte(name + ": " + StatusDown + "\n"))
+		}
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("ok"))
+}
+
+// HealthHandler подробный JSON отчёт по всем проверкам
+func (h *Health) HealthHandler(w http.ResponseWriter, r *http.Request) {
+	report := h.Run(r.Context())
+
+	w.Header().Set("Content-Type", "application/json")
+	if report.Status == StatusDown {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	json.NewEncoder(w).Encode(report)
+}
@@ -0,0 +1,156 @@
+// internal/health/health_test.go
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakePinger struct {
+	err error
+}
+
+func (p fakePinger) Ping(ctx context.Context) error {
+	return p.err
+}
+
+func serve(h http.HandlerFunc) *httptest.ResponseRecorder {
+	rr := httptest.NewRecorder()
+	h(rr, httptest.NewRequest(http.MethodGet, "/", nil))
+	return rr
+}
+
+func TestLivez_AlwaysOK(t *testing.T) {
+	h := New()
+	h.Register("postgres", true, PingCheck(fakePinger{err: errors.New("down")}))
+	h.SetDraining()
+
+	rr := serve(h.LivezHandler)
+	assert.Equal(t, http.StatusOK, rr.Code)
+}
+
+func TestReadyz_AllUp(t *testing.T) {
+	h := New()
+	h.Register("postgres", true, PingCheck(fakePinger{}))
+	h.Register("cache_warmup", true, WarmupCheck(func() bool { return true }))
+
+	rr := serve(h.ReadyzHandler)
+	assert.Equal(t, http.StatusOK, rr.Code)
+}
+
+func TestReadyz_CriticalDown(t *testing.T) {
+	h := New()
+	h.Register("postgres", true, PingCheck(fakePinger{err: errors.New("connection refused")}))
+	h.Register("cache_warmup", true, WarmupCheck(func() bool { return true }))
+
+	rr := serve(h.ReadyzHandler)
+	assert.Equal(t, http.StatusServiceUnavailable, rr.Code)
+	assert.Contains(t, rr.Body.String(), "postgres")
+	assert.NotContains(t, rr.Body.String(), "cache_warmup")
+}
+
+func TestReadyz_WarmupInProgress(t *testing.T) {
+	h := New()
+	h.Register("cache_warmup", true, WarmupCheck(func() bool { return false }))
+
+	rr := serve(h.ReadyzHandler)
+	assert.Equal(t, http.StatusServiceUnavailable, rr.Code)
+}
+
+func TestReadyz_NonCriticalDownStillReady(t *testing.T) {
+	h := New()
+	h.Register("postgres", true, PingCheck(fakePinger{}))
+	h.Register("kafka", false, KafkaCheck(func() KafkaStatus { return KafkaStatus{Running: false} }))
+
+	rr := serve(h.ReadyzHandler)
+	assert.Equal(t, http.StatusOK, rr.Code)
+}
+
+func TestReadyz_Draining(t *testing.T) {
+	h := New()
+	h.Register("postgres", true, PingCheck(fakePinger{}))
+	h.SetDraining()
+
+	rr := serve(h.ReadyzHandler)
+	assert.Equal(t, http.StatusServiceUnavailable, rr.Code)
+	assert.Equal(t, "draining", rr.Body.String())
+}
+
+func TestHealth_Report(t *testing.T) {
+	lastFetch := time.Now()
+	h := New()
+	h.Register("postgres", true, PingCheck(fakePinger{}))
+	h.Register("kafka", false, KafkaCheck(func() KafkaStatus {
+		return KafkaStatus{Running: true, Lag: 42, LastFetch: lastFetch}
+	}))
+	h.Register("tracing", false, TracerCheck(func() ExporterStatus {
+		return ExporterStatus{LastError: errors.New("collector unavailable")}
+	}))
+
+	rr := serve(h.HealthHandler)
+	require.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "application/json", rr.Header().Get("Content-Type"))
+
+	var report Report
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &report))
+	assert.Equal(t, StatusDegraded, report.Status, "некритичная ошибка понижает статус до degraded")
+	assert.False(t, report.Draining)
+
+	require.Contains(t, report.Checks, "postgres")
+	assert.Equal(t, StatusUp, report.Checks["postgres"].Status)
+	assert.True(t, report.Checks["postgres"].Critical)
+
+	kafka := report.Checks["kafka"]
+	assert.Equal(t, StatusUp, kafka.Status)
+	assert.EqualValues(t, 42, kafka.Details["lag"])
+	assert.Equal(t, true, kafka.Details["running"])
+
+	tracing := report.Checks["tracing"]
+	assert.Equal(t, StatusDegraded, tracing.Status)
+	assert.Equal(t, "collector unavailable", tracing.Error)
+}
+
+func TestHealth_CriticalDownReturns503(t *testing.T) {
+	h := New()
+	h.Register("postgres", true, PingCheck(fakePinger{err: errors.New("timeout")}))
+
+	rr := serve(h.HealthHandler)
+	assert.Equal(t, http.StatusServiceUnavailable, rr.Code)
+
+	var report Report
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &report))
+	assert.Equal(t, StatusDown, report.Status)
+	assert.Equal(t, "timeout", report.Checks["postgres"].Error)
+}
+
+func TestKafkaCheck_FetchError(t *testing.T) {
+	res := KafkaCheck(func() KafkaStatus {
+		return KafkaStatus{Running: true, LastError: errors.New("broker not available")}
+	})(context.Background())
+
+	assert.Equal(t, StatusDegraded, res.Status)
+	assert.Equal(t, "broker not available", res.Error)
+}
+
+func TestRun_CheckTimeout(t *testing.T) {
+	h := New()
+	h.Register("slow", true, func(ctx context.Context) Result {
+		<-ctx.Done()
+		return Result{Status: StatusDown, Error: ctx.Err().Error()}
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	report := h.Run(ctx)
+	assert.Equal(t, StatusDown, report.Status)
+	assert.Equal(t, context.DeadlineExceeded.Error(), report.Checks["slow"].Error)
+}
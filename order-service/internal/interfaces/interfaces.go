@@ -1,12 +1,19 @@
 package interfaces
 
-import "order-service/models"
+import (
+	"context"
+	"time"
+
+	"order-service/models"
+)
 
 // Database интерфейс для работы с базой данных
 type Database interface {
-	SaveOrder(order *models.Order) error
-	GetOrder(orderUID string) (*models.Order, error)
+	// SaveOrder statusChanged — у ранее сохранённого заказа сменился статус хотя бы одного товара
+	SaveOrder(ctx context.Context, order *models.Order) (statusChanged bool, err error)
+	GetOrder(ctx context.Context, orderUID string) (*models.Order, error)
 	GetRecentOrders(limit int) (map[string]*models.Order, error)
+	ListOrders(ctx context.Context, limit, offset int) ([]*models.Order, error)
 	Close() error
 }
 
@@ -14,5 +21,48 @@ type Database interface {
 type Cache interface {
 	Set(orderUID string, order *models.Order)
 	Get(orderUID string) (*models.Order, bool)
+	GetOrLoad(orderUID string, load LoadFunc) (*models.Order, error)
+	// BulkSet записывает заказы, которых ещё нет в кэше; имеющиеся записи не перезаписываются
 	BulkSet(orders map[string]*models.Order)
+	Delete(orderUID string)
+	Clear()
+	Len() int
+	Stats() CacheStats
+	Close() error
+}
+
+// LoadFunc загружает заказ из первичного источника при промахе кэша
+type LoadFunc func(orderUID string) (*models.Order, error)
+
+// CacheStats снимок счётчиков кэша
+type CacheStats struct {
+	Hits      uint64
+	Misses    uint64
+	Evictions uint64
+	// Size записей в памяти реплики; у Redis не считается, чтобы снимок не сканировал базу
+	Size int
+}
+
+// AccessRecorder учитывает обращения к заказам для прогрева кэша
+type AccessRecorder interface {
+	RecordAccess(orderUID string)
+}
+
+// OrderFilter условия выборки страницы заказов, пустые поля не ограничивают
+type OrderFilter struct {
+	CustomerID      string
+	DeliveryService string
+	CreatedAfter    *time.Time
+	CreatedBefore   *time.Time
+}
+
+// OrderBatchStore пакетные выборки: каждая читает часть заказа сразу для всех ключей,
+// без запроса на каждый заказ. На них строятся GraphQL и постраничный список заказов
+type OrderBatchStore interface {
+	ListOrderHeaders(ctx context.Context, f OrderFilter, limit, offset int) ([]*models.Order, error)
+	OrdersByCustomers(ctx context.Context, ids []string, limit int) (map[string][]*models.Order, error)
+	OrderCountsByCustomer(ctx context.Context, ids []string) (map[string]int, error)
+	DeliveriesByOrder(ctx context.Context, uids []string) (map[string]models.Delivery, error)
+	PaymentsByOrder(ctx context.Context, uids []string) (map[string]models.Payment, error)
+	ItemsByOrder(ctx context.Context, uids []string) (map[string][]models.Item, error)
 }
@@ -0,0 +1,142 @@
+// internal/invalidation/invalidation.go
+package invalidation
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+
+	"order-service/internal/interfaces"
+	"order-service/internal/logging"
+	"order-service/internal/metrics"
+
+	"github.com/lib/pq"
+)
+
+// Channel канал PostgreSQL LISTEN/NOTIFY для событий инвалидации кэша
+const Channel = "order_invalidation"
+
+const (
+	ModeEvict   = "evict"   // удалить заказ из локального кэша
+	ModeRefresh = "refresh" // перечитать заказ из БД и положить в кэш
+
+	pingInterval = 90 * time.Second
+)
+
+// Event полезная нагрузка NOTIFY
+type Event struct {
+	OrderUID string `json:"order_uid"`
+	Origin   string `json:"origin"`
+}
+
+// Payload сериализует событие для pg_notify
+func Payload(orderUID, origin string) (string, error) {
+	data, err := json.Marshal(Event{OrderUID: orderUID, Origin: origin})
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// NewOrigin идентификатор реплики, чтобы не обрабатывать собственные события
+func NewOrigin() string {
+	host, err := os.Hostname()
+	if err != nil {
+		host = "unknown"
+	}
+	return fmt.Sprintf("%s-%d", host, os.Getpid())
+}
+
+// Listener подписка реплики на события инвалидации
+type Listener struct {
+	listener *pq.Listener
+	cache    interfaces.Cache
+	db       interfaces.Database
+	origin   string
+	mode     string
+}
+
+func NewListener(dsn, origin, mode string, cache interfaces.Cache, db interfaces.Database) (*Listener, error) {
+	if mode != ModeEvict && mode != ModeRefresh {
+		return nil, fmt.Errorf("неизвестный режим инвалидации %q", mode)
+	}
+
+	l := pq.NewListener(dsn, time.Second, time.Minute, func(ev pq.ListenerEventType, err error) {
+		if err != nil {
+			slog.Error("Ошибка соединения LISTEN", logging.Err(err))
+		}
+	})
+	if err := l.Listen(Channel); err != nil {
+		l.Close()
+		return nil, fmt.Errorf("не удалось подписаться на %s: %w", Channel, err)
+	}
+
+	return &Listener{
+		listener: l,
+		cache:    cache,
+		db:       db,
+		origin:   origin,
+		mode:     mode,
+	}, nil
+}
+
+func (l *Listener) Run(ctx context.Context) {
+	ticker := time.NewTicker(pingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			slog.InfoContext(ctx, "Подписка на инвалидацию остановлена по контексту")
+			return
+		case n := <-l.listener.Notify:
+			if n == nil {
+				// соединение переустановлено, события за время разрыва потеряны
+				slog.WarnContext(ctx, "LISTEN переподключен, локальный кэш сброшен")
+				l.cache.Clear()
+				metrics.CacheInvalidations.WithLabelValues("reset").Inc()
+				continue
+			}
+			l.handle(ctx, n.Extra)
+		case <-ticker.C:
+			if err := l.listener.Ping(); err != nil {
+				slog.ErrorContext(ctx, "Ошибка ping LISTEN", logging.Err(err))
+			}
+		}
+	}
+}
+
+func (l *Listener) handle(ctx context.Context, payload string) {
+	var ev Event
+	if err := json.Unmarshal([]byte(payload), &ev); err != nil || ev.OrderUID == "" {
+		slog.WarnContext(ctx, "Некорректное событие инвалидации", slog.String("payload", payload), logging.Err(err))
+		metrics.CacheInvalidations.WithLabelValues("error").Inc()
+		return
+	}
+
+	if ev.Origin == l.origin {
+		// своя запись уже положена в кэш консюмером
+		metrics.CacheInvalidations.WithLabelValues("skip_own").Inc()
+		return
+	}
+
+	if l.mode == ModeRefresh {
+		order, err := l.db.GetOrder(ctx, ev.OrderUID)
+		if err == nil {
+			l.cache.Set(ev.OrderUID, order)
+			metrics.CacheInvalidations.WithLabelValues("refresh").Inc()
+			return
+		}
+		slog.WarnContext(ctx, "Не удалось обновить заказ после инвалидации", slog.String("order_uid", ev.OrderUID), logging.Err(err))
+	}
+
+	l.cache.Delete(ev.OrderUID)
+	metrics.CacheInvalidations.WithLabelValues("evict").Inc()
+}
+
+func (l *Listener) Close() error {
+	return l.listener.Close()
+}
@@ -0,0 +1,101 @@
+// internal/invalidation/invalidation_test.go
+package invalidation
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"order-service/internal/cache"
+	"order-service/internal/mocks"
+	"order-service/models"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestListener(t *testing.T, mode string, db *mocks.MockDatabase) (*Listener, *cache.Cache) {
+	c := cache.New(5*time.Minute, 100)
+	t.Cleanup(func() { c.Close() })
+	return &Listener{cache: c, db: db, origin: "replica-a", mode: mode}, c
+}
+
+func TestPayloadRoundTrip(t *testing.T) {
+	payload, err := Payload("test123", "replica-b")
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"order_uid":"test123","origin":"replica-b"}`, payload)
+}
+
+func TestListener_EvictsForeignEvent(t *testing.T) {
+	l, c := newTestListener(t, ModeEvict, nil)
+	c.Set("test123", &models.Order{OrderUID: "test123"})
+
+	payload, _ := Payload("test123", "replica-b")
+	l.handle(context.Background(), payload)
+
+	_, found := c.Get("test123")
+	assert.False(t, found)
+}
+
+func TestListener_SkipsOwnEvent(t *testing.T) {
+	l, c := newTestListener(t, ModeEvict, nil)
+	c.Set("test123", &models.Order{OrderUID: "test123"})
+
+	payload, _ := Payload("test123", "replica-a")
+	l.handle(context.Background(), payload)
+
+	_, found := c.Get("test123")
+	assert.True(t, found, "собственные события не должны сбрасывать кэш")
+}
+
+func TestListener_RefreshFromDB(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockDB := mocks.NewMockDatabase(ctrl)
+	l, c := newTestListener(t, ModeRefresh, mockDB)
+	c.Set("test123", &models.Order{OrderUID: "test123", TrackNumber: "OLD"})
+
+	mockDB.EXPECT().GetOrder(gomock.Any(), "test123").Return(&models.Order{OrderUID: "test123", TrackNumber: "NEW"}, nil)
+
+	payload, _ := Payload("test123", "replica-b")
+	l.handle(context.Background(), payload)
+
+	result, found := c.Get("test123")
+	require.True(t, found)
+	assert.Equal(t, "NEW", result.TrackNumber)
+}
+
+func TestListener_RefreshFailureFallsBackToEvict(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockDB := mocks.NewMockDatabase(ctrl)
+	l, c := newTestListener(t, ModeRefresh, mockDB)
+	c.Set("test123", &models.Order{OrderUID: "test123"})
+
+	mockDB.EXPECT().GetOrder(gomock.Any(), "test123").Return(nil, errors.New("db connection failed"))
+
+	payload, _ := Payload("test123", "replica-b")
+	l.handle(context.Background(), payload)
+
+	_, found := c.Get("test123")
+	assert.False(t, found)
+}
+
+func TestListener_IgnoresMalformedPayload(t *testing.T) {
+	l, c := newTestListener(t, ModeEvict, nil)
+	c.Set("test123", &models.Order{OrderUID: "test123"})
+
+	l.handle(context.Background(), "not json")
+	l.handle(context.Background(), `{"origin":"replica-b"}`)
+
+	assert.Equal(t, 1, c.Len())
+}
+
+func TestNewListener_UnknownMode(t *testing.T) {
+	_, err := NewListener("postgres://localhost/none", "replica-a", "bogus", nil, nil)
+	assert.Error(t, err)
+}
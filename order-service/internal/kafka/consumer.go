@@ -6,12 +6,19 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"log"
+	"log/slog"
 	"math"
+	"order-service/internal/audit"
+	"order-service/internal/config"
 	"order-service/internal/interfaces"
+	"order-service/internal/logging"
 	"order-service/internal/metrics"
+	"order-service/internal/pubsub"
 	"order-service/internal/validation"
+	"order-service/internal/webhook"
 	"order-service/models"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"go.opentelemetry.io/otel/attribute"
@@ -30,45 +37,83 @@ type Consumer struct {
 	retryDelay  time.Duration
 	backoffMode string // "fixed" или "exponential"
 	tracer      trace.Tracer
+
+	// Audit журнал записей заказов; nil — журнал выключен
+	Audit *audit.Logger
+	// Hub подписчики на новые заказы; nil — рассылки нет
+	Hub *pubsub.Hub
+	// Webhooks рассылка сохранённых заказов партнёрам; nil — вебхуки выключены
+	Webhooks *webhook.Dispatcher
+
+	// состояние для health проверок
+	running   atomic.Bool
+	statusMu  sync.Mutex
+	lastErr   error
+	lastFetch time.Time
 }
 
-func NewConsumer(brokers []string, topic, groupID, dlqTopic string, db interfaces.Database, cache interfaces.Cache, tracer trace.Tracer) *Consumer {
+// Status состояние консюмера для /health
+type Status struct {
+	Running   bool
+	Lag       int64
+	LastError error
+	LastFetch time.Time
+}
+
+// NewConsumer reader и DLQ writer с TLS и SASL из конфигурации; ошибка — если не читаются сертификаты
+func NewConsumer(cfg config.KafkaConfig, db interfaces.Database, cache interfaces.Cache, tracer trace.Tracer) (*Consumer, error) {
+	dialer, transport, err := transports(cfg)
+	if err != nil {
+		return nil, err
+	}
 	r := kafka.NewReader(kafka.ReaderConfig{
-		Brokers:        brokers,
-		Topic:          topic,
-		GroupID:        groupID,
+		Brokers:        cfg.Brokers,
+		Topic:          cfg.Topic,
+		GroupID:        cfg.GroupID,
 		CommitInterval: 0,
+		Dialer:         dialer,
 	})
+	dlqWriter := &kafka.Writer{
+		Addr:     kafka.TCP(cfg.Brokers...),
+		Topic:    cfg.DLQTopic,
+		Balancer: &kafka.LeastBytes{},
+	}
+	// nil *kafka.Transport в интерфейсе RoundTripper не равен nil, поэтому только при настройке
+	if transport != nil {
+		dlqWriter.Transport = transport
+	}
 	return &Consumer{
-		reader: r,
-		db:     db,
-		cache:  cache,
-		dlqWriter: &kafka.Writer{
-			Addr:     kafka.TCP(brokers...),
-			Topic:    dlqTopic,
-			Balancer: &kafka.LeastBytes{},
-		},
-		maxRetries:  3,
-		retryDelay:  2 * time.Second,
-		backoffMode: "exponential", // можно "fixed"
+		reader:      r,
+		db:          db,
+		cache:       cache,
+		dlqWriter:   dlqWriter,
+		maxRetries:  cfg.MaxRetries,
+		retryDelay:  cfg.RetryDelay,
+		backoffMode: cfg.BackoffMode,
 		tracer:      tracer,
-	}
+	}, nil
 }
 
 func (c *Consumer) Run(ctx context.Context) {
+	c.running.Store(true)
+	defer c.running.Store(false)
+
 	for {
 		m, err := c.reader.FetchMessage(ctx)
 		if err != nil {
 			if errors.Is(err, context.Canceled) {
-				log.Println("консюмер остановился по контексту")
+				slog.InfoContext(ctx, "Консюмер остановился по контексту")
 				return
 			}
-			log.Println("Ошибка выборки Kafka:", err)
+			slog.ErrorContext(ctx, "Ошибка выборки Kafka", logging.Err(err))
+			c.setFetchResult(err)
 			continue
 		}
+		c.setFetchResult(nil)
 
 		if err := c.processWithRetry(ctx, m); err != nil {
-			log.Printf("Ошибка после всех ретраев: %v", err)
+			slog.ErrorContext(ctx, "Ошибка после всех ретраев, сообщение уходит в DLQ",
+				logging.Err(err), slog.Int("partition", m.Partition), slog.Int64("offset", m.Offset))
 			c.sendToDLQ(ctx, m.Value)
 		} else {
 			c.commit(ctx, m)
@@ -95,7 +140,8 @@ func (c *Consumer) processWithRetry(ctx context.Context, m kafka.Message) error
 		if c.backoffMode == "exponential" {
 			delay = time.Duration(float64(c.retryDelay) * math.Pow(2, float64(attempt)))
 		}
-		log.Printf("ошибка обработки (попытка %d/%d): %v, жду %v перед повтором", attempt+1, c.maxRetries, err, delay)
+		slog.WarnContext(ctx, "Ошибка обработки, повтор",
+			logging.Err(err), slog.Int("attempt", attempt+1), slog.Int("max_retries", c.maxRetries), slog.Duration("delay", delay))
 
 		select {
 		case <-time.After(delay):
@@ -128,6 +174,7 @@ func (c *Consumer) processMessage(ctx context.Context, m kafka.Message) error {
 	}
 
 	span.SetAttributes(attribute.String("order.uid", order.OrderUID))
+	ctx = logging.WithOrderUID(ctx, order.OrderUID)
 
 	if err := validation.ValidateOrder(&order); err != nil {
 		errMsg := "невалидные данные заказа"
@@ -138,7 +185,8 @@ func (c *Consumer) processMessage(ctx context.Context, m kafka.Message) error {
 		return err
 	}
 
-	if err := c.db.SaveOrder(&order); err != nil {
+	statusChanged, err := c.db.SaveOrder(ctx, &order)
+	if err != nil {
 		errMsg := "ошибка сохранения в БД"
 		err := fmt.Errorf(errMsg+": %w", err)
 		span.RecordError(err)
@@ -148,8 +196,11 @@ func (c *Consumer) processMessage(ctx context.Context, m kafka.Message) error {
 	}
 
 	c.cache.Set(order.OrderUID, &order)
+	c.Audit.Record(ctx, audit.ActionWrite, order.OrderUID, nil)
+	c.Hub.Publish(&order)
+	c.Webhooks.NotifyStored(ctx, &order, statusChanged)
 	msgSucc := "Заказ " + order.OrderUID + " успешно обработан и сохранен"
-	log.Println(msgSucc)
+	slog.InfoContext(ctx, "Заказ обработан и сохранен")
 	span.SetStatus(codes.Ok, msgSucc)
 	metrics.OrdersProcessed.WithLabelValues("kafka", "success").Inc()
 	return nil
@@ -157,7 +208,7 @@ func (c *Consumer) processMessage(ctx context.Context, m kafka.Message) error {
 
 func (c *Consumer) commit(ctx context.Context, m kafka.Message) {
 	if err := c.reader.CommitMessages(ctx, m); err != nil {
-		log.Println("Ошибка коммита:", err)
+		slog.ErrorContext(ctx, "Ошибка коммита", logging.Err(err), slog.Int64("offset", m.Offset))
 	}
 }
 
@@ -168,20 +219,41 @@ func (c *Consumer) sendToDLQ(ctx context.Context, msg []byte) {
 	if err != nil {
 		switch {
 		case errors.Is(err, context.Canceled):
-			log.Println("контекст отменён, выйдем")
+			slog.InfoContext(ctx, "Контекст отменён, сообщение не отправлено в DLQ")
 		case errors.Is(err, context.DeadlineExceeded):
-			log.Println("таймаут при записи в DLQ")
+			slog.ErrorContext(ctx, "Таймаут при записи в DLQ")
 		default:
-			log.Println("не удалось отправить в DLQ:", err)
+			slog.ErrorContext(ctx, "Не удалось отправить в DLQ", logging.Err(err))
 		}
 	}
 }
 
+func (c *Consumer) setFetchResult(err error) {
+	c.statusMu.Lock()
+	defer c.statusMu.Unlock()
+	c.lastErr = err
+	if err == nil {
+		c.lastFetch = time.Now()
+	}
+}
+
+// Status запущен ли цикл чтения, отставание группы и результат последней выборки
+func (c *Consumer) Status() Status {
+	c.statusMu.Lock()
+	defer c.statusMu.Unlock()
+	return Status{
+		Running:   c.running.Load(),
+		Lag:       c.reader.Stats().Lag,
+		LastError: c.lastErr,
+		LastFetch: c.lastFetch,
+	}
+}
+
 func (c *Consumer) Close() {
 	if err := c.reader.Close(); err != nil {
-		log.Println("Ошибка закрытия reader:", err)
+		slog.Error("Ошибка закрытия reader", logging.Err(err))
 	}
 	if err := c.dlqWriter.Close(); err != nil {
-		log.Println("Ошибка закрытия DLQ writer:", err)
+		slog.Error("Ошибка закрытия DLQ writer", logging.Err(err))
 	}
 }
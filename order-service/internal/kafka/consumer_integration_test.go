@@ -13,6 +13,7 @@ import (
 
 	"github.com/brianvoe/gofakeit/v6"
 
+	"order-service/internal/config"
 	"order-service/internal/db"
 	"order-service/internal/mocks"
 	"order-service/models"
@@ -123,15 +124,20 @@ func TestKafkaConsumer_Integration(t *testing.T) {
 	mockCache.EXPECT().Set(gomock.Any(), gomock.Any()).AnyTimes()
 	mockCache.EXPECT().BulkSet(gomock.Any()).AnyTimes()
 
-	consumer := NewConsumer(
-		[]string{broker},
-		"orders",
-		"group1",
-		"orders_dlq",
+	cfg := config.Default().Kafka
+	cfg.Brokers = []string{broker}
+	cfg.Topic = "orders"
+	cfg.GroupID = "group1"
+	cfg.DLQTopic = "orders_dlq"
+	consumer, err := NewConsumer(
+		cfg,
 		dbConn,
 		mockCache,
 		otel.Tracer("test"),
 	)
+	if err != nil {
+		t.Fatalf("failed to create consumer: %v", err)
+	}
 
 	order := createIntegrationTestOrder()
 	msgBytes, _ := json.Marshal(order)
@@ -140,7 +146,7 @@ func TestKafkaConsumer_Integration(t *testing.T) {
 	err = consumer.processMessage(context.Background(), msg)
 	assert.NoError(t, err)
 
-	savedOrder, err := dbConn.GetOrder(order.OrderUID)
+	savedOrder, err := dbConn.GetOrder(context.Background(), order.OrderUID)
 	assert.NoError(t, err)
 	assert.Equal(t, order.OrderUID, savedOrder.OrderUID)
 	assert.Equal(t, order.Delivery.Name, savedOrder.Delivery.Name)
@@ -3,18 +3,25 @@ package kafka
 import (
 	"context"
 	"encoding/json"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 	"time"
 
 	"go.opentelemetry.io/otel"
 
+	"order-service/internal/audit"
+	"order-service/internal/config"
 	"order-service/internal/mocks"
+	"order-service/internal/pubsub"
+	"order-service/internal/webhook"
 	"order-service/models"
 
 	"github.com/brianvoe/gofakeit/v6"
 	"github.com/golang/mock/gomock"
 	"github.com/segmentio/kafka-go"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func createTestOrder() *models.Order {
@@ -97,23 +104,147 @@ func TestConsumer_ProcessMessage_ValidMessage(t *testing.T) {
 	mockDB := mocks.NewMockDatabase(ctrl)
 	mockCache := mocks.NewMockCache(ctrl)
 
-	consumer := NewConsumer(
-		[]string{"localhost:9092"},
-		"test",
-		"group",
-		"dlq",
+	cfg := config.Default().Kafka
+	cfg.Brokers = []string{"localhost:9092"}
+	cfg.Topic = "test"
+	cfg.GroupID = "group"
+	cfg.DLQTopic = "dlq"
+	consumer, err := NewConsumer(
+		cfg,
 		mockDB,
 		mockCache,
 		otel.Tracer("test"),
 	)
+	require.NoError(t, err)
 
 	order := createTestOrder()
 	messageBytes, _ := json.Marshal(order)
 	msg := kafka.Message{Value: messageBytes}
 
-	mockDB.EXPECT().SaveOrder(gomock.Any()).Return(nil)
+	mockDB.EXPECT().SaveOrder(gomock.Any(), gomock.Any()).Return(false, nil)
 	mockCache.EXPECT().Set(order.OrderUID, gomock.Any())
 
-	err := consumer.processMessage(context.Background(), msg)
+	err = consumer.processMessage(context.Background(), msg)
 	assert.NoError(t, err)
 }
+
+type auditEvents []audit.Event
+
+func (a *auditEvents) InsertAuditEvents(ctx context.Context, events []audit.Event) error {
+	*a = append(*a, events...)
+	return nil
+}
+
+func (a *auditEvents) ListAuditEvents(ctx context.Context, orderUID string, limit int) ([]audit.Event, error) {
+	return *a, nil
+}
+
+func TestConsumer_ProcessMessage_RecordsAudit(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mockDB := mocks.NewMockDatabase(ctrl)
+	mockCache := mocks.NewMockCache(ctrl)
+
+	cfg := config.Default().Kafka
+	cfg.Brokers = []string{"localhost:9092"}
+	consumer, err := NewConsumer(cfg, mockDB, mockCache, otel.Tracer("test"))
+	require.NoError(t, err)
+	var events auditEvents
+	consumer.Audit = audit.New(&events, 10, time.Hour)
+
+	order := createTestOrder()
+	messageBytes, _ := json.Marshal(order)
+
+	mockDB.EXPECT().SaveOrder(gomock.Any(), gomock.Any()).Return(false, nil)
+	mockCache.EXPECT().Set(order.OrderUID, gomock.Any())
+	assert.NoError(t, consumer.processMessage(context.Background(), kafka.Message{Value: messageBytes}))
+
+	// битое сообщение не сохраняется и в журнал не попадает
+	assert.Error(t, consumer.processMessage(context.Background(), kafka.Message{Value: []byte("{")}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	consumer.Audit.Run(ctx)
+
+	require.Len(t, events, 1)
+	assert.Equal(t, audit.ActorKafka, events[0].Actor)
+	assert.Equal(t, audit.ActionWrite, events[0].Action)
+	assert.Equal(t, order.OrderUID, events[0].OrderUID)
+}
+
+func TestConsumer_ProcessMessage_PublishesOrder(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mockDB := mocks.NewMockDatabase(ctrl)
+	mockCache := mocks.NewMockCache(ctrl)
+
+	cfg := config.Default().Kafka
+	cfg.Brokers = []string{"localhost:9092"}
+	consumer, err := NewConsumer(cfg, mockDB, mockCache, otel.Tracer("test"))
+	require.NoError(t, err)
+	consumer.Hub = pubsub.NewHub()
+	sub := consumer.Hub.Subscribe(pubsub.Filter{}, 10)
+	defer sub.Close()
+
+	order := createTestOrder()
+	messageBytes, _ := json.Marshal(order)
+
+	mockDB.EXPECT().SaveOrder(gomock.Any(), gomock.Any()).Return(false, nil)
+	mockCache.EXPECT().Set(order.OrderUID, gomock.Any())
+	require.NoError(t, consumer.processMessage(context.Background(), kafka.Message{Value: messageBytes}))
+	// заказ, не прошедший валидацию, подписчикам не уходит
+	invalid, _ := json.Marshal(models.Order{OrderUID: "bad"})
+	assert.Error(t, consumer.processMessage(context.Background(), kafka.Message{Value: invalid}))
+
+	require.Len(t, sub.C, 1)
+	assert.Equal(t, order.OrderUID, (<-sub.C).OrderUID)
+}
+
+// oneWebhook одна активная подписка без учёта результатов доставки
+type oneWebhook struct{ sub webhook.Subscription }
+
+func (s oneWebhook) ActiveWebhooks(ctx context.Context) ([]webhook.Subscription, error) {
+	return []webhook.Subscription{s.sub}, nil
+}
+
+func (s oneWebhook) WebhookDelivered(ctx context.Context, id int64) error { return nil }
+
+func (s oneWebhook) WebhookFailed(ctx context.Context, id int64, lastError string, maxFailures int) (bool, error) {
+	return false, nil
+}
+
+func TestConsumer_ProcessMessage_NotifiesWebhooks(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mockDB := mocks.NewMockDatabase(ctrl)
+	mockCache := mocks.NewMockCache(ctrl)
+
+	received := make(chan webhook.Payload, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var p webhook.Payload
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&p))
+		received <- p
+	}))
+	defer srv.Close()
+
+	cfg := config.Default().Kafka
+	cfg.Brokers = []string{"localhost:9092"}
+	consumer, err := NewConsumer(cfg, mockDB, mockCache, otel.Tracer("test"))
+	require.NoError(t, err)
+	consumer.Webhooks = webhook.New(oneWebhook{webhook.Subscription{ID: 1, URL: srv.URL, Secret: "s"}},
+		config.Default().Webhook, otel.Tracer("test"))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go consumer.Webhooks.Run(ctx)
+
+	order := createTestOrder()
+	messageBytes, _ := json.Marshal(order)
+	mockDB.EXPECT().SaveOrder(gomock.Any(), gomock.Any()).Return(false, nil)
+	mockCache.EXPECT().Set(order.OrderUID, gomock.Any())
+	require.NoError(t, consumer.processMessage(context.Background(), kafka.Message{Value: messageBytes}))
+
+	select {
+	case p := <-received:
+		assert.Equal(t, webhook.EventOrderStored, p.Event)
+		assert.Equal(t, order.OrderUID, p.Order.OrderUID)
+	case <-time.After(5 * time.Second):
+		t.Fatal("вебхук не отправлен")
+	}
+}
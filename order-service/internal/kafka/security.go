@@ -0,0 +1,73 @@
+// internal/kafka/security.go
+package kafka
+
+import (
+	"crypto/tls"
+	"fmt"
+	"time"
+
+	"order-service/internal/config"
+	"order-service/internal/tlsconfig"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/segmentio/kafka-go/sasl"
+	"github.com/segmentio/kafka-go/sasl/plain"
+	"github.com/segmentio/kafka-go/sasl/scram"
+)
+
+// dialTimeout как у kafka.DefaultDialer
+const dialTimeout = 10 * time.Second
+
+// transports подключения reader и DLQ writer с TLS и SASL из конфигурации.
+// Без TLS и SASL возвращает nil: kafka-go использует свои значения по умолчанию
+func transports(cfg config.KafkaConfig) (*kafka.Dialer, *kafka.Transport, error) {
+	tlsCfg, err := clientTLS(cfg.TLS)
+	if err != nil {
+		return nil, nil, err
+	}
+	mechanism, err := saslMechanism(cfg.SASL)
+	if err != nil {
+		return nil, nil, err
+	}
+	if tlsCfg == nil && mechanism == nil {
+		return nil, nil, nil
+	}
+
+	dialer := &kafka.Dialer{
+		Timeout:       dialTimeout,
+		DualStack:     true,
+		TLS:           tlsCfg,
+		SASLMechanism: mechanism,
+	}
+	transport := &kafka.Transport{
+		DialTimeout: dialTimeout,
+		TLS:         tlsCfg,
+		SASL:        mechanism,
+	}
+	return dialer, transport, nil
+}
+
+func clientTLS(cfg config.KafkaTLSConfig) (*tls.Config, error) {
+	if !cfg.Enabled {
+		return nil, nil
+	}
+	tlsCfg, err := tlsconfig.Client(cfg.CAFile, cfg.CertFile, cfg.KeyFile, cfg.InsecureSkipVerify)
+	if err != nil {
+		return nil, fmt.Errorf("kafka TLS: %w", err)
+	}
+	return tlsCfg, nil
+}
+
+func saslMechanism(cfg config.KafkaSASLConfig) (sasl.Mechanism, error) {
+	switch cfg.Mechanism {
+	case "":
+		return nil, nil
+	case "plain":
+		return plain.Mechanism{Username: cfg.Username, Password: cfg.Password}, nil
+	case "scram-sha-256":
+		return scram.Mechanism(scram.SHA256, cfg.Username, cfg.Password)
+	case "scram-sha-512":
+		return scram.Mechanism(scram.SHA512, cfg.Username, cfg.Password)
+	}
+	return nil, fmt.Errorf("неизвестный механизм SASL %q", cfg.Mechanism)
+}
@@ -0,0 +1,65 @@
+package kafka
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"order-service/internal/config"
+
+	"github.com/segmentio/kafka-go/sasl/plain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTransports_Plaintext(t *testing.T) {
+	dialer, transport, err := transports(config.Default().Kafka)
+	require.NoError(t, err)
+	assert.Nil(t, dialer)
+	assert.Nil(t, transport)
+}
+
+func TestTransports_TLSAndSASL(t *testing.T) {
+	cfg := config.Default().Kafka
+	cfg.TLS = config.KafkaTLSConfig{Enabled: true}
+	cfg.SASL = config.KafkaSASLConfig{Mechanism: "plain", Username: "svc", Password: "secret"}
+
+	dialer, transport, err := transports(cfg)
+	require.NoError(t, err)
+	require.NotNil(t, dialer.TLS)
+	assert.Same(t, dialer.TLS, transport.TLS)
+	assert.Equal(t, plain.Mechanism{Username: "svc", Password: "secret"}, dialer.SASLMechanism)
+	assert.Equal(t, dialer.SASLMechanism, transport.SASL)
+}
+
+func TestSASLMechanism(t *testing.T) {
+	for mechanism, name := range map[string]string{
+		"plain":         "PLAIN",
+		"scram-sha-256": "SCRAM-SHA-256",
+		"scram-sha-512": "SCRAM-SHA-512",
+	} {
+		m, err := saslMechanism(config.KafkaSASLConfig{Mechanism: mechanism, Username: "svc", Password: "secret"})
+		require.NoError(t, err, mechanism)
+		assert.Equal(t, name, m.Name())
+	}
+
+	m, err := saslMechanism(config.KafkaSASLConfig{})
+	require.NoError(t, err)
+	assert.Nil(t, m)
+
+	_, err = saslMechanism(config.KafkaSASLConfig{Mechanism: "gssapi"})
+	assert.Error(t, err)
+}
+
+func TestTransports_BadCA(t *testing.T) {
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	require.NoError(t, os.WriteFile(caFile, []byte("not a pem"), 0o600))
+
+	cfg := config.Default().Kafka
+	cfg.TLS = config.KafkaTLSConfig{Enabled: true, CAFile: caFile}
+	_, _, err := transports(cfg)
+	assert.Error(t, err)
+
+	_, err = NewConsumer(cfg, nil, nil, nil)
+	assert.Error(t, err)
+}
@@ -0,0 +1,90 @@
+// internal/logging/logging.go
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+
+	"order-service/internal/config"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+type orderUIDKey struct{}
+
+// WithOrderUID кладёт uid заказа в контекст, он попадёт во все записи с этим контекстом
+func WithOrderUID(ctx context.Context, orderUID string) context.Context {
+	return context.WithValue(ctx, orderUIDKey{}, orderUID)
+}
+
+// OrderUID uid заказа из контекста или пустая строка
+func OrderUID(ctx context.Context) string {
+	uid, _ := ctx.Value(orderUIDKey{}).(string)
+	return uid
+}
+
+// Err атрибут ошибки для записи лога
+func Err(err error) slog.Attr {
+	return slog.Any("error", err)
+}
+
+// New логгер по конфигурации: json (по умолчанию) или text, с полями trace_id, span_id и order_uid из контекста
+func New(cfg config.LoggingConfig, w io.Writer) (*slog.Logger, error) {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(cfg.Level)); err != nil {
+		return nil, fmt.Errorf("некорректный уровень логирования %q: %w", cfg.Level, err)
+	}
+	opts := &slog.HandlerOptions{Level: level}
+
+	var h slog.Handler
+	switch cfg.Format {
+	case "json", "":
+		h = slog.NewJSONHandler(w, opts)
+	case "text":
+		h = slog.NewTextHandler(w, opts)
+	default:
+		return nil, fmt.Errorf("некорректный формат логов %q", cfg.Format)
+	}
+	return slog.New(contextHandler{Handler: h}), nil
+}
+
+// Setup делает логгер глобальным, стандартный log тоже пишет через него
+func Setup(cfg config.LoggingConfig) error {
+	logger, err := New(cfg, os.Stdout)
+	if err != nil {
+		return err
+	}
+	slog.SetDefault(logger)
+	return nil
+}
+
+// contextHandler дополняет записи полями корреляции из контекста
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h contextHandler) Handle(ctx context.Context, r slog.Record) error {
+	if ctx != nil {
+		if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+			r.AddAttrs(
+				slog.String("trace_id", sc.TraceID().String()),
+				slog.String("span_id", sc.SpanID().String()),
+			)
+		}
+		if uid := OrderUID(ctx); uid != "" {
+			r.AddAttrs(slog.String("order_uid", uid))
+		}
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{Handler: h.Handler.WithAttrs(attrs)}
+}
+
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{Handler: h.Handler.WithGroup(name)}
+}
@@ -0,0 +1,106 @@
+// internal/logging/logging_test.go
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"order-service/internal/config"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func newTestLogger(t *testing.T, level string) (*slog.Logger, *bytes.Buffer) {
+	var buf bytes.Buffer
+	logger, err := New(config.LoggingConfig{Level: level, Format: "json"}, &buf)
+	require.NoError(t, err)
+	return logger, &buf
+}
+
+func decode(t *testing.T, buf *bytes.Buffer) map[string]interface{} {
+	var rec map[string]interface{}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &rec))
+	return rec
+}
+
+func TestNew_JSONWithCorrelationFields(t *testing.T) {
+	logger, buf := newTestLogger(t, "info")
+
+	traceID, _ := trace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+	spanID, _ := trace.SpanIDFromHex("00f067aa0ba902b7")
+	ctx := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID: traceID,
+		SpanID:  spanID,
+	}))
+	ctx = WithOrderUID(ctx, "b563feb7b2b84b6test")
+
+	logger.ErrorContext(ctx, "Ошибка сохранения", Err(errors.New("connection reset")))
+
+	rec := decode(t, buf)
+	assert.Equal(t, "ERROR", rec["level"])
+	assert.Equal(t, "Ошибка сохранения", rec["msg"])
+	assert.Equal(t, "connection reset", rec["error"])
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", rec["trace_id"])
+	assert.Equal(t, "00f067aa0ba902b7", rec["span_id"])
+	assert.Equal(t, "b563feb7b2b84b6test", rec["order_uid"])
+}
+
+func TestNew_NoCorrelationWithoutContext(t *testing.T) {
+	logger, buf := newTestLogger(t, "info")
+
+	logger.Info("Запуск сервера", slog.String("addr", ":8081"))
+
+	rec := decode(t, buf)
+	assert.Equal(t, ":8081", rec["addr"])
+	assert.NotContains(t, rec, "trace_id")
+	assert.NotContains(t, rec, "order_uid")
+}
+
+func TestNew_LevelFilter(t *testing.T) {
+	logger, buf := newTestLogger(t, "warn")
+
+	logger.Info("не попадёт")
+	logger.Debug("тоже не попадёт")
+	assert.Zero(t, buf.Len())
+
+	logger.Warn("попадёт")
+	assert.Contains(t, buf.String(), "попадёт")
+}
+
+func TestNew_WithAttrsKeepsCorrelation(t *testing.T) {
+	logger, buf := newTestLogger(t, "debug")
+
+	logger.With(slog.String("component", "kafka")).DebugContext(WithOrderUID(context.Background(), "uid-1"), "Обработка")
+
+	rec := decode(t, buf)
+	assert.Equal(t, "kafka", rec["component"])
+	assert.Equal(t, "uid-1", rec["order_uid"])
+}
+
+func TestNew_TextFormat(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := New(config.LoggingConfig{Level: "info", Format: "text"}, &buf)
+	require.NoError(t, err)
+
+	logger.InfoContext(WithOrderUID(context.Background(), "uid-1"), "ok")
+	assert.True(t, strings.Contains(buf.String(), "order_uid=uid-1"))
+}
+
+func TestNew_InvalidConfig(t *testing.T) {
+	_, err := New(config.LoggingConfig{Level: "verbose", Format: "json"}, &bytes.Buffer{})
+	assert.Error(t, err)
+
+	_, err = New(config.LoggingConfig{Level: "info", Format: "xml"}, &bytes.Buffer{})
+	assert.Error(t, err)
+}
+
+func TestOrderUID_Empty(t *testing.T) {
+	assert.Equal(t, "", OrderUID(context.Background()))
+}
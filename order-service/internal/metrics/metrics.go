@@ -29,7 +29,43 @@ var (
 			Name: "cache_operations_total",
 			Help: "Total cache operations",
 		},
-		[]string{"type", "result"}, // type: get, set; result: hit, miss
+		[]string{"type", "result"}, // type: get, set, delete, clear, load; result: hit, miss, success, error, shared, negative_hit
+	)
+
+	CacheInvalidations = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "cache_invalidations_total",
+			Help: "Total cache invalidation events received from other replicas",
+		},
+		[]string{"action"}, // action: evict, refresh, skip_own, reset, error
+	)
+
+	CacheWarmupTarget = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "cache_warmup_target_orders",
+			Help: "Number of orders selected for cache warm-up",
+		},
+	)
+
+	CacheWarmupLoaded = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "cache_warmup_loaded_orders",
+			Help: "Number of orders loaded into cache by warm-up so far",
+		},
+	)
+
+	CacheWarmupReady = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "cache_warmup_ready",
+			Help: "1 when cache warm-up has finished",
+		},
+	)
+
+	CacheWarmupDuration = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "cache_warmup_duration_seconds",
+			Help: "Duration of the last cache warm-up",
+		},
 	)
 
 	DBOperations = promauto.NewCounterVec(
@@ -37,7 +73,7 @@ var (
 			Name: "db_operations_total",
 			Help: "Total database operations",
 		},
-		[]string{"operation", "status"}, // operation: save, get; status: success, error
+		[]string{"operation", "status"}, // operation: save, get, list, batch, record_access; status: success, error
 	)
 
 	HTTPRequests = promauto.NewCounterVec(
@@ -45,7 +81,23 @@ var (
 			Name: "http_requests_total",
 			Help: "Total HTTP requests",
 		},
-		[]string{"method", "path", "status"},
+		[]string{"method", "path", "status"}, // path: шаблон маршрута ServeMux или unmatched; status: код ответа
+	)
+
+	HTTPRequestsInFlight = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "http_requests_in_flight",
+			Help: "Number of HTTP requests currently being served",
+		},
+	)
+
+	HTTPResponseSize = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "http_response_size_bytes",
+			Help:    "HTTP response body size",
+			Buckets: prometheus.ExponentialBuckets(100, 4, 8), // 100B .. 1.6MB
+		},
+		[]string{"method", "path"},
 	)
 
 	HTTPResponseTime = promauto.NewHistogramVec(
@@ -56,6 +108,139 @@ var (
 		},
 		[]string{"method", "path"},
 	)
+
+	AuthRequests = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "auth_requests_total",
+			Help: "Total authentication decisions for protected endpoints",
+		},
+		[]string{"method", "result"}, // method: api_key, jwt, none; result: success, unauthenticated, invalid, forbidden, error
+	)
+
+	HTTPRateLimited = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "http_rate_limited_total",
+			Help: "Total HTTP requests rejected with 429 by the rate limiter",
+		},
+		[]string{"method", "path", "client"}, // path: шаблон маршрута; client: ip, api_key, jwt, auth_failures
+	)
+
+	RateLimitBuckets = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "http_rate_limit_buckets",
+			Help: "Number of active rate limiter buckets (route and client pairs)",
+		},
+	)
+
+	TLSReloads = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "tls_reload_total",
+			Help: "Total reloads of the HTTP server certificate and client CA",
+		},
+		[]string{"result"}, // result: success, error
+	)
+
+	TLSCertExpiry = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "tls_certificate_expiry_timestamp_seconds",
+			Help: "NotAfter of the loaded HTTP server certificate as a Unix timestamp",
+		},
+	)
+
+	AuditEvents = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "audit_events_total",
+			Help: "Total audit log events by action and outcome",
+		},
+		[]string{"action", "result"}, // action: read, write; result: written, dropped, error
+	)
+
+	StreamSubscribers = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "order_stream_subscribers",
+			Help: "Number of active subscribers to newly ingested orders",
+		},
+	)
+
+	StreamDropped = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: "order_stream_dropped_total",
+			Help: "Total orders not delivered to a subscriber because its buffer was full",
+		},
+	)
+
+	StreamDisconnects = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "order_stream_disconnects_total",
+			Help: "Total closed order streams by transport and reason",
+		},
+		[]string{"transport", "reason"}, // transport: sse, websocket; reason: client, write_timeout, error, shutdown
+	)
+
+	GRPCRequests = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "grpc_requests_total",
+			Help: "Total gRPC requests",
+		},
+		[]string{"method", "code"}, // method: полное имя /order.v1.OrderService/GetOrder; code: код статуса gRPC
+	)
+
+	GRPCRateLimited = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "grpc_rate_limited_total",
+			Help: "Total gRPC calls rejected with RESOURCE_EXHAUSTED by the rate limiter",
+		},
+		[]string{"method", "client"}, // client: ip, api_key, jwt, auth_failures
+	)
+
+	GRPCResponseTime = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "grpc_response_time_seconds",
+			Help:    "gRPC unary call duration",
+			Buckets: []float64{0.01, 0.05, 0.1, 0.5, 1, 2, 5},
+		},
+		[]string{"method"},
+	)
+
+	WebhookDeliveries = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "webhook_deliveries_total",
+			Help: "Total webhook delivery attempts and events by outcome",
+		},
+		[]string{"result"}, // result: delivered, retry, failed, dropped
+	)
+
+	WebhookResponseTime = promauto.NewHistogram(
+		prometheus.HistogramOpts{
+			Name:    "webhook_response_time_seconds",
+			Help:    "Webhook subscriber response time per attempt",
+			Buckets: []float64{0.05, 0.1, 0.5, 1, 2, 5, 10},
+		},
+	)
+
+	WebhooksDisabled = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: "webhooks_disabled_total",
+			Help: "Total webhook subscriptions disabled after repeated failed deliveries",
+		},
+	)
+
+	GraphQLRequests = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "graphql_requests_total",
+			Help: "Total GraphQL requests by outcome",
+		},
+		[]string{"result"}, // result: success, error (ошибки в ответе, включая частичные), bad_request, too_complex
+	)
+
+	GraphQLBatchSize = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "graphql_batch_keys",
+			Help:    "Number of keys fetched by one batched GraphQL loader query",
+			Buckets: []float64{1, 2, 5, 10, 20, 50, 100},
+		},
+		[]string{"loader"}, // loader: deliveries, payments, items, customer_counts, customer_orders
+	)
 )
 
 func InitMetrics() {
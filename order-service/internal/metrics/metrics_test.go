@@ -51,3 +51,18 @@ func TestHTTPResponseTimeHistogram(t *testing.T) {
 
 	require.Equal(t, 1, testutil.CollectAndCount(HTTPResponseTime))
 }
+
+func TestHTTPRequestsInFlightGauge(t *testing.T) {
+	HTTPRequestsInFlight.Inc()
+	require.Equal(t, float64(1), testutil.ToFloat64(HTTPRequestsInFlight))
+	HTTPRequestsInFlight.Dec()
+	require.Equal(t, float64(0), testutil.ToFloat64(HTTPRequestsInFlight))
+}
+
+func TestHTTPResponseSizeHistogram(t *testing.T) {
+	require.Equal(t, 0, testutil.CollectAndCount(HTTPResponseSize))
+
+	HTTPResponseSize.WithLabelValues("GET", "/order/{uid}").Observe(1024)
+
+	require.Equal(t, 1, testutil.CollectAndCount(HTTPResponseSize))
+}
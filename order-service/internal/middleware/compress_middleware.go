@@ -0,0 +1,203 @@
+// internal/middleware/compress_middleware.go
+package middleware
+
+import (
+	"compress/gzip"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/andybalholm/brotli"
+)
+
+const (
+	encodingBrotli = "br"
+	encodingGzip   = "gzip"
+
+	// ответы меньше этого размера не сжимаются: заголовки сжатого ответа съедят выигрыш
+	minCompressSize = 512
+)
+
+var (
+	gzipPool   = sync.Pool{New: func() interface{} { return gzip.NewWriter(io.Discard) }}
+	brotliPool = sync.Pool{New: func() interface{} { return brotli.NewWriterLevel(io.Discard, brotli.DefaultCompression) }}
+)
+
+// CompressMiddleware сжимает ответ в br или gzip по Accept-Encoding клиента.
+// Не трогает уже сжатые ответы (например /metrics), 206/304, поток событий и несжимаемые типы
+func CompressMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Accept-Encoding")
+
+		encoding := negotiateEncoding(r.Header.Get("Accept-Encoding"))
+		if encoding == "" || r.Method == http.MethodHead {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		cw := &compressWriter{ResponseWriter: w, encoding: encoding}
+		defer cw.Close()
+		next.ServeHTTP(cw, r)
+	})
+}
+
+// negotiateEncoding выбирает кодировку из Accept-Encoding, br предпочтительнее gzip при равном q
+func negotiateEncoding(header string) string {
+	if header == "" {
+		return ""
+	}
+
+	best, bestQ := "", 0.0
+	wildcardQ := -1.0
+	accepted := map[string]float64{}
+	for _, part := range strings.Split(header, ",") {
+		coding, q := parseCoding(part)
+		if coding == "*" {
+			wildcardQ = q
+			continue
+		}
+		accepted[coding] = q
+	}
+
+	for _, coding := range []string{encodingBrotli, encodingGzip} {
+		q, ok := accepted[coding]
+		if !ok && wildcardQ >= 0 {
+			q, ok = wildcardQ, true
+		}
+		if ok && q > bestQ {
+			best, bestQ = coding, q
+		}
+	}
+	return best
+}
+
+func parseCoding(part string) (string, float64) {
+	coding, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+	q := 1.0
+	for _, param := range strings.Split(params, ";") {
+		key, val, ok := strings.Cut(strings.TrimSpace(param), "=")
+		if ok && strings.EqualFold(key, "q") {
+			if parsed, err := strconv.ParseFloat(val, 64); err == nil {
+				q = parsed
+			}
+		}
+	}
+	return strings.ToLower(strings.TrimSpace(coding)), q
+}
+
+// compressible сжимаем только текст; text/event-stream отдаётся потоком и буферизовать его нельзя
+func compressible(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	switch {
+	case mediaType == "text/event-stream":
+		return false
+	case strings.HasPrefix(mediaType, "text/"):
+		return true
+	case mediaType == "application/json", mediaType == "application/javascript", mediaType == "image/svg+xml":
+		return true
+	}
+	return false
+}
+
+type compressWriter struct {
+	http.ResponseWriter
+	encoding    string
+	encoder     io.WriteCloser
+	wroteHeader bool
+}
+
+func (cw *compressWriter) WriteHeader(code int) {
+	if cw.wroteHeader {
+		cw.ResponseWriter.WriteHeader(code)
+		return
+	}
+	cw.wroteHeader = true
+
+	if cw.shouldCompress(code) {
+		h := cw.Header()
+		h.Set("Content-Encoding", cw.encoding)
+		h.Del("Content-Length")
+		// Accept-Ranges относится к несжатому представлению
+		h.Del("Accept-Ranges")
+		cw.encoder = newEncoder(cw.encoding, cw.ResponseWriter)
+	}
+	cw.ResponseWriter.WriteHeader(code)
+}
+
+func (cw *compressWriter) shouldCompress(code int) bool {
+	if code < http.StatusOK || code == http.StatusNoContent || code == http.StatusPartialContent || code == http.StatusNotModified {
+		return false
+	}
+
+	h := cw.Header()
+	if h.Get("Content-Encoding") != "" {
+		return false
+	}
+	if size, err := strconv.Atoi(h.Get("Content-Length")); err == nil && size < minCompressSize {
+		return false
+	}
+	return compressible(h.Get("Content-Type"))
+}
+
+func (cw *compressWriter) Write(b []byte) (int, error) {
+	if !cw.wroteHeader {
+		// тип определяется так же, как это сделал бы net/http без обёртки
+		if cw.Header().Get("Content-Type") == "" {
+			cw.Header().Set("Content-Type", http.DetectContentType(b))
+		}
+		cw.WriteHeader(http.StatusOK)
+	}
+	if cw.encoder == nil {
+		return cw.ResponseWriter.Write(b)
+	}
+	return cw.encoder.Write(b)
+}
+
+// Flush выталкивает уже сжатые данные клиенту
+func (cw *compressWriter) Flush() {
+	if !cw.wroteHeader {
+		cw.WriteHeader(http.StatusOK)
+	}
+	if f, ok := cw.encoder.(interface{ Flush() error }); ok {
+		f.Flush()
+	}
+	http.NewResponseController(cw.ResponseWriter).Flush()
+}
+
+// Close дописывает хвост сжатого потока и возвращает кодировщик в пул
+func (cw *compressWriter) Close() error {
+	if cw.encoder == nil {
+		return nil
+	}
+	err := cw.encoder.Close()
+	switch enc := cw.encoder.(type) {
+	case *gzip.Writer:
+		gzipPool.Put(enc)
+	case *brotli.Writer:
+		brotliPool.Put(enc)
+	}
+	cw.encoder = nil
+	return err
+}
+
+// Unwrap даёт http.ResponseController доступ к дедлайнам исходного writer
+func (cw *compressWriter) Unwrap() http.ResponseWriter {
+	return cw.ResponseWriter
+}
+
+func newEncoder(encoding string, w io.Writer) io.WriteCloser {
+	if encoding == encodingBrotli {
+		enc := brotliPool.Get().(*brotli.Writer)
+		enc.Reset(w)
+		return enc
+	}
+	enc := gzipPool.Get().(*gzip.Writer)
+	enc.Reset(w)
+	return enc
+}
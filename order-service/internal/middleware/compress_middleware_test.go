@@ -0,0 +1,175 @@
+// internal/middleware/compress_middleware_test.go
+package middleware
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var largeJSON = `{"orders":"` + strings.Repeat("a", 4*minCompressSize) + `"}`
+
+func serveCompressed(h http.HandlerFunc, acceptEncoding string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/orders", nil)
+	if acceptEncoding != "" {
+		req.Header.Set("Accept-Encoding", acceptEncoding)
+	}
+	w := httptest.NewRecorder()
+	CompressMiddleware(h).ServeHTTP(w, req)
+	return w
+}
+
+func jsonHandler(body string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, body)
+	}
+}
+
+func TestNegotiateEncoding(t *testing.T) {
+	tests := map[string]string{
+		"":                      "",
+		"identity":              "",
+		"gzip":                  encodingGzip,
+		"gzip, deflate, br":     encodingBrotli,
+		"br;q=0.5, gzip;q=0.8":  encodingGzip,
+		"br;q=0, gzip":          encodingGzip,
+		"gzip;q=0":              "",
+		"*":                     encodingBrotli,
+		"*;q=0.1, br;q=0":       encodingGzip,
+		"GZIP ; Q=1":            encodingGzip,
+		"deflate, compress":     "",
+		"br;q=1.0, gzip;q=1.0":  encodingBrotli,
+		"gzip;q=0.9, *;q=0.95":  encodingBrotli,
+		"br;q=0, gzip;q=0, *":   "",
+		"gzip;q=abc":            encodingGzip,
+		"  br  ,   gzip;q=0.2 ": encodingBrotli,
+	}
+	for header, expected := range tests {
+		assert.Equal(t, expected, negotiateEncoding(header), header)
+	}
+}
+
+func TestCompressMiddleware_Gzip(t *testing.T) {
+	w := serveCompressed(jsonHandler(largeJSON), "gzip")
+
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "gzip", w.Header().Get("Content-Encoding"))
+	assert.Equal(t, "Accept-Encoding", w.Header().Get("Vary"))
+	assert.Less(t, w.Body.Len(), len(largeJSON))
+
+	zr, err := gzip.NewReader(w.Body)
+	require.NoError(t, err)
+	body, err := io.ReadAll(zr)
+	require.NoError(t, err)
+	assert.Equal(t, largeJSON, string(body))
+}
+
+func TestCompressMiddleware_Brotli(t *testing.T) {
+	w := serveCompressed(jsonHandler(largeJSON), "gzip, br")
+
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "br", w.Header().Get("Content-Encoding"))
+
+	body, err := io.ReadAll(brotli.NewReader(w.Body))
+	require.NoError(t, err)
+	assert.Equal(t, largeJSON, string(body))
+}
+
+func TestCompressMiddleware_PoolReuse(t *testing.T) {
+	for i := 0; i < 3; i++ {
+		w := serveCompressed(jsonHandler(largeJSON), "gzip")
+		zr, err := gzip.NewReader(w.Body)
+		require.NoError(t, err)
+		body, err := io.ReadAll(zr)
+		require.NoError(t, err)
+		assert.Equal(t, largeJSON, string(body))
+	}
+}
+
+func TestCompressMiddleware_Skipped(t *testing.T) {
+	tests := []struct {
+		name           string
+		acceptEncoding string
+		handler        http.HandlerFunc
+	}{
+		{name: "no accept-encoding", handler: jsonHandler(largeJSON)},
+		{name: "identity only", acceptEncoding: "identity", handler: jsonHandler(largeJSON)},
+		{
+			name: "small body", acceptEncoding: "gzip",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				w.Header().Set("Content-Length", "2")
+				io.WriteString(w, "{}")
+			},
+		},
+		{
+			name: "not modified", acceptEncoding: "gzip",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusNotModified)
+			},
+		},
+		{
+			name: "already encoded", acceptEncoding: "gzip",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "text/plain")
+				w.Header().Set("Content-Encoding", "identity")
+				io.WriteString(w, largeJSON)
+			},
+		},
+		{
+			name: "event stream", acceptEncoding: "gzip",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "text/event-stream")
+				io.WriteString(w, largeJSON)
+			},
+		},
+		{
+			name: "binary", acceptEncoding: "gzip",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "image/png")
+				io.WriteString(w, largeJSON)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := serveCompressed(tt.handler, tt.acceptEncoding)
+			assert.NotContains(t, []string{"gzip", "br"}, w.Header().Get("Content-Encoding"))
+			assert.Equal(t, "Accept-Encoding", w.Header().Get("Vary"))
+		})
+	}
+}
+
+func TestCompressMiddleware_DetectsContentType(t *testing.T) {
+	w := serveCompressed(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "<html><body>"+strings.Repeat("x", 2*minCompressSize)+"</body></html>")
+	}, "gzip")
+
+	assert.Equal(t, "gzip", w.Header().Get("Content-Encoding"))
+	assert.Contains(t, w.Header().Get("Content-Type"), "text/html")
+}
+
+func TestCompressMiddleware_Flush(t *testing.T) {
+	w := serveCompressed(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, largeJSON)
+		require.NoError(t, http.NewResponseController(w).Flush())
+	}, "gzip")
+
+	assert.True(t, w.Flushed)
+	zr, err := gzip.NewReader(w.Body)
+	require.NoError(t, err)
+	body, err := io.ReadAll(zr)
+	require.NoError(t, err)
+	assert.Equal(t, largeJSON, string(body))
+}
@@ -2,51 +2,75 @@ package middleware
 
 import (
 	"net/http"
+	"strconv"
 	"time"
 
 	"order-service/internal/metrics"
-
-	"github.com/gorilla/mux"
 )
 
+// unmatchedRoute метка для запросов, не попавших ни в один шаблон, чтобы сырые URL не раздували кардинальность
+const unmatchedRoute = "unmatched"
+
 func MetricsMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		start := time.Now()
+		metrics.HTTPRequestsInFlight.Inc()
+		defer metrics.HTTPRequestsInFlight.Dec()
 
-		// создаем для перехвата статуса
+		// создаем для перехвата статуса и размера ответа
 		rw := &responseWriter{ResponseWriter: w, statusCode: http.StatusOK}
 
 		next.ServeHTTP(rw, r)
 
 		duration := time.Since(start).Seconds()
 
-		// получаем путь из роутера
-		path := r.URL.Path
-		if route := mux.CurrentRoute(r); route != nil {
-			if tpl, err := route.GetPathTemplate(); err == nil && tpl != "" {
-				path = tpl
-			}
+		// шаблон маршрута ServeMux записывает в переданный ему запрос
+		path := unmatchedRoute
+		if r.Pattern != "" {
+			path = routeFromPattern(r.Pattern)
 		}
 
 		metrics.HTTPRequests.WithLabelValues(
 			r.Method,
 			path,
-			http.StatusText(rw.statusCode),
+			strconv.Itoa(rw.statusCode),
 		).Inc()
 
 		metrics.HTTPResponseTime.WithLabelValues(
 			r.Method,
 			path,
 		).Observe(duration)
+
+		metrics.HTTPResponseSize.WithLabelValues(
+			r.Method,
+			path,
+		).Observe(float64(rw.bytes))
 	})
 }
 
 type responseWriter struct {
 	http.ResponseWriter
-	statusCode int
+	statusCode  int
+	bytes       int
+	wroteHeader bool
 }
 
 func (rw *responseWriter) WriteHeader(code int) {
-	rw.statusCode = code
+	if !rw.wroteHeader {
+		rw.statusCode = code
+		rw.wroteHeader = true
+	}
 	rw.ResponseWriter.WriteHeader(code)
 }
+
+func (rw *responseWriter) Write(b []byte) (int, error) {
+	rw.wroteHeader = true
+	n, err := rw.ResponseWriter.Write(b)
+	rw.bytes += n
+	return n, err
+}
+
+// Unwrap даёт http.ResponseController доступ к Flush и дедлайнам исходного writer
+func (rw *responseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
@@ -10,7 +10,8 @@ import (
 
 	"order-service/internal/metrics"
 
-	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	dto "github.com/prometheus/client_model/go"
 	"github.com/stretchr/testify/assert"
 )
@@ -19,22 +20,24 @@ func TestMetricsMiddleware_Integration(t *testing.T) {
 
 	metrics.HTTPRequests.Reset()
 	metrics.HTTPResponseTime.Reset()
+	metrics.HTTPResponseSize.Reset()
 
-	r := mux.NewRouter()
-	r.Handle("/test", MetricsMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /order/{uid}", func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusCreated)
 		w.Write([]byte("ok"))
-	})))
+	})
+	h := MetricsMiddleware(mux)
 
-
-	req := httptest.NewRequest(http.MethodGet, "/test", nil)
+	req := httptest.NewRequest(http.MethodGet, "/order/abc123", nil)
 	rec := httptest.NewRecorder()
-	r.ServeHTTP(rec, req)
+	h.ServeHTTP(rec, req)
 
 	assert.Equal(t, http.StatusCreated, rec.Result().StatusCode)
 	assert.Equal(t, "ok", rec.Body.String())
 
-	mf, err := metrics.HTTPRequests.MetricVec.GetMetricWithLabelValues("GET", "/test", "Created")
+	// метка — шаблон маршрута, а не сырой URL; статус числом
+	mf, err := metrics.HTTPRequests.MetricVec.GetMetricWithLabelValues("GET", "/order/{uid}", "201")
 	assert.NoError(t, err)
 	assert.NotNil(t, mf)
 
@@ -43,7 +46,51 @@ func TestMetricsMiddleware_Integration(t *testing.T) {
 	assert.NoError(t, err)
 	assert.EqualValues(t, 1, pb.GetCounter().GetValue())
 
-	mfTime, err := metrics.HTTPResponseTime.MetricVec.GetMetricWithLabelValues("GET", "/test")
+	mfTime, err := metrics.HTTPResponseTime.MetricVec.GetMetricWithLabelValues("GET", "/order/{uid}")
 	assert.NoError(t, err)
 	assert.NotNil(t, mfTime)
+
+	mfSize, err := metrics.HTTPResponseSize.MetricVec.GetMetricWithLabelValues("GET", "/order/{uid}")
+	assert.NoError(t, err)
+	pb = &dto.Metric{}
+	assert.NoError(t, mfSize.(prometheus.Metric).Write(pb))
+	assert.EqualValues(t, 2, pb.GetHistogram().GetSampleSum())
+
+	assert.EqualValues(t, 0, testutil.ToFloat64(metrics.HTTPRequestsInFlight))
+}
+
+func TestMetricsMiddleware_UnmatchedRoute_Integration(t *testing.T) {
+	metrics.HTTPRequests.Reset()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /order/{uid}", func(w http.ResponseWriter, r *http.Request) {})
+	h := MetricsMiddleware(mux)
+
+	for _, path := range []string{"/random-1", "/random-2", "/wp-admin.php"} {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	assert.EqualValues(t, 3, testutil.ToFloat64(metrics.HTTPRequests.WithLabelValues("GET", unmatchedRoute, "404")))
+	assert.Equal(t, 1, testutil.CollectAndCount(metrics.HTTPRequests))
+}
+
+func TestMetricsMiddleware_InFlight_Integration(t *testing.T) {
+	inside := make(chan struct{})
+	release := make(chan struct{})
+	h := MetricsMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(inside)
+		<-release
+	}))
+
+	done := make(chan struct{})
+	go func() {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/slow", nil))
+		close(done)
+	}()
+
+	<-inside
+	assert.EqualValues(t, 1, testutil.ToFloat64(metrics.HTTPRequestsInFlight))
+	close(release)
+	<-done
+	assert.EqualValues(t, 0, testutil.ToFloat64(metrics.HTTPRequestsInFlight))
 }
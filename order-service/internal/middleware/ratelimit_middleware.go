@@ -0,0 +1,263 @@
+package middleware
+
+import (
+	"context"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"order-service/internal/auth"
+	"order-service/internal/config"
+	"order-service/internal/metrics"
+)
+
+// sweepInterval как часто из памяти убираются корзины, успевшие наполниться
+const sweepInterval = time.Minute
+
+// authFailuresRoute корзина неудачных попыток аутентификации с одного IP, общая для всех маршрутов
+const authFailuresRoute = "auth_failures"
+
+// RateLimiter token bucket на пару маршрут + клиент. Клиент — API ключ или sub JWT,
+// без аутентификации — IP. Лимиты задаются по шаблону маршрута ServeMux
+type RateLimiter struct {
+	def          config.RateLimit
+	routes       map[string]config.RateLimit
+	authFailures config.RateLimit
+	trustXFF     bool
+
+	mu        sync.Mutex
+	buckets   map[bucketKey]*bucket
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+type bucketKey struct {
+	route  string
+	client string
+}
+
+type bucket struct {
+	tokens  float64
+	updated time.Time
+	// full когда корзина наполнится, после этого её можно забыть
+	full time.Time
+}
+
+// decision результат списания токена для заголовков ответа
+type decision struct {
+	allowed   bool
+	limit     config.RateLimit
+	remaining int
+	// reset через сколько корзина наполнится, retryAfter — через сколько появится токен
+	reset      time.Duration
+	retryAfter time.Duration
+}
+
+// NewRateLimiter лимитер по конфигурации, nil при выключенном ограничении: Limit у nil ничего не делает
+func NewRateLimiter(cfg config.RateLimitConfig) *RateLimiter {
+	if !cfg.Enabled {
+		return nil
+	}
+	return &RateLimiter{
+		def:          cfg.Default,
+		routes:       cfg.Routes,
+		authFailures: cfg.AuthFailures,
+		trustXFF:     cfg.TrustForwardedFor,
+		buckets:      make(map[bucketKey]*bucket),
+		now:          time.Now,
+	}
+}
+
+// Limit ограничивает частоту запросов к маршруту. Ставится внутрь ServeMux после аутентификации:
+// шаблон маршрута и клиент к этому моменту известны. Превышение — 429 с Retry-After,
+// на все ответы маршрута выставляются RateLimit-Limit, RateLimit-Remaining, RateLimit-Reset и RateLimit-Policy
+func (l *RateLimiter) Limit(next http.Handler) http.Handler {
+	if l == nil {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		route := r.Pattern
+		if route == "" {
+			route = unmatchedRoute
+		}
+		client, kind := l.client(r)
+		d := l.take(route, client)
+
+		h := w.Header()
+		h.Set("RateLimit-Limit", strconv.Itoa(d.limit.Burst))
+		h.Set("RateLimit-Remaining", strconv.Itoa(d.remaining))
+		h.Set("RateLimit-Reset", seconds(d.reset))
+		// окно, за которое корзина наполняется с нуля
+		h.Set("RateLimit-Policy", strconv.Itoa(d.limit.Burst)+";w="+seconds(time.Duration(float64(d.limit.Burst)/d.limit.RPS*float64(time.Second))))
+
+		if !d.allowed {
+			h.Set("Retry-After", seconds(d.retryAfter))
+			metrics.HTTPRateLimited.WithLabelValues(r.Method, routeFromPattern(route), kind).Inc()
+			http.Error(w, "слишком много запросов, повторите позже", http.StatusTooManyRequests)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// LimitAuthFailures ограничивает по IP неудачные попытки аутентификации. Ставится перед ней:
+// токен списывается только за ответ 401, а IP с пустой корзиной получает 429, не доходя
+// до проверки учётных данных. Успешные клиенты за общим IP этой корзиной не ограничиваются.
+// Без заданной корзины auth_failures ничего не делает
+func (l *RateLimiter) LimitAuthFailures(next http.Handler) http.Handler {
+	if l == nil || l.authFailures.Burst < 1 {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		client := "ip:" + l.clientIP(r)
+		if d := l.spend(authFailuresRoute, client, l.authFailures, 0); !d.allowed {
+			w.Header().Set("Retry-After", seconds(d.retryAfter))
+			metrics.HTTPRateLimited.WithLabelValues(r.Method, routeFromPattern(r.Pattern), authFailuresRoute).Inc()
+			http.Error(w, "слишком много неудачных попыток аутентификации, повторите позже", http.StatusTooManyRequests)
+			return
+		}
+		rw := &responseWriter{ResponseWriter: w}
+		next.ServeHTTP(rw, r)
+		if rw.statusCode == http.StatusUnauthorized {
+			l.spend(authFailuresRoute, client, l.authFailures, 1)
+		}
+	})
+}
+
+// Allow списывает токен вызова не по HTTP (gRPC): route — полное имя метода, клиент берётся из контекста
+// после аутентификации, без неё — IP из remoteAddr. Возвращает, разрешён ли вызов, через сколько
+// появится токен и тип клиента для метрик. У nil все вызовы разрешены
+func (l *RateLimiter) Allow(ctx context.Context, route, remoteAddr string) (bool, time.Duration, string) {
+	if l == nil {
+		return true, 0, ""
+	}
+	client, kind := "ip:"+hostOf(remoteAddr), "ip"
+	if p := auth.FromContext(ctx); p != nil {
+		client, kind = p.Method+":"+p.Subject, p.Method
+	}
+	d := l.take(route, client)
+	return d.allowed, d.retryAfter, kind
+}
+
+// AllowAuthAttempt есть ли у IP из remoteAddr токены в корзине неудачных попыток аутентификации;
+// токен не списывается. Для вызовов не по HTTP (gRPC) перед проверкой учётных данных, как LimitAuthFailures.
+// У nil и без корзины auth_failures попытки не ограничиваются
+func (l *RateLimiter) AllowAuthAttempt(remoteAddr string) (bool, time.Duration) {
+	if l == nil || l.authFailures.Burst < 1 {
+		return true, 0
+	}
+	d := l.spend(authFailuresRoute, "ip:"+hostOf(remoteAddr), l.authFailures, 0)
+	return d.allowed, d.retryAfter
+}
+
+// AuthFailed списывает токен за неудачную попытку аутентификации с IP из remoteAddr
+func (l *RateLimiter) AuthFailed(remoteAddr string) {
+	if l == nil || l.authFailures.Burst < 1 {
+		return
+	}
+	l.spend(authFailuresRoute, "ip:"+hostOf(remoteAddr), l.authFailures, 1)
+}
+
+// hostOf адрес без порта
+func hostOf(remoteAddr string) string {
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		return remoteAddr
+	}
+	return host
+}
+
+// take списывает токен из корзины клиента на маршруте
+func (l *RateLimiter) take(route, client string) decision {
+	limit, ok := l.routes[route]
+	if !ok {
+		limit = l.def
+	}
+	return l.spend(route, client, limit, 1)
+}
+
+// spend списывает cost токенов, если в корзине есть хотя бы один; cost 0 — только проверка
+func (l *RateLimiter) spend(route, client string, limit config.RateLimit, cost float64) decision {
+	burst := float64(limit.Burst)
+	now := l.now()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.sweep(now)
+
+	key := bucketKey{route: route, client: client}
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: burst, updated: now}
+		l.buckets[key] = b
+		metrics.RateLimitBuckets.Set(float64(len(l.buckets)))
+	}
+	b.tokens = math.Min(burst, b.tokens+now.Sub(b.updated).Seconds()*limit.RPS)
+	b.updated = now
+
+	d := decision{limit: limit}
+	if b.tokens >= 1 {
+		b.tokens -= cost
+		d.allowed = true
+	} else {
+		d.retryAfter = refill(1-b.tokens, limit.RPS)
+	}
+	d.remaining = int(b.tokens)
+	d.reset = refill(burst-b.tokens, limit.RPS)
+	b.full = now.Add(d.reset)
+	return d
+}
+
+// sweep убирает наполнившиеся корзины: новая корзина того же клиента будет такой же
+func (l *RateLimiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < sweepInterval {
+		return
+	}
+	l.lastSweep = now
+	for key, b := range l.buckets {
+		if !now.Before(b.full) {
+			delete(l.buckets, key)
+		}
+	}
+	metrics.RateLimitBuckets.Set(float64(len(l.buckets)))
+}
+
+// client ключ корзины и тип клиента для метрик
+func (l *RateLimiter) client(r *http.Request) (string, string) {
+	if p := auth.FromContext(r.Context()); p != nil {
+		return p.Method + ":" + p.Subject, p.Method
+	}
+	return "ip:" + l.clientIP(r), "ip"
+}
+
+// clientIP адрес клиента по настройке trust_forwarded_for
+func (l *RateLimiter) clientIP(r *http.Request) string {
+	return ResolveClientIP(r, l.trustXFF)
+}
+
+// ResolveClientIP адрес соединения; за своим прокси (trustForwardedFor) — последний адрес из X-Forwarded-For,
+// его дописал прокси, а более ранние клиент мог подставить сам. Общий для лимитера и журнала аудита
+func ResolveClientIP(r *http.Request, trustForwardedFor bool) string {
+	if trustForwardedFor {
+		if xff := r.Header.Values("X-Forwarded-For"); len(xff) > 0 {
+			parts := strings.Split(xff[len(xff)-1], ",")
+			if ip := strings.TrimSpace(parts[len(parts)-1]); net.ParseIP(ip) != nil {
+				return ip
+			}
+		}
+	}
+	return clientIP(r)
+}
+
+func refill(tokens, rps float64) time.Duration {
+	return time.Duration(tokens / rps * float64(time.Second))
+}
+
+// seconds целые секунды с округлением вверх, как требуют Retry-After и RateLimit-Reset
+func seconds(d time.Duration) string {
+	return strconv.FormatInt(int64(math.Ceil(d.Seconds())), 10)
+}
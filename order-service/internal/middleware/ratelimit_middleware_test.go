@@ -0,0 +1,207 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"order-service/internal/auth"
+	"order-service/internal/config"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeClock время лимитера, двигается тестом
+type fakeClock struct{ t time.Time }
+
+func (c *fakeClock) now() time.Time          { return c.t }
+func (c *fakeClock) advance(d time.Duration) { c.t = c.t.Add(d) }
+
+func newTestLimiter(cfg config.RateLimitConfig) (http.Handler, *RateLimiter, *fakeClock) {
+	cfg.Enabled = true
+	l := NewRateLimiter(cfg)
+	clock := &fakeClock{t: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	l.now = clock.now
+
+	mux := http.NewServeMux()
+	ok := func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusNoContent) }
+	mux.Handle("GET /order/{uid}", l.Limit(http.HandlerFunc(ok)))
+	mux.Handle("GET /api/v1/orders", l.Limit(http.HandlerFunc(ok)))
+	return mux, l, clock
+}
+
+func get(h http.Handler, target string, edit ...func(*http.Request)) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, target, nil)
+	req.RemoteAddr = "10.0.0.1:40000"
+	for _, fn := range edit {
+		fn(req)
+	}
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, req)
+	return rr
+}
+
+func TestRateLimiter_BurstThen429(t *testing.T) {
+	h, _, clock := newTestLimiter(config.RateLimitConfig{Default: config.RateLimit{RPS: 1, Burst: 3}})
+
+	for i := 2; i >= 0; i-- {
+		rr := get(h, "/order/a")
+		require.Equal(t, http.StatusNoContent, rr.Code)
+		assert.Equal(t, "3", rr.Header().Get("RateLimit-Limit"))
+		assert.Equal(t, "3;w=3", rr.Header().Get("RateLimit-Policy"))
+		assert.Equal(t, strconv.Itoa(i), rr.Header().Get("RateLimit-Remaining"))
+	}
+
+	rr := get(h, "/order/a")
+	assert.Equal(t, http.StatusTooManyRequests, rr.Code)
+	assert.Equal(t, "1", rr.Header().Get("Retry-After"))
+	assert.Equal(t, "0", rr.Header().Get("RateLimit-Remaining"))
+	assert.Equal(t, "3", rr.Header().Get("RateLimit-Reset"))
+
+	// через секунду накопился один токен
+	clock.advance(time.Second)
+	assert.Equal(t, http.StatusNoContent, get(h, "/order/a").Code)
+	assert.Equal(t, http.StatusTooManyRequests, get(h, "/order/a").Code)
+}
+
+func TestRateLimiter_RouteOverride(t *testing.T) {
+	h, _, _ := newTestLimiter(config.RateLimitConfig{
+		Default: config.RateLimit{RPS: 10, Burst: 5},
+		Routes:  map[string]config.RateLimit{"GET /order/{uid}": {RPS: 1, Burst: 1}},
+	})
+
+	assert.Equal(t, http.StatusNoContent, get(h, "/order/a").Code)
+	// шаблон маршрута общий: перебор uid расходует одну корзину
+	assert.Equal(t, http.StatusTooManyRequests, get(h, "/order/b").Code)
+
+	// у другого маршрута своя корзина с лимитом по умолчанию
+	rr := get(h, "/api/v1/orders")
+	assert.Equal(t, http.StatusNoContent, rr.Code)
+	assert.Equal(t, "5", rr.Header().Get("RateLimit-Limit"))
+}
+
+func TestRateLimiter_ClientKeys(t *testing.T) {
+	h, l, _ := newTestLimiter(config.RateLimitConfig{Default: config.RateLimit{RPS: 1, Burst: 1}})
+	as := func(subject string) func(*http.Request) {
+		return func(r *http.Request) {
+			p := &auth.Principal{Subject: subject, Method: auth.MethodAPIKey}
+			*r = *r.WithContext(auth.WithPrincipal(r.Context(), p))
+		}
+	}
+	fromIP := func(addr string) func(*http.Request) {
+		return func(r *http.Request) { r.RemoteAddr = addr }
+	}
+
+	assert.Equal(t, http.StatusNoContent, get(h, "/order/a", as("billing")).Code)
+	assert.Equal(t, http.StatusTooManyRequests, get(h, "/order/a", as("billing")).Code)
+	// другой ключ с того же адреса не страдает
+	assert.Equal(t, http.StatusNoContent, get(h, "/order/a", as("reports")).Code)
+
+	assert.Equal(t, http.StatusNoContent, get(h, "/order/a").Code)
+	assert.Equal(t, http.StatusTooManyRequests, get(h, "/order/a").Code)
+	assert.Equal(t, http.StatusNoContent, get(h, "/order/a", fromIP("10.0.0.2:40000")).Code)
+
+	assert.Contains(t, l.buckets, bucketKey{route: "GET /order/{uid}", client: "api_key:billing"})
+	assert.Contains(t, l.buckets, bucketKey{route: "GET /order/{uid}", client: "ip:10.0.0.1"})
+}
+
+func TestRateLimiter_ForwardedFor(t *testing.T) {
+	xff := func(v string) func(*http.Request) {
+		return func(r *http.Request) { r.Header.Set("X-Forwarded-For", v) }
+	}
+
+	// без доверия заголовок игнорируется: иначе клиент сменит его и получит новую корзину
+	h, _, _ := newTestLimiter(config.RateLimitConfig{Default: config.RateLimit{RPS: 1, Burst: 1}})
+	assert.Equal(t, http.StatusNoContent, get(h, "/order/a", xff("1.1.1.1")).Code)
+	assert.Equal(t, http.StatusTooManyRequests, get(h, "/order/a", xff("2.2.2.2")).Code)
+
+	// за своим прокси клиент — последний адрес цепочки
+	h, l, _ := newTestLimiter(config.RateLimitConfig{Default: config.RateLimit{RPS: 1, Burst: 1}, TrustForwardedFor: true})
+	assert.Equal(t, http.StatusNoContent, get(h, "/order/a", xff("9.9.9.9, 1.1.1.1")).Code)
+	assert.Equal(t, http.StatusNoContent, get(h, "/order/a", xff("9.9.9.9, 2.2.2.2")).Code)
+	assert.Equal(t, http.StatusTooManyRequests, get(h, "/order/a", xff("8.8.8.8, 2.2.2.2")).Code)
+	assert.Contains(t, l.buckets, bucketKey{route: "GET /order/{uid}", client: "ip:1.1.1.1"})
+}
+
+func TestResolveClientIP(t *testing.T) {
+	r := httptest.NewRequest("GET", "/order/a", nil)
+	r.RemoteAddr = "10.0.0.1:40000"
+	r.Header.Add("X-Forwarded-For", "9.9.9.9")
+	r.Header.Add("X-Forwarded-For", "8.8.8.8, 1.1.1.1")
+
+	assert.Equal(t, "10.0.0.1", ResolveClientIP(r, false))
+	assert.Equal(t, "1.1.1.1", ResolveClientIP(r, true))
+
+	// мусор в заголовке — адрес соединения
+	r.Header.Set("X-Forwarded-For", "unknown")
+	assert.Equal(t, "10.0.0.1", ResolveClientIP(r, true))
+}
+
+func TestRateLimiter_SweepFullBuckets(t *testing.T) {
+	h, l, clock := newTestLimiter(config.RateLimitConfig{Default: config.RateLimit{RPS: 1, Burst: 2}})
+
+	get(h, "/order/a")
+	get(h, "/api/v1/orders")
+	require.Len(t, l.buckets, 2)
+
+	clock.advance(sweepInterval)
+	get(h, "/order/a")
+	// корзина списка наполнилась и удалена, корзина заказа снова используется
+	assert.Len(t, l.buckets, 1)
+}
+
+func TestRateLimiter_AuthFailures(t *testing.T) {
+	l := NewRateLimiter(config.RateLimitConfig{
+		Enabled:      true,
+		Default:      config.RateLimit{RPS: 100, Burst: 100},
+		AuthFailures: config.RateLimit{RPS: 1, Burst: 2},
+	})
+	clock := &fakeClock{t: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	l.now = clock.now
+	// аутентификация: без ключа 401, с ключом пропускает
+	authn := func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-API-Key") == "" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+	h := l.LimitAuthFailures(http.HandlerFunc(authn))
+	withKey := func(r *http.Request) { r.Header.Set("X-API-Key", "k") }
+
+	// успешные запросы корзину неудач не тратят
+	for i := 0; i < 5; i++ {
+		require.Equal(t, http.StatusNoContent, get(h, "/order/a", withKey).Code)
+	}
+	assert.Equal(t, http.StatusUnauthorized, get(h, "/order/a").Code)
+	assert.Equal(t, http.StatusUnauthorized, get(h, "/order/a").Code)
+
+	// корзина неудач пуста: IP отсекается до проверки учётных данных
+	rr := get(h, "/order/a")
+	assert.Equal(t, http.StatusTooManyRequests, rr.Code)
+	assert.Equal(t, "1", rr.Header().Get("Retry-After"))
+	assert.Equal(t, http.StatusTooManyRequests, get(h, "/order/a", withKey).Code)
+
+	// другой IP не затронут
+	other := func(r *http.Request) { r.RemoteAddr = "10.0.0.2:40000" }
+	assert.Equal(t, http.StatusUnauthorized, get(h, "/order/a", other).Code)
+
+	clock.advance(time.Second)
+	assert.Equal(t, http.StatusNoContent, get(h, "/order/a", withKey).Code)
+}
+
+func TestRateLimiter_Disabled(t *testing.T) {
+	l := NewRateLimiter(config.RateLimitConfig{Enabled: false, Default: config.RateLimit{RPS: 1, Burst: 1}})
+	require.Nil(t, l)
+
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	h := l.LimitAuthFailures(l.Limit(next))
+	for i := 0; i < 5; i++ {
+		rr := get(h, "/order/a")
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Empty(t, rr.Header().Get("RateLimit-Limit"))
+	}
+}
@@ -0,0 +1,94 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	tracesdk "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var recorder = tracetest.NewSpanRecorder()
+
+// глобальный провайдер подменяется один раз: трейсер пакета привязывается к первому
+func TestMain(m *testing.M) {
+	otel.SetTracerProvider(tracesdk.NewTracerProvider(tracesdk.WithSpanProcessor(recorder)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	os.Exit(m.Run())
+}
+
+func lastSpan(t *testing.T) tracesdk.ReadOnlySpan {
+	spans := recorder.Ended()
+	require.NotEmpty(t, spans)
+	return spans[len(spans)-1]
+}
+
+func attr(span tracesdk.ReadOnlySpan, key attribute.Key) attribute.Value {
+	for _, kv := range span.Attributes() {
+		if kv.Key == key {
+			return kv.Value
+		}
+	}
+	return attribute.Value{}
+}
+
+func TestTracingMiddleware_ServerSpan(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /order/{uid}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/order/abc", nil)
+	req.Header.Set("User-Agent", "test-agent")
+	rr := httptest.NewRecorder()
+	TracingMiddleware(mux).ServeHTTP(rr, req)
+
+	span := lastSpan(t)
+	assert.Equal(t, "GET /order/{uid}", span.Name())
+	assert.Equal(t, trace.SpanKindServer, span.SpanKind())
+	assert.Equal(t, "GET", attr(span, "http.request.method").AsString())
+	assert.Equal(t, "/order/abc", attr(span, "url.path").AsString())
+	assert.Equal(t, "/order/{uid}", attr(span, "http.route").AsString())
+	assert.Equal(t, int64(404), attr(span, "http.response.status_code").AsInt64())
+	assert.Equal(t, "test-agent", attr(span, "user_agent.original").AsString())
+	assert.NotEqual(t, codes.Error, span.Status().Code, "4xx не ошибка сервера")
+}
+
+func TestTracingMiddleware_ContinuesIncomingTrace(t *testing.T) {
+	var handlerSpan trace.SpanContext
+	h := TracingMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handlerSpan = trace.SpanContextFromContext(r.Context())
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/health", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	h.ServeHTTP(httptest.NewRecorder(), req)
+
+	span := lastSpan(t)
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", span.SpanContext().TraceID().String())
+	assert.Equal(t, "00f067aa0ba902b7", span.Parent().SpanID().String())
+	assert.True(t, span.Parent().IsRemote())
+	assert.Equal(t, span.SpanContext().SpanID(), handlerSpan.SpanID(), "обработчик получает контекст серверного спана")
+	assert.Equal(t, "GET", span.Name(), "без шаблона маршрута имя — только метод")
+}
+
+func TestTracingMiddleware_ServerErrorStatus(t *testing.T) {
+	h := TracingMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "boom", http.StatusInternalServerError)
+	}))
+
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/order/x", nil))
+
+	span := lastSpan(t)
+	assert.Equal(t, codes.Error, span.Status().Code)
+	assert.Equal(t, int64(500), attr(span, "http.response.status_code").AsInt64())
+}
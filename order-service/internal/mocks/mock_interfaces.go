@@ -5,6 +5,8 @@
 package mocks
 
 import (
+	context "context"
+	interfaces "order-service/internal/interfaces"
 	models "order-service/models"
 	reflect "reflect"
 
@@ -49,18 +51,18 @@ func (mr *MockDatabaseMockRecorder) Close() *gomock.Call {
 }
 
 // GetOrder mocks base method.
-func (m *MockDatabase) GetOrder(orderUID string) (*models.Order, error) {
+func (m *MockDatabase) GetOrder(ctx context.Context, orderUID string) (*models.Order, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetOrder", orderUID)
+	ret := m.ctrl.Call(m, "GetOrder", ctx, orderUID)
 	ret0, _ := ret[0].(*models.Order)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GetOrder indicates an expected call of GetOrder.
-func (mr *MockDatabaseMockRecorder) GetOrder(orderUID interface{}) *gomock.Call {
+func (mr *MockDatabaseMockRecorder) GetOrder(ctx, orderUID interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetOrder", reflect.TypeOf((*MockDatabase)(nil).GetOrder), orderUID)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetOrder", reflect.TypeOf((*MockDatabase)(nil).GetOrder), ctx, orderUID)
 }
 
 // GetRecentOrders mocks base method.
@@ -78,18 +80,34 @@ func (mr *MockDatabaseMockRecorder) GetRecentOrders(limit interface{}) *gomock.C
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetRecentOrders", reflect.TypeOf((*MockDatabase)(nil).GetRecentOrders), limit)
 }
 
+// ListOrders mocks base method.
+func (m *MockDatabase) ListOrders(ctx context.Context, limit, offset int) ([]*models.Order, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListOrders", ctx, limit, offset)
+	ret0, _ := ret[0].([]*models.Order)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListOrders indicates an expected call of ListOrders.
+func (mr *MockDatabaseMockRecorder) ListOrders(ctx, limit, offset interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListOrders", reflect.TypeOf((*MockDatabase)(nil).ListOrders), ctx, limit, offset)
+}
+
 // SaveOrder mocks base method.
-func (m *MockDatabase) SaveOrder(order *models.Order) error {
+func (m *MockDatabase) SaveOrder(ctx context.Context, order *models.Order) (bool, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "SaveOrder", order)
-	ret0, _ := ret[0].(error)
-	return ret0
+	ret := m.ctrl.Call(m, "SaveOrder", ctx, order)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
 }
 
 // SaveOrder indicates an expected call of SaveOrder.
-func (mr *MockDatabaseMockRecorder) SaveOrder(order interface{}) *gomock.Call {
+func (mr *MockDatabaseMockRecorder) SaveOrder(ctx, order interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveOrder", reflect.TypeOf((*MockDatabase)(nil).SaveOrder), order)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveOrder", reflect.TypeOf((*MockDatabase)(nil).SaveOrder), ctx, order)
 }
 
 // MockCache is a mock of Cache interface.
@@ -127,6 +145,44 @@ func (mr *MockCacheMockRecorder) BulkSet(orders interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BulkSet", reflect.TypeOf((*MockCache)(nil).BulkSet), orders)
 }
 
+// Clear mocks base method.
+func (m *MockCache) Clear() {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "Clear")
+}
+
+// Clear indicates an expected call of Clear.
+func (mr *MockCacheMockRecorder) Clear() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Clear", reflect.TypeOf((*MockCache)(nil).Clear))
+}
+
+// Close mocks base method.
+func (m *MockCache) Close() error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Close")
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Close indicates an expected call of Close.
+func (mr *MockCacheMockRecorder) Close() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Close", reflect.TypeOf((*MockCache)(nil).Close))
+}
+
+// Delete mocks base method.
+func (m *MockCache) Delete(orderUID string) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "Delete", orderUID)
+}
+
+// Delete indicates an expected call of Delete.
+func (mr *MockCacheMockRecorder) Delete(orderUID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockCache)(nil).Delete), orderUID)
+}
+
 // Get mocks base method.
 func (m *MockCache) Get(orderUID string) (*models.Order, bool) {
 	m.ctrl.T.Helper()
@@ -142,6 +198,35 @@ func (mr *MockCacheMockRecorder) Get(orderUID interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Get", reflect.TypeOf((*MockCache)(nil).Get), orderUID)
 }
 
+// GetOrLoad mocks base method.
+func (m *MockCache) GetOrLoad(orderUID string, load interfaces.LoadFunc) (*models.Order, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetOrLoad", orderUID, load)
+	ret0, _ := ret[0].(*models.Order)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetOrLoad indicates an expected call of GetOrLoad.
+func (mr *MockCacheMockRecorder) GetOrLoad(orderUID, load interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetOrLoad", reflect.TypeOf((*MockCache)(nil).GetOrLoad), orderUID, load)
+}
+
+// Len mocks base method.
+func (m *MockCache) Len() int {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Len")
+	ret0, _ := ret[0].(int)
+	return ret0
+}
+
+// Len indicates an expected call of Len.
+func (mr *MockCacheMockRecorder) Len() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Len", reflect.TypeOf((*MockCache)(nil).Len))
+}
+
 // Set mocks base method.
 func (m *MockCache) Set(orderUID string, order *models.Order) {
 	m.ctrl.T.Helper()
@@ -153,3 +238,17 @@ func (mr *MockCacheMockRecorder) Set(orderUID, order interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Set", reflect.TypeOf((*MockCache)(nil).Set), orderUID, order)
 }
+
+// Stats mocks base method.
+func (m *MockCache) Stats() interfaces.CacheStats {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Stats")
+	ret0, _ := ret[0].(interfaces.CacheStats)
+	return ret0
+}
+
+// Stats indicates an expected call of Stats.
+func (mr *MockCacheMockRecorder) Stats() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Stats", reflect.TypeOf((*MockCache)(nil).Stats))
+}
@@ -0,0 +1,19 @@
+// internal/openapi/openapi.go
+package openapi
+
+import (
+	_ "embed"
+	"net/http"
+)
+
+// Spec спецификация OpenAPI 3 всех эндпоинтов сервиса.
+// При изменении маршрутов или models.Order правится вместе с кодом, расхождения ловит openapi_test.go
+//
+//go:embed openapi.json
+var Spec []byte
+
+// Handler отдаёт спецификацию, её читает Swagger UI из /static/swagger.html
+func Handler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(Spec)
+}
@@ -0,0 +1,364 @@
+// internal/openapi/openapi_test.go
+package openapi_test
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"order-service/internal/audit"
+	"order-service/internal/auth"
+	"order-service/internal/config"
+	"order-service/internal/handlers"
+	"order-service/internal/health"
+	"order-service/internal/mocks"
+	"order-service/internal/openapi"
+	"order-service/internal/router"
+	"order-service/models"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers"
+	"github.com/getkin/kin-openapi/routers/legacy"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+func loadSpec(t *testing.T) *openapi3.T {
+	doc, err := openapi3.NewLoader().LoadFromData(openapi.Spec)
+	require.NoError(t, err)
+	require.NoError(t, doc.Validate(context.Background()))
+	return doc
+}
+
+func testOrder() *models.Order {
+	return &models.Order{
+		OrderUID:    "b563feb7b2b84b6test123",
+		TrackNumber: "WBILMTESTTRACK123",
+		Entry:       "WBIL",
+		Delivery: models.Delivery{
+			Name:    "Test Testov",
+			Phone:   "+9720000000",
+			Zip:     "2639809",
+			City:    "Kiryat Mozkin",
+			Address: "Ploshad Mira 15",
+			Region:  "Kraiot",
+			Email:   "test@gmail.com",
+		},
+		Payment: models.Payment{
+			Transaction:  "real-transaction-12345",
+			Currency:     "USD",
+			Provider:     "wbpay",
+			Amount:       1817,
+			PaymentDt:    time.Now().Unix(),
+			Bank:         "alpha",
+			DeliveryCost: 1500,
+			GoodsTotal:   317,
+		},
+		Items: []models.Item{{
+			ChrtID:      9934930,
+			TrackNumber: "WBILMTESTTRACK123",
+			Price:       453,
+			Rid:         "ab4219087a764ae0btest",
+			Name:        "Mascaras",
+			Sale:        30,
+			Size:        "0",
+			TotalPrice:  317,
+			NmID:        2389212,
+			Brand:       "Vivienne Sabo",
+			Status:      202,
+		}},
+		Locale:          "en",
+		CustomerID:      "test",
+		DeliveryService: "meest",
+		Shardkey:        "9",
+		SmID:            99,
+		DateCreated:     time.Now().Add(-24 * time.Hour).UTC().Truncate(time.Second),
+		OofShard:        "1",
+	}
+}
+
+// validateExchange проверяет запрос и ответ роутера сервиса по спецификации
+func validateExchange(t *testing.T, specRouter routers.Router, req *http.Request, body []byte, w *httptest.ResponseRecorder) error {
+	t.Helper()
+
+	route, pathParams, err := specRouter.FindRoute(req)
+	require.NoError(t, err, "маршрута %s %s нет в спецификации", req.Method, req.URL.Path)
+
+	if body != nil {
+		req.Body = io.NopCloser(bytes.NewReader(body))
+	}
+	reqInput := &openapi3filter.RequestValidationInput{
+		Request:    req,
+		PathParams: pathParams,
+		Route:      route,
+		Options:    &openapi3filter.Options{AuthenticationFunc: requireCredentials},
+	}
+	// запрос без учётных данных заведомо не проходит security, проверяется только ответ 401
+	if w.Code != http.StatusUnauthorized {
+		require.NoError(t, openapi3filter.ValidateRequest(context.Background(), reqInput))
+	}
+
+	respInput := &openapi3filter.ResponseValidationInput{
+		RequestValidationInput: reqInput,
+		Status:                 w.Code,
+		Header:                 w.Header(),
+		Options:                &openapi3filter.Options{IncludeResponseStatus: true},
+	}
+	respInput.SetBodyBytes(w.Body.Bytes())
+	return openapi3filter.ValidateResponse(context.Background(), respInput)
+}
+
+// requireCredentials схема безопасности из спецификации выполнена, если передан её заголовок
+func requireCredentials(ctx context.Context, input *openapi3filter.AuthenticationInput) error {
+	req := input.RequestValidationInput.Request
+	switch input.SecuritySchemeName {
+	case "ApiKey":
+		if req.Header.Get(input.SecurityScheme.Name) != "" {
+			return nil
+		}
+	case "BearerAuth":
+		if strings.HasPrefix(req.Header.Get("Authorization"), "Bearer ") {
+			return nil
+		}
+	}
+	return errors.New("нет учётных данных схемы " + input.SecuritySchemeName)
+}
+
+// fakeKeyStore ключ testAPIKey с областью admin и testReaderKey без pii:read
+type fakeKeyStore struct{}
+
+const (
+	testAPIKey    = "osk_openapi_test"
+	testReaderKey = "osk_openapi_reader"
+)
+
+func (fakeKeyStore) LookupAPIKey(ctx context.Context, keyHash string) (*auth.APIKey, error) {
+	switch keyHash {
+	case auth.HashAPIKey(testAPIKey):
+		return &auth.APIKey{Name: "openapi-test", Scopes: []string{auth.ScopeAdmin}}, nil
+	case auth.HashAPIKey(testReaderKey):
+		return &auth.APIKey{Name: "openapi-reader", Scopes: []string{auth.ScopeOrdersRead}}, nil
+	}
+	return nil, sql.ErrNoRows
+}
+
+// fakeAuditStore одно событие чтения и одно из Kafka на любой заказ
+type fakeAuditStore struct{}
+
+func (fakeAuditStore) InsertAuditEvents(ctx context.Context, events []audit.Event) error {
+	return nil
+}
+
+func (fakeAuditStore) ListAuditEvents(ctx context.Context, orderUID string, limit int) ([]audit.Event, error) {
+	at := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	return []audit.Event{
+		{ID: 2, Actor: "api_key:support", Action: audit.ActionRead, OrderUID: orderUID, At: at.Add(time.Minute),
+			SourceIP: "192.0.2.10", TraceID: "4bf92f3577b34da6a3ce929d0e0e4736"},
+		{ID: 1, Actor: audit.ActorKafka, Action: audit.ActionWrite, OrderUID: orderUID, At: at},
+	}, nil
+}
+
+func newTestRouter(t *testing.T, cache *mocks.MockCache, db *mocks.MockDatabase) http.Handler {
+	authn, err := auth.New(config.AuthConfig{Enabled: true}, fakeKeyStore{})
+	require.NoError(t, err)
+	h := handlers.NewHandler(cache, db, noop.NewTracerProvider().Tracer("test"))
+	h.Audit = audit.New(fakeAuditStore{}, 1, time.Hour)
+	return router.New(h, health.New(), authn, nil)
+}
+
+func TestSpec_Valid(t *testing.T) {
+	doc := loadSpec(t)
+
+	for _, path := range []string{"/api/v1/orders", "/api/v1/orders/{uid}", "/order/{uid}",
+		"/orders/stream", "/orders/stream/ws", "/graphql", "/livez", "/readyz", "/health", "/metrics", "/openapi.json", "/admin/audit"} {
+		assert.NotNil(t, doc.Paths.Find(path), path)
+	}
+}
+
+func TestSpec_OrderConstraints(t *testing.T) {
+	doc := loadSpec(t)
+	schema := doc.Components.Schemas["Order"].Value
+
+	assert.ElementsMatch(t, []string{"order_uid", "track_number", "entry", "delivery", "payment", "items", "locale",
+		"customer_id", "delivery_service", "shardkey", "sm_id", "date_created", "oof_shard"}, schema.Required)
+
+	uid := schema.Properties["order_uid"].Value
+	assert.EqualValues(t, 5, uid.MinLength)
+	assert.EqualValues(t, 50, *uid.MaxLength)
+
+	require.NoError(t, schema.VisitJSON(toJSONValue(t, testOrder())))
+
+	bad := testOrder()
+	bad.Locale = "eng"
+	assert.Error(t, schema.VisitJSON(toJSONValue(t, bad)))
+
+	bad = testOrder()
+	bad.Payment.Currency = "usd"
+	assert.Error(t, schema.VisitJSON(toJSONValue(t, bad)))
+
+	bad = testOrder()
+	bad.Items[0].Sale = 101
+	assert.Error(t, schema.VisitJSON(toJSONValue(t, bad)))
+}
+
+func TestHandlers_MatchSpec(t *testing.T) {
+	specRouter, err := legacy.NewRouter(loadSpec(t))
+	require.NoError(t, err)
+
+	order := testOrder()
+	orderJSON, err := json.Marshal(order)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name   string
+		method string
+		target string
+		body   []byte
+		header http.Header
+		setup  func(cache *mocks.MockCache, db *mocks.MockDatabase)
+		status int
+	}{
+		{
+			name: "get order", method: http.MethodGet, target: "/api/v1/orders/" + order.OrderUID,
+			setup: func(cache *mocks.MockCache, db *mocks.MockDatabase) {
+				cache.EXPECT().GetOrLoad(order.OrderUID, gomock.Any()).Return(order, nil)
+			},
+			status: http.StatusOK,
+		},
+		{
+			name: "get order if-modified-since ignored", method: http.MethodGet, target: "/api/v1/orders/" + order.OrderUID,
+			header: http.Header{"If-Modified-Since": {time.Now().UTC().Format(http.TimeFormat)}},
+			setup: func(cache *mocks.MockCache, db *mocks.MockDatabase) {
+				cache.EXPECT().GetOrLoad(order.OrderUID, gomock.Any()).Return(order, nil)
+			},
+			status: http.StatusOK,
+		},
+		{
+			name: "get order masked", method: http.MethodGet, target: "/api/v1/orders/" + order.OrderUID,
+			header: http.Header{"X-Api-Key": {testReaderKey}},
+			setup: func(cache *mocks.MockCache, db *mocks.MockDatabase) {
+				cache.EXPECT().GetOrLoad(order.OrderUID, gomock.Any()).Return(order, nil)
+			},
+			status: http.StatusOK,
+		},
+		{
+			name: "get order legacy", method: http.MethodGet, target: "/order/" + order.OrderUID,
+			setup: func(cache *mocks.MockCache, db *mocks.MockDatabase) {
+				cache.EXPECT().GetOrLoad(order.OrderUID, gomock.Any()).Return(order, nil)
+			},
+			status: http.StatusOK,
+		},
+		{
+			name: "order not found", method: http.MethodGet, target: "/api/v1/orders/missing-uid",
+			setup: func(cache *mocks.MockCache, db *mocks.MockDatabase) {
+				cache.EXPECT().GetOrLoad("missing-uid", gomock.Any()).Return(nil, sql.ErrNoRows)
+			},
+			status: http.StatusNotFound,
+		},
+		{
+			name: "list orders", method: http.MethodGet, target: "/api/v1/orders?limit=5&offset=10",
+			setup: func(cache *mocks.MockCache, db *mocks.MockDatabase) {
+				db.EXPECT().ListOrders(gomock.Any(), 5, 10).Return([]*models.Order{order}, nil)
+			},
+			status: http.StatusOK,
+		},
+		{
+			name: "list orders csv", method: http.MethodGet, target: "/api/v1/orders",
+			header: http.Header{"X-Api-Key": {testReaderKey}, "Accept": {"text/csv"}},
+			setup: func(cache *mocks.MockCache, db *mocks.MockDatabase) {
+				db.EXPECT().ListOrders(gomock.Any(), 20, 0).Return([]*models.Order{order}, nil)
+			},
+			status: http.StatusOK,
+		},
+		{
+			name: "create order", method: http.MethodPost, target: "/api/v1/orders", body: orderJSON,
+			setup: func(cache *mocks.MockCache, db *mocks.MockDatabase) {
+				db.EXPECT().SaveOrder(gomock.Any(), gomock.Any()).Return(false, nil)
+				cache.EXPECT().Set(order.OrderUID, gomock.Any())
+			},
+			status: http.StatusCreated,
+		},
+		{
+			name: "unauthorized", method: http.MethodGet, target: "/api/v1/orders/" + order.OrderUID,
+			header: http.Header{"X-Api-Key": {"osk_wrong"}},
+			status: http.StatusUnauthorized,
+		},
+		{name: "audit", method: http.MethodGet, target: "/admin/audit?order_uid=" + order.OrderUID + "&limit=10", status: http.StatusOK},
+		{
+			name: "audit forbidden", method: http.MethodGet, target: "/admin/audit?order_uid=" + order.OrderUID,
+			header: http.Header{"X-Api-Key": {testReaderKey}},
+			status: http.StatusForbidden,
+		},
+		{name: "livez", method: http.MethodGet, target: "/livez", status: http.StatusOK},
+		{name: "readyz", method: http.MethodGet, target: "/readyz", status: http.StatusOK},
+		{name: "health", method: http.MethodGet, target: "/health", status: http.StatusOK},
+		{name: "metrics", method: http.MethodGet, target: "/metrics", status: http.StatusOK},
+		{name: "openapi", method: http.MethodGet, target: "/openapi.json", status: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			mockCache := mocks.NewMockCache(ctrl)
+			mockDB := mocks.NewMockDatabase(ctrl)
+			if tt.setup != nil {
+				tt.setup(mockCache, mockDB)
+			}
+			mux := newTestRouter(t, mockCache, mockDB)
+
+			w := httptest.NewRecorder()
+			served := httptest.NewRequest(tt.method, tt.target, bytes.NewReader(tt.body))
+			served.Header.Set("X-API-Key", testAPIKey)
+			for key, values := range tt.header {
+				served.Header[key] = values
+			}
+			mux.ServeHTTP(w, served)
+			require.Equal(t, tt.status, w.Code, w.Body.String())
+
+			req := httptest.NewRequest(tt.method, tt.target, nil)
+			req.Header = served.Header.Clone()
+			if tt.body != nil {
+				req.Header.Set("Content-Type", "application/json")
+			}
+			assert.NoError(t, validateExchange(t, specRouter, req, tt.body, w))
+		})
+	}
+}
+
+// ответ, расходящийся со схемой, тест обязан поймать
+func TestHandlers_SpecDetectsDrift(t *testing.T) {
+	specRouter, err := legacy.NewRouter(loadSpec(t))
+	require.NoError(t, err)
+
+	ctrl := gomock.NewController(t)
+	mockCache := mocks.NewMockCache(ctrl)
+	mockCache.EXPECT().GetOrLoad("short", gomock.Any()).Return(&models.Order{OrderUID: "short"}, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/orders/short", nil)
+	req.Header.Set("X-API-Key", testAPIKey)
+	w := httptest.NewRecorder()
+	newTestRouter(t, mockCache, mocks.NewMockDatabase(ctrl)).ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	err = validateExchange(t, specRouter, req, nil, w)
+	assert.Error(t, err)
+}
+
+func toJSONValue(t *testing.T, v interface{}) interface{} {
+	data, err := json.Marshal(v)
+	require.NoError(t, err)
+	var out interface{}
+	require.NoError(t, json.Unmarshal(data, &out))
+	return out
+}
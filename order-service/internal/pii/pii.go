@@ -0,0 +1,88 @@
+// internal/pii/pii.go
+package pii
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"order-service/models"
+)
+
+// mask заменитель скрытой части значения
+const mask = "***"
+
+// Field поле заказа с персональными данными и правило его маскирования
+type Field struct {
+	// Name путь поля в JSON, он же колонка CSV
+	Name string
+	Mask func(string) string
+	ref  func(*models.Order) *string
+}
+
+// Fields все персональные данные заказа. Город, регион и индекс не скрываются: по ним строится аналитика доставки
+var Fields = []Field{
+	{Name: "delivery.name", Mask: MaskName, ref: func(o *models.Order) *string { return &o.Delivery.Name }},
+	{Name: "delivery.phone", Mask: MaskPhone, ref: func(o *models.Order) *string { return &o.Delivery.Phone }},
+	{Name: "delivery.address", Mask: MaskAll, ref: func(o *models.Order) *string { return &o.Delivery.Address }},
+	{Name: "delivery.email", Mask: MaskEmail, ref: func(o *models.Order) *string { return &o.Delivery.Email }},
+	{Name: "payment.transaction", Mask: MaskTail, ref: func(o *models.Order) *string { return &o.Payment.Transaction }},
+}
+
+// Mask копия заказа со скрытыми Fields. Исходный заказ не меняется: это может быть объект из кэша
+func Mask(o *models.Order) *models.Order {
+	if o == nil {
+		return nil
+	}
+	masked := *o
+	for _, f := range Fields {
+		val := f.ref(&masked)
+		*val = f.Mask(*val)
+	}
+	return &masked
+}
+
+// MaskPhone оставляет код страны и 4 последние цифры: +79991234567 → +7******4567
+func MaskPhone(phone string) string {
+	r := []rune(phone)
+	if len(r) <= 6 {
+		return strings.Repeat("*", len(r))
+	}
+	return string(r[:2]) + strings.Repeat("*", len(r)-6) + string(r[len(r)-4:])
+}
+
+// MaskEmail оставляет первую букву и домен: john@mail.ru → j***@mail.ru
+func MaskEmail(email string) string {
+	at := strings.LastIndexByte(email, '@')
+	if at <= 0 {
+		return MaskAll(email)
+	}
+	first, _ := utf8.DecodeRuneInString(email)
+	return string(first) + mask + email[at:]
+}
+
+// MaskName оставляет первую букву каждого слова: Иван Петров → И*** П***
+func MaskName(name string) string {
+	words := strings.Fields(name)
+	for i, w := range words {
+		first, _ := utf8.DecodeRuneInString(w)
+		words[i] = string(first) + mask
+	}
+	return strings.Join(words, " ")
+}
+
+// MaskTail оставляет 4 последних символа, по ним идентификатор сверяют с выпиской
+func MaskTail(val string) string {
+	r := []rune(val)
+	if len(r) <= 8 {
+		return MaskAll(val)
+	}
+	return mask + string(r[len(r)-4:])
+}
+
+// MaskAll скрывает значение целиком, пустое остаётся пустым
+func MaskAll(val string) string {
+	if val == "" {
+		return ""
+	}
+	return mask
+}
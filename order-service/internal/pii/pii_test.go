@@ -0,0 +1,66 @@
+// internal/pii/pii_test.go
+package pii
+
+import (
+	"testing"
+
+	"order-service/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMaskers(t *testing.T) {
+	tests := []struct {
+		name string
+		mask func(string) string
+		in   string
+		want string
+	}{
+		{"phone", MaskPhone, "+79991231234", "+7******1234"},
+		{"phone short", MaskPhone, "+1234", "*****"},
+		{"phone empty", MaskPhone, "", ""},
+		{"email", MaskEmail, "john@mail.ru", "j***@mail.ru"},
+		{"email cyrillic", MaskEmail, "иван@почта.рф", "и***@почта.рф"},
+		{"email without at", MaskEmail, "john", "***"},
+		{"email empty", MaskEmail, "", ""},
+		{"name", MaskName, "Иван  Петров", "И*** П***"},
+		{"name empty", MaskName, "", ""},
+		{"tail", MaskTail, "b563feb7b2b84b6test", "***test"},
+		{"tail short", MaskTail, "12345678", "***"},
+		{"all", MaskAll, "Ploshad Mira 15", "***"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.mask(tt.in))
+		})
+	}
+}
+
+func TestMask_CopiesOrder(t *testing.T) {
+	order := &models.Order{
+		OrderUID: "b563feb7b2b84b6test",
+		Delivery: models.Delivery{
+			Name: "Test Testov", Phone: "+9720000000", City: "Kiryat Mozkin",
+			Address: "Ploshad Mira 15", Email: "test@gmail.com",
+		},
+		Payment: models.Payment{Transaction: "b563feb7b2b84b6test", Amount: 1817},
+	}
+	original := *order
+
+	masked := Mask(order)
+	require.NotSame(t, order, masked)
+	assert.Equal(t, original, *order, "исходный заказ не должен меняться")
+
+	assert.Equal(t, "T*** T***", masked.Delivery.Name)
+	assert.Equal(t, "+9*****0000", masked.Delivery.Phone)
+	assert.Equal(t, "***", masked.Delivery.Address)
+	assert.Equal(t, "t***@gmail.com", masked.Delivery.Email)
+	assert.Equal(t, "***test", masked.Payment.Transaction)
+	// остальные поля как есть
+	assert.Equal(t, "Kiryat Mozkin", masked.Delivery.City)
+	assert.Equal(t, 1817, masked.Payment.Amount)
+	assert.Equal(t, order.OrderUID, masked.OrderUID)
+
+	assert.Nil(t, Mask(nil))
+}
@@ -0,0 +1,114 @@
+// internal/pubsub/hub.go
+package pubsub
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"order-service/internal/metrics"
+	"order-service/models"
+)
+
+// Filter условия подписки; пустое поле — без ограничения
+type Filter struct {
+	CustomerID      string
+	DeliveryService string
+}
+
+// Match подходит ли заказ под фильтр
+func (f Filter) Match(o *models.Order) bool {
+	return (f.CustomerID == "" || f.CustomerID == o.CustomerID) &&
+		(f.DeliveryService == "" || f.DeliveryService == o.DeliveryService)
+}
+
+// Hub рассылает принятые заказы подписчикам внутри процесса.
+// Publish не ждёт подписчиков: медленному заказ не достаётся, пока он не разберёт свой буфер
+type Hub struct {
+	mu     sync.RWMutex
+	subs   map[*Subscription]struct{}
+	closed bool
+}
+
+// Subscription подписка на заказы. Заказы из C общие для всех подписчиков, менять их нельзя
+type Subscription struct {
+	C <-chan *models.Order
+
+	ch      chan *models.Order
+	filter  Filter
+	hub     *Hub
+	once    sync.Once
+	dropped atomic.Uint64
+}
+
+func NewHub() *Hub {
+	return &Hub{subs: make(map[*Subscription]struct{})}
+}
+
+// Subscribe подписка с буфером на bufferSize заказов
+func (h *Hub) Subscribe(filter Filter, bufferSize int) *Subscription {
+	ch := make(chan *models.Order, bufferSize)
+	s := &Subscription{C: ch, ch: ch, filter: filter, hub: h}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed {
+		// после остановки подписка сразу закрыта
+		s.once.Do(func() { close(ch) })
+		return s
+	}
+	h.subs[s] = struct{}{}
+	metrics.StreamSubscribers.Inc()
+	return s
+}
+
+// Publish отдаёт заказ подходящим подписчикам; у nil хаба ничего не делает
+func (h *Hub) Publish(o *models.Order) {
+	if h == nil {
+		return
+	}
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	for s := range h.subs {
+		if !s.filter.Match(o) {
+			continue
+		}
+		select {
+		case s.ch <- o:
+		default:
+			s.dropped.Add(1)
+			metrics.StreamDropped.Inc()
+		}
+	}
+}
+
+// Close закрывает все подписки и не принимает новые: открытые потоки клиентов
+// завершаются и не держат остановку серверов
+func (h *Hub) Close() {
+	h.mu.Lock()
+	h.closed = true
+	subs := make([]*Subscription, 0, len(h.subs))
+	for s := range h.subs {
+		subs = append(subs, s)
+	}
+	h.mu.Unlock()
+
+	for _, s := range subs {
+		s.Close()
+	}
+}
+
+// Dropped сколько заказов не досталось подписчику из-за полного буфера
+func (s *Subscription) Dropped() uint64 {
+	return s.dropped.Load()
+}
+
+// Close отписка; C закрывается. Повторный вызов ничего не делает
+func (s *Subscription) Close() {
+	s.once.Do(func() {
+		s.hub.mu.Lock()
+		delete(s.hub.subs, s)
+		s.hub.mu.Unlock()
+		close(s.ch)
+		metrics.StreamSubscribers.Dec()
+	})
+}
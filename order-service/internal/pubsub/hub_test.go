@@ -0,0 +1,86 @@
+// internal/pubsub/hub_test.go
+package pubsub
+
+import (
+	"testing"
+
+	"order-service/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func order(uid, customer, service string) *models.Order {
+	return &models.Order{OrderUID: uid, CustomerID: customer, DeliveryService: service}
+}
+
+func TestHub_PublishFilter(t *testing.T) {
+	h := NewHub()
+	all := h.Subscribe(Filter{}, 10)
+	defer all.Close()
+	byCustomer := h.Subscribe(Filter{CustomerID: "alice"}, 10)
+	defer byCustomer.Close()
+	byBoth := h.Subscribe(Filter{CustomerID: "alice", DeliveryService: "meest"}, 10)
+	defer byBoth.Close()
+
+	h.Publish(order("a", "alice", "meest"))
+	h.Publish(order("b", "bob", "meest"))
+	h.Publish(order("c", "alice", "dhl"))
+
+	assert.Len(t, all.C, 3)
+	require.Len(t, byCustomer.C, 2)
+	assert.Equal(t, "a", (<-byCustomer.C).OrderUID)
+	assert.Equal(t, "c", (<-byCustomer.C).OrderUID)
+	require.Len(t, byBoth.C, 1)
+	assert.Equal(t, "a", (<-byBoth.C).OrderUID)
+}
+
+func TestHub_SlowSubscriber(t *testing.T) {
+	h := NewHub()
+	slow := h.Subscribe(Filter{}, 1)
+	defer slow.Close()
+	fast := h.Subscribe(Filter{}, 10)
+	defer fast.Close()
+
+	// Publish не блокируется на полном буфере, остальные подписчики получают всё
+	for _, uid := range []string{"a", "b", "c"} {
+		h.Publish(order(uid, "", ""))
+	}
+	assert.Len(t, fast.C, 3)
+	assert.Len(t, slow.C, 1)
+	assert.Equal(t, uint64(2), slow.Dropped())
+	assert.Equal(t, "a", (<-slow.C).OrderUID)
+}
+
+func TestHub_Close(t *testing.T) {
+	h := NewHub()
+	s := h.Subscribe(Filter{}, 1)
+	s.Close()
+	s.Close()
+
+	_, ok := <-s.C
+	assert.False(t, ok)
+	// после отписки Publish не пишет в закрытый канал
+	assert.NotPanics(t, func() { h.Publish(order("a", "", "")) })
+}
+
+func TestHub_CloseAll(t *testing.T) {
+	h := NewHub()
+	s := h.Subscribe(Filter{}, 1)
+	h.Close()
+
+	_, ok := <-s.C
+	assert.False(t, ok)
+	s.Close()
+
+	// подписка после остановки хаба сразу закрыта
+	late := h.Subscribe(Filter{}, 1)
+	_, ok = <-late.C
+	assert.False(t, ok)
+	late.Close()
+}
+
+func TestHub_Nil(t *testing.T) {
+	var h *Hub
+	assert.NotPanics(t, func() { h.Publish(order("a", "", "")) })
+}
@@ -0,0 +1,93 @@
+// internal/router/router.go
+package router
+
+import (
+	"net/http"
+
+	"order-service/internal/auth"
+	"order-service/internal/handlers"
+	"order-service/internal/health"
+	"order-service/internal/middleware"
+	"order-service/internal/openapi"
+)
+
+// APIPrefix версия публичного API
+const APIPrefix = "/api/v1"
+
+// New маршруты сервиса на шаблонах ServeMux с методами.
+// Несовпадение метода ServeMux отвечает 405 с заголовком Allow, неизвестный путь — 404.
+// authn задаёт область доступа каждого маршрута; nil — аутентификация выключена.
+// limiter ограничивает частоту запросов к защищённым маршрутам по клиенту после аутентификации,
+// а до неё — неудачные попытки аутентификации по IP; nil — без ограничений.
+// Открыты только пробы, веб-интерфейс и спецификация: данных заказов в них нет
+func New(h *handlers.Handler, checks *health.Health, authn *auth.Authenticator, limiter *middleware.RateLimiter) *http.ServeMux {
+	mux := http.NewServeMux()
+	protect := protector(authn, limiter)
+
+	// API v1
+	mux.Handle("GET "+APIPrefix+"/orders", protect(auth.ScopeOrdersRead, h.ListOrdersHandler))
+	mux.Handle("POST "+APIPrefix+"/orders", protect(auth.ScopeOrdersWrite, h.CreateOrderHandler))
+	mux.Handle("GET "+APIPrefix+"/orders/{uid}", protect(auth.ScopeOrdersRead, h.OrderHandler))
+
+	// старый адрес, на него ходит веб-интерфейс и внешние клиенты
+	mux.Handle("GET /order/{uid}", protect(auth.ScopeOrdersRead, h.OrderHandler))
+
+	// потоки новых заказов: SSE и WebSocket с теми же фильтрами
+	mux.Handle("GET /orders/stream", protect(auth.ScopeOrdersRead, h.OrdersStreamHandler))
+	mux.Handle("GET /orders/stream/ws", protect(auth.ScopeOrdersRead, h.OrdersWebSocketHandler))
+
+	// GraphQL: те же заказы с выбором полей, данные только по чтению
+	if h.GraphQL != nil {
+		mux.Handle("POST /graphql", protect(auth.ScopeOrdersRead, h.GraphQL.ServeHTTP))
+	}
+
+	ops(mux, h, checks, protect)
+	mux.HandleFunc("GET /openapi.json", openapi.Handler)
+
+	// администрирование: журнал аудита только по области admin
+	mux.Handle("GET /admin/audit", protect(auth.ScopeAdmin, h.AuditHandler))
+
+	// веб-интерфейс: только корень, остальные пути не подменяются index.html
+	mux.Handle("GET /static/", http.StripPrefix("/static/", http.FileServer(http.Dir(webDir(h)))))
+	// Swagger UI собирается в образ вне web, иначе его закрыл бы смонтированный каталог
+	mux.Handle("GET /static/swagger-ui/", http.StripPrefix("/static/swagger-ui/", http.FileServer(http.Dir(swaggerUIDir(h)))))
+	mux.HandleFunc("GET /{$}", h.WebInterfaceHandler)
+
+	return mux
+}
+
+func swaggerUIDir(h *handlers.Handler) string {
+	if h.SwaggerUIDir == "" {
+		return "web/swagger-ui"
+	}
+	return h.SwaggerUIDir
+}
+
+func webDir(h *handlers.Handler) string {
+	if h.WebDir == "" {
+		return "web"
+	}
+	return h.WebDir
+}
+
+// Ops служебные маршруты для листенера без TLS: пробы, /health и /metrics с той же защитой, что на основном
+func Ops(h *handlers.Handler, checks *health.Health, authn *auth.Authenticator, limiter *middleware.RateLimiter) *http.ServeMux {
+	mux := http.NewServeMux()
+	ops(mux, h, checks, protector(authn, limiter))
+	return mux
+}
+
+// ops служебные: пробы открыты для оркестратора, подробности и метрики — по области metrics:read
+func ops(mux *http.ServeMux, h *handlers.Handler, checks *health.Health, protect func(string, http.HandlerFunc) http.Handler) {
+	mux.HandleFunc("GET /livez", checks.LivezHandler)
+	mux.HandleFunc("GET /readyz", checks.ReadyzHandler)
+	mux.Handle("GET /health", protect(auth.ScopeMetricsRead, checks.HealthHandler))
+	mux.Handle("GET /metrics", protect(auth.ScopeMetricsRead, h.MetricsHandler))
+}
+
+// protector оборачивает маршрут: корзина неудач аутентификации по IP, проверка области, лимит клиента
+func protector(authn *auth.Authenticator, limiter *middleware.RateLimiter) func(string, http.HandlerFunc) http.Handler {
+	return func(scope string, fn http.HandlerFunc) http.Handler {
+		return limiter.LimitAuthFailures(authn.Require(scope, limiter.Limit(fn)))
+	}
+}
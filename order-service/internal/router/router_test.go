@@ -0,0 +1,276 @@
+// internal/router/router_test.go
+package router
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"order-service/internal/auth"
+	"order-service/internal/config"
+	"order-service/internal/handlers"
+	"order-service/internal/health"
+	"order-service/internal/middleware"
+	"order-service/internal/mocks"
+	"order-service/internal/pubsub"
+	"order-service/models"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+func newTestRouter(t *testing.T) (http.Handler, *mocks.MockCache, *mocks.MockDatabase) {
+	ctrl := gomock.NewController(t)
+	mockCache := mocks.NewMockCache(ctrl)
+	mockDB := mocks.NewMockDatabase(ctrl)
+
+	h := handlers.NewHandler(mockCache, mockDB, noop.NewTracerProvider().Tracer("test"))
+	h.WebDir = "../../web"
+	return New(h, health.New(), nil, nil), mockCache, mockDB
+}
+
+func serve(h http.Handler, method, target string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(method, target, nil))
+	return w
+}
+
+func TestRouter_OrderRoutes(t *testing.T) {
+	r, mockCache, _ := newTestRouter(t)
+
+	for _, path := range []string{"/api/v1/orders/test123", "/order/test123"} {
+		mockCache.EXPECT().GetOrLoad("test123", gomock.Any()).Return(&models.Order{OrderUID: "test123"}, nil)
+
+		w := serve(r, http.MethodGet, path)
+		assert.Equal(t, http.StatusOK, w.Code, path)
+		assert.Contains(t, w.Body.String(), `"order_uid":"test123"`, path)
+	}
+}
+
+func TestRouter_ListOrders(t *testing.T) {
+	r, _, mockDB := newTestRouter(t)
+	mockDB.EXPECT().ListOrders(gomock.Any(), 20, 0).Return([]*models.Order{}, nil)
+
+	w := serve(r, http.MethodGet, "/api/v1/orders")
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestRouter_MethodNotAllowed(t *testing.T) {
+	r, _, _ := newTestRouter(t)
+
+	w := serve(r, http.MethodDelete, "/api/v1/orders/test123")
+	assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+	assert.Equal(t, "GET, HEAD", w.Header().Get("Allow"))
+
+	w = serve(r, http.MethodPost, "/order/test123")
+	assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+
+	w = serve(r, http.MethodPut, "/api/v1/orders")
+	assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+	assert.Equal(t, "GET, HEAD, POST", w.Header().Get("Allow"))
+}
+
+func TestRouter_NotFound(t *testing.T) {
+	r, _, _ := newTestRouter(t)
+
+	for _, path := range []string{"/unknown", "/order", "/api/v2/orders", "/order/a/b"} {
+		w := serve(r, http.MethodGet, path)
+		assert.Equal(t, http.StatusNotFound, w.Code, path)
+		assert.NotContains(t, w.Body.String(), "<html", path)
+	}
+}
+
+func TestRouter_WebInterface(t *testing.T) {
+	r, _, _ := newTestRouter(t)
+
+	w := serve(r, http.MethodGet, "/")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "<html")
+
+	w = serve(r, http.MethodGet, "/static/script.js")
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestRouter_SwaggerUIDir(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "swagger-ui-bundle.js"), []byte("// bundle"), 0o644))
+
+	h := handlers.NewHandler(mocks.NewMockCache(ctrl), mocks.NewMockDatabase(ctrl), noop.NewTracerProvider().Tracer("test"))
+	h.WebDir = "../../web"
+	h.SwaggerUIDir = dir
+	r := New(h, health.New(), nil, nil)
+
+	// файлы Swagger UI берутся из своего каталога, остальной интерфейс — из web
+	w := serve(r, http.MethodGet, "/static/swagger-ui/swagger-ui-bundle.js")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "// bundle", w.Body.String())
+	assert.Equal(t, http.StatusOK, serve(r, http.MethodGet, "/static/swagger.html").Code)
+}
+
+func TestRouter_ServiceEndpoints(t *testing.T) {
+	r, _, _ := newTestRouter(t)
+
+	for _, path := range []string{"/livez", "/readyz", "/health", "/metrics", "/openapi.json"} {
+		w := serve(r, http.MethodGet, path)
+		assert.Equal(t, http.StatusOK, w.Code, path)
+	}
+}
+
+func TestOps(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	h := handlers.NewHandler(mocks.NewMockCache(ctrl), mocks.NewMockDatabase(ctrl), noop.NewTracerProvider().Tracer("test"))
+	r := Ops(h, health.New(), nil, nil)
+
+	for _, path := range []string{"/livez", "/readyz", "/health", "/metrics"} {
+		assert.Equal(t, http.StatusOK, serve(r, http.MethodGet, path).Code, path)
+	}
+	// API на служебном листенере не отдаётся
+	assert.Equal(t, http.StatusNotFound, serve(r, http.MethodGet, "/api/v1/orders").Code)
+}
+
+func TestRouter_OrdersStream(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	h := handlers.NewHandler(mocks.NewMockCache(ctrl), mocks.NewMockDatabase(ctrl), noop.NewTracerProvider().Tracer("test"))
+	h.Hub = pubsub.NewHub()
+	// остановленный хаб сразу закрывает поток, запрос не висит
+	h.Hub.Close()
+	r := New(h, health.New(), nil, nil)
+
+	w := serve(r, http.MethodGet, "/orders/stream?customer_id=test")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "text/event-stream", w.Header().Get("Content-Type"))
+
+	w = serve(r, http.MethodPost, "/orders/stream")
+	assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+}
+
+func TestRouter_GraphQL(t *testing.T) {
+	r, _, _ := newTestRouter(t)
+	// без обработчика маршрут не регистрируется
+	assert.Equal(t, http.StatusNotFound, serve(r, http.MethodPost, "/graphql").Code)
+
+	ctrl := gomock.NewController(t)
+	h := handlers.NewHandler(mocks.NewMockCache(ctrl), mocks.NewMockDatabase(ctrl), noop.NewTracerProvider().Tracer("test"))
+	h.GraphQL = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"data":{}}`))
+	})
+	authn, err := auth.New(config.AuthConfig{Enabled: true}, fakeKeyStore{
+		auth.HashAPIKey("osk_reader"):     {Name: "reader", Scopes: []string{auth.ScopeOrdersRead}},
+		auth.HashAPIKey("osk_prometheus"): {Name: "prometheus", Scopes: []string{auth.ScopeMetricsRead}},
+	})
+	require.NoError(t, err)
+	r = New(h, health.New(), authn, nil)
+
+	request := func(method, key string) int {
+		req := httptest.NewRequest(method, "/graphql", nil)
+		if key != "" {
+			req.Header.Set("X-API-Key", key)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+	assert.Equal(t, http.StatusOK, request(http.MethodPost, "osk_reader"))
+	assert.Equal(t, http.StatusUnauthorized, request(http.MethodPost, ""))
+	assert.Equal(t, http.StatusForbidden, request(http.MethodPost, "osk_prometheus"))
+	assert.Equal(t, http.StatusMethodNotAllowed, request(http.MethodGet, "osk_reader"))
+}
+
+type fakeKeyStore map[string]*auth.APIKey
+
+func (f fakeKeyStore) LookupAPIKey(ctx context.Context, keyHash string) (*auth.APIKey, error) {
+	if key, ok := f[keyHash]; ok {
+		return key, nil
+	}
+	return nil, sql.ErrNoRows
+}
+
+func TestRouter_Auth(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mockCache := mocks.NewMockCache(ctrl)
+	h := handlers.NewHandler(mockCache, mocks.NewMockDatabase(ctrl), noop.NewTracerProvider().Tracer("test"))
+	h.WebDir = "../../web"
+
+	authn, err := auth.New(config.AuthConfig{Enabled: true}, fakeKeyStore{
+		auth.HashAPIKey("osk_reader"):     {Name: "reader", Scopes: []string{auth.ScopeOrdersRead}},
+		auth.HashAPIKey("osk_prometheus"): {Name: "prometheus", Scopes: []string{auth.ScopeMetricsRead}},
+		auth.HashAPIKey("osk_admin"):      {Name: "admin", Scopes: []string{auth.ScopeAdmin}},
+	})
+	require.NoError(t, err)
+	r := New(h, health.New(), authn, nil)
+
+	request := func(method, target, key string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, nil)
+		if key != "" {
+			req.Header.Set("X-API-Key", key)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	// без ключа закрыты данные заказов, подробный health и метрики
+	for _, path := range []string{"/api/v1/orders", "/api/v1/orders/test123", "/order/test123", "/health", "/metrics"} {
+		assert.Equal(t, http.StatusUnauthorized, request(http.MethodGet, path, "").Code, path)
+	}
+	assert.Equal(t, http.StatusUnauthorized, request(http.MethodPost, "/api/v1/orders", "").Code)
+
+	// пробы, веб-интерфейс и спецификация открыты
+	for _, path := range []string{"/livez", "/readyz", "/openapi.json", "/", "/static/script.js"} {
+		assert.Equal(t, http.StatusOK, request(http.MethodGet, path, "").Code, path)
+	}
+
+	mockCache.EXPECT().GetOrLoad("test123", gomock.Any()).Return(&models.Order{OrderUID: "test123"}, nil)
+	assert.Equal(t, http.StatusOK, request(http.MethodGet, "/api/v1/orders/test123", "osk_reader").Code)
+	assert.Equal(t, http.StatusForbidden, request(http.MethodPost, "/api/v1/orders", "osk_reader").Code)
+	assert.Equal(t, http.StatusForbidden, request(http.MethodGet, "/metrics", "osk_reader").Code)
+
+	assert.Equal(t, http.StatusOK, request(http.MethodGet, "/metrics", "osk_prometheus").Code)
+	assert.Equal(t, http.StatusForbidden, request(http.MethodGet, "/api/v1/orders/test123", "osk_prometheus").Code)
+
+	// журнал аудита только для admin; у обработчика нет журнала, поэтому 404 после проверки области
+	assert.Equal(t, http.StatusUnauthorized, request(http.MethodGet, "/admin/audit?order_uid=test123", "").Code)
+	assert.Equal(t, http.StatusForbidden, request(http.MethodGet, "/admin/audit?order_uid=test123", "osk_reader").Code)
+	assert.Equal(t, http.StatusNotFound, request(http.MethodGet, "/admin/audit?order_uid=test123", "osk_admin").Code)
+
+	// потоки заказов по области orders:read; у обработчика нет хаба, поэтому 404 после проверки области
+	for _, path := range []string{"/orders/stream", "/orders/stream/ws"} {
+		assert.Equal(t, http.StatusUnauthorized, request(http.MethodGet, path, "").Code, path)
+		assert.Equal(t, http.StatusForbidden, request(http.MethodGet, path, "osk_prometheus").Code, path)
+		assert.Equal(t, http.StatusNotFound, request(http.MethodGet, path, "osk_reader").Code, path)
+	}
+}
+
+func TestRouter_RateLimit(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mockCache := mocks.NewMockCache(ctrl)
+	h := handlers.NewHandler(mockCache, mocks.NewMockDatabase(ctrl), noop.NewTracerProvider().Tracer("test"))
+	h.WebDir = "../../web"
+
+	limiter := middleware.NewRateLimiter(config.RateLimitConfig{
+		Enabled: true,
+		Default: config.RateLimit{RPS: 0.01, Burst: 1},
+	})
+	r := New(h, health.New(), nil, limiter)
+
+	mockCache.EXPECT().GetOrLoad("test123", gomock.Any()).Return(&models.Order{OrderUID: "test123"}, nil)
+	w := serve(r, http.MethodGet, "/order/test123")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "0", w.Header().Get("RateLimit-Remaining"))
+
+	w = serve(r, http.MethodGet, "/order/other")
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.NotEmpty(t, w.Header().Get("Retry-After"))
+
+	// открытые маршруты не ограничиваются
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, http.StatusOK, serve(r, http.MethodGet, "/livez").Code)
+	}
+}
@@ -0,0 +1,31 @@
+// internal/tlsconfig/client.go
+package tlsconfig
+
+import (
+	"crypto/tls"
+	"fmt"
+)
+
+// Client tls.Config клиента: caFile пустой — системные корневые сертификаты,
+// certFile и keyFile — клиентский сертификат для mTLS
+func Client(caFile, certFile, keyFile string, insecureSkipVerify bool) (*tls.Config, error) {
+	cfg := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		InsecureSkipVerify: insecureSkipVerify,
+	}
+	if caFile != "" {
+		pool, err := loadCertPool(caFile)
+		if err != nil {
+			return nil, err
+		}
+		cfg.RootCAs = pool
+	}
+	if certFile != "" {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("не удалось загрузить клиентский сертификат: %w", err)
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+	return cfg, nil
+}
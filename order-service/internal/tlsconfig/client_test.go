@@ -0,0 +1,45 @@
+// internal/tlsconfig/client_test.go
+package tlsconfig
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCA(t)
+	caFile := ca.write(t, dir)
+	certFile, keyFile := ca.issue(t, dir, "client", 2, x509.ExtKeyUsageClientAuth)
+
+	cfg, err := Client("", "", "", false)
+	require.NoError(t, err)
+	assert.Nil(t, cfg.RootCAs, "без CA — системные корневые сертификаты")
+	assert.Empty(t, cfg.Certificates)
+	assert.Equal(t, uint16(tls.VersionTLS12), cfg.MinVersion)
+
+	cfg, err = Client(caFile, certFile, keyFile, true)
+	require.NoError(t, err)
+	assert.NotNil(t, cfg.RootCAs)
+	assert.Len(t, cfg.Certificates, 1)
+	assert.True(t, cfg.InsecureSkipVerify)
+}
+
+func TestClient_Errors(t *testing.T) {
+	dir := t.TempDir()
+	notPEM := filepath.Join(dir, "bad.pem")
+	require.NoError(t, os.WriteFile(notPEM, []byte("not a pem"), 0o600))
+
+	_, err := Client(notPEM, "", "", false)
+	assert.Error(t, err)
+	_, err = Client(filepath.Join(dir, "missing.crt"), "", "", false)
+	assert.Error(t, err)
+	_, err = Client("", notPEM, notPEM, false)
+	assert.Error(t, err)
+}
@@ -0,0 +1,135 @@
+// internal/tlsconfig/server.go
+package tlsconfig
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+
+	"order-service/internal/config"
+	"order-service/internal/metrics"
+)
+
+// Server TLS настройки HTTP сервера с перечитыванием сертификата и CA клиентов без перезапуска.
+// Новые соединения получают актуальные файлы, уже открытые остаются на старых
+type Server struct {
+	certFile, keyFile, caFile string
+	clientAuth                tls.ClientAuthType
+	minVersion                uint16
+
+	mu        sync.RWMutex
+	cert      *tls.Certificate
+	clientCAs *x509.CertPool
+}
+
+var clientAuthTypes = map[string]tls.ClientAuthType{
+	"none":    tls.NoClientCert,
+	"request": tls.VerifyClientCertIfGiven,
+	"require": tls.RequireAndVerifyClientCert,
+}
+
+var tlsVersions = map[string]uint16{
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// NewServer загружает сертификат и CA клиентов; nil, если HTTPS не настроен
+func NewServer(cfg config.ServerTLSConfig) (*Server, error) {
+	if !cfg.Enabled() {
+		return nil, nil
+	}
+	s := &Server{
+		certFile:   cfg.CertFile,
+		keyFile:    cfg.KeyFile,
+		caFile:     cfg.ClientCAFile,
+		clientAuth: clientAuthTypes[cfg.ClientAuth],
+		minVersion: tlsVersions[cfg.MinVersion],
+	}
+	if s.minVersion == 0 {
+		s.minVersion = tls.VersionTLS12
+	}
+	if err := s.Reload(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Reload перечитывает файлы. При ошибке остаются прежние сертификат и CA
+func (s *Server) Reload() error {
+	err := s.reload()
+	result := "success"
+	if err != nil {
+		result = "error"
+	}
+	metrics.TLSReloads.WithLabelValues(result).Inc()
+	return err
+}
+
+func (s *Server) reload() error {
+	cert, err := tls.LoadX509KeyPair(s.certFile, s.keyFile)
+	if err != nil {
+		return fmt.Errorf("не удалось загрузить сертификат сервера: %w", err)
+	}
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		return fmt.Errorf("некорректный сертификат сервера: %w", err)
+	}
+	cert.Leaf = leaf
+
+	var pool *x509.CertPool
+	if s.caFile != "" {
+		if pool, err = loadCertPool(s.caFile); err != nil {
+			return err
+		}
+	}
+
+	s.mu.Lock()
+	s.cert = &cert
+	s.clientCAs = pool
+	s.mu.Unlock()
+	metrics.TLSCertExpiry.Set(float64(leaf.NotAfter.Unix()))
+	return nil
+}
+
+// Certificate текущий сертификат сервера
+func (s *Server) Certificate() *x509.Certificate {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.cert.Leaf
+}
+
+// Config tls.Config для http.Server: сертификат и CA клиентов берутся на каждое рукопожатие
+func (s *Server) Config() *tls.Config {
+	return &tls.Config{
+		MinVersion:         s.minVersion,
+		NextProtos:         []string{"h2", "http/1.1"},
+		GetConfigForClient: s.configForClient,
+	}
+}
+
+func (s *Server) configForClient(*tls.ClientHelloInfo) (*tls.Config, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return &tls.Config{
+		MinVersion:   s.minVersion,
+		NextProtos:   []string{"h2", "http/1.1"},
+		Certificates: []tls.Certificate{*s.cert},
+		ClientAuth:   s.clientAuth,
+		ClientCAs:    s.clientCAs,
+	}, nil
+}
+
+func loadCertPool(path string) (*x509.CertPool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось прочитать CA: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, errors.New("в файле CA нет сертификатов PEM: " + path)
+	}
+	return pool, nil
+}
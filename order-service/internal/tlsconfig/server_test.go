@@ -0,0 +1,203 @@
+// internal/tlsconfig/server_test.go
+package tlsconfig
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"order-service/internal/config"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testCA самоподписанный CA, выпускающий сертификаты для тестов
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  []byte
+}
+
+func newTestCA(t *testing.T) *testCA {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(24 * time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return &testCA{cert: cert, key: key, pem: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})}
+}
+
+// issue выпускает сертификат и пишет cert и key в dir под именем name
+func (ca *testCA) issue(t *testing.T, dir, name string, serial int64, usage x509.ExtKeyUsage) (certFile, keyFile string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Duration(serial) * time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		DNSNames:     []string{"localhost"},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca.cert, &key.PublicKey, ca.key)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	certFile = filepath.Join(dir, name+".crt")
+	keyFile = filepath.Join(dir, name+".key")
+	require.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600))
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600))
+	return certFile, keyFile
+}
+
+func (ca *testCA) write(t *testing.T, dir string) string {
+	path := filepath.Join(dir, "ca.crt")
+	require.NoError(t, os.WriteFile(path, ca.pem, 0o600))
+	return path
+}
+
+// serve HTTPS сервер с конфигурацией s, отвечает 204
+func serve(t *testing.T, s *Server) *httptest.Server {
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	srv.TLS = s.Config()
+	srv.StartTLS()
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func get(t *testing.T, url string, clientCfg *tls.Config) (*http.Response, error) {
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: clientCfg}}
+	t.Cleanup(client.CloseIdleConnections)
+	resp, err := client.Get(url)
+	if err == nil {
+		resp.Body.Close()
+	}
+	return resp, err
+}
+
+func TestNewServer_Disabled(t *testing.T) {
+	s, err := NewServer(config.ServerTLSConfig{ClientAuth: "none", MinVersion: "1.2"})
+	require.NoError(t, err)
+	assert.Nil(t, s)
+}
+
+func TestServer_ReloadCertificate(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCA(t)
+	certFile, keyFile := ca.issue(t, dir, "server", 2, x509.ExtKeyUsageServerAuth)
+
+	s, err := NewServer(config.ServerTLSConfig{CertFile: certFile, KeyFile: keyFile, ClientAuth: "none", MinVersion: "1.2"})
+	require.NoError(t, err)
+	srv := serve(t, s)
+
+	clientCfg, err := Client(ca.write(t, dir), "", "", false)
+	require.NoError(t, err)
+	resp, err := get(t, srv.URL, clientCfg)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), resp.TLS.PeerCertificates[0].SerialNumber.Int64())
+
+	// новый сертификат под тем же именем подхватывается без перезапуска
+	ca.issue(t, dir, "server", 3, x509.ExtKeyUsageServerAuth)
+	require.NoError(t, s.Reload())
+	assert.Equal(t, int64(3), s.Certificate().SerialNumber.Int64())
+
+	clientCfg, err = Client(ca.write(t, dir), "", "", false)
+	require.NoError(t, err)
+	resp, err = get(t, srv.URL, clientCfg)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), resp.TLS.PeerCertificates[0].SerialNumber.Int64())
+
+	// битый файл не ломает сервер: остаётся прежний сертификат
+	require.NoError(t, os.WriteFile(keyFile, []byte("garbage"), 0o600))
+	assert.Error(t, s.Reload())
+	assert.Equal(t, int64(3), s.Certificate().SerialNumber.Int64())
+}
+
+func TestServer_ClientAuth(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCA(t)
+	caFile := ca.write(t, dir)
+	certFile, keyFile := ca.issue(t, dir, "server", 2, x509.ExtKeyUsageServerAuth)
+	clientCert, clientKey := ca.issue(t, dir, "client", 4, x509.ExtKeyUsageClientAuth)
+	// сертификат чужого CA сервер не принимает
+	otherCert, otherKey := newTestCA(t).issue(t, dir, "other", 5, x509.ExtKeyUsageClientAuth)
+
+	withCert, err := Client(caFile, clientCert, clientKey, false)
+	require.NoError(t, err)
+	withoutCert, err := Client(caFile, "", "", false)
+	require.NoError(t, err)
+	withOther, err := Client(caFile, otherCert, otherKey, false)
+	require.NoError(t, err)
+
+	tests := []struct {
+		clientAuth string
+		client     *tls.Config
+		ok         bool
+	}{
+		{"require", withCert, true},
+		{"require", withoutCert, false},
+		{"require", withOther, false},
+		{"request", withCert, true},
+		{"request", withoutCert, true},
+		{"request", withOther, false},
+	}
+	for _, tt := range tests {
+		s, err := NewServer(config.ServerTLSConfig{
+			CertFile: certFile, KeyFile: keyFile, ClientCAFile: caFile, ClientAuth: tt.clientAuth, MinVersion: "1.3",
+		})
+		require.NoError(t, err)
+
+		_, err = get(t, serve(t, s).URL, tt.client)
+		if tt.ok {
+			assert.NoError(t, err, tt.clientAuth)
+		} else {
+			assert.Error(t, err, tt.clientAuth)
+		}
+	}
+}
+
+func TestNewServer_Errors(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCA(t)
+	certFile, keyFile := ca.issue(t, dir, "server", 2, x509.ExtKeyUsageServerAuth)
+	notPEM := filepath.Join(dir, "bad.pem")
+	require.NoError(t, os.WriteFile(notPEM, []byte("not a pem"), 0o600))
+
+	for name, cfg := range map[string]config.ServerTLSConfig{
+		"missing cert": {CertFile: filepath.Join(dir, "missing.crt"), KeyFile: keyFile},
+		"bad ca":       {CertFile: certFile, KeyFile: keyFile, ClientCAFile: notPEM, ClientAuth: "require"},
+		"missing ca":   {CertFile: certFile, KeyFile: keyFile, ClientCAFile: filepath.Join(dir, "missing.crt"), ClientAuth: "require"},
+	} {
+		_, err := NewServer(cfg)
+		assert.Error(t, err, name)
+	}
+}
@@ -0,0 +1,43 @@
+package tracing
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	tracesdk "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// monitoredExporter запоминает результат последней отправки спанов для /health
+type monitoredExporter struct {
+	tracesdk.SpanExporter
+
+	mu         sync.Mutex
+	lastExport time.Time
+	lastErr    error
+}
+
+var exporterState = &monitoredExporter{}
+
+func (e *monitoredExporter) ExportSpans(ctx context.Context, spans []tracesdk.ReadOnlySpan) error {
+	err := e.SpanExporter.ExportSpans(ctx, spans)
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.lastErr = err
+	if err == nil {
+		e.lastExport = time.Now()
+	}
+	return err
+}
+
+func (e *monitoredExporter) status() (time.Time, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.lastExport, e.lastErr
+}
+
+// ExporterStatus время последней успешной отправки спанов и ошибка последней попытки
+func ExporterStatus() (lastExport time.Time, lastErr error) {
+	return exporterState.status()
+}
@@ -1,10 +1,19 @@
 package tracing
 
 import (
+	"context"
 	"fmt"
+	"os"
+	"strings"
+
+	"order-service/internal/config"
 
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/exporters/jaeger"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
 	"go.opentelemetry.io/otel/sdk/resource"
 	tracesdk "go.opentelemetry.io/otel/sdk/trace"
 	semconv "go.opentelemetry.io/otel/semconv/v1.28.0"
@@ -12,27 +21,118 @@ import (
 )
 
 // инициализирует трейсинг для приложения
-func InitTracer(serviceName string) (*tracesdk.TracerProvider, error) {
-	exp, err := jaeger.New(jaeger.WithCollectorEndpoint(
-		jaeger.WithEndpoint("http://jaeger:14268/api/traces"),
-	))
+func InitTracer(cfg config.TracingConfig) (*tracesdk.TracerProvider, error) {
+	ctx := context.Background()
+
+	res, err := newResource(ctx, cfg)
 	if err != nil {
-		return nil, fmt.Errorf("не удалось создать экспортера джагер: %v", err)
+		return nil, err
 	}
 
-	tp := tracesdk.NewTracerProvider(
-		tracesdk.WithBatcher(exp),
-		tracesdk.WithResource(resource.NewWithAttributes(
-			semconv.SchemaURL,
-			semconv.ServiceNameKey.String(serviceName),
-		)),
-	)
+	opts := []tracesdk.TracerProviderOption{
+		tracesdk.WithResource(res),
+		tracesdk.WithSampler(newSampler(cfg.SampleRatio)),
+	}
+
+	exp, err := newExporter(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+	// без экспортера спаны всё равно создаются: trace_id нужен для корреляции логов
+	if exp != nil {
+		exporterState = &monitoredExporter{SpanExporter: exp}
+		opts = append(opts, tracesdk.WithBatcher(exporterState))
+	} else {
+		exporterState = &monitoredExporter{}
+	}
+
+	tp := tracesdk.NewTracerProvider(opts...)
 
 	otel.SetTracerProvider(tp)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
 
 	return tp, nil
 }
 
+// newExporter возвращает nil для режима none
+func newExporter(ctx context.Context, cfg config.TracingConfig) (tracesdk.SpanExporter, error) {
+	switch cfg.Exporter {
+	case "otlp-grpc":
+		opts := []otlptracegrpc.Option{}
+		if strings.Contains(cfg.OTLPEndpoint, "://") {
+			opts = append(opts, otlptracegrpc.WithEndpointURL(cfg.OTLPEndpoint))
+		} else {
+			opts = append(opts, otlptracegrpc.WithEndpoint(cfg.OTLPEndpoint))
+		}
+		if cfg.OTLPInsecure {
+			opts = append(opts, otlptracegrpc.WithInsecure())
+		}
+		exp, err := otlptracegrpc.New(ctx, opts...)
+		if err != nil {
+			return nil, fmt.Errorf("не удалось создать OTLP gRPC экспортер: %w", err)
+		}
+		return exp, nil
+	case "otlp-http":
+		opts := []otlptracehttp.Option{}
+		if strings.Contains(cfg.OTLPEndpoint, "://") {
+			opts = append(opts, otlptracehttp.WithEndpointURL(cfg.OTLPEndpoint))
+		} else {
+			opts = append(opts, otlptracehttp.WithEndpoint(cfg.OTLPEndpoint))
+		}
+		if cfg.OTLPInsecure {
+			opts = append(opts, otlptracehttp.WithInsecure())
+		}
+		exp, err := otlptracehttp.New(ctx, opts...)
+		if err != nil {
+			return nil, fmt.Errorf("не удалось создать OTLP HTTP экспортер: %w", err)
+		}
+		return exp, nil
+	case "jaeger":
+		exp, err := jaeger.New(jaeger.WithCollectorEndpoint(
+			jaeger.WithEndpoint(cfg.JaegerEndpoint),
+		))
+		if err != nil {
+			return nil, fmt.Errorf("не удалось создать экспортера джагер: %v", err)
+		}
+		return exp, nil
+	case "stdout":
+		exp, err := stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+		if err != nil {
+			return nil, fmt.Errorf("не удалось создать stdout экспортер: %w", err)
+		}
+		return exp, nil
+	case "none", "":
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("неизвестный экспортер трейсов %q", cfg.Exporter)
+	}
+}
+
+// newSampler уважает решение вызывающего сервиса, корневые трейсы сэмплируются с долей ratio
+func newSampler(ratio float64) tracesdk.Sampler {
+	return tracesdk.ParentBased(tracesdk.TraceIDRatioBased(ratio))
+}
+
+func newResource(ctx context.Context, cfg config.TracingConfig) (*resource.Resource, error) {
+	res, err := resource.New(ctx,
+		resource.WithAttributes(
+			semconv.ServiceName(cfg.ServiceName),
+			semconv.ServiceVersion(cfg.ServiceVersion),
+			semconv.DeploymentEnvironmentName(cfg.Environment),
+		),
+		resource.WithHost(),
+		resource.WithProcessPID(),
+		resource.WithTelemetrySDK(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось собрать атрибуты ресурса: %w", err)
+	}
+	return res, nil
+}
+
 // возвращает трейсер для компонента
 func GetTracer(componentName string) trace.Tracer {
 	return otel.GetTracerProvider().Tracer(componentName)
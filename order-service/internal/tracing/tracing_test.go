@@ -2,14 +2,28 @@ package tracing_test
 
 import (
 	"context"
+	"order-service/internal/config"
 	"order-service/internal/tracing"
 	"testing"
 
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	tracesdk "go.opentelemetry.io/otel/sdk/trace"
 	"go.opentelemetry.io/otel/trace"
 )
 
+func testConfig(exporter string) config.TracingConfig {
+	cfg := config.Default().Tracing
+	cfg.ServiceName = "test-service"
+	cfg.Exporter = exporter
+	cfg.OTLPEndpoint = "localhost:4317"
+	cfg.JaegerEndpoint = "http://localhost:14268/api/traces"
+	return cfg
+}
+
 func TestInitTracer(t *testing.T) {
-	tp, err := tracing.InitTracer("test-service")
+	tp, err := tracing.InitTracer(testConfig("jaeger"))
 	if err != nil {
 		t.Fatalf("InitTracer вернул ошибку: %v", err)
 	}
@@ -27,6 +41,100 @@ func TestInitTracer(t *testing.T) {
 	}
 }
 
+func TestInitTracer_Exporters(t *testing.T) {
+	for _, exporter := range []string{"otlp-grpc", "otlp-http", "stdout", "none"} {
+		t.Run(exporter, func(t *testing.T) {
+			tp, err := tracing.InitTracer(testConfig(exporter))
+			if err != nil {
+				t.Fatalf("InitTracer(%s) вернул ошибку: %v", exporter, err)
+			}
+			// коллектора нет — сервис всё равно должен стартовать и останавливаться
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+			_ = tp.Shutdown(ctx)
+		})
+	}
+}
+
+func TestInitTracer_UnknownExporter(t *testing.T) {
+	if _, err := tracing.InitTracer(testConfig("zipkin")); err == nil {
+		t.Fatal("ожидали ошибку для неизвестного экспортера")
+	}
+}
+
+func TestInitTracer_NoneStillCreatesTraceIDs(t *testing.T) {
+	tp, err := tracing.InitTracer(testConfig("none"))
+	if err != nil {
+		t.Fatalf("InitTracer вернул ошибку: %v", err)
+	}
+	defer tp.Shutdown(context.Background())
+
+	_, span := tracing.GetTracer("test").Start(context.Background(), "op")
+	defer span.End()
+	if !span.SpanContext().TraceID().IsValid() {
+		t.Error("ожидали валидный trace_id в режиме none")
+	}
+}
+
+func TestInitTracer_Sampling(t *testing.T) {
+	cfg := testConfig("none")
+	cfg.SampleRatio = 0
+	tp, err := tracing.InitTracer(cfg)
+	if err != nil {
+		t.Fatalf("InitTracer вернул ошибку: %v", err)
+	}
+	defer tp.Shutdown(context.Background())
+
+	tracer := tracing.GetTracer("test")
+
+	_, root := tracer.Start(context.Background(), "root")
+	root.End()
+	if root.SpanContext().IsSampled() {
+		t.Error("при ratio=0 корневой спан не должен сэмплироваться")
+	}
+
+	// родитель из traceparent с флагом sampled — решение родителя приоритетнее
+	carrier := propagation.MapCarrier{"traceparent": "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"}
+	ctx := otel.GetTextMapPropagator().Extract(context.Background(), carrier)
+	_, child := tracer.Start(ctx, "child")
+	child.End()
+	if !child.SpanContext().IsSampled() {
+		t.Error("спан с сэмплированным родителем должен сэмплироваться")
+	}
+}
+
+func TestInitTracer_ResourceAttributes(t *testing.T) {
+	cfg := testConfig("none")
+	cfg.ServiceVersion = "1.2.3"
+	cfg.Environment = "staging"
+	tp, err := tracing.InitTracer(cfg)
+	if err != nil {
+		t.Fatalf("InitTracer вернул ошибку: %v", err)
+	}
+	defer tp.Shutdown(context.Background())
+
+	_, span := tracing.GetTracer("test").Start(context.Background(), "op")
+	defer span.End()
+
+	ro, ok := span.(tracesdk.ReadOnlySpan)
+	if !ok {
+		t.Fatal("ожидали спан sdk")
+	}
+	attrs := ro.Resource().Set()
+	for key, want := range map[attribute.Key]string{
+		"service.name":                "test-service",
+		"service.version":             "1.2.3",
+		"deployment.environment.name": "staging",
+	} {
+		if got, _ := attrs.Value(key); got.AsString() != want {
+			t.Errorf("%s = %q, ожидали %q", key, got.AsString(), want)
+		}
+	}
+	if _, ok := attrs.Value("host.name"); !ok {
+		t.Error("ожидали атрибут host.name")
+	}
+}
+
 func TestGetTracer(t *testing.T) {
 	tr := tracing.GetTracer("test-component")
 	if tr == nil {
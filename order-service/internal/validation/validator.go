@@ -1,6 +1,7 @@
 package validation
 
 import (
+	"errors"
 	"fmt"
 	"math"
 	"time"
@@ -155,7 +156,7 @@ func formatValidationError(err error) error {
 			fullMessage += fmt.Sprintf("%d. %s\n", i+1, msg)
 		}
 
-		return fmt.Errorf(fullMessage)
+		return errors.New(fullMessage)
 	}
 	return err
 }
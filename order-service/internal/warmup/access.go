@@ -0,0 +1,69 @@
+// internal/warmup/access.go
+package warmup
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"order-service/internal/logging"
+)
+
+// AccessStore хранилище счётчиков обращений к заказам
+type AccessStore interface {
+	RecordOrderAccesses(hits map[string]int) error
+}
+
+// AccessRecorder копит обращения к заказам в памяти и периодически
+// сбрасывает их в БД, чтобы не писать в неё на каждый GET
+type AccessRecorder struct {
+	store    AccessStore
+	interval time.Duration
+
+	mu   sync.Mutex
+	hits map[string]int
+}
+
+func NewAccessRecorder(store AccessStore, interval time.Duration) *AccessRecorder {
+	return &AccessRecorder{
+		store:    store,
+		interval: interval,
+		hits:     make(map[string]int),
+	}
+}
+
+func (a *AccessRecorder) RecordAccess(orderUID string) {
+	a.mu.Lock()
+	a.hits[orderUID]++
+	a.mu.Unlock()
+}
+
+// Run сбрасывает накопленные счётчики каждые interval и при остановке
+func (a *AccessRecorder) Run(ctx context.Context) {
+	ticker := time.NewTicker(a.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			a.Flush()
+		case <-ctx.Done():
+			a.Flush()
+			return
+		}
+	}
+}
+
+func (a *AccessRecorder) Flush() {
+	a.mu.Lock()
+	hits := a.hits
+	a.hits = make(map[string]int)
+	a.mu.Unlock()
+
+	if len(hits) == 0 {
+		return
+	}
+	if err := a.store.RecordOrderAccesses(hits); err != nil {
+		slog.Error("Ошибка записи журнала обращений", slog.Int("orders", len(hits)), logging.Err(err))
+	}
+}
@@ -0,0 +1,146 @@
+// internal/warmup/warmup.go
+package warmup
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"order-service/internal/interfaces"
+	"order-service/internal/logging"
+	"order-service/internal/metrics"
+	"order-service/models"
+)
+
+// Strategy способ выбора заказов для прогрева кэша
+type Strategy string
+
+const (
+	StrategyRecent   Strategy = "recent"   // последние N заказов по date_created
+	StrategyFrequent Strategy = "frequent" // самые запрашиваемые по order_access_log
+	StrategyNone     Strategy = "none"     // без прогрева
+
+	batchSize = 100
+)
+
+// OrderSource источник заказов для прогрева
+type OrderSource interface {
+	// OrdersByUID полные заказы пачкой, отсутствующие uid пропускаются
+	OrdersByUID(ctx context.Context, uids []string) (map[string]*models.Order, error)
+	GetRecentOrderUIDs(limit int) ([]string, error)
+	GetMostAccessedOrderUIDs(limit int) ([]string, error)
+}
+
+// Warmer заполняет кэш в фоне, не блокируя старт HTTP сервера
+type Warmer struct {
+	cache    interfaces.Cache
+	source   OrderSource
+	strategy Strategy
+	limit    int
+
+	ready    atomic.Bool
+	done     chan struct{}
+	doneOnce sync.Once
+}
+
+func New(cache interfaces.Cache, source OrderSource, strategy Strategy, limit int) (*Warmer, error) {
+	switch strategy {
+	case StrategyRecent, StrategyFrequent, StrategyNone:
+	default:
+		return nil, fmt.Errorf("неизвестная стратегия прогрева %q", strategy)
+	}
+	return &Warmer{
+		cache:    cache,
+		source:   source,
+		strategy: strategy,
+		limit:    limit,
+		done:     make(chan struct{}),
+	}, nil
+}
+
+// Ready true после завершения прогрева (в том числе неудачного)
+func (w *Warmer) Ready() bool {
+	return w.ready.Load()
+}
+
+// Done закрывается по завершении прогрева
+func (w *Warmer) Done() <-chan struct{} {
+	return w.done
+}
+
+// MarkReady помечает кэш готовым без прогрева, например после восстановления из снимка
+func (w *Warmer) MarkReady() {
+	w.doneOnce.Do(func() {
+		w.ready.Store(true)
+		metrics.CacheWarmupReady.Set(1)
+		close(w.done)
+	})
+}
+
+// Run выполняет прогрев. Ошибки не фатальны: кэш лишь ускоряет чтение,
+// поэтому готовность выставляется в любом случае.
+func (w *Warmer) Run(ctx context.Context) {
+	defer w.MarkReady()
+
+	start := time.Now()
+	loaded, err := w.warm(ctx)
+	duration := time.Since(start)
+	metrics.CacheWarmupDuration.Set(duration.Seconds())
+
+	if err != nil {
+		slog.WarnContext(ctx, "Прогрев кэша прерван",
+			slog.String("strategy", string(w.strategy)), slog.Int("loaded", loaded), logging.Err(err))
+		return
+	}
+	slog.InfoContext(ctx, "Кэш прогрет",
+		slog.String("strategy", string(w.strategy)), slog.Int("loaded", loaded), slog.Duration("duration", duration.Round(time.Millisecond)))
+}
+
+func (w *Warmer) warm(ctx context.Context) (int, error) {
+	metrics.CacheWarmupLoaded.Set(0)
+
+	var uids []string
+	var err error
+	switch w.strategy {
+	case StrategyNone:
+		metrics.CacheWarmupTarget.Set(0)
+		return 0, nil
+	case StrategyRecent:
+		uids, err = w.source.GetRecentOrderUIDs(w.limit)
+	case StrategyFrequent:
+		uids, err = w.source.GetMostAccessedOrderUIDs(w.limit)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("не удалось выбрать заказы: %w", err)
+	}
+	metrics.CacheWarmupTarget.Set(float64(len(uids)))
+
+	// заказы читаются пачками по batchSize. BulkSet не перезаписывает ключи, уже лежащие в кэше:
+	// записанное консьюмером после начала прогрева свежее прочитанного прогревом
+	loaded := 0
+	for start := 0; start < len(uids); start += batchSize {
+		if err := ctx.Err(); err != nil {
+			return loaded, err
+		}
+		chunk := uids[start:min(start+batchSize, len(uids))]
+		batch, err := w.source.OrdersByUID(ctx, chunk)
+		if err != nil {
+			if ctx.Err() != nil {
+				return loaded, ctx.Err()
+			}
+			slog.WarnContext(ctx, "Не удалось загрузить пачку заказов при прогреве",
+				slog.Int("batch", len(chunk)), logging.Err(err))
+			continue
+		}
+		if missing := len(chunk) - len(batch); missing > 0 {
+			slog.WarnContext(ctx, "Часть заказов для прогрева не найдена", slog.Int("missing", missing))
+		}
+		w.cache.BulkSet(batch)
+		loaded += len(batch)
+		metrics.CacheWarmupLoaded.Set(float64(loaded))
+	}
+	return loaded, nil
+}
@@ -0,0 +1,217 @@
+// internal/warmup/warmup_test.go
+package warmup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"order-service/internal/cache"
+	"order-service/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeSource struct {
+	recent   []string
+	frequent []string
+	missing  map[string]bool
+	listErr  error
+	gate     chan struct{}
+	batches  atomic.Int32
+}
+
+func (f *fakeSource) OrdersByUID(ctx context.Context, uids []string) (map[string]*models.Order, error) {
+	if f.gate != nil {
+		<-f.gate
+	}
+	f.batches.Add(1)
+	orders := make(map[string]*models.Order, len(uids))
+	for _, uid := range uids {
+		if !f.missing[uid] {
+			orders[uid] = &models.Order{OrderUID: uid, TrackNumber: "warm"}
+		}
+	}
+	return orders, nil
+}
+
+func (f *fakeSource) GetRecentOrderUIDs(limit int) ([]string, error) {
+	return f.recent, f.listErr
+}
+
+func (f *fakeSource) GetMostAccessedOrderUIDs(limit int) ([]string, error) {
+	return f.frequent, f.listErr
+}
+
+func uids(n int) []string {
+	out := make([]string, n)
+	for i := range out {
+		out[i] = fmt.Sprintf("order%d", i)
+	}
+	return out
+}
+
+func newTestCache(t *testing.T) *cache.Cache {
+	c := cache.New(5*time.Minute, 1000)
+	t.Cleanup(func() { c.Close() })
+	return c
+}
+
+func TestNew_UnknownStrategy(t *testing.T) {
+	_, err := New(nil, nil, "bogus", 10)
+	assert.Error(t, err)
+}
+
+func TestWarmer_Recent(t *testing.T) {
+	c := newTestCache(t)
+	src := &fakeSource{recent: uids(250), missing: map[string]bool{"order7": true}}
+	w, err := New(c, src, StrategyRecent, 250)
+	require.NoError(t, err)
+
+	w.Run(context.Background())
+
+	assert.True(t, w.Ready())
+	assert.Equal(t, 249, c.Len(), "недоступные заказы пропускаются")
+	assert.Equal(t, int32(3), src.batches.Load(), "заказы читаются пачками по batchSize")
+}
+
+func TestWarmer_KeepsFresherEntries(t *testing.T) {
+	c := newTestCache(t)
+	// заказ записан консьюмером, пока прогрев читал БД
+	c.Set("order1", &models.Order{OrderUID: "order1", TrackNumber: "fresh"})
+	w, err := New(c, &fakeSource{recent: uids(3)}, StrategyRecent, 3)
+	require.NoError(t, err)
+
+	w.Run(context.Background())
+
+	order, found := c.Get("order1")
+	require.True(t, found)
+	assert.Equal(t, "fresh", order.TrackNumber)
+	assert.Equal(t, 3, c.Len())
+}
+
+func TestWarmer_Frequent(t *testing.T) {
+	c := newTestCache(t)
+	src := &fakeSource{recent: uids(10), frequent: []string{"hot1", "hot2"}}
+	w, err := New(c, src, StrategyFrequent, 10)
+	require.NoError(t, err)
+
+	w.Run(context.Background())
+
+	assert.Equal(t, 2, c.Len())
+	_, found := c.Get("hot1")
+	assert.True(t, found)
+}
+
+func TestWarmer_None(t *testing.T) {
+	c := newTestCache(t)
+	w, err := New(c, &fakeSource{recent: uids(10)}, StrategyNone, 10)
+	require.NoError(t, err)
+
+	w.Run(context.Background())
+
+	assert.True(t, w.Ready())
+	assert.Equal(t, 0, c.Len())
+}
+
+func TestWarmer_ReadyAfterListError(t *testing.T) {
+	c := newTestCache(t)
+	w, err := New(c, &fakeSource{listErr: errors.New("db down")}, StrategyRecent, 10)
+	require.NoError(t, err)
+
+	w.Run(context.Background())
+
+	assert.True(t, w.Ready(), "ошибка прогрева не должна блокировать готовность")
+}
+
+func TestWarmer_NotReadyUntilDone(t *testing.T) {
+	c := newTestCache(t)
+	src := &fakeSource{recent: uids(3), gate: make(chan struct{})}
+	w, err := New(c, src, StrategyRecent, 3)
+	require.NoError(t, err)
+
+	go w.Run(context.Background())
+	assert.False(t, w.Ready())
+
+	close(src.gate)
+	select {
+	case <-w.Done():
+	case <-time.After(time.Second):
+		t.Fatal("прогрев не завершился")
+	}
+	assert.True(t, w.Ready())
+	assert.Equal(t, 3, c.Len())
+}
+
+func TestWarmer_StopsOnContextCancel(t *testing.T) {
+	c := newTestCache(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	w, err := New(c, &fakeSource{recent: uids(10)}, StrategyRecent, 10)
+	require.NoError(t, err)
+
+	w.Run(ctx)
+
+	assert.True(t, w.Ready())
+	assert.Equal(t, 0, c.Len())
+}
+
+func TestWarmer_MarkReadyIdempotent(t *testing.T) {
+	w, err := New(nil, nil, StrategyNone, 0)
+	require.NoError(t, err)
+
+	w.MarkReady()
+	w.MarkReady()
+
+	assert.True(t, w.Ready())
+}
+
+type fakeAccessStore struct {
+	mu    sync.Mutex
+	calls []map[string]int
+}
+
+func (f *fakeAccessStore) RecordOrderAccesses(hits map[string]int) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls = append(f.calls, hits)
+	return nil
+}
+
+func TestAccessRecorder_Flush(t *testing.T) {
+	store := &fakeAccessStore{}
+	rec := NewAccessRecorder(store, time.Hour)
+
+	rec.RecordAccess("test1")
+	rec.RecordAccess("test1")
+	rec.RecordAccess("test2")
+	rec.Flush()
+	rec.Flush()
+
+	require.Len(t, store.calls, 1, "пустой сброс не должен ходить в БД")
+	assert.Equal(t, map[string]int{"test1": 2, "test2": 1}, store.calls[0])
+}
+
+func TestAccessRecorder_FlushOnStop(t *testing.T) {
+	store := &fakeAccessStore{}
+	rec := NewAccessRecorder(store, time.Hour)
+	rec.RecordAccess("test1")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		rec.Run(ctx)
+		close(done)
+	}()
+	cancel()
+	<-done
+
+	require.Len(t, store.calls, 1)
+	assert.Equal(t, 1, store.calls[0]["test1"])
+}
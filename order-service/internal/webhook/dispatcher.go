@@ -0,0 +1,258 @@
+// internal/webhook/dispatcher.go
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"order-service/internal/config"
+	"order-service/internal/logging"
+	"order-service/internal/metrics"
+	"order-service/internal/pii"
+	"order-service/models"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// storeTimeout сколько ждать БД при чтении подписок и записи результата доставки
+const storeTimeout = 5 * time.Second
+
+// maxResponseBody сколько тела ответа подписчика читается для повторного использования соединения
+const maxResponseBody = 4 << 10
+
+type job struct {
+	ctx     context.Context
+	payload Payload
+}
+
+// Dispatcher рассылает события подписчикам в фоне: Notify только ставит заказ в очередь,
+// Run отправляет подписанные запросы с повторами. Очередь в памяти, при остановке
+// неотправленные события теряются
+type Dispatcher struct {
+	store  Store
+	cfg    config.WebhookConfig
+	client *http.Client
+	tracer trace.Tracer
+	queue  chan job
+	now    func() time.Time
+}
+
+// New рассылка с настройками очереди, попыток и выключения подписок из cfg
+func New(store Store, cfg config.WebhookConfig, tracer trace.Tracer) *Dispatcher {
+	return &Dispatcher{
+		store: store,
+		cfg:   cfg,
+		client: &http.Client{
+			Timeout: cfg.Timeout,
+			// POST после редиректа превращается в GET, ответ 3xx считается ошибкой
+			CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+		},
+		tracer: tracer,
+		queue:  make(chan job, cfg.QueueSize),
+		now:    time.Now,
+	}
+}
+
+// Notify ставит событие о сохранённом заказе в очередь; при переполнении событие
+// отбрасывается и учитывается в метрике. У nil рассылки ничего не делает
+func (d *Dispatcher) Notify(ctx context.Context, event string, o *models.Order) {
+	if d == nil {
+		return
+	}
+	j := job{
+		// trace_id и order_uid остаются в логах доставки, отмена запроса её не прерывает
+		ctx:     context.WithoutCancel(ctx),
+		payload: Payload{ID: newEventID(), Event: event, CreatedAt: d.now().UTC(), Order: o},
+	}
+	select {
+	case d.queue <- j:
+	default:
+		metrics.WebhookDeliveries.WithLabelValues("dropped").Inc()
+		slog.WarnContext(ctx, "Очередь вебхуков переполнена, событие не отправлено", slog.String("event", event))
+	}
+}
+
+// NotifyStored события сохранения заказа: order.stored и, если статус товаров сменился, order.status_changed
+func (d *Dispatcher) NotifyStored(ctx context.Context, o *models.Order, statusChanged bool) {
+	d.Notify(ctx, EventOrderStored, o)
+	if statusChanged {
+		d.Notify(ctx, EventOrderStatusChanged, o)
+	}
+}
+
+// Run разбирает очередь, пока не отменён ctx. Одновременно идёт не больше cfg.Workers доставок;
+// когда все заняты, очередь копится. После отмены текущие попытки завершаются без повторов
+func (d *Dispatcher) Run(ctx context.Context) {
+	sem := make(chan struct{}, d.cfg.Workers)
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	for {
+		select {
+		case <-ctx.Done():
+			if n := len(d.queue); n > 0 {
+				slog.Warn("Остановка рассылки вебхуков, события в очереди не отправлены", slog.Int("events", n))
+			}
+			return
+		case j := <-d.queue:
+			subs, err := d.subscriptions(j)
+			if err != nil {
+				metrics.WebhookDeliveries.WithLabelValues("failed").Inc()
+				slog.ErrorContext(j.ctx, "Не удалось получить подписки вебхуков", logging.Err(err))
+				continue
+			}
+			for _, sub := range subs {
+				select {
+				case sem <- struct{}{}:
+				case <-ctx.Done():
+					return
+				}
+				wg.Add(1)
+				go func() {
+					defer func() {
+						<-sem
+						wg.Done()
+					}()
+					d.deliver(ctx, j, sub)
+				}()
+			}
+		}
+	}
+}
+
+// subscriptions подписки, которым нужно событие
+func (d *Dispatcher) subscriptions(j job) ([]Subscription, error) {
+	ctx, cancel := context.WithTimeout(j.ctx, storeTimeout)
+	defer cancel()
+	all, err := d.store.ActiveWebhooks(ctx)
+	if err != nil {
+		return nil, err
+	}
+	subs := all[:0]
+	for _, s := range all {
+		if s.Match(j.payload.Event, j.payload.Order) {
+			subs = append(subs, s)
+		}
+	}
+	return subs, nil
+}
+
+// deliver отправляет событие подписчику до cfg.MaxAttempts раз с растущими паузами
+// и записывает результат: успех сбрасывает счётчик неудач, исчерпанные попытки его увеличивают
+func (d *Dispatcher) deliver(ctx context.Context, j job, sub Subscription) {
+	spanCtx, span := d.tracer.Start(j.ctx, "webhook.deliver", trace.WithAttributes(
+		attribute.Int64("webhook.id", sub.ID),
+		attribute.String("webhook.event", j.payload.Event),
+	))
+	defer span.End()
+	log := slog.With(slog.Int64("webhook_id", sub.ID), slog.String("event_id", j.payload.ID))
+
+	payload := j.payload
+	if !sub.IncludePII {
+		payload.Order = pii.Mask(payload.Order)
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "ошибка сериализации события")
+		log.ErrorContext(spanCtx, "Не удалось сериализовать событие вебхука", logging.Err(err))
+		return
+	}
+
+	for attempt := 1; ; attempt++ {
+		err = d.post(spanCtx, sub, payload, body)
+		if err == nil {
+			break
+		}
+		span.RecordError(err)
+		if attempt == d.cfg.MaxAttempts {
+			break
+		}
+		delay := d.backoff(attempt)
+		metrics.WebhookDeliveries.WithLabelValues("retry").Inc()
+		log.DebugContext(spanCtx, "Вебхук не доставлен, повтор", logging.Err(err),
+			slog.Int("attempt", attempt), slog.Duration("delay", delay))
+
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			// при остановке неудача не засчитывается подписке: её вины в этом нет
+			span.SetStatus(codes.Error, "рассылка остановлена")
+			log.WarnContext(spanCtx, "Остановка рассылки, вебхук не доставлен", logging.Err(err))
+			return
+		}
+	}
+
+	storeCtx, cancel := context.WithTimeout(spanCtx, storeTimeout)
+	defer cancel()
+	if err == nil {
+		metrics.WebhookDeliveries.WithLabelValues("delivered").Inc()
+		span.SetStatus(codes.Ok, "доставлен")
+		if err := d.store.WebhookDelivered(storeCtx, sub.ID); err != nil {
+			log.ErrorContext(spanCtx, "Не удалось записать доставку вебхука", logging.Err(err))
+		}
+		return
+	}
+
+	metrics.WebhookDeliveries.WithLabelValues("failed").Inc()
+	span.SetStatus(codes.Error, "попытки исчерпаны")
+	log.WarnContext(spanCtx, "Вебхук не доставлен", logging.Err(err), slog.Int("attempts", d.cfg.MaxAttempts))
+	disabled, storeErr := d.store.WebhookFailed(storeCtx, sub.ID, err.Error(), d.cfg.MaxFailures)
+	if storeErr != nil {
+		log.ErrorContext(spanCtx, "Не удалось записать неудачу вебхука", logging.Err(storeErr))
+		return
+	}
+	if disabled {
+		metrics.WebhooksDisabled.Inc()
+		log.WarnContext(spanCtx, "Подписка выключена после неудачных доставок подряд",
+			slog.String("url", sub.URL), slog.Int("max_failures", d.cfg.MaxFailures))
+	}
+}
+
+// post одна попытка; ошибка — сетевой сбой или ответ не 2xx
+func (d *Dispatcher) post(ctx context.Context, sub Subscription, p Payload, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, sub.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	ts := d.now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "order-service-webhook")
+	req.Header.Set(HeaderID, p.ID)
+	req.Header.Set(HeaderEvent, p.Event)
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(ts, 10))
+	req.Header.Set(HeaderSignature, Sign(sub.Secret, ts, body))
+
+	start := time.Now()
+	resp, err := d.client.Do(req)
+	metrics.WebhookResponseTime.Observe(time.Since(start).Seconds())
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, maxResponseBody))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("подписчик ответил %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// backoff пауза после attempt-й неудачной попытки: InitialBackoff, дальше вдвое больше, не выше MaxBackoff
+func (d *Dispatcher) backoff(attempt int) time.Duration {
+	delay := d.cfg.InitialBackoff
+	for i := 1; i < attempt && delay < d.cfg.MaxBackoff; i++ {
+		delay *= 2
+	}
+	return min(delay, d.cfg.MaxBackoff)
+}
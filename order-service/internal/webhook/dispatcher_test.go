@@ -0,0 +1,339 @@
+// internal/webhook/dispatcher_test.go
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"order-service/internal/config"
+	"order-service/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+// memStore подписки в памяти с той же логикой счётчика неудач, что и в PostgreSQL
+type memStore struct {
+	mu        sync.Mutex
+	subs      []Subscription
+	delivered map[int64]int
+	changed   chan struct{}
+	listErr   error
+}
+
+func newMemStore(subs ...Subscription) *memStore {
+	return &memStore{subs: subs, delivered: make(map[int64]int), changed: make(chan struct{}, 100)}
+}
+
+func (s *memStore) ActiveWebhooks(ctx context.Context) ([]Subscription, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.listErr != nil {
+		return nil, s.listErr
+	}
+	var active []Subscription
+	for _, sub := range s.subs {
+		if sub.DisabledAt == nil {
+			active = append(active, sub)
+		}
+	}
+	return active, nil
+}
+
+func (s *memStore) WebhookDelivered(ctx context.Context, id int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.delivered[id]++
+	s.find(id).Failures = 0
+	s.changed <- struct{}{}
+	return nil
+}
+
+func (s *memStore) WebhookFailed(ctx context.Context, id int64, lastError string, maxFailures int) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sub := s.find(id)
+	sub.Failures++
+	sub.LastError = lastError
+	disabled := maxFailures > 0 && sub.Failures >= maxFailures
+	if disabled {
+		now := time.Now()
+		sub.DisabledAt = &now
+	}
+	s.changed <- struct{}{}
+	return disabled, nil
+}
+
+func (s *memStore) find(id int64) *Subscription {
+	for i := range s.subs {
+		if s.subs[i].ID == id {
+			return &s.subs[i]
+		}
+	}
+	panic("подписка не найдена")
+}
+
+func (s *memStore) get(id int64) Subscription {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return *s.find(id)
+}
+
+// wait ждёт n записей результата доставки
+func (s *memStore) wait(t *testing.T, n int) {
+	t.Helper()
+	for i := 0; i < n; i++ {
+		select {
+		case <-s.changed:
+		case <-time.After(5 * time.Second):
+			t.Fatal("результат доставки не записан")
+		}
+	}
+}
+
+func testConfig() config.WebhookConfig {
+	cfg := config.Default().Webhook
+	cfg.MaxAttempts = 3
+	cfg.InitialBackoff = time.Millisecond
+	cfg.MaxBackoff = 4 * time.Millisecond
+	cfg.MaxFailures = 2
+	return cfg
+}
+
+func startDispatcher(t *testing.T, store Store, cfg config.WebhookConfig) *Dispatcher {
+	d := New(store, cfg, noop.NewTracerProvider().Tracer("test"))
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		d.Run(ctx)
+		close(done)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+	return d
+}
+
+func testOrder(uid, customer string) *models.Order {
+	return &models.Order{
+		OrderUID:        uid,
+		CustomerID:      customer,
+		DeliveryService: "meest",
+		Delivery:        models.Delivery{Name: "Test Testov", Phone: "+9720000000", Email: "test@gmail.com"},
+	}
+}
+
+type received struct {
+	header  http.Header
+	body    []byte
+	payload Payload
+}
+
+// receiver сервер партнёра: отвечает статусами из statuses по очереди, дальше 200
+func receiver(t *testing.T, statuses ...int) (*httptest.Server, chan received) {
+	ch := make(chan received, 10)
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		var p Payload
+		assert.NoError(t, json.Unmarshal(body, &p))
+		ch <- received{header: r.Header, body: body, payload: p}
+		if n := int(calls.Add(1)); n <= len(statuses) {
+			w.WriteHeader(statuses[n-1])
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv, ch
+}
+
+func next(t *testing.T, ch chan received) received {
+	t.Helper()
+	select {
+	case r := <-ch:
+		return r
+	case <-time.After(5 * time.Second):
+		t.Fatal("вебхук не пришёл")
+		return received{}
+	}
+}
+
+func TestDispatcher_SignedDelivery(t *testing.T) {
+	srv, ch := receiver(t)
+	store := newMemStore(Subscription{ID: 1, URL: srv.URL, Secret: "whsec_test", CustomerID: "alice"})
+	d := startDispatcher(t, store, testConfig())
+
+	d.Notify(context.Background(), EventOrderStored, testOrder("other", "bob"))
+	d.Notify(context.Background(), EventOrderStored, testOrder("wanted", "alice"))
+
+	// заказ bob не подходит под фильтр, первым приходит заказ alice
+	r := next(t, ch)
+	assert.Equal(t, "wanted", r.payload.Order.OrderUID)
+	assert.Equal(t, EventOrderStored, r.payload.Event)
+	assert.Equal(t, r.payload.ID, r.header.Get(HeaderID))
+	assert.Equal(t, EventOrderStored, r.header.Get(HeaderEvent))
+	assert.Equal(t, "application/json", r.header.Get("Content-Type"))
+
+	ts, err := strconv.ParseInt(r.header.Get(HeaderTimestamp), 10, 64)
+	require.NoError(t, err)
+	assert.True(t, Verify("whsec_test", r.header.Get(HeaderSignature), ts, r.body, DefaultTolerance))
+	// без include_pii персональные данные скрыты
+	assert.NotEqual(t, "Test Testov", r.payload.Order.Delivery.Name)
+
+	store.wait(t, 1)
+	assert.Equal(t, 1, store.delivered[1])
+}
+
+func TestDispatcher_NotifyStored(t *testing.T) {
+	srv, ch := receiver(t)
+	// подписка только на смену статуса
+	store := newMemStore(Subscription{ID: 1, URL: srv.URL, Secret: "s", Events: []string{EventOrderStatusChanged}})
+	d := startDispatcher(t, store, testConfig())
+
+	d.NotifyStored(context.Background(), testOrder("same", "alice"), false)
+	d.NotifyStored(context.Background(), testOrder("changed", "alice"), true)
+
+	r := next(t, ch)
+	assert.Equal(t, "changed", r.payload.Order.OrderUID)
+	assert.Equal(t, EventOrderStatusChanged, r.payload.Event)
+	store.wait(t, 1)
+	select {
+	case r := <-ch:
+		t.Fatalf("лишнее событие %s по заказу %s", r.payload.Event, r.payload.Order.OrderUID)
+	default:
+	}
+}
+
+func TestDispatcher_IncludePII(t *testing.T) {
+	srv, ch := receiver(t)
+	store := newMemStore(Subscription{ID: 1, URL: srv.URL, Secret: "s", IncludePII: true})
+	d := startDispatcher(t, store, testConfig())
+
+	d.Notify(context.Background(), EventOrderStored, testOrder("a", "alice"))
+	assert.Equal(t, "Test Testov", next(t, ch).payload.Order.Delivery.Name)
+}
+
+func TestDispatcher_Retry(t *testing.T) {
+	srv, ch := receiver(t, http.StatusInternalServerError, http.StatusBadGateway)
+	store := newMemStore(Subscription{ID: 1, URL: srv.URL, Secret: "s", Failures: 1})
+	d := startDispatcher(t, store, testConfig())
+
+	d.Notify(context.Background(), EventOrderStored, testOrder("a", "alice"))
+
+	// третья попытка успешна, событие то же самое
+	first := next(t, ch)
+	assert.Equal(t, first.payload.ID, next(t, ch).payload.ID)
+	assert.Equal(t, first.payload.ID, next(t, ch).payload.ID)
+
+	store.wait(t, 1)
+	assert.Equal(t, 1, store.delivered[1])
+	assert.Zero(t, store.get(1).Failures)
+}
+
+func TestDispatcher_AutoDisable(t *testing.T) {
+	srv, ch := receiver(t, 500, 500, 500, 500, 500, 500)
+	healthy, healthyCh := receiver(t)
+	store := newMemStore(
+		Subscription{ID: 1, URL: srv.URL, Secret: "s"},
+		Subscription{ID: 2, URL: healthy.URL, Secret: "s"},
+	)
+	d := startDispatcher(t, store, testConfig())
+
+	// каждое событие — три попытки, после двух недоставленных подписка выключается
+	d.Notify(context.Background(), EventOrderStored, testOrder("a", "alice"))
+	d.Notify(context.Background(), EventOrderStored, testOrder("b", "alice"))
+	store.wait(t, 4)
+	assert.Len(t, ch, 6)
+
+	sub := store.get(1)
+	assert.Equal(t, 2, sub.Failures)
+	assert.Equal(t, "подписчик ответил 500", sub.LastError)
+	require.NotNil(t, sub.DisabledAt)
+
+	// выключенной подписке больше не отправляется, соседняя работает
+	d.Notify(context.Background(), EventOrderStored, testOrder("c", "alice"))
+	store.wait(t, 1)
+	assert.Len(t, ch, 6)
+	assert.Len(t, healthyCh, 3)
+}
+
+func TestDispatcher_Unreachable(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	srv.Close()
+	store := newMemStore(Subscription{ID: 1, URL: srv.URL, Secret: "s"})
+	cfg := testConfig()
+	cfg.MaxFailures = 0
+	d := startDispatcher(t, store, cfg)
+
+	d.Notify(context.Background(), EventOrderStored, testOrder("a", "alice"))
+	store.wait(t, 1)
+
+	// max_failures 0 — подписка не выключается
+	sub := store.get(1)
+	assert.Equal(t, 1, sub.Failures)
+	assert.NotEmpty(t, sub.LastError)
+	assert.Nil(t, sub.DisabledAt)
+}
+
+func TestDispatcher_Redirect(t *testing.T) {
+	target, ch := receiver(t)
+	redirect := httptest.NewServer(http.RedirectHandler(target.URL, http.StatusFound))
+	defer redirect.Close()
+	store := newMemStore(Subscription{ID: 1, URL: redirect.URL, Secret: "s"})
+	d := startDispatcher(t, store, testConfig())
+
+	// редирект не выполняется: событие считается недоставленным
+	d.Notify(context.Background(), EventOrderStored, testOrder("a", "alice"))
+	store.wait(t, 1)
+	assert.Equal(t, "подписчик ответил 302", store.get(1).LastError)
+	assert.Empty(t, ch)
+}
+
+func TestDispatcher_QueueFull(t *testing.T) {
+	store := newMemStore()
+	cfg := testConfig()
+	cfg.QueueSize = 1
+	// Run не запущен, очередь не разбирается
+	d := New(store, cfg, noop.NewTracerProvider().Tracer("test"))
+
+	d.Notify(context.Background(), EventOrderStored, testOrder("a", "alice"))
+	assert.NotPanics(t, func() { d.Notify(context.Background(), EventOrderStored, testOrder("b", "alice")) })
+	assert.Len(t, d.queue, 1)
+}
+
+func TestDispatcher_StoreError(t *testing.T) {
+	srv, ch := receiver(t)
+	store := newMemStore(Subscription{ID: 1, URL: srv.URL, Secret: "s"})
+	store.listErr = errors.New("БД недоступна")
+	d := startDispatcher(t, store, testConfig())
+
+	d.Notify(context.Background(), EventOrderStored, testOrder("a", "alice"))
+	time.Sleep(50 * time.Millisecond)
+	assert.Empty(t, ch)
+}
+
+func TestDispatcher_Nil(t *testing.T) {
+	var d *Dispatcher
+	assert.NotPanics(t, func() { d.Notify(context.Background(), EventOrderStored, testOrder("a", "alice")) })
+}
+
+func TestDispatcher_Backoff(t *testing.T) {
+	d := New(newMemStore(), config.WebhookConfig{InitialBackoff: time.Second, MaxBackoff: 5 * time.Second},
+		noop.NewTracerProvider().Tracer("test"))
+
+	assert.Equal(t, time.Second, d.backoff(1))
+	assert.Equal(t, 2*time.Second, d.backoff(2))
+	assert.Equal(t, 4*time.Second, d.backoff(3))
+	assert.Equal(t, 5*time.Second, d.backoff(4))
+	assert.Equal(t, 5*time.Second, d.backoff(40))
+}
@@ -0,0 +1,124 @@
+// internal/webhook/webhook.go
+package webhook
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"slices"
+	"strconv"
+	"time"
+
+	"order-service/internal/pubsub"
+	"order-service/models"
+)
+
+// События рассылки
+const (
+	// EventOrderStored заказ сохранён из Kafka или API, в том числе повторно с изменениями
+	EventOrderStored = "order.stored"
+	// EventOrderStatusChanged при повторном сохранении у заказа сменился статус хотя бы одного товара
+	EventOrderStatusChanged = "order.status_changed"
+)
+
+// KnownEvents события, на которые можно подписаться
+var KnownEvents = []string{EventOrderStored, EventOrderStatusChanged}
+
+// Заголовки запроса к подписчику
+const (
+	HeaderID        = "X-Webhook-ID"
+	HeaderEvent     = "X-Webhook-Event"
+	HeaderTimestamp = "X-Webhook-Timestamp"
+	HeaderSignature = "X-Webhook-Signature"
+)
+
+// secretPrefix отличает секрет подписки от API ключей в логах и конфигах партнёров
+const secretPrefix = "whsec_"
+
+// Subscription подписка партнёра из таблицы webhooks
+type Subscription struct {
+	ID     int64  `json:"id"`
+	URL    string `json:"url"`
+	Secret string `json:"-"`
+	// Events на какие события подписка; пусто — на все
+	Events          []string `json:"events"`
+	CustomerID      string   `json:"customer_id,omitempty"`
+	DeliveryService string   `json:"delivery_service,omitempty"`
+	// IncludePII отдавать персональные данные доставки без маскирования
+	IncludePII bool `json:"include_pii"`
+	// Failures сколько событий подряд не доставлено после всех попыток
+	Failures        int        `json:"failures"`
+	LastError       string     `json:"last_error,omitempty"`
+	LastDeliveredAt *time.Time `json:"last_delivered_at,omitempty"`
+	CreatedAt       time.Time  `json:"created_at"`
+	// DisabledAt когда подписка выключена после MaxFailures неудач; nil — активна
+	DisabledAt *time.Time `json:"disabled_at,omitempty"`
+}
+
+// Match нужно ли отправить событие по заказу этому подписчику
+func (s *Subscription) Match(event string, o *models.Order) bool {
+	if len(s.Events) > 0 && !slices.Contains(s.Events, event) {
+		return false
+	}
+	return pubsub.Filter{CustomerID: s.CustomerID, DeliveryService: s.DeliveryService}.Match(o)
+}
+
+// Payload тело запроса к подписчику. ID одинаков во всех попытках и у всех подписчиков события,
+// по нему партнёр отбрасывает повторы
+type Payload struct {
+	ID        string        `json:"id"`
+	Event     string        `json:"event"`
+	CreatedAt time.Time     `json:"created_at"`
+	Order     *models.Order `json:"order"`
+}
+
+// Store подписки и их состояние доставки
+type Store interface {
+	// ActiveWebhooks подписки, не выключенные после неудач
+	ActiveWebhooks(ctx context.Context) ([]Subscription, error)
+	// WebhookDelivered сбрасывает счётчик неудач после успешной доставки
+	WebhookDelivered(ctx context.Context, id int64) error
+	// WebhookFailed учитывает недоставленное событие и выключает подписку, если неудач подряд
+	// набралось maxFailures (0 — не выключать); disabled — выключена ли она этим вызовом
+	WebhookFailed(ctx context.Context, id int64, lastError string, maxFailures int) (disabled bool, err error)
+}
+
+// Sign подпись тела: sha256=hex(HMAC-SHA256(secret, "<timestamp>.<body>")).
+// Время в подписи не даёт повторить перехваченный запрос позже
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// DefaultTolerance насколько timestamp запроса может расходиться с часами получателя
+const DefaultTolerance = 5 * time.Minute
+
+// Verify проверка подписи на стороне получателя; сравнение за постоянное время.
+// timestamp дальше tolerance от текущего времени отклоняется: перехваченный запрос нельзя повторить позже
+func Verify(secret, signature string, timestamp int64, body []byte, tolerance time.Duration) bool {
+	if d := time.Since(time.Unix(timestamp, 0)); d > tolerance || d < -tolerance {
+		return false
+	}
+	return hmac.Equal([]byte(signature), []byte(Sign(secret, timestamp, body)))
+}
+
+// GenerateSecret новый секрет подписки: whsec_ и 32 случайных байта в hex
+func GenerateSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return secretPrefix + hex.EncodeToString(b), nil
+}
+
+// newEventID идентификатор события для Payload.ID
+func newEventID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,63 @@
+// internal/webhook/webhook_test.go
+package webhook
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"order-service/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSignVerify(t *testing.T) {
+	body := []byte(`{"id":"1"}`)
+	ts := time.Now().Unix()
+	sig := Sign("whsec_test", ts, body)
+
+	assert.True(t, strings.HasPrefix(sig, "sha256="))
+	assert.True(t, Verify("whsec_test", sig, ts, body, DefaultTolerance))
+	// другой секрет, время или тело — подпись не сходится
+	assert.False(t, Verify("whsec_other", sig, ts, body, DefaultTolerance))
+	assert.False(t, Verify("whsec_test", sig, ts+1, body, DefaultTolerance))
+	assert.False(t, Verify("whsec_test", sig, ts, []byte(`{"id":"2"}`), DefaultTolerance))
+}
+
+func TestVerify_Tolerance(t *testing.T) {
+	body := []byte(`{"id":"1"}`)
+	for _, tc := range []struct {
+		name  string
+		shift time.Duration
+		ok    bool
+	}{
+		{"в пределах допуска", -4 * time.Minute, true},
+		{"старый запрос", -6 * time.Minute, false},
+		{"из будущего", 6 * time.Minute, false},
+	} {
+		ts := time.Now().Add(tc.shift).Unix()
+		sig := Sign("whsec_test", ts, body)
+		assert.Equal(t, tc.ok, Verify("whsec_test", sig, ts, body, DefaultTolerance), tc.name)
+	}
+}
+
+func TestSubscription_Match(t *testing.T) {
+	order := &models.Order{OrderUID: "a", CustomerID: "alice", DeliveryService: "meest"}
+
+	assert.True(t, (&Subscription{}).Match(EventOrderStored, order))
+	assert.True(t, (&Subscription{Events: []string{EventOrderStored}, CustomerID: "alice"}).Match(EventOrderStored, order))
+	assert.False(t, (&Subscription{Events: []string{"order.deleted"}}).Match(EventOrderStored, order))
+	assert.False(t, (&Subscription{DeliveryService: "dhl"}).Match(EventOrderStored, order))
+}
+
+func TestGenerateSecret(t *testing.T) {
+	a, err := GenerateSecret()
+	require.NoError(t, err)
+	b, err := GenerateSecret()
+	require.NoError(t, err)
+
+	assert.True(t, strings.HasPrefix(a, secretPrefix))
+	assert.Len(t, a, len(secretPrefix)+64)
+	assert.NotEqual(t, a, b)
+}
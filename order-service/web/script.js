@@ -1,3 +1,8 @@
+// ключ хранится только в этой вкладке браузера
+window.addEventListener('DOMContentLoaded', () => {
+    document.getElementById('apiKey').value = sessionStorage.getItem('apiKey') || '';
+});
+
 function fetchOrder() {
     const id = document.getElementById('orderId').value.trim();
     if (!id) {
@@ -5,14 +10,22 @@ function fetchOrder() {
         return;
     }
 
-    fetch('/order/' + encodeURIComponent(id))
+    const apiKey = document.getElementById('apiKey').value.trim();
+    sessionStorage.setItem('apiKey', apiKey);
+    const headers = apiKey ? { 'X-API-Key': apiKey } : {};
+
+    fetch('/order/' + encodeURIComponent(id), { headers })
         .then(resp => {
+            if (resp.status === 401) throw new Error('Нужен действующий API ключ');
+            if (resp.status === 403) throw new Error('У ключа нет доступа к заказам');
             if (!resp.ok) throw new Error('Заказ не найден');
-            return resp.json();
+            // маскирует сервер, страница только сообщает об этом
+            const masked = resp.headers.get('X-PII-Masked') === 'true';
+            return resp.json().then(data => ({ data, masked }));
         })
-        .then(data => {
+        .then(({ data, masked }) => {
             const result = document.getElementById('result');
-            result.innerHTML = renderOrder(data);
+            result.innerHTML = renderOrder(data, masked);
         })
         .catch(e => {
             const result = document.getElementById('result');
@@ -21,7 +34,7 @@ function fetchOrder() {
 }
 
 // Функция для форматирования и вывода данных заказа
-function renderOrder(order) {
+function renderOrder(order, masked) {
     function formatDate(ts) {
         const d = new Date(ts * 1000);
         return d.toLocaleString();
@@ -36,6 +49,7 @@ function renderOrder(order) {
     <h3>Заказ: ${order.order_uid}</h3>
     <strong>Трек номер:</strong> ${order.track_number}<br>
     <strong>Дата создания:</strong> ${formatISODate(order.date_created)}
+    ${masked ? '<p class="masked">Персональные данные скрыты: у ключа нет области pii:read</p>' : ''}
 
     <h4>Доставка</h4>
     Имя: ${order.delivery.name}<br>